@@ -11,19 +11,29 @@ import (
 	"time"
 
 	"bandwidth-monitor/dns"
+	"bandwidth-monitor/httpclient"
+	"bandwidth-monitor/integration"
 )
 
 // Client polls ADGuard Home's REST API for DNS statistics.
 type Client struct {
-	baseURL  string
-	user     string
-	pass     string
-	interval time.Duration
+	baseURL    string
+	user       string
+	pass       string
+	interval   time.Duration
+	httpClient *http.Client
 
-	mu    sync.RWMutex
-	stats *Stats
+	mu        sync.RWMutex
+	stats     *Stats
+	topLimit  int                  // entries kept in top-domain/client lists
+	upLatency map[string][]float64 // per-upstream avg-ms samples (see latency.go)
 
-	stopCh chan struct{}
+	// DHCP lease forwarding (see dhcp.go); set before Run
+	leaseSink LeaseSink
+	lastDHCP  time.Time
+
+	breaker *integration.Breaker
+	stopCh  chan struct{}
 }
 
 // Stats holds the latest snapshot from AdGuard Home /control/stats.
@@ -53,12 +63,26 @@ type Stats struct {
 // baseURL should be like "http://adguard.example.local" (no trailing slash).
 func New(baseURL, user, pass string, pollInterval time.Duration) *Client {
 	return &Client{
-		baseURL:  baseURL,
-		user:     user,
-		pass:     pass,
-		interval: pollInterval,
-		stopCh:   make(chan struct{}),
+		baseURL:    baseURL,
+		user:       user,
+		pass:       pass,
+		interval:   pollInterval,
+		topLimit:   10,
+		httpClient: httpclient.New(10 * time.Second),
+		breaker:    integration.NewBreaker("adguard"),
+		stopCh:     make(chan struct{}),
+	}
+}
+
+// SetTopLimit changes how many entries the top-domain and top-client
+// lists keep (default 10). Safe to call while the poll loop is running.
+func (c *Client) SetTopLimit(n int) {
+	if n <= 0 {
+		return
 	}
+	c.mu.Lock()
+	c.topLimit = n
+	c.mu.Unlock()
 }
 
 // Run starts the polling loop. Call in a goroutine.
@@ -85,46 +109,66 @@ func (c *Client) Stop() {
 	}
 }
 
+// poll attempts one fetch unless the circuit breaker is open; the
+// outcome feeds the breaker so a dead backend backs off instead of
+// logging on every tick.
 func (c *Client) poll() {
+	if !c.breaker.Allow() {
+		return
+	}
+	if err := c.pollOnce(); err != nil {
+		log.Printf("adguard: %v", err)
+		c.breaker.Failure(err)
+		return
+	}
+	c.breaker.Success()
+}
+
+func (c *Client) pollOnce() error {
 	url := c.baseURL + "/control/stats"
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		log.Printf("adguard: build request: %v", err)
-		return
+		return fmt.Errorf("build request: %w", err)
 	}
 	if c.user != "" {
 		req.SetBasicAuth(c.user, c.pass)
 	}
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		log.Printf("adguard: fetch stats: %v", err)
-		return
+		return fmt.Errorf("fetch stats: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		log.Printf("adguard: unexpected status %d: %s", resp.StatusCode, string(body))
-		return
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
 	}
 
 	var s Stats
 	if err := json.NewDecoder(resp.Body).Decode(&s); err != nil {
-		log.Printf("adguard: decode stats: %v", err)
-		return
+		return fmt.Errorf("decode stats: %w", err)
 	}
 
 	c.mu.Lock()
 	c.stats = &s
+	c.recordLatency(s.TopUpstreamsAvgTime)
 	c.mu.Unlock()
+
+	c.pollDHCP()
+	return nil
+}
+
+// Health reports the breaker's circuit state for /api/integrations.
+func (c *Client) Health() integration.BreakerStatus {
+	return c.breaker.Status()
 }
 
 // GetSummary returns a frontend-friendly summary, or nil if no data yet.
 func (c *Client) GetSummary() *dns.Summary {
 	c.mu.RLock()
 	s := c.stats
+	limit := c.topLimit
 	c.mu.RUnlock()
 	if s == nil {
 		return nil
@@ -142,14 +186,15 @@ func (c *Client) GetSummary() *dns.Summary {
 		BlockedTotal:   blockedTotal,
 		BlockedPercent: blockedPct,
 		AvgLatencyMs:   s.AvgProcessingTime * 1000,
-		TopQueried:     parseDomainEntries(s.TopQueriedDomains, 10),
-		TopBlocked:     parseDomainEntries(s.TopBlockedDomains, 10),
-		TopClients:     parseClientEntries(s.TopClients, 10),
+		TopQueried:     parseDomainEntries(s.TopQueriedDomains, limit),
+		TopBlocked:     parseDomainEntries(s.TopBlockedDomains, limit),
+		TopClients:     parseClientEntries(s.TopClients, limit),
 		Upstreams:      buildUpstreams(s.TopUpstreamsResponses, s.TopUpstreamsAvgTime),
 		QueriesSeries:  s.DNSQueries,
 		BlockedSeries:  s.BlockedFiltering,
 		TimeUnits:      s.TimeUnits,
 	}
+	c.fillLatency(sum.Upstreams)
 	return sum
 }
 
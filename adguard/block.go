@@ -0,0 +1,94 @@
+package adguard
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// accessList mirrors AdGuard Home's /control/access payloads.
+type accessList struct {
+	AllowedClients    []string `json:"allowed_clients"`
+	DisallowedClients []string `json:"disallowed_clients"`
+	BlockedHosts      []string `json:"blocked_hosts"`
+}
+
+// BlockDevice adds the IP to AdGuard's disallowed clients, cutting its
+// DNS resolution. Not a firewall — a device with a hardcoded resolver
+// slips past — but effective for the tablets this is aimed at. Used
+// for budget enforcement.
+func (c *Client) BlockDevice(ip string) error {
+	return c.setAccess(ip, true)
+}
+
+// UnblockDevice removes a block placed by BlockDevice.
+func (c *Client) UnblockDevice(ip string) error {
+	return c.setAccess(ip, false)
+}
+
+func (c *Client) setAccess(ip string, block bool) error {
+	var list accessList
+	if err := c.fetchAccessList(&list); err != nil {
+		return fmt.Errorf("fetch access list: %w", err)
+	}
+
+	kept := list.DisallowedClients[:0]
+	present := false
+	for _, d := range list.DisallowedClients {
+		if d == ip {
+			present = true
+			if !block {
+				continue
+			}
+		}
+		kept = append(kept, d)
+	}
+	list.DisallowedClients = kept
+	if block && !present {
+		list.DisallowedClients = append(list.DisallowedClients, ip)
+	} else if block == present {
+		return nil // already in the desired state
+	}
+
+	payload, _ := json.Marshal(list)
+	req, err := http.NewRequest("POST", c.baseURL+"/control/access/set", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.user != "" {
+		req.SetBasicAuth(c.user, c.pass)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("set access list: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("set access list: status %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+func (c *Client) fetchAccessList(list *accessList) error {
+	req, err := http.NewRequest("GET", c.baseURL+"/control/access/list", nil)
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	if c.user != "" {
+		req.SetBasicAuth(c.user, c.pass)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("status %d: %s", resp.StatusCode, string(body))
+	}
+	return json.NewDecoder(resp.Body).Decode(list)
+}
@@ -0,0 +1,84 @@
+package adguard
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// dhcpPollEvery throttles lease fetches: leases change far slower than
+// the stats poll runs.
+const dhcpPollEvery = 1 * time.Minute
+
+// Lease is one DHCP lease handed out by AdGuard Home's built-in server.
+type Lease struct {
+	MAC      string `json:"mac"`
+	IP       string `json:"ip"`
+	Hostname string `json:"hostname"`
+}
+
+// LeaseSink receives DHCP leases as they are observed; implemented by
+// the device inventory.
+type LeaseSink interface {
+	MergeLease(mac, ip, hostname string)
+}
+
+// SetLeaseSink enables DHCP lease polling and forwards leases to sink.
+// Safe to call while the poll loop is running.
+func (c *Client) SetLeaseSink(sink LeaseSink) {
+	c.mu.Lock()
+	c.leaseSink = sink
+	c.mu.Unlock()
+}
+
+// pollDHCP fetches /control/dhcp/status and merges active and static
+// leases into the sink. No-op unless a sink is set, and throttled to
+// dhcpPollEvery between fetches.
+func (c *Client) pollDHCP() {
+	c.mu.RLock()
+	sink := c.leaseSink
+	c.mu.RUnlock()
+	if sink == nil || time.Since(c.lastDHCP) < dhcpPollEvery {
+		return
+	}
+	c.lastDHCP = time.Now()
+
+	req, err := http.NewRequest("GET", c.baseURL+"/control/dhcp/status", nil)
+	if err != nil {
+		return
+	}
+	if c.user != "" {
+		req.SetBasicAuth(c.user, c.pass)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		log.Printf("adguard: fetch dhcp status: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		// Not acting as DHCP server (or older AdGuard); nothing to merge
+		return
+	}
+
+	var st struct {
+		Enabled      bool    `json:"enabled"`
+		Leases       []Lease `json:"leases"`
+		StaticLeases []Lease `json:"static_leases"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&st); err != nil {
+		log.Printf("adguard: decode dhcp status: %v", err)
+		return
+	}
+	if !st.Enabled {
+		return
+	}
+	for _, l := range append(st.Leases, st.StaticLeases...) {
+		if l.MAC == "" || l.IP == "" {
+			continue
+		}
+		sink.MergeLease(strings.ToLower(l.MAC), l.IP, l.Hostname)
+	}
+}
@@ -0,0 +1,87 @@
+package adguard
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"bandwidth-monitor/dns"
+)
+
+// AdGuard only reports a single average response time per upstream, so
+// the distribution is built locally: every poll contributes one sample
+// per upstream, bucketed over a rolling window. A flaky upstream shows
+// up as a fat tail that a lone average hides.
+
+var latencyBounds = []float64{10, 50, 100, 250, 1000} // bucket upper edges, ms
+
+const maxLatencySamples = 1440 // ~4h at the 10s default poll
+
+// recordLatency appends one avg-latency sample per upstream. Caller
+// holds c.mu.
+func (c *Client) recordLatency(avgEntries []map[string]float64) {
+	if c.upLatency == nil {
+		c.upLatency = make(map[string][]float64)
+	}
+	for _, m := range avgEntries {
+		for upstream, avgSec := range m {
+			s := append(c.upLatency[upstream], avgSec*1000)
+			if len(s) > maxLatencySamples {
+				s = s[len(s)-maxLatencySamples:]
+			}
+			c.upLatency[upstream] = s
+		}
+	}
+}
+
+// fillLatency decorates upstream stats with the locally bucketed
+// response-time distribution and percentiles.
+func (c *Client) fillLatency(ups []dns.UpstreamStat) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for i := range ups {
+		samples := c.upLatency[ups[i].Address]
+		if len(samples) == 0 {
+			continue
+		}
+		sorted := append([]float64(nil), samples...)
+		sort.Float64s(sorted)
+		ups[i].P50Ms = percentile(sorted, 50)
+		ups[i].P95Ms = percentile(sorted, 95)
+		hist := make(map[string]int, len(latencyBounds)+1)
+		for _, v := range samples {
+			hist[bucketLabel(v)]++
+		}
+		ups[i].Histogram = hist
+	}
+}
+
+// bucketLabel names the histogram bucket a sample falls in.
+func bucketLabel(ms float64) string {
+	prev := 0.0
+	for _, b := range latencyBounds {
+		if ms < b {
+			if prev == 0 {
+				return fmt.Sprintf("<%.0fms", b)
+			}
+			return fmt.Sprintf("%.0f-%.0fms", prev, b)
+		}
+		prev = b
+	}
+	return fmt.Sprintf(">%.0fms", prev)
+}
+
+// percentile returns the pth percentile of an already-sorted slice.
+func percentile(sorted []float64, pct float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(math.Ceil(pct/100*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
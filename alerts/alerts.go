@@ -0,0 +1,349 @@
+// Package alerts evaluates user-defined rules against the live
+// collectors — interface rates, talker country breakdowns, DNS blocking
+// and AP state — and fires webhook/ntfy/Slack/Discord notifications
+// when a condition holds for its configured duration. Alert state is
+// served at /api/alerts and rides the WebSocket payload, so the UI can
+// show firing alerts without polling.
+package alerts
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"bandwidth-monitor/collector"
+	"bandwidth-monitor/dns"
+	"bandwidth-monitor/events"
+	"bandwidth-monitor/talkers"
+	"bandwidth-monitor/unifi"
+)
+
+const defaultInterval = 30 * time.Second
+
+// Alert is the externally visible state of one rule instance (a rate
+// rule tracks one interface; ap_offline tracks each AP separately).
+type Alert struct {
+	Rule    string  `json:"rule"` // originating rule spec
+	Kind    string  `json:"kind"`
+	Target  string  `json:"target,omitempty"`
+	State   string  `json:"state"` // pending, firing or resolved
+	Value   float64 `json:"value,omitempty"`
+	Message string  `json:"message"`
+	// Since is when the condition first held; FiredAt/ResolvedAt track
+	// the last transition through each state. Unix milliseconds.
+	Since      int64 `json:"since,omitempty"`
+	FiredAt    int64 `json:"fired_at,omitempty"`
+	ResolvedAt int64 `json:"resolved_at,omitempty"`
+	// Muted marks an alert whose notifications are currently held by a
+	// quiet-hours window (see mutes.go); the state still updates.
+	Muted bool `json:"muted,omitempty"`
+
+	// suppressed remembers a firing edge that fell inside a quiet-hours
+	// window, so the notification can be delivered when the window ends
+	suppressed bool
+}
+
+// Engine periodically evaluates the configured rules.
+type Engine struct {
+	rules    []Rule
+	interval time.Duration
+
+	collector *collector.Collector
+	tracker   *talkers.Tracker
+	dnsProv   dns.Provider
+	unifi     *unifi.Client
+	bus       *events.Bus
+	notify    notifyConfig
+
+	mu         sync.RWMutex
+	state      map[string]*Alert // kind|target → current state
+	seen       map[string]bool   // countries observed (new_country)
+	seeded     bool              // first evaluation primes seen without firing
+	started    time.Time         // new_dest ignores first contacts before this
+	mutes      []Mute            // quiet-hours windows (see mutes.go)
+	nextMuteID int
+
+	stopCh chan struct{}
+}
+
+// New creates the engine; rules come from ParseRules. Optional sources
+// and notification sinks are attached with the setters before Run.
+func New(rules []Rule, c *collector.Collector, t *talkers.Tracker, interval time.Duration) *Engine {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	return &Engine{
+		rules:     rules,
+		interval:  interval,
+		collector: c,
+		tracker:   t,
+		state:     make(map[string]*Alert),
+		seen:      make(map[string]bool),
+		stopCh:    make(chan struct{}),
+	}
+}
+
+// SetDNSProvider attaches the DNS stats source for dns_block rules.
+// Call before Run.
+func (e *Engine) SetDNSProvider(p dns.Provider) { e.dnsProv = p }
+
+// SetUniFi attaches the controller client for ap_offline rules. Call
+// before Run.
+func (e *Engine) SetUniFi(c *unifi.Client) { e.unifi = c }
+
+// SetEventBus mirrors alert transitions onto the event bus. Call
+// before Run.
+func (e *Engine) SetEventBus(b *events.Bus) { e.bus = b }
+
+// SetNotify configures the notification sinks; any subset may be
+// empty. Call before Run.
+func (e *Engine) SetNotify(webhook, ntfy, slack, discord string) {
+	e.notify = notifyConfig{webhook: webhook, ntfy: ntfy, slack: slack, discord: discord}
+}
+
+func (e *Engine) Run() {
+	e.started = time.Now()
+	e.evaluate()
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			e.evaluate()
+		case <-e.stopCh:
+			return
+		}
+	}
+}
+
+func (e *Engine) Stop() {
+	select {
+	case <-e.stopCh:
+	default:
+		close(e.stopCh)
+	}
+}
+
+// GetAll returns every tracked alert, firing first, then by target.
+func (e *Engine) GetAll() []Alert {
+	e.mu.RLock()
+	out := make([]Alert, 0, len(e.state))
+	for _, a := range e.state {
+		out = append(out, *a)
+	}
+	e.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool {
+		if (out[i].State == "firing") != (out[j].State == "firing") {
+			return out[i].State == "firing"
+		}
+		if out[i].Kind != out[j].Kind {
+			return out[i].Kind < out[j].Kind
+		}
+		return out[i].Target < out[j].Target
+	})
+	return out
+}
+
+func (e *Engine) evaluate() {
+	now := time.Now()
+	for _, r := range e.rules {
+		switch r.Kind {
+		case KindRate:
+			e.evalRate(r, now)
+		case KindDNSBlock:
+			e.evalDNSBlock(r, now)
+		case KindNewCountry:
+			e.evalNewCountry(r, now)
+		case KindNewDest:
+			e.evalNewDest(r, now)
+		case KindAPOffline:
+			e.evalAPOffline(r, now)
+		case KindVPNLeak:
+			e.evalVPNLeak(r, now)
+		}
+	}
+	e.mu.Lock()
+	e.seeded = true
+	e.mu.Unlock()
+}
+
+func (e *Engine) evalRate(r Rule, now time.Time) {
+	if e.collector == nil {
+		return
+	}
+	for _, st := range e.collector.GetAll() {
+		if st.Name != r.Target {
+			continue
+		}
+		rate := st.RxRate + st.TxRate
+		msg := fmt.Sprintf("%s at %.0f B/s (threshold %.0f B/s for %s)", r.Target, rate, r.Threshold, r.For)
+		e.transition(r, r.Target, rate > r.Threshold, rate, msg, now)
+		return
+	}
+}
+
+func (e *Engine) evalDNSBlock(r Rule, now time.Time) {
+	if e.dnsProv == nil {
+		return
+	}
+	s := e.dnsProv.GetSummary()
+	if s == nil {
+		return
+	}
+	msg := fmt.Sprintf("DNS blocking at %.1f%% (threshold %.1f%% for %s)", s.BlockedPercent, r.Threshold, r.For)
+	e.transition(r, "dns", s.BlockedPercent > r.Threshold, s.BlockedPercent, msg, now)
+}
+
+func (e *Engine) evalNewCountry(r Rule, now time.Time) {
+	if e.tracker == nil {
+		return
+	}
+	for _, c := range e.tracker.GetCountryBreakdown(0) {
+		e.mu.RLock()
+		known := e.seen[c.Country]
+		seeded := e.seeded
+		e.mu.RUnlock()
+		if known {
+			// Clears the one-shot: the country is no longer new
+			e.transition(r, c.Country, false, 0, "", now)
+			continue
+		}
+		e.mu.Lock()
+		e.seen[c.Country] = true
+		e.mu.Unlock()
+		// The first evaluation primes the seen set from existing
+		// traffic; alerting on all of it would just be noise
+		if !seeded {
+			continue
+		}
+		msg := fmt.Sprintf("traffic to new country %s (%s)", c.Country, c.CountryName)
+		e.transition(r, c.Country, true, float64(c.Bytes), msg, now)
+	}
+}
+
+func (e *Engine) evalVPNLeak(r Rule, now time.Time) {
+	if e.tracker == nil {
+		return
+	}
+	for _, l := range e.tracker.GetLeaks() {
+		// A leak stays firing while packets keep arriving; five quiet
+		// minutes resolves it
+		active := now.UnixMilli()-l.LastSeen < (5 * time.Minute).Milliseconds()
+		msg := fmt.Sprintf("VPN leak: %s sent %d packet(s) in cleartext on the WAN", l.IP, l.Packets)
+		e.transition(r, l.IP, active, float64(l.Bytes), msg, now)
+	}
+}
+
+func (e *Engine) evalNewDest(r Rule, now time.Time) {
+	if e.tracker == nil {
+		return
+	}
+	// Hosts already talking when the engine started aren't news; the
+	// tracker persists first-contact times, so a genuinely new host
+	// from just before a restart was alerted on by the previous run.
+	since := e.started
+	if cutoff := now.Add(-2 * r.For); cutoff.After(since) {
+		since = cutoff
+	}
+	// r.For is the stay-up window here, not a hold duration, so the
+	// transition fires immediately and resolves once the window passes
+	oneShot := r
+	oneShot.For = 0
+	for _, fc := range e.tracker.FirstContactsSince(since) {
+		fresh := now.UnixMilli()-fc.FirstSeen < r.For.Milliseconds()
+		msg := fmt.Sprintf("first contact with new destination %s", fc.IP)
+		e.transition(oneShot, fc.IP, fresh, 0, msg, now)
+	}
+}
+
+func (e *Engine) evalAPOffline(r Rule, now time.Time) {
+	if e.unifi == nil {
+		return
+	}
+	s := e.unifi.GetSummary()
+	if s == nil {
+		return
+	}
+	for _, ap := range s.APs {
+		name := ap.Name
+		if name == "" {
+			name = ap.MAC
+		}
+		msg := fmt.Sprintf("AP %s is %s", name, ap.Status)
+		e.transition(r, name, ap.Status != "connected", 0, msg, now)
+	}
+}
+
+// transition applies one observation to the rule instance's state
+// machine: pending → firing once the condition has held for r.For,
+// firing → resolved when it clears. Notifications fire on both edges,
+// unless a quiet-hours mute holds them (see mutes.go).
+func (e *Engine) transition(r Rule, target string, holds bool, value float64, msg string, now time.Time) {
+	key := r.Kind + "|" + target
+
+	e.mu.Lock()
+	a, ok := e.state[key]
+	if !ok {
+		if !holds {
+			e.mu.Unlock()
+			return
+		}
+		a = &Alert{Rule: r.Spec, Kind: r.Kind, Target: target}
+		e.state[key] = a
+	}
+	muted := e.mutedLocked(r.Kind, target, now)
+	a.Muted = muted
+
+	var fired, resolved bool
+	if holds {
+		a.Value = value
+		a.Message = msg
+		if a.Since == 0 {
+			a.Since = now.UnixMilli()
+			if a.State != "firing" {
+				a.State = "pending"
+			}
+		}
+		if a.State != "firing" && now.UnixMilli()-a.Since >= r.For.Milliseconds() {
+			a.State = "firing"
+			a.FiredAt = now.UnixMilli()
+			if muted {
+				a.suppressed = true
+			} else {
+				fired = true
+			}
+		} else if a.State == "firing" && a.suppressed && !muted {
+			// Quiet hours ended with the condition still live — deliver
+			// the held notification now
+			a.suppressed = false
+			fired = true
+		}
+	} else {
+		if a.State == "firing" {
+			a.State = "resolved"
+			a.ResolvedAt = now.UnixMilli()
+			// Resolutions are held too, and one whose firing was never
+			// delivered needs no resolution at all
+			resolved = !muted && !a.suppressed
+			a.suppressed = false
+		} else if a.State == "pending" {
+			a.State = "resolved"
+		}
+		a.Since = 0
+	}
+	snapshot := *a
+	e.mu.Unlock()
+
+	if fired {
+		log.Printf("alerts: firing: %s", snapshot.Message)
+		e.bus.Publish("alerts", "alert_firing", target, snapshot.Message)
+		e.send(snapshot, false)
+	}
+	if resolved {
+		log.Printf("alerts: resolved: %s", snapshot.Message)
+		e.bus.Publish("alerts", "alert_resolved", target, snapshot.Message)
+		e.send(snapshot, true)
+	}
+}
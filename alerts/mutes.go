@@ -0,0 +1,93 @@
+package alerts
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"bandwidth-monitor/schedule"
+)
+
+// Quiet hours: a mute suppresses notifications for matching alerts
+// during recurring windows — "don't page about the backup server
+// between 01:00 and 05:00" — without touching the rule itself. The
+// state machine keeps running while muted, so /api/alerts still shows
+// the truth; only the outbound noise is held. A firing that is still
+// live when its window ends is delivered then, so a real incident
+// survives the quiet hours instead of vanishing into them.
+
+// Mute silences notifications for matching alerts while any of its
+// windows is active. An empty Kind matches every rule kind; an empty
+// Target matches every target, so one mute can cover a whole device or
+// a whole rule class.
+type Mute struct {
+	ID      int    `json:"id"`
+	Kind    string `json:"kind,omitempty"`
+	Target  string `json:"target,omitempty"`
+	Windows string `json:"windows"` // schedule syntax, e.g. "Mon-Fri 01:00-05:00"
+
+	win *schedule.Windows
+}
+
+// AddMute registers a quiet-hours window; windows uses the schedule
+// syntax ("[days] HH:MM-HH:MM", comma-separated).
+func (e *Engine) AddMute(kind, target, windows string) (Mute, error) {
+	if kind != "" {
+		switch kind {
+		case KindRate, KindNewCountry, KindNewDest, KindDNSBlock, KindAPOffline, KindVPNLeak:
+		default:
+			return Mute{}, fmt.Errorf("unknown rule kind %q", kind)
+		}
+	}
+	win, err := schedule.Parse(windows)
+	if err != nil {
+		return Mute{}, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.nextMuteID++
+	m := Mute{ID: e.nextMuteID, Kind: kind, Target: target, Windows: windows, win: win}
+	e.mutes = append(e.mutes, m)
+	return m, nil
+}
+
+// DeleteMute removes a mute by ID.
+func (e *Engine) DeleteMute(id int) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i, m := range e.mutes {
+		if m.ID == id {
+			e.mutes = append(e.mutes[:i], e.mutes[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("no mute with id %d", id)
+}
+
+// GetMutes lists the configured mutes in creation order.
+func (e *Engine) GetMutes() []Mute {
+	e.mu.RLock()
+	out := make([]Mute, len(e.mutes))
+	copy(out, e.mutes)
+	e.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// mutedLocked reports whether a mute currently covers the rule
+// kind/target pair; the caller holds e.mu.
+func (e *Engine) mutedLocked(kind, target string, now time.Time) bool {
+	for _, m := range e.mutes {
+		if m.Kind != "" && m.Kind != kind {
+			continue
+		}
+		if m.Target != "" && m.Target != target {
+			continue
+		}
+		if m.win.Active(now) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package alerts
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"time"
+
+	"bandwidth-monitor/httpclient"
+)
+
+// Notification delivery. Each configured sink gets every firing and
+// resolution; a failing sink is logged and skipped, never retried —
+// the next transition will try again and alert state is authoritative
+// at /api/alerts regardless.
+
+type notifyConfig struct {
+	webhook string // generic JSON POST
+	ntfy    string // ntfy topic URL, plain-text POST
+	slack   string // Slack incoming-webhook URL
+	discord string // Discord webhook URL
+}
+
+var notifyClient = httpclient.New(10 * time.Second)
+
+// send delivers one transition to every configured sink. Runs in a
+// goroutine so a slow sink never delays evaluation.
+func (e *Engine) send(a Alert, resolved bool) {
+	n := e.notify
+	if n.webhook == "" && n.ntfy == "" && n.slack == "" && n.discord == "" {
+		return
+	}
+	text := "ALERT: " + a.Message
+	if resolved {
+		text = "RESOLVED: " + a.Message
+	}
+	go func() {
+		if n.webhook != "" {
+			event := "firing"
+			if resolved {
+				event = "resolved"
+			}
+			postJSON(n.webhook, map[string]interface{}{"event": event, "alert": a})
+		}
+		if n.ntfy != "" {
+			postText(n.ntfy, text)
+		}
+		if n.slack != "" {
+			postJSON(n.slack, map[string]string{"text": text})
+		}
+		if n.discord != "" {
+			postJSON(n.discord, map[string]string{"content": text})
+		}
+	}()
+}
+
+func postJSON(url string, body interface{}) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return
+	}
+	resp, err := notifyClient.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		log.Printf("alerts: notify %s: %v", url, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Printf("alerts: notify %s: status %d", url, resp.StatusCode)
+	}
+}
+
+func postText(url, text string) {
+	resp, err := notifyClient.Post(url, "text/plain", bytes.NewReader([]byte(text)))
+	if err != nil {
+		log.Printf("alerts: notify %s: %v", url, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Printf("alerts: notify %s: status %d", url, resp.StatusCode)
+	}
+}
@@ -0,0 +1,127 @@
+package alerts
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule kinds understood by the engine.
+const (
+	KindRate       = "rate"        // interface rate above a threshold
+	KindNewCountry = "new_country" // traffic to/from a country not seen before
+	KindNewDest    = "new_dest"    // first contact with a remote host
+	KindDNSBlock   = "dns_block"   // DNS blocked percentage above a threshold
+	KindAPOffline  = "ap_offline"  // a wireless AP disconnected
+	KindVPNLeak    = "vpn_leak"    // protected traffic seen on the raw WAN
+)
+
+// Rule is one user-defined alert condition.
+type Rule struct {
+	Kind      string        `json:"kind"`
+	Target    string        `json:"target,omitempty"` // interface name for rate rules
+	Threshold float64       `json:"threshold,omitempty"`
+	For       time.Duration `json:"for,omitempty"` // condition must hold this long
+	Spec      string        `json:"spec"`          // the original text, for display
+}
+
+// ParseRules parses the comma-separated ALERT_RULES syntax. Each entry
+// is colon-separated:
+//
+//	rate:<iface>:<bytes/s>:<for>   e.g. rate:eth0:100M:5m
+//	dns_block:<percent>:<for>      e.g. dns_block:40:10m
+//	new_country                    fires once per newly seen country
+//	new_dest[:<window>]            fires on first contact with a remote
+//	                               host; the alert stays up for window
+//	                               (default 1h)
+//	ap_offline[:<for>]             e.g. ap_offline:2m
+//	vpn_leak                       a VPN kill-switch leak was detected
+//	                               (needs VPN_PROTECTED_NETS)
+//
+// Rate thresholds accept K/M/G suffixes (decimal, bytes per second).
+func ParseRules(spec string) ([]Rule, error) {
+	var rules []Rule
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		r := Rule{Kind: parts[0], Spec: entry}
+		var err error
+		switch r.Kind {
+		case KindRate:
+			if len(parts) != 4 {
+				return nil, fmt.Errorf("%q: want rate:<iface>:<bytes/s>:<for>", entry)
+			}
+			r.Target = parts[1]
+			if r.Threshold, err = parseBytesPerSec(parts[2]); err != nil {
+				return nil, fmt.Errorf("%q: %v", entry, err)
+			}
+			if r.For, err = time.ParseDuration(parts[3]); err != nil {
+				return nil, fmt.Errorf("%q: %v", entry, err)
+			}
+		case KindDNSBlock:
+			if len(parts) != 3 {
+				return nil, fmt.Errorf("%q: want dns_block:<percent>:<for>", entry)
+			}
+			if r.Threshold, err = strconv.ParseFloat(parts[1], 64); err != nil {
+				return nil, fmt.Errorf("%q: invalid percent %q", entry, parts[1])
+			}
+			if r.For, err = time.ParseDuration(parts[2]); err != nil {
+				return nil, fmt.Errorf("%q: %v", entry, err)
+			}
+		case KindNewCountry:
+			if len(parts) != 1 {
+				return nil, fmt.Errorf("%q: new_country takes no arguments", entry)
+			}
+		case KindNewDest:
+			if len(parts) > 2 {
+				return nil, fmt.Errorf("%q: want new_dest[:<window>]", entry)
+			}
+			r.For = time.Hour
+			if len(parts) == 2 {
+				if r.For, err = time.ParseDuration(parts[1]); err != nil {
+					return nil, fmt.Errorf("%q: %v", entry, err)
+				}
+			}
+		case KindVPNLeak:
+			if len(parts) != 1 {
+				return nil, fmt.Errorf("%q: vpn_leak takes no arguments", entry)
+			}
+		case KindAPOffline:
+			if len(parts) > 2 {
+				return nil, fmt.Errorf("%q: want ap_offline[:<for>]", entry)
+			}
+			if len(parts) == 2 {
+				if r.For, err = time.ParseDuration(parts[1]); err != nil {
+					return nil, fmt.Errorf("%q: %v", entry, err)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("%q: unknown rule kind %q", entry, r.Kind)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// parseBytesPerSec parses "250000", "100K", "12.5M" or "1G" into bytes
+// per second (decimal suffixes, matching how link speeds are quoted).
+func parseBytesPerSec(s string) (float64, error) {
+	mult := 1.0
+	switch {
+	case strings.HasSuffix(s, "K"):
+		mult, s = 1e3, strings.TrimSuffix(s, "K")
+	case strings.HasSuffix(s, "M"):
+		mult, s = 1e6, strings.TrimSuffix(s, "M")
+	case strings.HasSuffix(s, "G"):
+		mult, s = 1e9, strings.TrimSuffix(s, "G")
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("invalid rate threshold %q", s)
+	}
+	return v * mult, nil
+}
@@ -0,0 +1,179 @@
+// Package availability watches the latency prober to detect and log WAN
+// outages (start, end, duration) and computes a monthly availability
+// percentage — for writing strongly-worded emails to the ISP.
+package availability
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"bandwidth-monitor/prober"
+)
+
+const (
+	checkInterval = 10 * time.Second
+	maxOutages    = 200
+)
+
+// Outage is one detected WAN outage. End is zero while ongoing.
+type Outage struct {
+	Start      int64 `json:"start"`
+	End        int64 `json:"end,omitempty"`
+	DurationMs int64 `json:"duration_ms"`
+}
+
+// Summary is the availability report served at /api/availability.
+type Summary struct {
+	CurrentlyDown   bool     `json:"currently_down"`
+	Outages         []Outage `json:"outages"`
+	MonthStart      int64    `json:"month_start"`
+	MonthDowntimeMs int64    `json:"month_downtime_ms"`
+	MonthPct        float64  `json:"month_pct"`
+	Timestamp       int64    `json:"timestamp"`
+}
+
+type targetSnap struct {
+	sent     uint64
+	received uint64
+}
+
+// Monitor samples the prober's counters and tracks outage state. The WAN
+// is considered down when every probe target lost all probes since the
+// previous check.
+type Monitor struct {
+	prober *prober.Prober
+
+	mu      sync.RWMutex
+	prev    map[string]targetSnap
+	down    bool
+	downAt  time.Time
+	outages []Outage
+
+	stopCh chan struct{}
+}
+
+// New creates an availability Monitor on top of an existing Prober.
+func New(p *prober.Prober) *Monitor {
+	return &Monitor{
+		prober: p,
+		prev:   make(map[string]targetSnap),
+		stopCh: make(chan struct{}),
+	}
+}
+
+// Run starts the check loop. Call in a goroutine.
+func (m *Monitor) Run() {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.check()
+		case <-m.stopCh:
+			return
+		}
+	}
+}
+
+// Stop terminates the check loop.
+func (m *Monitor) Stop() {
+	select {
+	case <-m.stopCh:
+	default:
+		close(m.stopCh)
+	}
+}
+
+// GetSummary returns the current availability report.
+func (m *Monitor) GetSummary() *Summary {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	var downtime int64
+	for _, o := range m.outages {
+		start, end := o.Start, o.End
+		if end == 0 {
+			end = now.UnixMilli()
+		}
+		if end <= monthStart.UnixMilli() {
+			continue
+		}
+		if start < monthStart.UnixMilli() {
+			start = monthStart.UnixMilli()
+		}
+		downtime += end - start
+	}
+
+	elapsed := now.UnixMilli() - monthStart.UnixMilli()
+	pct := 100.0
+	if elapsed > 0 {
+		pct = 100 * (1 - float64(downtime)/float64(elapsed))
+	}
+
+	outages := make([]Outage, len(m.outages))
+	copy(outages, m.outages)
+	return &Summary{
+		CurrentlyDown:   m.down,
+		Outages:         outages,
+		MonthStart:      monthStart.UnixMilli(),
+		MonthDowntimeMs: downtime,
+		MonthPct:        pct,
+		Timestamp:       now.UnixMilli(),
+	}
+}
+
+// ---------- internal ----------
+
+// check compares probe counters against the previous sample: if every
+// target sent probes and none were answered, the WAN is down.
+func (m *Monitor) check() {
+	stats := m.prober.GetAll()
+	if len(stats) == 0 {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	anySent := false
+	anyReceived := false
+	newPrev := make(map[string]targetSnap, len(stats))
+	for _, s := range stats {
+		newPrev[s.Target] = targetSnap{sent: s.Sent, received: s.Received}
+		prev, ok := m.prev[s.Target]
+		if !ok {
+			continue
+		}
+		if s.Sent > prev.sent {
+			anySent = true
+		}
+		if s.Received > prev.received {
+			anyReceived = true
+		}
+	}
+	m.prev = newPrev
+	if !anySent {
+		return // prober not making progress — can't judge
+	}
+
+	now := time.Now()
+	if !anyReceived && !m.down {
+		m.down = true
+		m.downAt = now
+		m.outages = append(m.outages, Outage{Start: now.UnixMilli()})
+		if len(m.outages) > maxOutages {
+			m.outages = m.outages[len(m.outages)-maxOutages:]
+		}
+		log.Printf("availability: WAN outage started")
+	} else if anyReceived && m.down {
+		m.down = false
+		last := &m.outages[len(m.outages)-1]
+		last.End = now.UnixMilli()
+		last.DurationMs = last.End - last.Start
+		log.Printf("availability: WAN outage ended after %s", now.Sub(m.downAt).Round(time.Second))
+	}
+}
@@ -0,0 +1,209 @@
+// Package budget enforces per-device daily byte budgets — e.g. capping
+// a kid's tablet at 2 GB a day. Usage comes from the talker tracker's
+// per-client accounting (needs TALKERS_CLIENTS), summed since local
+// midnight, so the tracker's persisted buckets carry the day across
+// restarts. Status is served at /api/budgets; optionally an enforcer
+// (UniFi block-sta or AdGuard's access list) cuts a device off when its
+// budget runs out and lifts the block at the next reset.
+package budget
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"bandwidth-monitor/events"
+	"bandwidth-monitor/talkers"
+)
+
+const checkEvery = 30 * time.Second
+
+// ParseBudgets parses the BUDGETS syntax: comma-separated ip=size pairs
+// where size takes decimal M/G suffixes (megabytes/gigabytes) or a
+// plain byte count, e.g. "192.168.1.50=2G,192.168.1.51=500M".
+func ParseBudgets(spec string) (map[string]uint64, error) {
+	budgets := make(map[string]uint64)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		ip, size, ok := strings.Cut(pair, "=")
+		if !ok || ip == "" {
+			return nil, fmt.Errorf("%q: want ip=size", pair)
+		}
+		mult := 1.0
+		switch {
+		case strings.HasSuffix(size, "G"):
+			mult, size = 1e9, strings.TrimSuffix(size, "G")
+		case strings.HasSuffix(size, "M"):
+			mult, size = 1e6, strings.TrimSuffix(size, "M")
+		}
+		v, err := strconv.ParseFloat(size, 64)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("%q: invalid size", pair)
+		}
+		budgets[ip] = uint64(v * mult)
+	}
+	if len(budgets) == 0 {
+		return nil, fmt.Errorf("no budgets configured")
+	}
+	return budgets, nil
+}
+
+// Enforcer blocks a device's traffic when its budget is exhausted and
+// lifts the block at the next daily reset.
+type Enforcer interface {
+	BlockDevice(ip string) error
+	UnblockDevice(ip string) error
+}
+
+// Status is the externally visible state for one budgeted device.
+type Status struct {
+	Device      string  `json:"device"`
+	BudgetBytes uint64  `json:"budget_bytes"`
+	UsedBytes   uint64  `json:"used_bytes"`
+	Remaining   uint64  `json:"remaining_bytes"`
+	UsedPct     float64 `json:"used_pct"`
+	ResetAt     int64   `json:"reset_at"` // next local midnight, unix ms
+	Exceeded    bool    `json:"exceeded"`
+	Blocked     bool    `json:"blocked,omitempty"` // enforcement applied
+}
+
+// Tracker compares per-device usage against the configured budgets.
+type Tracker struct {
+	budgets  map[string]uint64 // device IP → daily budget in bytes
+	src      *talkers.Tracker
+	enforcer Enforcer
+	bus      *events.Bus
+
+	mu      sync.Mutex
+	day     time.Time // local midnight of the day being accounted
+	blocked map[string]bool
+	alerted map[string]bool // exceeded event fired today
+	status  []Status
+	stopCh  chan struct{}
+}
+
+// New creates the tracker; budgets comes from ParseBudgets and usage is
+// read from src's per-client accounting.
+func New(budgets map[string]uint64, src *talkers.Tracker) *Tracker {
+	return &Tracker{
+		budgets: budgets,
+		src:     src,
+		blocked: make(map[string]bool),
+		alerted: make(map[string]bool),
+		stopCh:  make(chan struct{}),
+	}
+}
+
+// SetEnforcer attaches the block/unblock hook applied when a budget
+// runs out. Call before Run.
+func (t *Tracker) SetEnforcer(e Enforcer) { t.enforcer = e }
+
+// SetEventBus attaches the bus that budget-exceeded events are
+// published to. Call before Run.
+func (t *Tracker) SetEventBus(b *events.Bus) { t.bus = b }
+
+// Run checks immediately and then on every tick. Call in a goroutine.
+func (t *Tracker) Run() {
+	t.check()
+	ticker := time.NewTicker(checkEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			t.check()
+		case <-t.stopCh:
+			return
+		}
+	}
+}
+
+// Stop terminates the check loop. Any active blocks are left in place:
+// the daemon going down shouldn't silently re-open the tap.
+func (t *Tracker) Stop() {
+	select {
+	case <-t.stopCh:
+	default:
+		close(t.stopCh)
+	}
+}
+
+func (t *Tracker) check() {
+	now := time.Now()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	t.mu.Lock()
+	if midnight.After(t.day) {
+		// New day: lift yesterday's blocks and re-arm the alerts.
+		// Blocks only exist when an enforcer is attached.
+		for ip := range t.blocked {
+			if err := t.enforcer.UnblockDevice(ip); err != nil {
+				log.Printf("budget: unblock %s: %v", ip, err)
+			} else {
+				log.Printf("budget: %s unblocked for the new day", ip)
+			}
+		}
+		t.day = midnight
+		t.blocked = make(map[string]bool)
+		t.alerted = make(map[string]bool)
+	}
+	blocked := t.blocked
+	alerted := t.alerted
+	t.mu.Unlock()
+
+	usage := t.src.ClientUsageSince(midnight)
+	resetAt := midnight.AddDate(0, 0, 1).UnixMilli()
+
+	out := make([]Status, 0, len(t.budgets))
+	for ip, limit := range t.budgets {
+		used := usage[ip]
+		st := Status{
+			Device:      ip,
+			BudgetBytes: limit,
+			UsedBytes:   used,
+			UsedPct:     float64(used) / float64(limit) * 100,
+			ResetAt:     resetAt,
+			Exceeded:    used >= limit,
+			Blocked:     blocked[ip],
+		}
+		if used < limit {
+			st.Remaining = limit - used
+		}
+		if st.Exceeded && !alerted[ip] {
+			alerted[ip] = true
+			msg := fmt.Sprintf("%s used %d MB of its %d MB daily budget", ip, used/1e6, limit/1e6)
+			log.Printf("budget: %s", msg)
+			t.bus.Publish("budget", "budget_exceeded", ip, msg)
+		}
+		if st.Exceeded && t.enforcer != nil && !blocked[ip] {
+			if err := t.enforcer.BlockDevice(ip); err != nil {
+				log.Printf("budget: block %s: %v", ip, err)
+			} else {
+				log.Printf("budget: %s blocked until midnight", ip)
+				blocked[ip] = true
+				st.Blocked = true
+			}
+		}
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Device < out[j].Device })
+
+	t.mu.Lock()
+	t.status = out
+	t.mu.Unlock()
+}
+
+// GetAll reports every budgeted device, sorted by address.
+func (t *Tracker) GetAll() []Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]Status, len(t.status))
+	copy(out, t.status)
+	return out
+}
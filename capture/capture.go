@@ -0,0 +1,144 @@
+// Package capture maintains a small rolling ring of recent packets
+// (headers only) so that when something odd appears in the dashboard,
+// the last minute of traffic can be snapshotted to a pcap for offline
+// analysis — without having had tcpdump running in advance.
+package capture
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+	"github.com/gopacket/gopacket/pcap"
+	"github.com/gopacket/gopacket/pcapgo"
+)
+
+const (
+	snapshotLen int32 = 128 // headers only — enough for IP/TCP/UDP + a little payload
+	capTimeout        = 100 * time.Millisecond
+	maxPackets        = 131072 // hard cap so a flood can't grow the ring unbounded
+)
+
+// packet is one retained capture record.
+type packet struct {
+	info gopacket.CaptureInfo
+	data []byte
+}
+
+// Ring captures continuously into a bounded time window.
+type Ring struct {
+	device      string
+	promiscuous bool
+	maxAge      time.Duration
+
+	mu       sync.Mutex
+	pkts     []packet
+	linkType layers.LinkType
+
+	stopCh chan struct{}
+}
+
+// New creates a Ring retaining the last maxAge of headers on device.
+func New(device string, promiscuous bool, maxAge time.Duration) *Ring {
+	if maxAge <= 0 {
+		maxAge = time.Minute
+	}
+	return &Ring{
+		device:      device,
+		promiscuous: promiscuous,
+		maxAge:      maxAge,
+		stopCh:      make(chan struct{}),
+	}
+}
+
+// Run opens the capture handle and fills the ring. Call in a goroutine.
+func (r *Ring) Run() {
+	handle, err := pcap.OpenLive(r.device, snapshotLen, r.promiscuous, capTimeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "capture: cannot open %s: %v\n", r.device, err)
+		return
+	}
+	defer handle.Close()
+
+	r.mu.Lock()
+	r.linkType = handle.LinkType()
+	r.mu.Unlock()
+
+	fmt.Fprintf(os.Stderr, "capture: %s ring holds last %s of headers\n", r.device, r.maxAge)
+
+	for {
+		select {
+		case <-r.stopCh:
+			return
+		default:
+		}
+		data, ci, err := handle.ReadPacketData()
+		if err != nil {
+			if err == pcap.NextErrorTimeoutExpired {
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "capture: read error on %s: %v\n", r.device, err)
+			return
+		}
+		r.mu.Lock()
+		r.pkts = append(r.pkts, packet{info: ci, data: data})
+		r.prune(ci.Timestamp)
+		r.mu.Unlock()
+	}
+}
+
+// Stop terminates the capture loop.
+func (r *Ring) Stop() {
+	select {
+	case <-r.stopCh:
+	default:
+		close(r.stopCh)
+	}
+}
+
+// prune drops packets older than maxAge (and enforces the count cap);
+// the caller holds r.mu.
+func (r *Ring) prune(now time.Time) {
+	cutoff := now.Add(-r.maxAge)
+	idx := 0
+	for idx < len(r.pkts) && r.pkts[idx].info.Timestamp.Before(cutoff) {
+		idx++
+	}
+	if over := len(r.pkts) - maxPackets; over > idx {
+		idx = over
+	}
+	if idx > 0 {
+		r.pkts = append(r.pkts[:0], r.pkts[idx:]...)
+	}
+}
+
+// WritePcap snapshots the current ring contents as a pcap stream.
+func (r *Ring) WritePcap(w io.Writer) error {
+	r.mu.Lock()
+	pkts := make([]packet, len(r.pkts))
+	copy(pkts, r.pkts)
+	linkType := r.linkType
+	r.mu.Unlock()
+
+	pw := pcapgo.NewWriter(w)
+	if err := pw.WriteFileHeader(uint32(snapshotLen), linkType); err != nil {
+		return err
+	}
+	for _, p := range pkts {
+		if err := pw.WritePacket(p.info, p.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Len returns the number of packets currently retained.
+func (r *Ring) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.pkts)
+}
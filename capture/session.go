@@ -0,0 +1,248 @@
+package capture
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gopacket/gopacket/layers"
+	"github.com/gopacket/gopacket/pcap"
+	"github.com/gopacket/gopacket/pcapgo"
+)
+
+const (
+	maxSessionDuration = 5 * time.Minute
+	maxSessions        = 2 // running concurrently
+	keepSessions       = 8 // finished, kept for retrieval
+	sessionSnapLen     = 65535
+	maxSessionPackets  = 262144
+)
+
+// Session is one time-limited, BPF-filtered capture started on demand —
+// a quick tcpdump-style investigation without SSH access to the box.
+type Session struct {
+	id        string
+	device    string
+	filter    string
+	duration  time.Duration
+	startedAt int64
+	linkType  layers.LinkType
+	stopCh    chan struct{}
+
+	mu        sync.Mutex
+	pkts      []packet
+	done      bool
+	truncated bool // hit the packet cap before the deadline
+}
+
+// SessionStatus is the JSON shape describing one session.
+type SessionStatus struct {
+	ID        string `json:"id"`
+	Device    string `json:"device"`
+	Filter    string `json:"filter,omitempty"`
+	Duration  string `json:"duration"`
+	StartedAt int64  `json:"started_at"`
+	Done      bool   `json:"done"`
+	Packets   int    `json:"packets"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// Manager tracks on-demand capture sessions.
+type Manager struct {
+	defaultDevice string
+	promiscuous   bool
+
+	mu       sync.Mutex
+	sessions []*Session
+}
+
+// NewManager creates a Manager; defaultDevice is used when a start
+// request names no interface.
+func NewManager(defaultDevice string, promiscuous bool) *Manager {
+	return &Manager{defaultDevice: defaultDevice, promiscuous: promiscuous}
+}
+
+// Start opens a filtered capture on device for the given duration.
+// The BPF filter is validated by the kernel at open time, so a bad
+// expression fails here rather than silently capturing nothing.
+func (m *Manager) Start(device, filter string, duration time.Duration) (*Session, error) {
+	if device == "" {
+		device = m.defaultDevice
+	}
+	if device == "" {
+		return nil, fmt.Errorf("no capture device configured")
+	}
+	if duration <= 0 {
+		duration = 30 * time.Second
+	}
+	if duration > maxSessionDuration {
+		duration = maxSessionDuration
+	}
+
+	m.mu.Lock()
+	running := 0
+	for _, s := range m.sessions {
+		if !s.finished() {
+			running++
+		}
+	}
+	if running >= maxSessions {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("too many captures running (max %d)", maxSessions)
+	}
+	m.mu.Unlock()
+
+	handle, err := pcap.OpenLive(device, sessionSnapLen, m.promiscuous, capTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", device, err)
+	}
+	if filter != "" {
+		if err := handle.SetBPFFilter(filter); err != nil {
+			handle.Close()
+			return nil, fmt.Errorf("BPF filter %q: %w", filter, err)
+		}
+	}
+
+	id := make([]byte, 4)
+	rand.Read(id)
+	s := &Session{
+		id:        hex.EncodeToString(id),
+		device:    device,
+		filter:    filter,
+		duration:  duration,
+		startedAt: time.Now().UnixMilli(),
+		linkType:  handle.LinkType(),
+		stopCh:    make(chan struct{}),
+	}
+
+	m.mu.Lock()
+	m.sessions = append(m.sessions, s)
+	if len(m.sessions) > keepSessions {
+		m.sessions = m.sessions[len(m.sessions)-keepSessions:]
+	}
+	m.mu.Unlock()
+
+	go s.run(handle)
+	fmt.Fprintf(os.Stderr, "capture: session %s on %s (filter %q, %s)\n", s.id, device, filter, duration)
+	return s, nil
+}
+
+// Get returns the session with the given id, or nil.
+func (m *Manager) Get(id string) *Session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range m.sessions {
+		if s.id == id {
+			return s
+		}
+	}
+	return nil
+}
+
+// GetAll returns the status of every retained session, newest first.
+func (m *Manager) GetAll() []SessionStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]SessionStatus, 0, len(m.sessions))
+	for i := len(m.sessions) - 1; i >= 0; i-- {
+		out = append(out, m.sessions[i].Status())
+	}
+	return out
+}
+
+// StopAll aborts any running sessions.
+func (m *Manager) StopAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range m.sessions {
+		s.stop()
+	}
+}
+
+// ---------- session internals ----------
+
+func (s *Session) run(handle *pcap.Handle) {
+	defer handle.Close()
+	deadline := time.Now().Add(s.duration)
+loop:
+	for time.Now().Before(deadline) {
+		select {
+		case <-s.stopCh:
+			break loop
+		default:
+		}
+		data, ci, err := handle.ReadPacketData()
+		if err != nil {
+			if err == pcap.NextErrorTimeoutExpired {
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "capture: session %s read error: %v\n", s.id, err)
+			break
+		}
+		s.mu.Lock()
+		if len(s.pkts) >= maxSessionPackets {
+			s.truncated = true
+			s.mu.Unlock()
+			break
+		}
+		s.pkts = append(s.pkts, packet{info: ci, data: data})
+		s.mu.Unlock()
+	}
+	s.mu.Lock()
+	s.done = true
+	s.mu.Unlock()
+}
+
+func (s *Session) stop() {
+	select {
+	case <-s.stopCh:
+	default:
+		close(s.stopCh)
+	}
+}
+
+func (s *Session) finished() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.done
+}
+
+// Status returns a copy of the session's public state.
+func (s *Session) Status() SessionStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return SessionStatus{
+		ID:        s.id,
+		Device:    s.device,
+		Filter:    s.filter,
+		Duration:  s.duration.String(),
+		StartedAt: s.startedAt,
+		Done:      s.done,
+		Packets:   len(s.pkts),
+		Truncated: s.truncated,
+	}
+}
+
+// WritePcap streams the session's packets captured so far.
+func (s *Session) WritePcap(w io.Writer) error {
+	s.mu.Lock()
+	pkts := make([]packet, len(s.pkts))
+	copy(pkts, s.pkts)
+	linkType := s.linkType
+	s.mu.Unlock()
+
+	pw := pcapgo.NewWriter(w)
+	if err := pw.WriteFileHeader(sessionSnapLen, linkType); err != nil {
+		return err
+	}
+	for _, p := range pkts {
+		if err := pw.WritePacket(p.info, p.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
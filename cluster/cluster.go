@@ -0,0 +1,195 @@
+// Package cluster implements the agent/aggregator multi-node mode. An
+// Agent runs alongside the local collectors and pushes periodic snapshots
+// to a central instance over WebSocket (authenticated with a shared
+// token); the central instance's Aggregator stores the latest snapshot
+// per node and serves them all from one dashboard.
+package cluster
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"bandwidth-monitor/collector"
+	"bandwidth-monitor/dns"
+	"bandwidth-monitor/talkers"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	topTalkers       = 20               // talker entries pushed per snapshot
+	reconnectBackoff = 5 * time.Second  // initial reconnect delay
+	maxBackoff       = 2 * time.Minute  // reconnect delay ceiling
+	staleAfter       = 90 * time.Second // node marked stale when silent this long
+)
+
+// Snapshot is one agent push: the local collector, talker and DNS state
+// at a point in time.
+type Snapshot struct {
+	Node       string      `json:"node"`
+	SentAt     int64       `json:"sent_at"`
+	Interfaces interface{} `json:"interfaces,omitempty"`
+	Talkers    interface{} `json:"talkers,omitempty"`
+	DNS        interface{} `json:"dns,omitempty"`
+}
+
+// ---------- agent side ----------
+
+// Agent pushes local snapshots to a central aggregator.
+type Agent struct {
+	centralURL string // ws:// or wss:// URL of the central /api/cluster/ws
+	token      string
+	node       string
+	interval   time.Duration
+
+	collector *collector.Collector
+	talkers   *talkers.Tracker
+	dns       dns.Provider
+
+	stopCh chan struct{}
+}
+
+func NewAgent(centralURL, token, node string, interval time.Duration, c *collector.Collector, t *talkers.Tracker, dp dns.Provider) *Agent {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	return &Agent{
+		centralURL: centralURL,
+		token:      token,
+		node:       node,
+		interval:   interval,
+		collector:  c,
+		talkers:    t,
+		dns:        dp,
+		stopCh:     make(chan struct{}),
+	}
+}
+
+// Run connects to the central instance and pushes snapshots every
+// interval, reconnecting with backoff on failure. Call in a goroutine.
+func (a *Agent) Run() {
+	backoff := reconnectBackoff
+	for {
+		conn, err := a.dial()
+		if err != nil {
+			log.Printf("cluster: connect %s: %v (retrying in %s)", a.centralURL, err, backoff)
+			select {
+			case <-time.After(backoff):
+			case <-a.stopCh:
+				return
+			}
+			if backoff *= 2; backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+			continue
+		}
+		log.Printf("cluster: connected to %s as node %q", a.centralURL, a.node)
+		backoff = reconnectBackoff
+
+		if done := a.pushLoop(conn); done {
+			conn.Close()
+			return
+		}
+		conn.Close()
+	}
+}
+
+func (a *Agent) Stop() {
+	select {
+	case <-a.stopCh:
+	default:
+		close(a.stopCh)
+	}
+}
+
+func (a *Agent) dial() (*websocket.Conn, error) {
+	hdr := http.Header{}
+	hdr.Set("Authorization", "Bearer "+a.token)
+	conn, _, err := websocket.DefaultDialer.Dial(a.centralURL, hdr)
+	return conn, err
+}
+
+// pushLoop sends snapshots until the connection breaks (returns false)
+// or the agent is stopped (returns true).
+func (a *Agent) pushLoop(conn *websocket.Conn) bool {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+	for {
+		if err := conn.WriteJSON(a.snapshot()); err != nil {
+			log.Printf("cluster: push failed: %v", err)
+			return false
+		}
+		select {
+		case <-ticker.C:
+		case <-a.stopCh:
+			return true
+		}
+	}
+}
+
+func (a *Agent) snapshot() *Snapshot {
+	s := &Snapshot{
+		Node:       a.node,
+		SentAt:     time.Now().UnixMilli(),
+		Interfaces: a.collector.GetAll(),
+		Talkers:    a.talkers.TopByBandwidth(topTalkers),
+	}
+	if a.dns != nil && a.dns.Available() {
+		s.DNS = a.dns.GetSummary()
+	}
+	return s
+}
+
+// ---------- aggregator side ----------
+
+// NodeState is the latest snapshot received from one node plus liveness.
+type NodeState struct {
+	Node     string          `json:"node"`
+	LastSeen int64           `json:"last_seen"`
+	Stale    bool            `json:"stale"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// Aggregator stores the most recent snapshot per node.
+type Aggregator struct {
+	mu    sync.RWMutex
+	nodes map[string]*NodeState
+}
+
+func NewAggregator() *Aggregator {
+	return &Aggregator{nodes: make(map[string]*NodeState)}
+}
+
+// Ingest records a raw snapshot from node.
+func (ag *Aggregator) Ingest(node string, data json.RawMessage) {
+	ag.mu.Lock()
+	defer ag.mu.Unlock()
+	st, ok := ag.nodes[node]
+	if !ok {
+		st = &NodeState{Node: node}
+		ag.nodes[node] = st
+		log.Printf("cluster: node %q joined", node)
+	}
+	st.LastSeen = time.Now().UnixMilli()
+	st.Data = data
+}
+
+// GetAll returns every known node sorted by name, with staleness marked.
+func (ag *Aggregator) GetAll() []NodeState {
+	ag.mu.RLock()
+	defer ag.mu.RUnlock()
+
+	cutoff := time.Now().Add(-staleAfter).UnixMilli()
+	out := make([]NodeState, 0, len(ag.nodes))
+	for _, st := range ag.nodes {
+		cp := *st
+		cp.Stale = cp.LastSeen < cutoff
+		out = append(out, cp)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Node < out[j].Node })
+	return out
+}
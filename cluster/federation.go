@@ -0,0 +1,137 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"bandwidth-monitor/httpclient"
+)
+
+const federationTimeout = 10 * time.Second // per federated query fan-out
+
+// federatedPaths maps the query names accepted by /api/federation to the
+// API paths proxied on each instance. A fixed allow-list keeps the
+// endpoint from becoming an open proxy.
+var federatedPaths = map[string]string{
+	"summary":    "/api/summary",
+	"talkers":    "/api/talkers/bandwidth",
+	"volume":     "/api/talkers/volume",
+	"interfaces": "/api/interfaces",
+	"dns":        "/api/dns",
+}
+
+// Peer is one federated bandwidth-monitor instance.
+type Peer struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// PeerResult is one instance's answer to a federated query.
+type PeerResult struct {
+	Name  string          `json:"name"`
+	Data  json.RawMessage `json:"data,omitempty"`
+	Error string          `json:"error,omitempty"`
+}
+
+// Federator fans a query out to peer instances and merges the responses,
+// a lighter alternative to full push aggregation.
+type Federator struct {
+	peers      []Peer
+	httpClient *http.Client
+}
+
+// NewFederator builds a Federator from a "name=url,name=url" spec. Bare
+// URLs get their host as the name.
+func NewFederator(spec string) (*Federator, error) {
+	var peers []Peer
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		p := Peer{URL: entry}
+		if name, url, ok := strings.Cut(entry, "="); ok && !strings.Contains(name, "/") {
+			p.Name, p.URL = name, url
+		}
+		p.URL = strings.TrimRight(p.URL, "/")
+		if !strings.HasPrefix(p.URL, "http://") && !strings.HasPrefix(p.URL, "https://") {
+			return nil, fmt.Errorf("peer %q: URL must be http(s)", entry)
+		}
+		if p.Name == "" {
+			p.Name = strings.TrimPrefix(strings.TrimPrefix(p.URL, "https://"), "http://")
+		}
+		peers = append(peers, p)
+	}
+	if len(peers) == 0 {
+		return nil, fmt.Errorf("no peers in %q", spec)
+	}
+	return &Federator{
+		peers:      peers,
+		httpClient: httpclient.New(federationTimeout),
+	}, nil
+}
+
+// Peers returns the configured peer list.
+func (f *Federator) Peers() []Peer {
+	return f.peers
+}
+
+// QueryPath resolves a federation query name to its API path.
+func QueryPath(q string) (string, bool) {
+	path, ok := federatedPaths[q]
+	return path, ok
+}
+
+// Query fetches path from every peer concurrently and returns one result
+// per peer, in peer order. Failures are reported per peer rather than
+// failing the whole query.
+func (f *Federator) Query(ctx context.Context, path string) []PeerResult {
+	results := make([]PeerResult, len(f.peers))
+	var wg sync.WaitGroup
+	for i, p := range f.peers {
+		wg.Add(1)
+		go func(i int, p Peer) {
+			defer wg.Done()
+			results[i] = f.fetch(ctx, p, path)
+		}(i, p)
+	}
+	wg.Wait()
+	return results
+}
+
+func (f *Federator) fetch(ctx context.Context, p Peer, path string) PeerResult {
+	res := PeerResult{Name: p.Name}
+	req, err := http.NewRequestWithContext(ctx, "GET", p.URL+path, nil)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		res.Error = fmt.Sprintf("status %d", resp.StatusCode)
+		return res
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	if !json.Valid(body) {
+		res.Error = "invalid JSON response"
+		return res
+	}
+	res.Data = body
+	return res
+}
@@ -10,30 +10,37 @@ import (
 	"github.com/gopacket/gopacket"
 	"github.com/gopacket/gopacket/layers"
 	"github.com/gopacket/gopacket/pcap"
+
+	"bandwidth-monitor/events"
+	"bandwidth-monitor/schedule"
 )
 
 const (
 	snapshotLen    int32 = 128 // only need IP headers for direction + length
 	capTimeout           = 100 * time.Millisecond
 	rateInterval         = 1 * time.Second
-	historyMaxAge        = 24 * time.Hour
+	historyMaxAge        = 24 * time.Hour // default; see SetHistoryRetention
 	historyPruneAt       = 86400
 )
 
 // InterfaceStat reports aggregate bandwidth seen on the SPAN interface,
 // classified as RX (remote → LOCAL_NETS) and TX (LOCAL_NETS → remote).
 type InterfaceStat struct {
-	Name      string   `json:"name"`
-	IfaceType string   `json:"iface_type"`
-	OperState string   `json:"oper_state"`
-	Addrs     []string `json:"addrs,omitempty"`
-	RxBytes   uint64   `json:"rx_bytes"`
-	TxBytes   uint64   `json:"tx_bytes"`
-	RxPackets uint64   `json:"rx_packets"`
-	TxPackets uint64   `json:"tx_packets"`
-	RxRate    float64  `json:"rx_rate"` // bytes/sec download
-	TxRate    float64  `json:"tx_rate"` // bytes/sec upload
-	Timestamp int64    `json:"timestamp"`
+	Name        string   `json:"name"`
+	DisplayName string   `json:"display_name,omitempty"`
+	IfaceType   string   `json:"iface_type"`
+	OperState   string   `json:"oper_state"`
+	Addrs       []string `json:"addrs,omitempty"`
+	RxBytes     uint64   `json:"rx_bytes"`
+	TxBytes     uint64   `json:"tx_bytes"`
+	RxPackets   uint64   `json:"rx_packets"`
+	TxPackets   uint64   `json:"tx_packets"`
+	RxRate      float64  `json:"rx_rate"` // bytes/sec download
+	TxRate      float64  `json:"tx_rate"` // bytes/sec upload
+	Timestamp   int64    `json:"timestamp"`
+	// Members breaks a bridge/bond capture device down into its enslaved
+	// ports; empty for plain interfaces.
+	Members []MemberStat `json:"members,omitempty"`
 }
 
 // HistoryPoint stores a single rate sample for the 24-hour history ring.
@@ -53,8 +60,12 @@ type SparkPoint struct {
 // traffic direction using LOCAL_NETS, replacing the /proc/net/dev approach.
 type Collector struct {
 	device      string
+	displayName string
 	promiscuous bool
 	localNets   []*net.IPNet
+	pause       *schedule.Windows
+	snapLen     int32
+	bpf         string
 
 	mu      sync.RWMutex
 	stat    InterfaceStat
@@ -67,6 +78,13 @@ type Collector struct {
 	rxPackets uint64
 	txPackets uint64
 
+	// Previous bridge/bond member counters (rateLoop only)
+	prevMember map[string]memberCounters
+
+	histMaxAge time.Duration
+
+	bus *events.Bus
+
 	stopCh chan struct{}
 }
 
@@ -83,39 +101,108 @@ func New(device string, promiscuous bool, localNets []*net.IPNet) *Collector {
 			IfaceType: "span",
 			OperState: "up",
 		},
-		history: make([]HistoryPoint, 0, historyPruneAt),
-		stopCh:  make(chan struct{}),
+		history:    make([]HistoryPoint, 0, historyPruneAt),
+		histMaxAge: historyMaxAge,
+		stopCh:     make(chan struct{}),
+	}
+}
+
+// SetCaptureOptions overrides the capture snaplen and BPF filter; zero
+// and empty keep the "ip or ip6" headers-only defaults. Call before Run.
+func (c *Collector) SetCaptureOptions(snapLen int32, bpf string) {
+	if snapLen > 0 {
+		c.snapLen = snapLen
+	}
+	if bpf != "" {
+		c.bpf = bpf
+	}
+}
+
+// SetHistoryRetention overrides the default 24-hour rate-history window.
+// Call before Run; non-positive values keep the default.
+func (c *Collector) SetHistoryRetention(d time.Duration) {
+	if d > 0 {
+		c.histMaxAge = d
 	}
 }
 
+// historyCap is the point count the ring may reach before pruning.
+func (c *Collector) historyCap() int {
+	return int(c.histMaxAge / rateInterval)
+}
+
+// SetDisplayName attaches a user-facing label (e.g. "WAN - Fiber") that is
+// reported alongside the device name in every summary. Call before Run.
+func (c *Collector) SetDisplayName(name string) {
+	c.displayName = name
+	c.mu.Lock()
+	c.stat.DisplayName = name
+	c.mu.Unlock()
+}
+
+// SetEventBus attaches the event bus that link flaps are published to.
+// Call before Run.
+func (c *Collector) SetEventBus(b *events.Bus) {
+	c.bus = b
+}
+
+// linkState is operState with unreadable sysfs (empty device name,
+// containers) mapped back to the historical hard-coded "up".
+func linkState(device string) string {
+	if s := operState(device); s != "unknown" {
+		return s
+	}
+	return "up"
+}
+
+// SetPauseWindows installs scheduled windows during which captured
+// packets are read but not counted. Call before Run.
+func (c *Collector) SetPauseWindows(w *schedule.Windows) {
+	c.pause = w
+}
+
 // Run opens the capture device and begins classifying packets.
 // It blocks until Stop() is called; intended to be launched as a goroutine.
 func (c *Collector) Run() {
+	// Rate computation runs regardless of capture so ingested byte counts
+	// (demo mode) still produce rates and history.
+	go c.rateLoop()
+
 	if c.device == "" {
 		fmt.Fprintln(os.Stderr, "collector: DEVICE not set — bandwidth collection disabled")
+		<-c.stopCh
 		return
 	}
 	if len(c.localNets) == 0 {
 		fmt.Fprintln(os.Stderr, "collector: LOCAL_NETS not set — cannot determine traffic direction")
+		<-c.stopCh
 		return
 	}
 
-	handle, err := pcap.OpenLive(c.device, snapshotLen, c.promiscuous, capTimeout)
+	snap := c.snapLen
+	if snap == 0 {
+		snap = snapshotLen
+	}
+	filter := c.bpf
+	if filter == "" {
+		filter = "ip or ip6"
+	}
+	handle, err := pcap.OpenLive(c.device, snap, c.promiscuous, capTimeout)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "collector: cannot open %s: %v\n", c.device, err)
 		fmt.Fprintln(os.Stderr, "collector: pcap requires root or CAP_NET_RAW")
+		<-c.stopCh
 		return
 	}
 	defer handle.Close()
 
-	if err := handle.SetBPFFilter("ip or ip6"); err != nil {
+	if err := handle.SetBPFFilter(filter); err != nil {
 		fmt.Fprintf(os.Stderr, "collector: BPF filter error: %v\n", err)
 	}
 
 	fmt.Fprintf(os.Stderr, "collector: capturing on %s (promiscuous=%v)\n", c.device, c.promiscuous)
 
-	go c.rateLoop()
-
+	paused := false
 	for {
 		select {
 		case <-c.stopCh:
@@ -130,6 +217,21 @@ func (c *Collector) Run() {
 			fmt.Fprintf(os.Stderr, "collector: read error on %s: %v\n", c.device, err)
 			return
 		}
+		// Keep draining the handle inside a pause window (so the kernel
+		// buffer doesn't back up), just don't count anything.
+		if c.pause != nil {
+			if now := c.pause.Active(time.Now()); now != paused {
+				paused = now
+				if paused {
+					fmt.Fprintf(os.Stderr, "collector: entering scheduled capture pause\n")
+				} else {
+					fmt.Fprintf(os.Stderr, "collector: leaving scheduled capture pause\n")
+				}
+			}
+			if paused {
+				continue
+			}
+		}
 		pkt := gopacket.NewPacket(data, handle.LinkType(), gopacket.DecodeOptions{
 			Lazy:   true,
 			NoCopy: true,
@@ -241,6 +343,17 @@ func (c *Collector) processPacket(pkt gopacket.Packet) {
 	c.accMu.Unlock()
 }
 
+// IngestBytes adds externally-sourced byte/packet counts to the
+// aggregate counters (demo mode and other non-capture flow sources).
+func (c *Collector) IngestBytes(rxBytes, txBytes, rxPackets, txPackets uint64) {
+	c.accMu.Lock()
+	c.rxBytes += rxBytes
+	c.txBytes += txBytes
+	c.rxPackets += rxPackets
+	c.txPackets += txPackets
+	c.accMu.Unlock()
+}
+
 // isLocal returns true when ip falls within any of the configured LOCAL_NETS.
 func (c *Collector) isLocal(ip net.IP) bool {
 	for _, n := range c.localNets {
@@ -259,6 +372,7 @@ func (c *Collector) rateLoop() {
 
 	var prevRx, prevTx uint64
 	prevTime := time.Now()
+	state := linkState(c.device)
 
 	for {
 		select {
@@ -269,6 +383,18 @@ func (c *Collector) rateLoop() {
 				continue
 			}
 
+			// Link flaps on the capture device are worth an event; the
+			// kernel's operstate also replaces the hard-coded "up"
+			if s := linkState(c.device); s != state {
+				typ := "iface_down"
+				if s == "up" {
+					typ = "iface_up"
+				}
+				c.bus.Publish("collector", typ, c.device,
+					fmt.Sprintf("%s went %s (was %s)", c.device, s, state))
+				state = s
+			}
+
 			c.accMu.Lock()
 			curRx := c.rxBytes
 			curTx := c.txBytes
@@ -278,27 +404,30 @@ func (c *Collector) rateLoop() {
 
 			rxRate := float64(curRx-prevRx) / dt
 			txRate := float64(curTx-prevTx) / dt
+			members := c.sampleMembers(dt)
 
 			c.mu.Lock()
 			c.stat = InterfaceStat{
-				Name:      c.device,
-				IfaceType: "span",
-				OperState: "up",
-				RxBytes:   curRx,
-				TxBytes:   curTx,
-				RxPackets: curRxPkt,
-				TxPackets: curTxPkt,
-				RxRate:    rxRate,
-				TxRate:    txRate,
-				Timestamp: now.UnixMilli(),
+				Name:        c.device,
+				DisplayName: c.displayName,
+				IfaceType:   "span",
+				OperState:   state,
+				RxBytes:     curRx,
+				TxBytes:     curTx,
+				RxPackets:   curRxPkt,
+				TxPackets:   curTxPkt,
+				RxRate:      rxRate,
+				TxRate:      txRate,
+				Timestamp:   now.UnixMilli(),
+				Members:     members,
 			}
 			c.history = append(c.history, HistoryPoint{
 				Timestamp: now.UnixMilli(),
 				RxRate:    rxRate,
 				TxRate:    txRate,
 			})
-			if len(c.history) > historyPruneAt {
-				cutoff := now.Add(-historyMaxAge).UnixMilli()
+			if len(c.history) > c.historyCap() {
+				cutoff := now.Add(-c.histMaxAge).UnixMilli()
 				idx := 0
 				for idx < len(c.history) && c.history[idx].Timestamp < cutoff {
 					idx++
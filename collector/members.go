@@ -0,0 +1,45 @@
+package collector
+
+// MemberStat reports kernel counters for one member port of a bridge or
+// bond capture device, so br-lan throughput can be attributed to the
+// physical ports behind it. Unlike the parent's capture-based numbers,
+// these are raw sysfs counters from the port's own perspective.
+type MemberStat struct {
+	Name      string  `json:"name"`
+	OperState string  `json:"oper_state"`
+	RxBytes   uint64  `json:"rx_bytes"`
+	TxBytes   uint64  `json:"tx_bytes"`
+	RxRate    float64 `json:"rx_rate"`
+	TxRate    float64 `json:"tx_rate"`
+}
+
+// memberCounters is the previous sysfs sample for rate computation.
+type memberCounters struct {
+	rx, tx uint64
+}
+
+// sampleMembers re-enumerates member ports and computes their rates from
+// the previous sample. Only called from rateLoop, so prevMember needs no
+// locking. Returns nil when the capture device has no members.
+func (c *Collector) sampleMembers(dt float64) []MemberStat {
+	ports := memberPorts(c.device)
+	if len(ports) == 0 {
+		return nil
+	}
+	if c.prevMember == nil {
+		c.prevMember = make(map[string]memberCounters)
+	}
+	out := make([]MemberStat, 0, len(ports))
+	for _, p := range ports {
+		rx := readCounter(p, "rx_bytes")
+		tx := readCounter(p, "tx_bytes")
+		m := MemberStat{Name: p, OperState: operState(p), RxBytes: rx, TxBytes: tx}
+		if prev, ok := c.prevMember[p]; ok && dt > 0 && rx >= prev.rx && tx >= prev.tx {
+			m.RxRate = float64(rx-prev.rx) / dt
+			m.TxRate = float64(tx-prev.tx) / dt
+		}
+		c.prevMember[p] = memberCounters{rx: rx, tx: tx}
+		out = append(out, m)
+	}
+	return out
+}
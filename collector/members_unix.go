@@ -0,0 +1,55 @@
+//go:build !windows
+
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Interface state and member-port counters come straight from sysfs on
+// Linux (and harmlessly read as absent elsewhere).
+
+// memberPorts lists the member interfaces when device is a bridge or a
+// bond, or nil for plain interfaces.
+func memberPorts(device string) []string {
+	// Bridge: one directory entry per enslaved port
+	if entries, err := os.ReadDir(filepath.Join("/sys/class/net", device, "brif")); err == nil {
+		ports := make([]string, 0, len(entries))
+		for _, e := range entries {
+			ports = append(ports, e.Name())
+		}
+		sort.Strings(ports)
+		return ports
+	}
+	// Bond: space-separated slave list
+	if b, err := os.ReadFile(filepath.Join("/sys/class/net", device, "bonding", "slaves")); err == nil {
+		ports := strings.Fields(string(b))
+		sort.Strings(ports)
+		return ports
+	}
+	return nil
+}
+
+// readCounter reads one statistics counter for an interface; missing or
+// malformed files read as zero.
+func readCounter(device, counter string) uint64 {
+	b, err := os.ReadFile(filepath.Join("/sys/class/net", device, "statistics", counter))
+	if err != nil {
+		return 0
+	}
+	v, _ := strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+	return v
+}
+
+// operState reads an interface's operational state ("up", "down", ...).
+func operState(device string) string {
+	b, err := os.ReadFile(filepath.Join("/sys/class/net", device, "operstate"))
+	if err != nil {
+		return "unknown"
+	}
+	return strings.TrimSpace(string(b))
+}
@@ -0,0 +1,41 @@
+package collector
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// collectorState is the persisted snapshot: the 24-hour history ring,
+// tagged with the device it was sampled on.
+type collectorState struct {
+	Device  string         `json:"device"`
+	History []HistoryPoint `json:"history"`
+}
+
+// ExportState serializes the rate history for persistence.
+func (c *Collector) ExportState() ([]byte, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return json.Marshal(collectorState{Device: c.device, History: c.history})
+}
+
+// ImportState restores history saved by ExportState, discarding it when
+// the capture device changed or entries have aged out. Call before Run.
+func (c *Collector) ImportState(data []byte) error {
+	var s collectorState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s.Device != c.device {
+		return nil // different interface; stale history would mislead
+	}
+	cutoff := time.Now().Add(-c.histMaxAge).UnixMilli()
+	idx := 0
+	for idx < len(s.History) && s.History[idx].Timestamp < cutoff {
+		idx++
+	}
+	c.mu.Lock()
+	c.history = append(c.history[:0], s.History[idx:]...)
+	c.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,196 @@
+// Package config loads a configuration file into the process
+// environment at startup. The file uses the same flat KEY=VALUE format
+// as the shipped env.example — which systemd's EnvironmentFile already
+// consumes — so the example file works verbatim with -config. Every
+// setting is a flat string, so a YAML or TOML syntax would add a
+// dependency without adding expressiveness.
+//
+// Real environment variables always win: a value from the file is only
+// applied when the variable is unset, so a unit file or container
+// environment can override individual settings without editing the
+// file. Known keys are type-checked (durations, booleans, integers,
+// CIDR lists) and all problems are reported together, so a typo fails
+// loudly at startup instead of being silently ignored mid-run.
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// durationKeys are validated with time.ParseDuration.
+var durationKeys = map[string]bool{
+	"PCAP_RING":                  true,
+	"TALKERS_BUCKET":             true,
+	"TALKERS_RETENTION":          true,
+	"HISTORY_RETENTION":          true,
+	"PERSIST_INTERVAL":           true,
+	"EVENTS_RETENTION":           true,
+	"FLOW_RETENTION":             true,
+	"NFT_INTERVAL":               true,
+	"PING_INTERVAL":              true,
+	"HTTP_CHECK_INTERVAL":        true,
+	"TRACE_INTERVAL":             true,
+	"SNMP_INTERVAL":              true,
+	"WIREGUARD_INTERVAL":         true,
+	"OPENVPN_INTERVAL":           true,
+	"DOCKER_INTERVAL":            true,
+	"PROXMOX_INTERVAL":           true,
+	"MODEM_INTERVAL":             true,
+	"EDGEOS_INTERVAL":            true,
+	"INVENTORY_INTERVAL":         true,
+	"NETBOX_INTERVAL":            true,
+	"AGENT_INTERVAL":             true,
+	"ALERT_INTERVAL":             true,
+	"SEEN_RETENTION":             true,
+	"MQTT_INTERVAL":              true,
+	"OPENWRT_INTERVAL":           true,
+	"OPNSENSE_INTERVAL":          true,
+	"HOSTAPD_INTERVAL":           true,
+	"SERVICE_DISCOVERY_INTERVAL": true,
+	"HTTP_TIMEOUT":               true,
+}
+
+// boolKeys are validated with strconv.ParseBool.
+var boolKeys = map[string]bool{
+	"PROMISCUOUS":              true,
+	"WAN_FAILOVER":             true,
+	"MTU_DETECT":               true,
+	"PROCESS_MONITOR":          true,
+	"NFT_ACCOUNTING":           true,
+	"LAN_MATRIX":               true,
+	"TALKERS_CLIENTS":          true,
+	"DNS_SNIFF":                true,
+	"ARP_WATCH":                true,
+	"SNI_INSPECT":              true,
+	"INVENTORY_SWEEP":          true,
+	"WIREGUARD_STATS":          true,
+	"ADGUARD_DHCP":             true,
+	"DNS_QUERYLOG":             true,
+	"UPDATE_CHECK":             true,
+	"HTTP_INSECURE":            true,
+	"AUTH_STATIC":              true,
+	"SERVICE_DISCOVERY":        true,
+	"SERVICE_DISCOVERY_ACTIVE": true,
+	"DEMO":                     true,
+}
+
+// intKeys must parse as positive integers.
+var intKeys = map[string]bool{
+	"TOP_LIMIT":       true,
+	"CAPTURE_SNAPLEN": true,
+	"ENRICH_BUDGET":   true,
+	"QUOTA_CYCLE_DAY": true,
+}
+
+// cidrListKeys must parse as comma-separated CIDRs.
+var cidrListKeys = map[string]bool{
+	"LOCAL_NETS":         true,
+	"VPN_PROTECTED_NETS": true,
+}
+
+// Load reads the file at path and applies its values to the
+// environment, skipping any variable that is already set. All syntax
+// and type errors are collected and returned as one error.
+func Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var errs []string
+	applied := 0
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// Tolerate "export KEY=value" so a sourced shell profile works too
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, found := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !found || !validKey(key) {
+			errs = append(errs, fmt.Sprintf("line %d: not a KEY=VALUE line", i+1))
+			continue
+		}
+		value = unquote(strings.TrimSpace(value))
+
+		if err := checkValue(key, value); err != nil {
+			errs = append(errs, fmt.Sprintf("line %d: %s: %v", i+1, key, err))
+			continue
+		}
+		if os.Getenv(key) == "" {
+			os.Setenv(key, value)
+			applied++
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s: %s", path, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// validKey matches the environment-variable names used throughout:
+// upper-case letters, digits and underscores.
+func validKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		switch {
+		case r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9':
+		case r == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// unquote strips one matching pair of single or double quotes, the way
+// systemd's EnvironmentFile does.
+func unquote(v string) string {
+	if len(v) >= 2 {
+		if (v[0] == '"' && v[len(v)-1] == '"') || (v[0] == '\'' && v[len(v)-1] == '\'') {
+			return v[1 : len(v)-1]
+		}
+	}
+	return v
+}
+
+// checkValue type-checks the keys the daemon is known to parse; unknown
+// keys pass through so newer files work with older binaries.
+func checkValue(key, value string) error {
+	switch {
+	case durationKeys[key]:
+		if _, err := time.ParseDuration(value); err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+	case boolKeys[key]:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+	case intKeys[key]:
+		if n, err := strconv.Atoi(value); err != nil || n <= 0 {
+			return fmt.Errorf("invalid positive integer %q", value)
+		}
+	case cidrListKeys[key]:
+		for _, cidr := range strings.Split(value, ",") {
+			cidr = strings.TrimSpace(cidr)
+			if cidr == "" {
+				continue
+			}
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				return fmt.Errorf("invalid CIDR %q", cidr)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+// Package demo fabricates plausible interface, talker, DNS and WiFi data
+// so the dashboard can be developed and showcased on any machine, without
+// root, pcap or any configured integration.
+package demo
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"bandwidth-monitor/collector"
+	"bandwidth-monitor/dns"
+	"bandwidth-monitor/talkers"
+	"bandwidth-monitor/unifi"
+)
+
+const tickInterval = 2 * time.Second
+
+// remoteHost is one synthetic remote endpoint with a traffic weight.
+type remoteHost struct {
+	ip     string
+	proto  string
+	ipVer  string
+	weight float64
+}
+
+// A fixed cast of plausible remote services (real CDN/cloud ranges so
+// GeoIP enrichment works when MMDBs are present).
+var remoteHosts = []remoteHost{
+	{"142.250.74.78", "TCP", "IPv4", 0.22},  // Google
+	{"151.101.1.140", "TCP", "IPv4", 0.15},  // Fastly
+	{"104.16.132.229", "TCP", "IPv4", 0.13}, // Cloudflare
+	{"13.107.42.14", "TCP", "IPv4", 0.10},   // Microsoft
+	{"52.84.150.39", "TCP", "IPv4", 0.09},   // AWS CloudFront
+	{"185.199.108.153", "TCP", "IPv4", 0.07},
+	{"2606:4700::6810:84e5", "TCP", "IPv6", 0.07},
+	{"2a00:1450:4001:81d::200e", "TCP", "IPv6", 0.06},
+	{"162.159.200.123", "UDP", "IPv4", 0.05}, // NTP
+	{"8.8.8.8", "UDP", "IPv4", 0.03},
+	{"45.33.32.156", "TCP", "IPv4", 0.02},
+	{"198.41.0.4", "ICMP", "IPv4", 0.01},
+}
+
+var demoDomains = []string{
+	"www.googleapis.com", "cdn.fastly.net", "github.io", "api.github.com",
+	"graph.microsoft.com", "images.example-cdn.com", "update.example.org",
+	"www.wikipedia.org", "static.cloudflareinsights.com", "time.cloudflare.com",
+}
+
+var blockedDomains = []string{
+	"ads.doubleclick.net", "tracking.example-analytics.com", "pixel.adserver.io",
+	"telemetry.example.net", "banners.adnetwork.example",
+}
+
+// Generator drives the synthetic data: it feeds the collector and talker
+// pipelines and itself implements dns.Provider.
+type Generator struct {
+	collector *collector.Collector
+	talkers   *talkers.Tracker
+	wifi      *unifi.Client
+	localIPs  []string
+
+	mu      sync.RWMutex
+	summary *dns.Summary
+
+	rnd    *rand.Rand
+	phase  float64 // drives the slow diurnal-ish rate curve
+	stopCh chan struct{}
+}
+
+// New builds a Generator feeding c and t. Synthetic local clients are
+// drawn from the first IPv4 net in localNets so direction detection and
+// private-IP filtering behave as they would with real traffic.
+func New(c *collector.Collector, t *talkers.Tracker, localNets []*net.IPNet) *Generator {
+	g := &Generator{
+		collector: c,
+		talkers:   t,
+		wifi:      unifi.New("https://demo.invalid", "", "", "default", time.Hour),
+		rnd:       rand.New(rand.NewSource(time.Now().UnixNano())),
+		stopCh:    make(chan struct{}),
+	}
+	base := "192.168.77."
+	for _, n := range localNets {
+		if ip4 := n.IP.To4(); ip4 != nil {
+			base = fmt.Sprintf("%d.%d.%d.", ip4[0], ip4[1], ip4[2])
+			break
+		}
+	}
+	for i := 10; i < 18; i++ {
+		g.localIPs = append(g.localIPs, fmt.Sprintf("%s%d", base, i))
+	}
+	return g
+}
+
+// WiFi returns the client serving the fabricated WiFi summary.
+func (g *Generator) WiFi() *unifi.Client { return g.wifi }
+
+// Run generates data every tick until Stop. Call in a goroutine.
+func (g *Generator) Run() {
+	log.Println("demo: generating synthetic data")
+	g.tick()
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			g.tick()
+		case <-g.stopCh:
+			return
+		}
+	}
+}
+
+func (g *Generator) Stop() {
+	select {
+	case <-g.stopCh:
+	default:
+		close(g.stopCh)
+	}
+}
+
+// ---------- dns.Provider ----------
+
+func (g *Generator) GetSummary() *dns.Summary {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.summary
+}
+
+func (g *Generator) Available() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.summary != nil
+}
+
+// ---------- generation ----------
+
+func (g *Generator) tick() {
+	g.phase += 0.01
+
+	// Aggregate WAN rates: slow sine wave plus jitter, download-heavy
+	rxRate := 4e6 * (1.2 + math.Sin(g.phase)) * (0.8 + 0.4*g.rnd.Float64()) // ~bytes/sec
+	txRate := rxRate * (0.15 + 0.1*g.rnd.Float64())
+	secs := tickInterval.Seconds()
+	rxBytes := uint64(rxRate * secs)
+	txBytes := uint64(txRate * secs)
+	g.collector.IngestBytes(rxBytes, txBytes, rxBytes/1200, txBytes/1200)
+
+	// Spread the same traffic across the remote host cast
+	for _, h := range remoteHosts {
+		share := uint64(float64(rxBytes+txBytes) * h.weight * (0.5 + g.rnd.Float64()))
+		if share == 0 {
+			continue
+		}
+		local := g.localIPs[g.rnd.Intn(len(g.localIPs))]
+		if g.rnd.Float64() < 0.7 {
+			g.talkers.IngestFlow(h.ip, local, h.proto, h.ipVer, share)
+		} else {
+			g.talkers.IngestFlow(local, h.ip, h.proto, h.ipVer, share)
+		}
+	}
+
+	g.updateDNS()
+	g.updateWiFi()
+}
+
+func (g *Generator) updateDNS() {
+	total := 18000 + g.rnd.Intn(4000)
+	blocked := total * (12 + g.rnd.Intn(8)) / 100
+
+	s := &dns.Summary{
+		ProviderName:   "Demo DNS",
+		TotalQueries:   total,
+		BlockedTotal:   blocked,
+		BlockedPercent: float64(blocked) / float64(total) * 100,
+		AvgLatencyMs:   8 + 6*g.rnd.Float64(),
+		TimeUnits:      "hours",
+	}
+	for i, d := range demoDomains {
+		s.TopQueried = append(s.TopQueried, dns.DomainStat{Domain: d, Count: total / (8 + i*3)})
+	}
+	for i, d := range blockedDomains {
+		s.TopBlocked = append(s.TopBlocked, dns.DomainStat{Domain: d, Count: blocked / (4 + i*2)})
+	}
+	for i, ip := range g.localIPs {
+		s.TopClients = append(s.TopClients, dns.ClientStat{IP: ip, Count: total / (6 + i*2)})
+	}
+	s.Upstreams = []dns.UpstreamStat{
+		{Address: "https://dns.example/dns-query", Responses: total * 3 / 4, AvgMs: 9.1},
+		{Address: "tls://backup.dns.example", Responses: total / 4, AvgMs: 14.7},
+	}
+	for i := 0; i < 24; i++ {
+		q := 500 + g.rnd.Intn(700)
+		s.QueriesSeries = append(s.QueriesSeries, q)
+		s.BlockedSeries = append(s.BlockedSeries, q*(10+g.rnd.Intn(10))/100)
+	}
+
+	g.mu.Lock()
+	g.summary = s
+	g.mu.Unlock()
+}
+
+func (g *Generator) updateWiFi() {
+	aps := []unifi.APInfo{
+		{Name: "Living Room", Model: "U6-Lite", MAC: "aa:bb:cc:00:00:01", IP: g.localIPs[0], Status: "connected", Uptime: 864000},
+		{Name: "Office", Model: "U6-Pro", MAC: "aa:bb:cc:00:00:02", IP: g.localIPs[1], Status: "connected", Uptime: 432000},
+	}
+	var clients []unifi.ClientInfo
+	names := []string{"laptop", "phone", "tablet", "tv", "thermostat", "console"}
+	totalClients := 0
+	for i, n := range names {
+		ap := &aps[i%len(aps)]
+		ap.NumClients++
+		totalClients++
+		clients = append(clients, unifi.ClientInfo{
+			MAC:      fmt.Sprintf("de:mo:00:00:00:%02d", i),
+			Hostname: n,
+			IP:       g.localIPs[(i+2)%len(g.localIPs)],
+			SSID:     "DemoNet",
+			APMAC:    ap.MAC,
+			APName:   ap.Name,
+			Signal:   -45 - g.rnd.Intn(25),
+			Channel:  36,
+			Radio:    "na",
+			RxRate:   1e5 * g.rnd.Float64(),
+			TxRate:   2e4 * g.rnd.Float64(),
+		})
+	}
+	g.wifi.SetSummary(&unifi.Summary{
+		TotalAPs:     len(aps),
+		TotalClients: totalClients,
+		APs:          aps,
+		SSIDs:        []unifi.SSIDStat{{Name: "DemoNet", NumClients: totalClients}},
+		Clients:      clients,
+	})
+}
@@ -0,0 +1,242 @@
+// Package discovery listens for SSDP/UPnP and mDNS announcements on the
+// local network and attaches the advertised services (Chromecast,
+// printers, cameras, ...) to the device inventory. Passive by default —
+// devices chatter plenty on their own; active mode additionally sends
+// an SSDP M-SEARCH and an mDNS service enumeration each interval to
+// shake loose the quiet ones.
+package discovery
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"bandwidth-monitor/inventory"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+var (
+	ssdpAddr = &net.UDPAddr{IP: net.IPv4(239, 255, 255, 250), Port: 1900}
+	mdnsAddr = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+)
+
+type Listener struct {
+	inv      *inventory.Inventory
+	active   bool
+	interval time.Duration
+	stopCh   chan struct{}
+}
+
+// New creates a listener feeding inv. When active is true, discovery
+// probes are sent every interval as well.
+func New(inv *inventory.Inventory, active bool, interval time.Duration) *Listener {
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	return &Listener{
+		inv:      inv,
+		active:   active,
+		interval: interval,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+func (l *Listener) Run() {
+	if c, err := net.ListenMulticastUDP("udp4", nil, ssdpAddr); err != nil {
+		log.Printf("discovery: ssdp listen: %v", err)
+	} else {
+		go l.readSSDP(c)
+	}
+	if c, err := net.ListenMulticastUDP("udp4", nil, mdnsAddr); err != nil {
+		log.Printf("discovery: mdns listen: %v", err)
+	} else {
+		go l.readMDNS(c)
+	}
+
+	if l.active {
+		l.probe()
+		ticker := time.NewTicker(l.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				l.probe()
+			case <-l.stopCh:
+				return
+			}
+		}
+	}
+	<-l.stopCh
+}
+
+func (l *Listener) Stop() {
+	select {
+	case <-l.stopCh:
+	default:
+		close(l.stopCh)
+	}
+}
+
+// readSSDP consumes NOTIFY announcements (and M-SEARCH responses when
+// the socket is the probe socket).
+func (l *Listener) readSSDP(c *net.UDPConn) {
+	defer c.Close()
+	buf := make([]byte, 4096)
+	for {
+		select {
+		case <-l.stopCh:
+			return
+		default:
+		}
+		c.SetReadDeadline(time.Now().Add(1 * time.Second))
+		n, addr, err := c.ReadFromUDP(buf)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				continue
+			}
+			return
+		}
+		l.handleSSDP(addr.IP.String(), string(buf[:n]))
+	}
+}
+
+func (l *Listener) handleSSDP(ip, msg string) {
+	var service, server string
+	for _, line := range strings.Split(msg, "\r\n") {
+		k, v, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		v = strings.TrimSpace(v)
+		switch strings.ToUpper(k) {
+		case "NT", "ST":
+			service = ssdpService(v)
+		case "SERVER":
+			server = v
+		}
+	}
+	if service != "" {
+		l.inv.MergeService(ip, service, "")
+		_ = server // model strings are too noisy for names
+	}
+}
+
+// ssdpService condenses a UPnP NT/ST value to a readable service label:
+// "urn:dial-multiscreen-org:service:dial:1" → "upnp:dial".
+func ssdpService(v string) string {
+	if v == "" || v == "ssdp:all" || v == "upnp:rootdevice" || strings.HasPrefix(v, "uuid:") {
+		return ""
+	}
+	if parts := strings.Split(v, ":"); len(parts) >= 4 && parts[0] == "urn" {
+		return "upnp:" + parts[len(parts)-2]
+	}
+	return "upnp:" + v
+}
+
+// readMDNS consumes multicast DNS responses; queries from other hosts
+// are skipped.
+func (l *Listener) readMDNS(c *net.UDPConn) {
+	defer c.Close()
+	buf := make([]byte, 9000)
+	var d layers.DNS
+	for {
+		select {
+		case <-l.stopCh:
+			return
+		default:
+		}
+		c.SetReadDeadline(time.Now().Add(1 * time.Second))
+		n, addr, err := c.ReadFromUDP(buf)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				continue
+			}
+			return
+		}
+		if err := d.DecodeFromBytes(buf[:n], gopacket.NilDecodeFeedback); err != nil || !d.QR {
+			continue
+		}
+		l.handleMDNS(addr.IP.String(), &d)
+	}
+}
+
+func (l *Listener) handleMDNS(ip string, d *layers.DNS) {
+	for _, rr := range append(append([]layers.DNSResourceRecord(nil), d.Answers...), d.Additionals...) {
+		name := string(rr.Name)
+		switch rr.Type {
+		case layers.DNSTypePTR:
+			// "_googlecast._tcp.local" → instance "Living Room TV...";
+			// the service type is the record name, the human-readable
+			// instance prefix makes a good device name
+			// Skip the meta-service used for enumeration
+			if !strings.HasPrefix(name, "_") || strings.HasPrefix(name, "_services._dns-sd") {
+				continue
+			}
+			service := strings.TrimSuffix(name, ".local")
+			if instance, rest, ok := strings.Cut(string(rr.PTR), "._"); ok && strings.HasPrefix("_"+rest, service) {
+				l.inv.MergeService(ip, service, instance)
+			} else {
+				l.inv.MergeService(ip, service, "")
+			}
+		case layers.DNSTypeA, layers.DNSTypeAAAA:
+			// "office-printer.local" names the device holding the address
+			if host := strings.TrimSuffix(name, ".local"); host != "" && !strings.HasPrefix(host, "_") && rr.IP != nil {
+				l.inv.MergeService(rr.IP.String(), "", host)
+			}
+		}
+	}
+}
+
+// probe actively solicits announcements: an SSDP M-SEARCH (answers come
+// back unicast, so a short-lived socket collects them) and an mDNS
+// service enumeration (answers arrive on the multicast group the
+// passive listener already watches).
+func (l *Listener) probe() {
+	go l.probeSSDP()
+
+	if c, err := net.DialUDP("udp4", nil, mdnsAddr); err == nil {
+		c.Write(mdnsEnumQuery())
+		c.Close()
+	}
+}
+
+func (l *Listener) probeSSDP() {
+	c, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return
+	}
+	defer c.Close()
+	search := fmt.Sprintf("M-SEARCH * HTTP/1.1\r\nHOST: %s\r\nMAN: \"ssdp:discover\"\r\nMX: 2\r\nST: ssdp:all\r\n\r\n", ssdpAddr)
+	c.WriteToUDP([]byte(search), ssdpAddr)
+
+	deadline := time.Now().Add(5 * time.Second)
+	buf := make([]byte, 4096)
+	for time.Now().Before(deadline) {
+		c.SetReadDeadline(deadline)
+		n, addr, err := c.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		l.handleSSDP(addr.IP.String(), string(buf[:n]))
+	}
+}
+
+// mdnsEnumQuery builds a PTR query for _services._dns-sd._udp.local.
+func mdnsEnumQuery() []byte {
+	q := layers.DNS{
+		Questions: []layers.DNSQuestion{{
+			Name:  []byte("_services._dns-sd._udp.local"),
+			Type:  layers.DNSTypePTR,
+			Class: layers.DNSClassIN,
+		}},
+	}
+	buf := gopacket.NewSerializeBuffer()
+	if err := q.SerializeTo(buf, gopacket.SerializeOptions{}); err != nil {
+		return nil
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,47 @@
+package dns
+
+// Chain is an ordered list of providers with automatic failover: the
+// first provider currently reporting data serves the summary, so a dead
+// AdGuard instance hands over to the next configured backend without a
+// restart. The summary's provider_name tells the frontend which backend
+// is active.
+type Chain struct {
+	providers []Provider
+}
+
+// NewChain builds a failover chain in priority order. A single provider
+// is returned as-is.
+func NewChain(providers ...Provider) Provider {
+	if len(providers) == 1 {
+		return providers[0]
+	}
+	return &Chain{providers: providers}
+}
+
+// GetSummary returns the summary of the highest-priority provider that
+// currently has data, or nil if none do.
+func (c *Chain) GetSummary() *Summary {
+	for _, p := range c.providers {
+		if p.Available() {
+			return p.GetSummary()
+		}
+	}
+	return nil
+}
+
+// Available returns true if any provider in the chain has data.
+func (c *Chain) Available() bool {
+	for _, p := range c.providers {
+		if p.Available() {
+			return true
+		}
+	}
+	return false
+}
+
+// Stop terminates every provider in the chain.
+func (c *Chain) Stop() {
+	for _, p := range c.providers {
+		p.Stop()
+	}
+}
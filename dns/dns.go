@@ -17,6 +17,15 @@ type Summary struct {
 	BlockedPercent float64 `json:"blocked_pct"`
 	AvgLatencyMs   float64 `json:"avg_latency_ms"`
 
+	// Recursive resolvers (Knot, PowerDNS Recursor) don't block, but do
+	// report cache efficiency and per-rcode answer counts.
+	CacheHitPercent float64        `json:"cache_hit_pct,omitempty"`
+	Rcodes          map[string]int `json:"rcodes,omitempty"`
+
+	// Health is non-empty when the backend is degraded, e.g. "throttled"
+	// while a rate-limited API is being backed off.
+	Health string `json:"health,omitempty"`
+
 	TopQueried []DomainStat `json:"top_queried"`
 	TopBlocked []DomainStat `json:"top_blocked"`
 	TopClients []ClientStat `json:"top_clients"`
@@ -34,15 +43,22 @@ type DomainStat struct {
 	Count  int    `json:"count"`
 }
 
-// ClientStat is a single client IP + count entry.
+// ClientStat is a single client IP + count entry. Name is filled at
+// serve time from the device inventory when the IP has one.
 type ClientStat struct {
 	IP    string `json:"ip"`
+	Name  string `json:"name,omitempty"`
 	Count int    `json:"count"`
 }
 
-// UpstreamStat is a single upstream server entry.
+// UpstreamStat is a single upstream server entry. Percentiles and the
+// histogram (bucket label → samples) are filled by providers that
+// sample latency over time rather than reporting one average.
 type UpstreamStat struct {
-	Address   string  `json:"address"`
-	Responses int     `json:"responses"`
-	AvgMs     float64 `json:"avg_ms"`
+	Address   string         `json:"address"`
+	Responses int            `json:"responses"`
+	AvgMs     float64        `json:"avg_ms"`
+	P50Ms     float64        `json:"p50_ms,omitempty"`
+	P95Ms     float64        `json:"p95_ms,omitempty"`
+	Histogram map[string]int `json:"histogram,omitempty"`
 }
@@ -0,0 +1,301 @@
+// Package dnslog tails AdGuard Home's query log and maintains the
+// aggregates the provider's stats API can't answer: per-client per-hour
+// query counts and first-seen timestamps per domain ("when did this
+// device first talk to that tracker?"). State survives restarts via a
+// JSON snapshot, the same pattern the device inventory uses.
+package dnslog
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"bandwidth-monitor/httpclient"
+)
+
+const (
+	hourlyRetention = 7 * 24 * time.Hour
+	firstSeenCap    = 50000
+	saveInterval    = 5 * time.Minute
+	fetchLimit      = 500
+)
+
+// state is the persisted aggregate snapshot.
+type state struct {
+	Hourly    map[int64]map[string]int `json:"hourly"`     // hour unix → client → queries
+	FirstSeen map[string]int64         `json:"first_seen"` // domain → unix ms
+}
+
+// HourlyStat is one hour of per-client query counts.
+type HourlyStat struct {
+	Hour    int64          `json:"hour"` // unix seconds, start of hour
+	Clients map[string]int `json:"clients"`
+}
+
+// DomainSeen records when a domain was first queried.
+type DomainSeen struct {
+	Domain    string `json:"domain"`
+	FirstSeen int64  `json:"first_seen"` // unix ms
+}
+
+// Logger polls the AdGuard query log and aggregates it.
+type Logger struct {
+	baseURL    string
+	user       string
+	pass       string
+	path       string
+	interval   time.Duration
+	httpClient *http.Client
+
+	mu       sync.RWMutex
+	state    *state
+	dirty    bool
+	lastSeen time.Time
+
+	stopCh chan struct{}
+}
+
+// logEntry is the slice of an AdGuard query-log record we care about.
+type logEntry struct {
+	Time     string `json:"time"`
+	Client   string `json:"client"`
+	Question struct {
+		Name string `json:"name"`
+	} `json:"question"`
+}
+
+// New creates a query-log aggregator, loading any previous snapshot.
+func New(baseURL, user, pass, path string, pollInterval time.Duration) *Logger {
+	l := &Logger{
+		baseURL:    baseURL,
+		user:       user,
+		pass:       pass,
+		path:       path,
+		interval:   pollInterval,
+		httpClient: httpclient.New(15 * time.Second),
+		state: &state{
+			Hourly:    make(map[int64]map[string]int),
+			FirstSeen: make(map[string]int64),
+		},
+		stopCh: make(chan struct{}),
+	}
+	l.load()
+	return l
+}
+
+// Run starts the poll/save loop. Call in a goroutine.
+func (l *Logger) Run() {
+	l.poll()
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+	saveTicker := time.NewTicker(saveInterval)
+	defer saveTicker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			l.poll()
+		case <-saveTicker.C:
+			l.save()
+		case <-l.stopCh:
+			l.save()
+			return
+		}
+	}
+}
+
+// Stop terminates the loop, flushing the snapshot.
+func (l *Logger) Stop() {
+	select {
+	case <-l.stopCh:
+	default:
+		close(l.stopCh)
+	}
+}
+
+// Available reports whether any aggregate data exists yet.
+func (l *Logger) Available() bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.state.Hourly) > 0 || len(l.state.FirstSeen) > 0
+}
+
+// PerClientHourly returns per-client query counts for the last n hours,
+// oldest first.
+func (l *Logger) PerClientHourly(n int) []HourlyStat {
+	cutoff := time.Now().Add(-time.Duration(n) * time.Hour).Unix()
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	out := make([]HourlyStat, 0, n)
+	for hour, clients := range l.state.Hourly {
+		if hour < cutoff {
+			continue
+		}
+		cp := make(map[string]int, len(clients))
+		for c, v := range clients {
+			cp[c] = v
+		}
+		out = append(out, HourlyStat{Hour: hour, Clients: cp})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Hour < out[j].Hour })
+	return out
+}
+
+// FirstSeenOf returns the first-seen timestamp for one domain, or zero.
+func (l *Logger) FirstSeenOf(domain string) int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.state.FirstSeen[domain]
+}
+
+// NewestDomains returns the most recently first-seen domains, newest
+// first, capped at limit.
+func (l *Logger) NewestDomains(limit int) []DomainSeen {
+	l.mu.RLock()
+	out := make([]DomainSeen, 0, len(l.state.FirstSeen))
+	for d, ts := range l.state.FirstSeen {
+		out = append(out, DomainSeen{Domain: d, FirstSeen: ts})
+	}
+	l.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].FirstSeen > out[j].FirstSeen })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// ---------- internal ----------
+
+func (l *Logger) poll() {
+	req, err := http.NewRequest("GET", l.baseURL+"/control/querylog?limit="+strconv.Itoa(fetchLimit), nil)
+	if err != nil {
+		log.Printf("dnslog: build request: %v", err)
+		return
+	}
+	if l.user != "" {
+		req.SetBasicAuth(l.user, l.pass)
+	}
+
+	resp, err := l.httpClient.Do(req)
+	if err != nil {
+		log.Printf("dnslog: fetch querylog: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		log.Printf("dnslog: querylog returned %d: %s", resp.StatusCode, string(body))
+		return
+	}
+
+	var payload struct {
+		Data []logEntry `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		log.Printf("dnslog: decode querylog: %v", err)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Entries come newest-first; only account those we haven't seen
+	newest := l.lastSeen
+	for _, e := range payload.Data {
+		ts, err := time.Parse(time.RFC3339Nano, e.Time)
+		if err != nil || !ts.After(l.lastSeen) {
+			continue
+		}
+		if ts.After(newest) {
+			newest = ts
+		}
+		hour := ts.Truncate(time.Hour).Unix()
+		if l.state.Hourly[hour] == nil {
+			l.state.Hourly[hour] = make(map[string]int)
+		}
+		l.state.Hourly[hour][e.Client]++
+		if d := e.Question.Name; d != "" {
+			if _, ok := l.state.FirstSeen[d]; !ok {
+				l.state.FirstSeen[d] = ts.UnixMilli()
+			}
+		}
+		l.dirty = true
+	}
+	l.lastSeen = newest
+	l.pruneLocked()
+}
+
+// pruneLocked enforces retention. Caller holds l.mu.
+func (l *Logger) pruneLocked() {
+	cutoff := time.Now().Add(-hourlyRetention).Unix()
+	for hour := range l.state.Hourly {
+		if hour < cutoff {
+			delete(l.state.Hourly, hour)
+			l.dirty = true
+		}
+	}
+	// The first-seen table grows one entry per unique domain ever; cap
+	// it by evicting the oldest entries in bulk
+	if len(l.state.FirstSeen) > firstSeenCap {
+		type kv struct {
+			d  string
+			ts int64
+		}
+		all := make([]kv, 0, len(l.state.FirstSeen))
+		for d, ts := range l.state.FirstSeen {
+			all = append(all, kv{d, ts})
+		}
+		sort.Slice(all, func(i, j int) bool { return all[i].ts < all[j].ts })
+		for _, e := range all[:len(all)-firstSeenCap] {
+			delete(l.state.FirstSeen, e.d)
+		}
+		l.dirty = true
+	}
+}
+
+func (l *Logger) load() {
+	b, err := os.ReadFile(l.path)
+	if err != nil {
+		return // first run
+	}
+	var s state
+	if err := json.Unmarshal(b, &s); err != nil {
+		log.Printf("dnslog: parse %s: %v", l.path, err)
+		return
+	}
+	if s.Hourly == nil {
+		s.Hourly = make(map[int64]map[string]int)
+	}
+	if s.FirstSeen == nil {
+		s.FirstSeen = make(map[string]int64)
+	}
+	l.state = &s
+}
+
+func (l *Logger) save() {
+	l.mu.Lock()
+	if !l.dirty {
+		l.mu.Unlock()
+		return
+	}
+	b, err := json.Marshal(l.state)
+	l.dirty = false
+	l.mu.Unlock()
+	if err != nil {
+		return
+	}
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		log.Printf("dnslog: write %s: %v", tmp, err)
+		return
+	}
+	if err := os.Rename(tmp, l.path); err != nil {
+		log.Printf("dnslog: rename %s: %v", tmp, err)
+	}
+}
@@ -0,0 +1,215 @@
+// Package docker collects per-container network counters from the Docker
+// Engine API over /var/run/docker.sock, so "which container is eating the
+// uplink" is answerable directly from the dashboard.
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultSocket = "/var/run/docker.sock"
+
+// ContainerStat is the per-container snapshot with rates computed between polls.
+type ContainerStat struct {
+	ID       string             `json:"id"`
+	Name     string             `json:"name"`
+	Image    string             `json:"image"`
+	State    string             `json:"state"`
+	Networks map[string]NetStat `json:"networks"` // keyed by in-container interface (eth0, ...)
+	RxBytes  uint64             `json:"rx_bytes"` // sum over all networks
+	TxBytes  uint64             `json:"tx_bytes"`
+	RxRate   float64            `json:"rx_rate"`
+	TxRate   float64            `json:"tx_rate"`
+}
+
+// NetStat is the counter pair for one container network interface.
+type NetStat struct {
+	RxBytes uint64 `json:"rx_bytes"`
+	TxBytes uint64 `json:"tx_bytes"`
+}
+
+type byteSnap struct {
+	rx uint64
+	tx uint64
+}
+
+// Client polls the Docker Engine API on a fixed interval.
+type Client struct {
+	socket   string
+	interval time.Duration
+	http     *http.Client
+
+	mu       sync.RWMutex
+	stats    []ContainerStat
+	prev     map[string]byteSnap // keyed by container ID
+	lastPoll time.Time
+
+	stopCh chan struct{}
+}
+
+// New creates a Docker stats client. socket defaults to /var/run/docker.sock.
+func New(socket string, pollInterval time.Duration) *Client {
+	if socket == "" {
+		socket = defaultSocket
+	}
+	if pollInterval <= 0 {
+		pollInterval = 10 * time.Second
+	}
+	return &Client{
+		socket:   socket,
+		interval: pollInterval,
+		http: &http.Client{
+			Timeout: 15 * time.Second,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", socket)
+				},
+			},
+		},
+		prev:   make(map[string]byteSnap),
+		stopCh: make(chan struct{}),
+	}
+}
+
+// Run starts the polling loop. Call in a goroutine.
+func (c *Client) Run() {
+	c.poll()
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.poll()
+		case <-c.stopCh:
+			return
+		}
+	}
+}
+
+// Stop terminates the polling loop.
+func (c *Client) Stop() {
+	select {
+	case <-c.stopCh:
+	default:
+		close(c.stopCh)
+	}
+}
+
+// GetAll returns the latest container stats sorted by total traffic.
+func (c *Client) GetAll() []ContainerStat {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	cp := make([]ContainerStat, len(c.stats))
+	copy(cp, c.stats)
+	return cp
+}
+
+// Available returns true if the client has successfully polled at least once.
+func (c *Client) Available() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.stats != nil
+}
+
+// ---------- internal ----------
+
+// listEntry is the subset of GET /containers/json we need.
+type listEntry struct {
+	ID    string   `json:"Id"`
+	Names []string `json:"Names"`
+	Image string   `json:"Image"`
+	State string   `json:"State"`
+}
+
+// statsEntry is the subset of GET /containers/{id}/stats we need.
+type statsEntry struct {
+	Networks map[string]struct {
+		RxBytes uint64 `json:"rx_bytes"`
+		TxBytes uint64 `json:"tx_bytes"`
+	} `json:"networks"`
+}
+
+func (c *Client) poll() {
+	var containers []listEntry
+	if err := c.getJSON("/containers/json", &containers); err != nil {
+		log.Printf("docker: list containers: %v", err)
+		return
+	}
+
+	now := time.Now()
+	dt := now.Sub(c.lastPoll).Seconds()
+
+	stats := make([]ContainerStat, 0, len(containers))
+	newPrev := make(map[string]byteSnap, len(containers))
+	for _, ct := range containers {
+		var se statsEntry
+		if err := c.getJSON("/containers/"+ct.ID+"/stats?stream=false&one-shot=true", &se); err != nil {
+			log.Printf("docker: stats for %s: %v", ct.ID[:12], err)
+			continue
+		}
+		cs := ContainerStat{
+			ID:       ct.ID[:12],
+			Name:     containerName(ct.Names),
+			Image:    ct.Image,
+			State:    ct.State,
+			Networks: make(map[string]NetStat, len(se.Networks)),
+		}
+		for iface, n := range se.Networks {
+			cs.Networks[iface] = NetStat{RxBytes: n.RxBytes, TxBytes: n.TxBytes}
+			cs.RxBytes += n.RxBytes
+			cs.TxBytes += n.TxBytes
+		}
+		if prev, ok := c.prev[cs.ID]; ok && dt > 0 && !c.lastPoll.IsZero() {
+			if cs.RxBytes >= prev.rx {
+				cs.RxRate = float64(cs.RxBytes-prev.rx) / dt
+			}
+			if cs.TxBytes >= prev.tx {
+				cs.TxRate = float64(cs.TxBytes-prev.tx) / dt
+			}
+		}
+		newPrev[cs.ID] = byteSnap{rx: cs.RxBytes, tx: cs.TxBytes}
+		stats = append(stats, cs)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].RxBytes+stats[i].TxBytes > stats[j].RxBytes+stats[j].TxBytes
+	})
+
+	c.mu.Lock()
+	c.stats = stats
+	c.prev = newPrev
+	c.lastPoll = now
+	c.mu.Unlock()
+}
+
+func (c *Client) getJSON(path string, v interface{}) error {
+	// Host is ignored by the unix-socket dialer but required by net/http
+	resp, err := c.http.Get("http://docker" + path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("status %d: %s", resp.StatusCode, string(body))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// containerName strips the leading slash Docker puts on primary names.
+func containerName(names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(names[0], "/")
+}
@@ -0,0 +1,355 @@
+// Package edgeos polls a Ubiquiti EdgeOS (EdgeRouter) or VyOS device with
+// the legacy web GUI for interface counters, NAT rule stats and DPI
+// ("traffic analysis") data via the /api/edge/data.json endpoint.
+package edgeos
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"bandwidth-monitor/httpclient"
+)
+
+const maxDPIEntries = 100 // top client/app pairs kept per poll
+
+type Client struct {
+	baseURL    string
+	user       string
+	pass       string
+	interval   time.Duration
+	httpClient *http.Client
+
+	mu      sync.RWMutex
+	summary *Summary
+	stopCh  chan struct{}
+
+	loggedIn bool
+
+	// rate tracking
+	lastPoll time.Time
+	prevIf   map[string]byteSnap // keyed by interface name
+}
+
+type byteSnap struct {
+	tx uint64
+	rx uint64
+}
+
+type InterfaceStat struct {
+	Name      string   `json:"name"`
+	Up        bool     `json:"up"`
+	Addresses []string `json:"addresses,omitempty"`
+	RxBytes   uint64   `json:"rx_bytes"`
+	TxBytes   uint64   `json:"tx_bytes"`
+	RxErrors  uint64   `json:"rx_errors"`
+	TxErrors  uint64   `json:"tx_errors"`
+	RxRate    float64  `json:"rx_rate"` // bytes/sec since last poll
+	TxRate    float64  `json:"tx_rate"`
+}
+
+type NATRuleStat struct {
+	Rule        string `json:"rule"`
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+	Packets     uint64 `json:"packets"`
+	Bytes       uint64 `json:"bytes"`
+}
+
+// DPIStat is one client/application pair from EdgeOS traffic analysis.
+// Only populated when DPI is enabled on the router.
+type DPIStat struct {
+	Client   string `json:"client"`
+	App      string `json:"app"`
+	Category string `json:"category,omitempty"`
+	RxBytes  uint64 `json:"rx_bytes"`
+	TxBytes  uint64 `json:"tx_bytes"`
+}
+
+type Summary struct {
+	Interfaces []InterfaceStat `json:"interfaces"`
+	NATRules   []NATRuleStat   `json:"nat_rules"`
+	DPI        []DPIStat       `json:"dpi,omitempty"`
+}
+
+func New(baseURL, user, pass string, pollInterval time.Duration) *Client {
+	jar, _ := cookiejar.New(nil)
+	// Routers speak self-signed HTTPS; skip verification
+	hc := httpclient.NewInsecure(15 * time.Second)
+	hc.Jar = jar
+	// The login form replies with a redirect; following it is fine,
+	// but we must not follow redirects on data requests (an expired
+	// session redirects to the login page instead of failing).
+	hc.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		user:       user,
+		pass:       pass,
+		interval:   pollInterval,
+		httpClient: hc,
+		stopCh:     make(chan struct{}),
+	}
+}
+
+func (c *Client) Run() {
+	c.poll()
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.poll()
+		case <-c.stopCh:
+			return
+		}
+	}
+}
+
+func (c *Client) Stop() {
+	select {
+	case <-c.stopCh:
+	default:
+		close(c.stopCh)
+	}
+}
+
+func (c *Client) GetSummary() *Summary {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.summary
+}
+
+func (c *Client) Available() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.summary != nil
+}
+
+func (c *Client) poll() {
+	if !c.loggedIn {
+		if err := c.login(); err != nil {
+			log.Printf("edgeos: login failed: %v", err)
+			return
+		}
+	}
+	dashboard, err := c.fetchData("dashboard")
+	if err != nil {
+		// If the session expired, re-login once and retry
+		log.Printf("edgeos: fetch dashboard: %v (re-authenticating)", err)
+		c.loggedIn = false
+		if err := c.login(); err != nil {
+			log.Printf("edgeos: re-login failed: %v", err)
+			return
+		}
+		dashboard, err = c.fetchData("dashboard")
+		if err != nil {
+			log.Printf("edgeos: fetch dashboard after re-login: %v", err)
+			return
+		}
+	}
+
+	now := time.Now()
+	dt := now.Sub(c.lastPoll).Seconds()
+	if c.lastPoll.IsZero() {
+		dt = 0
+	}
+
+	sum := &Summary{Interfaces: c.parseInterfaces(dashboard, dt)}
+
+	// NAT and DPI stats are best-effort: older firmware and VyOS builds
+	// don't expose them, and DPI requires traffic analysis to be enabled.
+	if raw, err := c.fetchData("nat_stats"); err == nil {
+		sum.NATRules = parseNATRules(raw)
+	}
+	if raw, err := c.fetchData("dpi_stats"); err == nil {
+		sum.DPI = parseDPI(raw)
+	}
+
+	newIf := make(map[string]byteSnap, len(sum.Interfaces))
+	for _, ifc := range sum.Interfaces {
+		newIf[ifc.Name] = byteSnap{tx: ifc.TxBytes, rx: ifc.RxBytes}
+	}
+
+	c.mu.Lock()
+	c.summary = sum
+	c.prevIf = newIf
+	c.lastPoll = now
+	c.mu.Unlock()
+}
+
+// login submits the GUI login form; the session cookie lands in the jar.
+func (c *Client) login() error {
+	form := url.Values{"username": {c.user}, "password": {c.pass}}
+	resp, err := c.httpClient.PostForm(c.baseURL+"/", form)
+	if err != nil {
+		return fmt.Errorf("POST %s/: %w", c.baseURL, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	// Successful login responds 303 to the dashboard; 200 means the login
+	// page was re-rendered (bad credentials).
+	if resp.StatusCode == http.StatusOK {
+		return fmt.Errorf("bad credentials")
+	}
+	if resp.StatusCode != http.StatusSeeOther && resp.StatusCode != http.StatusFound {
+		return fmt.Errorf("login returned status %d", resp.StatusCode)
+	}
+	c.loggedIn = true
+	return nil
+}
+
+// fetchData retrieves one named data set and returns its "output" payload.
+func (c *Client) fetchData(name string) (json.RawMessage, error) {
+	u := c.baseURL + "/api/edge/data.json?data=" + name
+	resp, err := c.httpClient.Get(u)
+	if err != nil {
+		return nil, fmt.Errorf("GET %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	var dr struct {
+		Success string          `json:"success"`
+		Output  json.RawMessage `json:"output"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&dr); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", name, err)
+	}
+	if dr.Success != "1" {
+		return nil, fmt.Errorf("%s request unsuccessful", name)
+	}
+	return dr.Output, nil
+}
+
+// rawInterface matches the dashboard interface entries. EdgeOS serializes
+// every counter as a string.
+type rawInterface struct {
+	Up        string   `json:"up"`
+	Addresses []string `json:"addresses"`
+	Stats     struct {
+		RxBytes  string `json:"rx_bytes"`
+		TxBytes  string `json:"tx_bytes"`
+		RxErrors string `json:"rx_errors"`
+		TxErrors string `json:"tx_errors"`
+	} `json:"stats"`
+}
+
+func (c *Client) parseInterfaces(raw json.RawMessage, dt float64) []InterfaceStat {
+	var out struct {
+		Interfaces map[string]rawInterface `json:"interfaces"`
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		log.Printf("edgeos: parse interfaces: %v", err)
+		return nil
+	}
+
+	stats := make([]InterfaceStat, 0, len(out.Interfaces))
+	for name, ri := range out.Interfaces {
+		s := InterfaceStat{
+			Name:      name,
+			Up:        ri.Up == "true",
+			Addresses: ri.Addresses,
+			RxBytes:   parseUint(ri.Stats.RxBytes),
+			TxBytes:   parseUint(ri.Stats.TxBytes),
+			RxErrors:  parseUint(ri.Stats.RxErrors),
+			TxErrors:  parseUint(ri.Stats.TxErrors),
+		}
+		if dt > 0 {
+			if prev, ok := c.prevIf[name]; ok && s.RxBytes >= prev.rx && s.TxBytes >= prev.tx {
+				s.RxRate = float64(s.RxBytes-prev.rx) / dt
+				s.TxRate = float64(s.TxBytes-prev.tx) / dt
+			}
+		}
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
+
+func parseNATRules(raw json.RawMessage) []NATRuleStat {
+	var out struct {
+		Rules []struct {
+			Rule        json.Number `json:"rule"`
+			Type        string      `json:"type"`
+			Description string      `json:"description"`
+			Packets     string      `json:"pkts"`
+			Bytes       string      `json:"bytes"`
+		} `json:"rules"`
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		log.Printf("edgeos: parse nat_stats: %v", err)
+		return nil
+	}
+	rules := make([]NATRuleStat, 0, len(out.Rules))
+	for _, r := range out.Rules {
+		rules = append(rules, NATRuleStat{
+			Rule:        r.Rule.String(),
+			Type:        r.Type,
+			Description: r.Description,
+			Packets:     parseUint(r.Packets),
+			Bytes:       parseUint(r.Bytes),
+		})
+	}
+	return rules
+}
+
+// parseDPI flattens the traffic-analysis export (client IP → "App|Category"
+// → counters) into the top entries by total bytes.
+func parseDPI(raw json.RawMessage) []DPIStat {
+	var export map[string]map[string]struct {
+		RxBytes string `json:"rx_bytes"`
+		TxBytes string `json:"tx_bytes"`
+	}
+	if err := json.Unmarshal(raw, &export); err != nil {
+		log.Printf("edgeos: parse dpi_stats: %v", err)
+		return nil
+	}
+
+	var stats []DPIStat
+	for client, apps := range export {
+		for key, counters := range apps {
+			s := DPIStat{
+				Client:  client,
+				App:     key,
+				RxBytes: parseUint(counters.RxBytes),
+				TxBytes: parseUint(counters.TxBytes),
+			}
+			if app, cat, ok := strings.Cut(key, "|"); ok {
+				s.App, s.Category = app, cat
+			}
+			if s.RxBytes == 0 && s.TxBytes == 0 {
+				continue
+			}
+			stats = append(stats, s)
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return (stats[i].RxBytes + stats[i].TxBytes) > (stats[j].RxBytes + stats[j].TxBytes)
+	})
+	if len(stats) > maxDPIEntries {
+		stats = stats[:maxDPIEntries]
+	}
+	return stats
+}
+
+func parseUint(s string) uint64 {
+	n, _ := strconv.ParseUint(strings.TrimSpace(s), 10, 64)
+	return n
+}
+
+func (c *Client) String() string {
+	return fmt.Sprintf("EdgeOS[%s]", c.baseURL)
+}
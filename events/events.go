@@ -0,0 +1,150 @@
+// Package events is the internal append-only event bus. Subsystems
+// publish notable occurrences — interface flaps, newly discovered
+// devices, capture errors — and the bus retains them for /api/events,
+// streams them to WebSocket subscribers and round-trips them through
+// the persist store. Publishing to a nil bus is a no-op, so subsystems
+// can publish unconditionally.
+package events
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRetention = 24 * time.Hour
+	maxEvents        = 10000 // hard cap regardless of retention
+	subBuffer        = 64
+)
+
+// Event is one published occurrence.
+type Event struct {
+	Time    time.Time `json:"time"`
+	Source  string    `json:"source"`            // publishing subsystem
+	Type    string    `json:"type"`              // e.g. "iface_down", "device_new"
+	Subject string    `json:"subject,omitempty"` // interface, IP, peer, ...
+	Message string    `json:"message"`
+}
+
+// Bus holds retained events and fans new ones out to subscribers.
+type Bus struct {
+	mu        sync.RWMutex
+	events    []Event
+	retention time.Duration
+	subs      map[chan Event]struct{}
+}
+
+// New creates a bus retaining events for the given window (zero keeps
+// the 24h default).
+func New(retention time.Duration) *Bus {
+	if retention <= 0 {
+		retention = defaultRetention
+	}
+	return &Bus{
+		retention: retention,
+		subs:      make(map[chan Event]struct{}),
+	}
+}
+
+// Publish appends one event and fans it out. A subscriber that has
+// fallen behind misses the event rather than blocking the publisher.
+func (b *Bus) Publish(source, typ, subject, message string) {
+	if b == nil {
+		return
+	}
+	e := Event{Time: time.Now(), Source: source, Type: typ, Subject: subject, Message: message}
+
+	b.mu.Lock()
+	b.events = append(b.events, e)
+	b.prune(e.Time)
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+	b.mu.Unlock()
+}
+
+// prune drops events past retention or over the hard cap. Caller holds
+// b.mu.
+func (b *Bus) prune(now time.Time) {
+	cutoff := now.Add(-b.retention)
+	idx := 0
+	for idx < len(b.events) && b.events[idx].Time.Before(cutoff) {
+		idx++
+	}
+	if over := len(b.events) - idx - maxEvents; over > 0 {
+		idx += over
+	}
+	if idx > 0 {
+		b.events = append([]Event(nil), b.events[idx:]...)
+	}
+}
+
+// Recent returns retained events newest first, optionally filtered by
+// source, capped at n entries (<=0 returns everything).
+func (b *Bus) Recent(n int, source string) []Event {
+	if b == nil {
+		return nil
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	out := make([]Event, 0, len(b.events))
+	for i := len(b.events) - 1; i >= 0; i-- {
+		if source != "" && b.events[i].Source != source {
+			continue
+		}
+		out = append(out, b.events[i])
+		if n > 0 && len(out) >= n {
+			break
+		}
+	}
+	return out
+}
+
+// Subscribe returns a channel receiving every event published from now
+// on. Pair with Unsubscribe.
+func (b *Bus) Subscribe() chan Event {
+	ch := make(chan Event, subBuffer)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+	return ch
+}
+
+// Unsubscribe detaches a channel returned by Subscribe.
+func (b *Bus) Unsubscribe(ch chan Event) {
+	b.mu.Lock()
+	delete(b.subs, ch)
+	b.mu.Unlock()
+}
+
+// ExportState serializes the retained events for the persist store.
+func (b *Bus) ExportState() ([]byte, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return json.Marshal(b.events)
+}
+
+// ImportState restores events saved by ExportState, dropping any past
+// retention. Call before publishers start.
+func (b *Bus) ImportState(data []byte) error {
+	var evs []Event
+	if err := json.Unmarshal(data, &evs); err != nil {
+		return err
+	}
+	cutoff := time.Now().Add(-b.retention)
+	keep := evs[:0]
+	for _, e := range evs {
+		if !e.Time.Before(cutoff) {
+			keep = append(keep, e)
+		}
+	}
+	b.mu.Lock()
+	b.events = keep
+	b.mu.Unlock()
+	return nil
+}
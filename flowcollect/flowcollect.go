@@ -0,0 +1,472 @@
+// Package flowcollect ingests flow exports — NetFlow v5/v9, IPFIX and
+// sFlow v5 — from switches and routers that can't be mirrored, feeding
+// the decoded flows into the talkers bucket pipeline. The dashboard
+// then works identically to capture mode, without pcap or CAP_NET_RAW
+// on this box. Decoding is deliberately narrow: addresses, protocol and
+// octet counts; everything else in the exports is skipped.
+package flowcollect
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	maxDatagram  = 65535
+	maxTemplates = 1024 // per-process cap across all exporters
+)
+
+// FlowSink receives decoded flows; implemented by talkers.Tracker.
+type FlowSink interface {
+	IngestFlow(srcIP, dstIP, proto, ipVersion string, bytes uint64)
+}
+
+// template is one cached NetFlow v9 / IPFIX record layout.
+type template struct {
+	fields []tplField
+	width  int // fixed record length (templates with variable fields are dropped)
+}
+
+type tplField struct {
+	typ    uint16
+	length int
+}
+
+// Collector listens for flow datagrams on one UDP socket.
+type Collector struct {
+	addr string
+	sink FlowSink
+
+	conn      net.PacketConn
+	templates map[string]*template // exporter:domain:id
+
+	mu   sync.Mutex
+	seen map[string]time.Time // exporter → first datagram, for the hello log
+
+	stopCh chan struct{}
+}
+
+// New creates a collector listening on addr ("ip:port" or ":port").
+func New(addr string, sink FlowSink) *Collector {
+	return &Collector{
+		addr:      addr,
+		sink:      sink,
+		templates: make(map[string]*template),
+		seen:      make(map[string]time.Time),
+		stopCh:    make(chan struct{}),
+	}
+}
+
+// Run binds the socket and decodes datagrams until Stop. Call in a
+// goroutine.
+func (c *Collector) Run() {
+	conn, err := net.ListenPacket("udp", c.addr)
+	if err != nil {
+		log.Printf("flowcollect: listen %s: %v", c.addr, err)
+		return
+	}
+	c.conn = conn
+	defer conn.Close()
+
+	buf := make([]byte, maxDatagram)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-c.stopCh:
+				return
+			default:
+				log.Printf("flowcollect: read: %v", err)
+				return
+			}
+		}
+		exporter := from.String()
+		if host, _, err := net.SplitHostPort(exporter); err == nil {
+			exporter = host
+		}
+		c.handle(buf[:n], exporter)
+	}
+}
+
+// Stop closes the socket, unblocking Run.
+func (c *Collector) Stop() {
+	select {
+	case <-c.stopCh:
+	default:
+		close(c.stopCh)
+		if c.conn != nil {
+			c.conn.Close()
+		}
+	}
+}
+
+// Available reports whether any exporter has sent a datagram yet.
+func (c *Collector) Available() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.seen) > 0
+}
+
+func (c *Collector) handle(data []byte, exporter string) {
+	if len(data) < 4 {
+		return
+	}
+	var kind string
+	switch binary.BigEndian.Uint16(data[:2]) {
+	case 5:
+		kind = "NetFlow v5"
+		c.decodeV5(data)
+	case 9:
+		kind = "NetFlow v9"
+		c.decodeV9(data, exporter)
+	case 10:
+		kind = "IPFIX"
+		c.decodeIPFIX(data, exporter)
+	case 0:
+		if binary.BigEndian.Uint32(data[:4]) != 5 {
+			return
+		}
+		kind = "sFlow v5"
+		c.decodeSFlow(data)
+	default:
+		return
+	}
+	c.hello(exporter, kind)
+}
+
+// hello logs each exporter once, so a misconfigured switch is easy to
+// spot without flooding the log.
+func (c *Collector) hello(exporter, kind string) {
+	c.mu.Lock()
+	_, known := c.seen[exporter]
+	if !known {
+		c.seen[exporter] = time.Now()
+	}
+	c.mu.Unlock()
+	if !known {
+		log.Printf("flowcollect: receiving %s from %s", kind, exporter)
+	}
+}
+
+func (c *Collector) emit(src, dst net.IP, proto uint8, bytes uint64) {
+	if src == nil || dst == nil || bytes == 0 {
+		return
+	}
+	ipVersion := "IPv4"
+	if src.To4() == nil {
+		ipVersion = "IPv6"
+	}
+	c.sink.IngestFlow(src.String(), dst.String(), protoName(proto), ipVersion, bytes)
+}
+
+// protoName collapses to the same names the Zeek ingester uses.
+func protoName(p uint8) string {
+	switch p {
+	case 6:
+		return "TCP"
+	case 17:
+		return "UDP"
+	case 1, 58:
+		return "ICMP"
+	default:
+		return "Other"
+	}
+}
+
+// ---------- NetFlow v5 ----------
+
+func (c *Collector) decodeV5(data []byte) {
+	r := &reader{b: data}
+	r.skip(2) // version
+	count := int(r.u16())
+	r.skip(18) // uptime, secs, nsecs, seq, engine type/id
+	sampling := r.u16()
+	rate := uint64(1)
+	if sampling>>14 == 1 && sampling&0x3fff > 0 {
+		rate = uint64(sampling & 0x3fff)
+	}
+	for i := 0; i < count && r.ok(); i++ {
+		src := net.IP(r.take(4))
+		dst := net.IP(r.take(4))
+		r.skip(8) // nexthop, input, output
+		r.skip(4) // packets
+		octets := uint64(r.u32())
+		r.skip(14) // first, last, ports, pad, tcp flags
+		proto := r.u8()
+		r.skip(9) // tos, AS numbers, masks, pad
+		if r.ok() {
+			c.emit(src, dst, proto, octets*rate)
+		}
+	}
+}
+
+// ---------- NetFlow v9 / IPFIX ----------
+
+// The two formats share the flowset structure; they differ in header
+// layout and which set IDs carry templates.
+
+func (c *Collector) decodeV9(data []byte, exporter string) {
+	r := &reader{b: data}
+	r.skip(12) // version, count, uptime, secs
+	r.skip(4)  // sequence
+	domain := r.u32()
+	c.decodeSets(r, exporter, domain, 0)
+}
+
+func (c *Collector) decodeIPFIX(data []byte, exporter string) {
+	r := &reader{b: data}
+	r.skip(12) // version, length, export time, sequence
+	domain := r.u32()
+	c.decodeSets(r, exporter, domain, 2)
+}
+
+func (c *Collector) decodeSets(r *reader, exporter string, domain uint32, tplSetID uint16) {
+	for r.ok() && r.remaining() >= 4 {
+		setID := r.u16()
+		setLen := int(r.u16())
+		if setLen < 4 || setLen-4 > r.remaining() {
+			return
+		}
+		set := &reader{b: r.take(setLen - 4)}
+		switch {
+		case setID == tplSetID:
+			c.parseTemplates(set, exporter, domain, tplSetID == 2)
+		case setID >= 256:
+			c.parseData(set, exporter, domain, setID)
+		}
+		// Options templates and anything else are skipped
+	}
+}
+
+func (c *Collector) parseTemplates(r *reader, exporter string, domain uint32, ipfix bool) {
+	for r.ok() && r.remaining() >= 4 {
+		id := r.u16()
+		count := int(r.u16())
+		t := &template{}
+		for i := 0; i < count && r.ok(); i++ {
+			typ := r.u16()
+			length := int(r.u16())
+			if ipfix && typ&0x8000 != 0 {
+				r.skip(4)    // enterprise number
+				typ = 0xffff // never matched below
+			}
+			t.fields = append(t.fields, tplField{typ: typ, length: length})
+			t.width += length
+		}
+		if !r.ok() || t.width == 0 {
+			return
+		}
+		for _, f := range t.fields {
+			if f.length == 0xffff {
+				// Variable-length fields make records unseekable;
+				// drop the template rather than misparse data
+				t = nil
+				break
+			}
+		}
+		if t == nil {
+			continue
+		}
+		if len(c.templates) >= maxTemplates {
+			return
+		}
+		c.templates[tplKey(exporter, domain, id)] = t
+	}
+}
+
+func (c *Collector) parseData(r *reader, exporter string, domain uint32, setID uint16) {
+	t, ok := c.templates[tplKey(exporter, domain, setID)]
+	if !ok {
+		return // template not seen yet; exporters resend periodically
+	}
+	for r.remaining() >= t.width {
+		var src, dst net.IP
+		var octets uint64
+		var proto uint8
+		for _, f := range t.fields {
+			v := r.take(f.length)
+			switch f.typ {
+			case 8, 27: // IPV4/IPV6_SRC_ADDR
+				src = net.IP(v)
+			case 12, 28: // IPV4/IPV6_DST_ADDR
+				dst = net.IP(v)
+			case 1: // IN_BYTES / octetDeltaCount
+				octets = uintN(v)
+			case 4: // PROTOCOL
+				if len(v) == 1 {
+					proto = v[0]
+				}
+			}
+		}
+		if !r.ok() {
+			return
+		}
+		c.emit(src, dst, proto, octets)
+	}
+}
+
+func tplKey(exporter string, domain uint32, id uint16) string {
+	return fmt.Sprintf("%s:%d:%d", exporter, domain, id)
+}
+
+// ---------- sFlow v5 ----------
+
+func (c *Collector) decodeSFlow(data []byte) {
+	r := &reader{b: data}
+	r.skip(4) // version
+	addrType := r.u32()
+	if addrType == 2 {
+		r.skip(16)
+	} else {
+		r.skip(4)
+	}
+	r.skip(12) // sub-agent, sequence, uptime
+	samples := int(r.u32())
+	for i := 0; i < samples && r.ok(); i++ {
+		sampleType := r.u32()
+		sampleLen := int(r.u32())
+		if sampleLen > r.remaining() {
+			return
+		}
+		sample := &reader{b: r.take(sampleLen)}
+		switch sampleType {
+		case 1: // flow sample
+			c.parseFlowSample(sample, false)
+		case 3: // expanded flow sample
+			c.parseFlowSample(sample, true)
+		}
+	}
+}
+
+func (c *Collector) parseFlowSample(r *reader, expanded bool) {
+	r.skip(4) // sequence
+	if expanded {
+		r.skip(8) // source id class + index
+	} else {
+		r.skip(4)
+	}
+	rate := uint64(r.u32())
+	if rate == 0 {
+		rate = 1
+	}
+	r.skip(8) // sample pool, drops
+	if expanded {
+		r.skip(16) // input/output format + value
+	} else {
+		r.skip(8)
+	}
+	records := int(r.u32())
+	for i := 0; i < records && r.ok(); i++ {
+		recType := r.u32()
+		recLen := int(r.u32())
+		if recLen > r.remaining() {
+			return
+		}
+		rec := &reader{b: r.take(recLen)}
+		if recType == 1 { // raw packet header
+			c.parseRawHeader(rec, rate)
+		}
+	}
+}
+
+func (c *Collector) parseRawHeader(r *reader, rate uint64) {
+	headerProto := r.u32()
+	frameLen := uint64(r.u32())
+	r.skip(8) // stripped, header length
+	if headerProto != 1 {
+		return // only ethernet headers are understood
+	}
+	r.skip(12) // MACs
+	etherType := r.u16()
+	if etherType == 0x8100 { // 802.1Q
+		r.skip(2)
+		etherType = r.u16()
+	}
+	var src, dst net.IP
+	var proto uint8
+	switch etherType {
+	case 0x0800:
+		r.skip(9)
+		proto = r.u8()
+		r.skip(2) // checksum
+		src = net.IP(r.take(4))
+		dst = net.IP(r.take(4))
+	case 0x86dd:
+		r.skip(6)
+		proto = r.u8()
+		r.skip(1) // hop limit
+		src = net.IP(r.take(16))
+		dst = net.IP(r.take(16))
+	default:
+		return
+	}
+	if r.ok() {
+		c.emit(src, dst, proto, frameLen*rate)
+	}
+}
+
+// ---------- byte reader ----------
+
+// reader walks a datagram; any overrun poisons it so callers can bail
+// with a single check instead of guarding every read.
+type reader struct {
+	b   []byte
+	off int
+	bad bool
+}
+
+func (r *reader) ok() bool       { return !r.bad }
+func (r *reader) remaining() int { return len(r.b) - r.off }
+
+func (r *reader) take(n int) []byte {
+	if r.bad || n < 0 || r.remaining() < n {
+		r.bad = true
+		return nil
+	}
+	v := r.b[r.off : r.off+n]
+	r.off += n
+	return v
+}
+
+func (r *reader) skip(n int) { r.take(n) }
+
+func (r *reader) u8() uint8 {
+	v := r.take(1)
+	if v == nil {
+		return 0
+	}
+	return v[0]
+}
+
+func (r *reader) u16() uint16 {
+	v := r.take(2)
+	if v == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(v)
+}
+
+func (r *reader) u32() uint32 {
+	v := r.take(4)
+	if v == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(v)
+}
+
+// uintN decodes a big-endian unsigned integer of 1-8 bytes, the widths
+// NetFlow/IPFIX use for counters.
+func uintN(v []byte) uint64 {
+	if len(v) > 8 {
+		return 0
+	}
+	var out uint64
+	for _, b := range v {
+		out = out<<8 | uint64(b)
+	}
+	return out
+}
@@ -0,0 +1,219 @@
+// Package flowexport emits finished per-host traffic buckets as NetFlow
+// v9 datagrams, so the monitor can feed existing flow pipelines
+// (ElastiFlow, nfcapd, ...) without a second capture process. Each
+// record carries the remote host as the source address with in/out
+// octets from the local network's perspective; the local side is
+// aggregated away and exported as the unspecified address.
+package flowexport
+
+import (
+	"bytes"
+	"encoding/binary"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"bandwidth-monitor/flowlog"
+)
+
+const (
+	templateV4ID = 256
+	templateV6ID = 257
+
+	// Records per datagram, keeping packets comfortably under a 1500
+	// MTU (v4 records are 28 bytes, v6 records 52)
+	maxPerPacket = 24
+)
+
+// Exporter sends NetFlow v9 packets to a single collector over UDP.
+// Templates are included in every datagram — wasteful for high-rate
+// exporters, but this one sends a handful of packets per minute and
+// collectors may restart at any time.
+type Exporter struct {
+	conn net.Conn
+	boot time.Time
+
+	mu  sync.Mutex
+	seq uint32
+}
+
+// New dials the collector address ("host:port"). UDP is connectionless,
+// so only resolution errors surface here.
+func New(addr string) (*Exporter, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Exporter{conn: conn, boot: time.Now()}, nil
+}
+
+// Stop closes the collector connection.
+func (e *Exporter) Stop() {
+	e.conn.Close()
+}
+
+// Export encodes the records into one or more datagrams and sends them.
+func (e *Exporter) Export(recs []flowlog.Record) {
+	var v4, v6 []flowlog.Record
+	for _, r := range recs {
+		ip := net.ParseIP(r.IP)
+		if ip == nil {
+			continue
+		}
+		if ip.To4() != nil {
+			v4 = append(v4, r)
+		} else {
+			v6 = append(v6, r)
+		}
+	}
+	for len(v4) > 0 || len(v6) > 0 {
+		var pkt4, pkt6 []flowlog.Record
+		if n := min(len(v4), maxPerPacket); n > 0 {
+			pkt4, v4 = v4[:n], v4[n:]
+		}
+		if n := min(len(v6), maxPerPacket-len(pkt4)); n > 0 {
+			pkt6, v6 = v6[:n], v6[n:]
+		}
+		if err := e.send(pkt4, pkt6); err != nil {
+			log.Printf("flowexport: send: %v", err)
+			return
+		}
+	}
+}
+
+// send writes one datagram: both templates plus the data flowsets.
+func (e *Exporter) send(v4, v6 []flowlog.Record) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	var body bytes.Buffer
+	writeTemplates(&body)
+	count := 2 // the two template records
+	if len(v4) > 0 {
+		e.writeFlowset(&body, templateV4ID, v4)
+		count += len(v4)
+	}
+	if len(v6) > 0 {
+		e.writeFlowset(&body, templateV6ID, v6)
+		count += len(v6)
+	}
+
+	var pkt bytes.Buffer
+	writeU16(&pkt, 9) // version
+	writeU16(&pkt, uint16(count))
+	writeU32(&pkt, uint32(now.Sub(e.boot).Milliseconds())) // sysUptime
+	writeU32(&pkt, uint32(now.Unix()))
+	writeU32(&pkt, e.seq)
+	writeU32(&pkt, 0) // source ID
+	pkt.Write(body.Bytes())
+	e.seq++
+
+	_, err := e.conn.Write(pkt.Bytes())
+	return err
+}
+
+// v9 field types used by the templates.
+const (
+	fieldInBytes  = 1
+	fieldInPkts   = 2
+	fieldSrcV4    = 8
+	fieldDstV4    = 12
+	fieldLastSw   = 21
+	fieldFirstSw  = 22
+	fieldOutBytes = 23
+	fieldSrcV6    = 27
+	fieldDstV6    = 28
+)
+
+// writeTemplates appends the template flowset defining both record
+// layouts: address pair, in/out octets, packets, first/last switched.
+func writeTemplates(b *bytes.Buffer) {
+	fields := func(srcType, dstType, addrLen uint16) [][2]uint16 {
+		return [][2]uint16{
+			{srcType, addrLen},
+			{dstType, addrLen},
+			{fieldInBytes, 4},
+			{fieldOutBytes, 4},
+			{fieldInPkts, 4},
+			{fieldFirstSw, 4},
+			{fieldLastSw, 4},
+		}
+	}
+	tpls := []struct {
+		id uint16
+		f  [][2]uint16
+	}{
+		{templateV4ID, fields(fieldSrcV4, fieldDstV4, 4)},
+		{templateV6ID, fields(fieldSrcV6, fieldDstV6, 16)},
+	}
+
+	var set bytes.Buffer
+	for _, t := range tpls {
+		writeU16(&set, t.id)
+		writeU16(&set, uint16(len(t.f)))
+		for _, f := range t.f {
+			writeU16(&set, f[0])
+			writeU16(&set, f[1])
+		}
+	}
+	writeU16(b, 0) // template flowset ID
+	writeU16(b, uint16(4+set.Len()))
+	b.Write(set.Bytes())
+}
+
+// writeFlowset appends one data flowset for records of a single family.
+func (e *Exporter) writeFlowset(b *bytes.Buffer, tplID uint16, recs []flowlog.Record) {
+	var set bytes.Buffer
+	for _, r := range recs {
+		ip := net.ParseIP(r.IP)
+		if tplID == templateV4ID {
+			set.Write(ip.To4())
+			set.Write(net.IPv4zero.To4())
+		} else {
+			set.Write(ip.To16())
+			set.Write(net.IPv6zero)
+		}
+		writeU32(&set, clamp32(r.RxBytes))
+		writeU32(&set, clamp32(r.TxBytes))
+		writeU32(&set, clamp32(r.Packets))
+		writeU32(&set, e.uptimeAt(r.Start))
+		writeU32(&set, e.uptimeAt(r.End))
+	}
+	for set.Len()%4 != 0 {
+		set.WriteByte(0)
+	}
+	writeU16(b, tplID)
+	writeU16(b, uint16(4+set.Len()))
+	b.Write(set.Bytes())
+}
+
+// uptimeAt maps a unix-ms timestamp onto the exporter's sysUptime
+// clock, which is what FIRST/LAST_SWITCHED are defined against.
+func (e *Exporter) uptimeAt(unixMs int64) uint32 {
+	ms := e.boot.UnixMilli()
+	if unixMs < ms {
+		return 0 // bucket started before this process; pin to boot
+	}
+	return uint32(unixMs - ms)
+}
+
+func clamp32(v uint64) uint32 {
+	if v > 0xffffffff {
+		return 0xffffffff
+	}
+	return uint32(v)
+}
+
+func writeU16(b *bytes.Buffer, v uint16) {
+	var buf [2]byte
+	binary.BigEndian.PutUint16(buf[:], v)
+	b.Write(buf[:])
+}
+
+func writeU32(b *bytes.Buffer, v uint32) {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], v)
+	b.Write(buf[:])
+}
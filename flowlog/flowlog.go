@@ -0,0 +1,212 @@
+// Package flowlog persists finished per-host traffic buckets to an
+// append-only JSON-lines file — a lightweight audit trail answering
+// "what talked to what last Tuesday" without an external database.
+// Retention is time-based: an hourly compaction pass rewrites the file,
+// dropping records older than the configured window.
+package flowlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const compactInterval = time.Hour
+
+// Record is one finished per-host minute bucket.
+type Record struct {
+	Start   int64  `json:"start"` // unix ms
+	End     int64  `json:"end"`
+	IP      string `json:"ip"`
+	Bytes   uint64 `json:"bytes"`
+	RxBytes uint64 `json:"rx_bytes"`
+	TxBytes uint64 `json:"tx_bytes"`
+	Packets uint64 `json:"packets"`
+	Country string `json:"country,omitempty"`
+	ASN     uint   `json:"asn,omitempty"`
+	ASOrg   string `json:"as_org,omitempty"`
+	Device  string `json:"device,omitempty"`
+}
+
+// Logger appends records to the JSONL file and enforces retention.
+type Logger struct {
+	path      string
+	retention time.Duration
+
+	mu sync.Mutex
+	f  *os.File
+
+	stopCh chan struct{}
+}
+
+// New creates a flow logger writing to path, keeping records for the
+// given retention window.
+func New(path string, retention time.Duration) *Logger {
+	return &Logger{
+		path:      path,
+		retention: retention,
+		stopCh:    make(chan struct{}),
+	}
+}
+
+// Run compacts on a fixed cadence. Call in a goroutine.
+func (l *Logger) Run() {
+	l.compact()
+	ticker := time.NewTicker(compactInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			l.compact()
+		case <-l.stopCh:
+			l.mu.Lock()
+			if l.f != nil {
+				l.f.Close()
+				l.f = nil
+			}
+			l.mu.Unlock()
+			return
+		}
+	}
+}
+
+// Stop terminates the compaction loop and closes the file.
+func (l *Logger) Stop() {
+	select {
+	case <-l.stopCh:
+	default:
+		close(l.stopCh)
+	}
+}
+
+// Append writes records to the log.
+func (l *Logger) Append(recs []Record) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.openLocked() {
+		return
+	}
+	w := bufio.NewWriter(l.f)
+	enc := json.NewEncoder(w)
+	for _, r := range recs {
+		if err := enc.Encode(r); err != nil {
+			log.Printf("flowlog: encode: %v", err)
+			return
+		}
+	}
+	if err := w.Flush(); err != nil {
+		log.Printf("flowlog: write %s: %v", l.path, err)
+	}
+}
+
+// Query returns records overlapping [from, to] (unix ms, zero means
+// unbounded) for the given IP (empty matches all), newest last, capped
+// at limit entries from the tail.
+func (l *Logger) Query(from, to int64, ip string, limit int) []Record {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.Open(l.path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var out []Record
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		var r Record
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			continue
+		}
+		if from != 0 && r.End < from {
+			continue
+		}
+		if to != 0 && r.Start > to {
+			continue
+		}
+		if ip != "" && r.IP != ip {
+			continue
+		}
+		out = append(out, r)
+	}
+	if limit > 0 && len(out) > limit {
+		out = out[len(out)-limit:]
+	}
+	return out
+}
+
+// openLocked lazily opens the append handle. Caller holds l.mu.
+func (l *Logger) openLocked() bool {
+	if l.f != nil {
+		return true
+	}
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		log.Printf("flowlog: open %s: %v", l.path, err)
+		return false
+	}
+	l.f = f
+	return true
+}
+
+// compact rewrites the file keeping only records within retention.
+func (l *Logger) compact() {
+	cutoff := time.Now().Add(-l.retention).UnixMilli()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	in, err := os.Open(l.path)
+	if err != nil {
+		return // nothing logged yet
+	}
+	tmp := l.path + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		in.Close()
+		log.Printf("flowlog: write %s: %v", tmp, err)
+		return
+	}
+
+	w := bufio.NewWriter(out)
+	sc := bufio.NewScanner(in)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		var r Record
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			continue
+		}
+		if r.End < cutoff {
+			continue
+		}
+		w.WriteString(line)
+		w.WriteByte('\n')
+	}
+	in.Close()
+	if err := w.Flush(); err != nil {
+		log.Printf("flowlog: flush %s: %v", tmp, err)
+		out.Close()
+		return
+	}
+	out.Close()
+
+	// Swap in the compacted file and reopen the append handle on it
+	if l.f != nil {
+		l.f.Close()
+		l.f = nil
+	}
+	if err := os.Rename(tmp, l.path); err != nil {
+		log.Printf("flowlog: rename %s: %v", tmp, err)
+	}
+}
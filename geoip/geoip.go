@@ -18,12 +18,18 @@ type Result struct {
 	ASOrg       string `json:"as_org,omitempty"`
 }
 
-// DB wraps the MaxMind MMDB readers with a lookup cache.
+// DB wraps the MaxMind MMDB readers with a lookup cache. The cache is
+// keyed by the announced prefix the databases hand back, not the
+// individual IP, so every address inside e.g. one CDN /20 shares a
+// single entry — on a busy link that is orders of magnitude fewer
+// entries and tree walks.
 type DB struct {
 	country *maxminddb.Reader
 	asn     *maxminddb.Reader
 	mu      sync.RWMutex
-	cache   map[string]*Result
+	cache   map[string]*Result // masked prefix ("203.0.113.0/24") → result
+	lens4   []int              // prefix lengths present in cache, most specific first
+	lens6   []int
 }
 
 // cityRecord is the minimal struct for MMDB city/country lookups.
@@ -88,48 +94,148 @@ func (db *DB) Available() bool {
 	return db.country != nil || db.asn != nil
 }
 
-// Lookup returns geo information for an IP address. Results are cached.
+// Lookup returns geo information for an IP address. Results are cached
+// per containing prefix.
 func (db *DB) Lookup(ipStr string) *Result {
 	if db == nil || !db.Available() {
 		return nil
 	}
 
-	db.mu.RLock()
-	if r, ok := db.cache[ipStr]; ok {
-		db.mu.RUnlock()
-		return r
-	}
-	db.mu.RUnlock()
-
 	ip := net.ParseIP(ipStr)
 	if ip == nil {
 		return nil
 	}
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+
+	if r := db.cached(ip); r != nil {
+		return r
+	}
 
 	r := &Result{}
+	var network *net.IPNet
 
 	if db.country != nil {
 		var rec cityRecord
-		if err := db.country.Lookup(ip, &rec); err == nil {
-			r.Country = rec.Country.ISOCode
-			r.CountryName = rec.Country.Names["en"]
-			if name, ok := rec.City.Names["en"]; ok {
-				r.City = name
+		if n, ok, err := db.country.LookupNetwork(ip, &rec); err == nil {
+			if ok {
+				r.Country = rec.Country.ISOCode
+				r.CountryName = rec.Country.Names["en"]
+				if name, ok := rec.City.Names["en"]; ok {
+					r.City = name
+				}
 			}
+			network = narrower(network, n)
 		}
 	}
 
 	if db.asn != nil {
 		var rec asnRecord
-		if err := db.asn.Lookup(ip, &rec); err == nil {
-			r.ASN = rec.ASN
-			r.ASOrg = rec.Org
+		if n, ok, err := db.asn.LookupNetwork(ip, &rec); err == nil {
+			if ok {
+				r.ASN = rec.ASN
+				r.ASOrg = rec.Org
+			}
+			network = narrower(network, n)
 		}
 	}
 
+	db.store(ip, network, r)
+	return r
+}
+
+// LookupCached returns the result for ip only if its prefix is already
+// in the cache — no MMDB read. Callers rationing lookups (enrichment
+// budget) use it to serve the cheap case before deciding to spend.
+func (db *DB) LookupCached(ipStr string) *Result {
+	if db == nil || !db.Available() {
+		return nil
+	}
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return nil
+	}
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	return db.cached(ip)
+}
+
+// cached checks the prefix cache: mask the IP at every prefix length
+// seen so far and look for a hit. The length list stays tiny in
+// practice (announced prefixes cluster around a handful of sizes), so
+// this is a few map probes, not a tree walk.
+func (db *DB) cached(ip net.IP) *Result {
+	bits := len(ip) * 8
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	lens := db.lens4
+	if bits == 128 {
+		lens = db.lens6
+	}
+	for _, ones := range lens {
+		mask := net.CIDRMask(ones, bits)
+		key := (&net.IPNet{IP: ip.Mask(mask), Mask: mask}).String()
+		if r, ok := db.cache[key]; ok {
+			return r
+		}
+	}
+	return nil
+}
+
+// store caches the result under the prefix both databases agree is
+// uniform. A nil or family-mismatched network (both lookups failed)
+// degrades to a host route so repeated misses still get cached.
+func (db *DB) store(ip net.IP, network *net.IPNet, r *Result) {
+	bits := len(ip) * 8
+	if network == nil || maskBits(network) != bits {
+		mask := net.CIDRMask(bits, bits)
+		network = &net.IPNet{IP: ip, Mask: mask}
+	}
+	ones, _ := network.Mask.Size()
+
 	db.mu.Lock()
-	db.cache[ipStr] = r
-	db.mu.Unlock()
+	defer db.mu.Unlock()
 
-	return r
+	db.cache[network.String()] = r
+
+	lens := &db.lens4
+	if bits == 128 {
+		lens = &db.lens6
+	}
+	for i, l := range *lens {
+		if l == ones {
+			return
+		}
+		if l < ones {
+			*lens = append((*lens)[:i], append([]int{ones}, (*lens)[i:]...)...)
+			return
+		}
+	}
+	*lens = append(*lens, ones)
+}
+
+// narrower keeps the more specific of two networks containing the same
+// IP; the record is constant across the narrower one in both databases.
+func narrower(a, b *net.IPNet) *net.IPNet {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	aOnes, aBits := a.Mask.Size()
+	bOnes, bBits := b.Mask.Size()
+	if aBits == bBits && bOnes > aOnes {
+		return b
+	}
+	return a
+}
+
+func maskBits(n *net.IPNet) int {
+	_, bits := n.Mask.Size()
+	return bits
 }
@@ -0,0 +1,92 @@
+package handler
+
+// Optional authentication for the HTTP surface. AUTH_USER/AUTH_PASS
+// enable HTTP basic auth (the browser's dialog covers the dashboard);
+// API_TOKENS is a comma-separated list of bearer tokens for headless
+// clients. Either credential is accepted when both are configured.
+// Once enabled, /api/* and the WebSockets are protected; static assets
+// join the gate with AUTH_STATIC=true. WebSocket clients that cannot
+// set headers may pass ?token=<token> instead.
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+var (
+	authUser   string
+	authPass   string
+	authTokens []string
+	authStatic bool
+)
+
+// ConfigureAuth installs the credentials checked by RequireAuth and
+// reports whether any were supplied. Call once at startup.
+func ConfigureAuth(user, pass, tokens string, protectStatic bool) bool {
+	authUser, authPass = user, pass
+	authStatic = protectStatic
+	authTokens = nil
+	for _, t := range strings.Split(tokens, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			authTokens = append(authTokens, t)
+		}
+	}
+	return authEnabled()
+}
+
+func authEnabled() bool {
+	return (authUser != "" && authPass != "") || len(authTokens) > 0
+}
+
+// RequireAuth gates /api/* (and, with AUTH_STATIC, everything else)
+// behind the configured credentials. A pass-through when none are
+// configured. The cluster agent WebSocket keeps its own CLUSTER_TOKEN
+// gate so remote agents don't also need an API token.
+func RequireAuth(next http.Handler) http.Handler {
+	if !authEnabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authStatic && !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r.URL.Path == "/api/cluster/ws" || authorized(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if authUser != "" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="bandwidth-monitor"`)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+	})
+}
+
+func authorized(r *http.Request) bool {
+	if authUser != "" && authPass != "" {
+		if u, p, ok := r.BasicAuth(); ok {
+			userOK := subtle.ConstantTimeCompare([]byte(u), []byte(authUser)) == 1
+			passOK := subtle.ConstantTimeCompare([]byte(p), []byte(authPass)) == 1
+			if userOK && passOK {
+				return true
+			}
+		}
+	}
+	if len(authTokens) > 0 {
+		supplied := ""
+		if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+			supplied = strings.TrimPrefix(h, "Bearer ")
+		} else {
+			// Browsers cannot attach headers to WebSocket upgrades
+			supplied = r.URL.Query().Get("token")
+		}
+		for _, t := range authTokens {
+			if subtle.ConstantTimeCompare([]byte(supplied), []byte(t)) == 1 {
+				return true
+			}
+		}
+	}
+	return false
+}
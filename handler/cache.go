@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Response cache for the expensive aggregation endpoints: several
+// dashboard widgets polling at once would otherwise each walk the full
+// bucket history. Entries are keyed by request URI and live for a few
+// hundred milliseconds — long enough to collapse a poll burst, short
+// enough that nobody notices staleness. Concurrent misses for the same
+// key each compute once; with sub-second TTLs that window is too small
+// to be worth a singleflight.
+
+// Cache tiers: Short for per-widget aggregations recomputed from the
+// bucket window, Long for endpoints that read history off disk.
+const (
+	CacheShort = 250 * time.Millisecond
+	CacheLong  = 2 * time.Second
+)
+
+type cacheEntry struct {
+	body        []byte
+	contentType string
+	expires     time.Time
+}
+
+var (
+	respCache   = make(map[string]*cacheEntry)
+	respCacheMu sync.RWMutex
+	cacheHits   atomic.Uint64
+	cacheMisses atomic.Uint64
+)
+
+// cacheRecorder tees a handler's response so it can be replayed.
+type cacheRecorder struct {
+	http.ResponseWriter
+	status int
+	body   []byte
+}
+
+func (c *cacheRecorder) WriteHeader(status int) {
+	c.status = status
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *cacheRecorder) Write(b []byte) (int, error) {
+	c.body = append(c.body, b...)
+	return c.ResponseWriter.Write(b)
+}
+
+// Cached wraps a handler with a TTL response cache. Only successful GET
+// responses are stored; everything else passes straight through.
+func Cached(ttl time.Duration, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			next(w, r)
+			return
+		}
+		key := r.URL.RequestURI()
+
+		respCacheMu.RLock()
+		e, ok := respCache[key]
+		respCacheMu.RUnlock()
+		if ok && time.Now().Before(e.expires) {
+			cacheHits.Add(1)
+			w.Header().Set("Content-Type", e.contentType)
+			w.Write(e.body)
+			return
+		}
+		cacheMisses.Add(1)
+
+		rec := &cacheRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r)
+		if rec.status != http.StatusOK {
+			return
+		}
+		respCacheMu.Lock()
+		respCache[key] = &cacheEntry{
+			body:        rec.body,
+			contentType: rec.Header().Get("Content-Type"),
+			expires:     time.Now().Add(ttl),
+		}
+		// Distinct ?limit=/?range= combinations accumulate; sweep the
+		// expired ones whenever the map gets noticeably large
+		if len(respCache) > 1024 {
+			now := time.Now()
+			for k, e := range respCache {
+				if now.After(e.expires) {
+					delete(respCache, k)
+				}
+			}
+		}
+		respCacheMu.Unlock()
+	}
+}
+
+var startTime = time.Now()
+
+// SelfMetrics reports the daemon's own resource use and the response
+// cache's effectiveness.
+func SelfMetrics() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+		hits, misses := cacheHits.Load(), cacheMisses.Load()
+		hitPct := 0.0
+		if hits+misses > 0 {
+			hitPct = float64(hits) / float64(hits+misses) * 100
+		}
+		respCacheMu.RLock()
+		entries := len(respCache)
+		respCacheMu.RUnlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"uptime_s":      int64(time.Since(startTime).Seconds()),
+			"goroutines":    runtime.NumGoroutine(),
+			"heap_alloc":    ms.HeapAlloc,
+			"heap_sys":      ms.HeapSys,
+			"gc_runs":       ms.NumGC,
+			"cache_hits":    hits,
+			"cache_misses":  misses,
+			"cache_hit_pct": hitPct,
+			"cache_entries": entries,
+		})
+	}
+}
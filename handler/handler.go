@@ -1,15 +1,52 @@
 package handler
 
 import (
+	"crypto/subtle"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
+	"bandwidth-monitor/alerts"
+	"bandwidth-monitor/availability"
+	"bandwidth-monitor/budget"
+	"bandwidth-monitor/capture"
+	"bandwidth-monitor/cluster"
 	"bandwidth-monitor/collector"
 	"bandwidth-monitor/dns"
+	"bandwidth-monitor/dnslog"
+	"bandwidth-monitor/docker"
+	"bandwidth-monitor/edgeos"
+	"bandwidth-monitor/events"
+	"bandwidth-monitor/flowlog"
+	"bandwidth-monitor/httpcheck"
+	"bandwidth-monitor/integration"
+	"bandwidth-monitor/inventory"
+	"bandwidth-monitor/modem"
+	"bandwidth-monitor/mtu"
+	"bandwidth-monitor/netbox"
+	"bandwidth-monitor/nftacct"
+	"bandwidth-monitor/openvpn"
+	"bandwidth-monitor/openwrt"
+	"bandwidth-monitor/opnsense"
+	"bandwidth-monitor/overrides"
+	"bandwidth-monitor/privacy"
+	"bandwidth-monitor/prober"
+	"bandwidth-monitor/procmon"
+	"bandwidth-monitor/proxmox"
+	"bandwidth-monitor/quota"
+	"bandwidth-monitor/snmp"
+	"bandwidth-monitor/suricata"
 	"bandwidth-monitor/talkers"
+	"bandwidth-monitor/tracer"
 	"bandwidth-monitor/unifi"
+	"bandwidth-monitor/version"
+	"bandwidth-monitor/wan"
+	"bandwidth-monitor/wireguard"
 
 	"github.com/gorilla/websocket"
 )
@@ -18,31 +55,1021 @@ var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool { return true },
 }
 
+// nodeLabel identifies this instance in multi-node setups; attached to
+// every API response (X-Node-Label header) and composite payload.
+var nodeLabel string
+
+// SetNodeLabel configures the node/site label. Call once at startup.
+func SetNodeLabel(label string) { nodeLabel = label }
+
+// NodeLabel returns the configured node/site label ("" when unset).
+func NodeLabel() string { return nodeLabel }
+
+// Top-N truncation defaults: talker lists cap at 10 entries,
+// country/ASN breakdowns at 20. TOP_LIMIT raises both globally;
+// ?limit= overrides either per request.
+var (
+	topTalkerLimit = 10
+	breakdownLimit = 20
+)
+
+// SetTopLimit overrides the default top-N caps. Call once at startup.
+func SetTopLimit(n int) {
+	topTalkerLimit = n
+	breakdownLimit = n
+}
+
+// limitParam returns the ?limit= query parameter when it is a positive
+// integer, otherwise fallback.
+func limitParam(r *http.Request, fallback int) int {
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return fallback
+}
+
+// WithNodeLabel stamps every response with the X-Node-Label header so
+// downstream systems can distinguish sources.
+func WithNodeLabel(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if nodeLabel != "" {
+			w.Header().Set("X-Node-Label", nodeLabel)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func InterfaceStats(c *collector.Collector) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(c.GetAll())
+		json.NewEncoder(w).Encode(c.GetAll())
+	}
+}
+
+func InterfaceHistory(c *collector.Collector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(c.GetHistory())
+	}
+}
+
+func TopTalkersBandwidth(t *talkers.Tracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(t.TopByBandwidth(limitParam(r, topTalkerLimit)))
+	}
+}
+
+// TopTalkersVolume serves cumulative top talkers. The whole retained
+// window by default; ?window=1h or ?from=/?to= (unix ms) narrow it,
+// ?limit= adjusts the list length.
+func TopTalkersVolume(t *talkers.Tracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		var from, to time.Time
+		q := r.URL.Query()
+		if v := q.Get("window"); v != "" {
+			if d, err := time.ParseDuration(v); err == nil && d > 0 {
+				from = time.Now().Add(-d)
+			}
+		}
+		if v := q.Get("from"); v != "" {
+			if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+				from = time.UnixMilli(ms)
+			}
+		}
+		if v := q.Get("to"); v != "" {
+			if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+				to = time.UnixMilli(ms)
+			}
+		}
+		json.NewEncoder(w).Encode(t.TopByVolumeRange(limitParam(r, topTalkerLimit), from, to))
+	}
+}
+
+// MTUEvents returns path-MTU findings from the detector.
+func MTUEvents(d *mtu.Detector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if d == nil {
+			w.Write([]byte("null"))
+			return
+		}
+		json.NewEncoder(w).Encode(d.GetEvents())
+	}
+}
+
+// LANCounters returns per-LAN-device totals from nftables accounting;
+// null unless NFT_ACCOUNTING is enabled.
+func LANCounters(c *nftacct.Collector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if c == nil {
+			w.Write([]byte("null"))
+			return
+		}
+		json.NewEncoder(w).Encode(c.GetAll())
+	}
+}
+
+// Processes returns per-process traffic attribution (nethogs-style);
+// null unless PROCESS_MONITOR is enabled.
+func Processes(m *procmon.Monitor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if m == nil {
+			w.Write([]byte("null"))
+			return
+		}
+		json.NewEncoder(w).Encode(m.GetAll())
+	}
+}
+
+func DSCPBreakdown(t *talkers.Tracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(t.GetDSCPBreakdown())
+	}
+}
+
+// DualStack returns per-destination IPv4/IPv6 byte splits.
+func DualStack(t *talkers.Tracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(t.GetDualStackBreakdown(limitParam(r, breakdownLimit)))
+	}
+}
+
+// VPNLeaks reports protected addresses caught in cleartext on the raw
+// WAN; null unless VPN_PROTECTED_NETS is configured.
+func VPNLeaks(t *talkers.Tracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(t.GetLeaks())
+	}
+}
+
+// ARPAnomalies reports detected MAC/IP conflicts and gratuitous-ARP
+// storms; null unless ARP_WATCH is enabled.
+func ARPAnomalies(t *talkers.Tracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(t.GetARPAnomalies())
+	}
+}
+
+// ProtocolSeries returns bytes per minute per L4 protocol.
+func ProtocolSeries(t *talkers.Tracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(t.GetProtocolSeries())
+	}
+}
+
+// IPVersionSeries returns bytes per minute per IP version.
+func IPVersionSeries(t *talkers.Tracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(t.GetIPVersionSeries())
+	}
+}
+
+// FlowHistory searches the persisted flow log. Query parameters: from/to
+// (unix ms), ip, limit (default 1000 newest matches).
+func FlowHistory(l *flowlog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if l == nil {
+			w.Write([]byte("null"))
+			return
+		}
+		q := r.URL.Query()
+		var from, to int64
+		if v := q.Get("from"); v != "" {
+			from, _ = strconv.ParseInt(v, 10, 64)
+		}
+		if v := q.Get("to"); v != "" {
+			to, _ = strconv.ParseInt(v, 10, 64)
+		}
+		limit := 1000
+		if v := q.Get("limit"); v != "" {
+			if n, err := strconv.Atoi(v); err == nil && n > 0 {
+				limit = n
+			}
+		}
+		json.NewEncoder(w).Encode(l.Query(from, to, q.Get("ip"), limit))
+	}
+}
+
+// DNSAnalytics serves query-log aggregates: per-client hourly counts for
+// the last ?hours= (default 24), the newest first-seen domains, and with
+// ?domain= the first-seen timestamp of one domain. Null unless
+// DNS_QUERYLOG is enabled.
+func DNSAnalytics(l *dnslog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if l == nil {
+			w.Write([]byte("null"))
+			return
+		}
+		q := r.URL.Query()
+		if d := q.Get("domain"); d != "" {
+			json.NewEncoder(w).Encode(map[string]any{
+				"domain":     d,
+				"first_seen": l.FirstSeenOf(d),
+			})
+			return
+		}
+		hours := 24
+		if v := q.Get("hours"); v != "" {
+			if n, err := strconv.Atoi(v); err == nil && n > 0 {
+				hours = n
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"hourly":      l.PerClientHourly(hours),
+			"new_domains": l.NewestDomains(100),
+		})
+	}
+}
+
+// Integrations lists registered integrations and their data readiness.
+func Integrations(reg *integration.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if reg == nil {
+			w.Write([]byte("null"))
+			return
+		}
+		json.NewEncoder(w).Encode(reg.Statuses())
+	}
+}
+
+// LANMatrix returns the internal subnet-to-subnet traffic matrix; null
+// unless LAN_MATRIX is enabled.
+func LANMatrix(t *talkers.Tracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(t.GetLANMatrix())
+	}
+}
+
+// ICMPSources lists the hosts originating the most ICMP, with the
+// per-type breakdown already visible in the protocol stats. ?limit=
+// overrides the list cap.
+func ICMPSources(t *talkers.Tracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(t.ICMPSources(limitParam(r, topTalkerLimit)))
+	}
+}
+
+// TalkerClients serves per-internal-device attribution: the top LAN
+// clients by external traffic, or — with ?ip=<client> — that device's
+// remote destinations. Responds null until TALKERS_CLIENTS is enabled.
+// ?limit= overrides the list cap.
+func TalkerClients(t *talkers.Tracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		n := limitParam(r, topTalkerLimit)
+		if ip := r.URL.Query().Get("ip"); ip != "" {
+			json.NewEncoder(w).Encode(t.ClientRemotes(ip, n))
+			return
+		}
+		json.NewEncoder(w).Encode(t.TopClients(n))
+	}
+}
+
+// CaptureStatus reports each capture device's snaplen, active BPF
+// filter and whether capture is running.
+func CaptureStatus(t *talkers.Tracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(t.CaptureStatuses())
+	}
+}
+
+// Quota reports monthly data-cap usage per capped interface; null
+// unless QUOTA_CAPS is configured.
+func Quota(t *quota.Tracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if t == nil {
+			w.Write([]byte("null"))
+			return
+		}
+		json.NewEncoder(w).Encode(t.GetAll())
+	}
+}
+
+// WANStatus reports the active default-route interface, the failover
+// history and the active-WAN timeline; null unless WAN_FAILOVER is on.
+func WANStatus(m *wan.Monitor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if m == nil {
+			w.Write([]byte("null"))
+			return
+		}
+		json.NewEncoder(w).Encode(m.GetStatus())
+	}
+}
+
+// Budgets reports daily per-device budget usage; empty unless BUDGETS
+// is configured.
+func Budgets(t *budget.Tracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if t == nil {
+			w.Write([]byte("[]"))
+			return
+		}
+		json.NewEncoder(w).Encode(t.GetAll())
+	}
+}
+
+// Alerts reports the current state of every evaluated alert rule.
+func Alerts(e *alerts.Engine) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if e == nil {
+			w.Write([]byte("[]"))
+			return
+		}
+		json.NewEncoder(w).Encode(e.GetAll())
+	}
+}
+
+// AlertMutes is the quiet-hours CRUD API: GET lists the configured
+// mutes, POST creates one from {"kind","target","windows"} (kind and
+// target optional, windows in the schedule syntax) and DELETE removes
+// one by "id".
+func AlertMutes(e *alerts.Engine) http.HandlerFunc {
+	type muteBody struct {
+		Kind    string `json:"kind"`
+		Target  string `json:"target"`
+		Windows string `json:"windows"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if e == nil {
+			w.Write([]byte("null"))
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(w).Encode(e.GetMutes())
+		case http.MethodPost:
+			var b muteBody
+			if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+				http.Error(w, `{"error":"invalid JSON body"}`, http.StatusBadRequest)
+				return
+			}
+			m, err := e.AddMute(b.Kind, b.Target, b.Windows)
+			if err != nil {
+				http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(m)
+		case http.MethodDelete:
+			id, err := strconv.Atoi(r.URL.Query().Get("id"))
+			if err != nil {
+				http.Error(w, `{"error":"invalid id"}`, http.StatusBadRequest)
+				return
+			}
+			if err := e.DeleteMute(id); err != nil {
+				http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
+		default:
+			http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// CaptureControl enables or disables accounting on one capture device:
+// POST /api/capture/control?device=eth0&enabled=false. Guarded by the
+// same PCAP_TOKEN as the other capture admin endpoints; disabled when
+// no token is configured.
+func CaptureControl(t *talkers.Tracker, token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if token == "" {
+			http.Error(w, `{"error":"capture control disabled"}`, http.StatusForbidden)
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		device := r.URL.Query().Get("device")
+		if device == "" {
+			http.Error(w, `{"error":"missing device parameter"}`, http.StatusBadRequest)
+			return
+		}
+		enabled, err := strconv.ParseBool(r.URL.Query().Get("enabled"))
+		if err != nil {
+			http.Error(w, `{"error":"enabled must be true or false"}`, http.StatusBadRequest)
+			return
+		}
+		if err := t.SetDeviceCapture(device, enabled); err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"device": device, "enabled": enabled})
+	}
+}
+
+// DNSIPMap serves the IP→domain map learned by sniffing captured DNS
+// responses. Empty until DNS_SNIFF is enabled.
+func DNSIPMap(t *talkers.Tracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(t.DNSIPMap())
+	}
+}
+
+// HopDistribution returns the estimated hop-distance histogram for
+// tracked remote hosts plus recent sudden-change events.
+func HopDistribution(t *talkers.Tracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"distribution": t.GetHopDistribution(),
+			"events":       t.GetTTLEvents(),
+		})
+	}
+}
+
+// HTTPChecks returns the live synthetic HTTP check results.
+func HTTPChecks(c *httpcheck.Checker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if c == nil {
+			w.Write([]byte("null"))
+			return
+		}
+		json.NewEncoder(w).Encode(c.GetAll())
+	}
+}
+
+// HTTPCheckHistory returns 24 hours of per-minute TTFB/availability
+// aggregates keyed by check name.
+func HTTPCheckHistory(c *httpcheck.Checker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if c == nil {
+			w.Write([]byte("null"))
+			return
+		}
+		json.NewEncoder(w).Encode(c.GetHistory())
+	}
+}
+
+func PingStats(p *prober.Prober) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if p == nil {
+			w.Write([]byte("null"))
+			return
+		}
+		json.NewEncoder(w).Encode(p.GetAll())
+	}
+}
+
+func PingHistory(p *prober.Prober) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if p == nil {
+			w.Write([]byte("null"))
+			return
+		}
+		json.NewEncoder(w).Encode(p.GetHistory())
+	}
+}
+
+func Paths(tr *tracer.Tracer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if tr == nil {
+			w.Write([]byte("null"))
+			return
+		}
+		json.NewEncoder(w).Encode(tr.GetAll())
+	}
+}
+
+func PathEvents(tr *tracer.Tracer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if tr == nil {
+			w.Write([]byte("null"))
+			return
+		}
+		json.NewEncoder(w).Encode(tr.GetEvents())
+	}
+}
+
+func SNMPDevices(sp *snmp.Poller) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if sp == nil {
+			w.Write([]byte("null"))
+			return
+		}
+		json.NewEncoder(w).Encode(sp.GetAll())
+	}
+}
+
+func WireGuardStats(wg *wireguard.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if wg == nil {
+			w.Write([]byte("null"))
+			return
+		}
+		json.NewEncoder(w).Encode(wg.GetAll())
+	}
+}
+
+func OpenVPNStats(ov *openvpn.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if ov == nil {
+			w.Write([]byte("null"))
+			return
+		}
+		json.NewEncoder(w).Encode(ov.GetSummary())
+	}
+}
+
+func ContainerStats(dk *docker.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if dk == nil {
+			w.Write([]byte("null"))
+			return
+		}
+		json.NewEncoder(w).Encode(dk.GetAll())
+	}
+}
+
+func ProxmoxGuests(px *proxmox.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if px == nil {
+			w.Write([]byte("null"))
+			return
+		}
+		json.NewEncoder(w).Encode(px.GetAll())
+	}
+}
+
+func ModemStatus(md *modem.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if md == nil {
+			w.Write([]byte("null"))
+			return
+		}
+		json.NewEncoder(w).Encode(md.GetStatus())
+	}
+}
+
+func ModemHistory(md *modem.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if md == nil {
+			w.Write([]byte("null"))
+			return
+		}
+		json.NewEncoder(w).Encode(md.GetHistory())
+	}
+}
+
+func IDSEvents(su *suricata.Tailer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if su == nil {
+			w.Write([]byte("null"))
+			return
+		}
+		json.NewEncoder(w).Encode(su.GetEvents())
+	}
+}
+
+func EdgeOSStats(ec *edgeos.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if ec == nil || !ec.Available() {
+			w.Write([]byte("null"))
+			return
+		}
+		json.NewEncoder(w).Encode(ec.GetSummary())
+	}
+}
+
+func OpenWrtStats(oc *openwrt.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if oc == nil || !oc.Available() {
+			w.Write([]byte("null"))
+			return
+		}
+		json.NewEncoder(w).Encode(oc.GetSummary())
+	}
+}
+
+func OPNsenseStats(fw *opnsense.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if fw == nil || !fw.Available() {
+			w.Write([]byte("null"))
+			return
+		}
+		json.NewEncoder(w).Encode(fw.GetSummary())
+	}
+}
+
+func Availability(av *availability.Monitor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if av == nil {
+			w.Write([]byte("null"))
+			return
+		}
+		json.NewEncoder(w).Encode(av.GetSummary())
+	}
+}
+
+// Devices is the inventory CRUD API: GET lists devices, POST creates a
+// manual entry, PUT assigns name/icon/tags to an existing device and
+// DELETE removes one (POST/PUT/DELETE address devices by "id").
+func Devices(inv *inventory.Inventory) http.HandlerFunc {
+	type deviceBody struct {
+		ID   string   `json:"id"`
+		MAC  string   `json:"mac"`
+		IP   string   `json:"ip"`
+		Name string   `json:"name"`
+		Icon string   `json:"icon"`
+		Tags []string `json:"tags"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if inv == nil {
+			w.Write([]byte("null"))
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(w).Encode(inv.GetAll())
+		case http.MethodPost:
+			var b deviceBody
+			if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+				http.Error(w, `{"error":"invalid JSON body"}`, http.StatusBadRequest)
+				return
+			}
+			d, err := inv.Create(b.MAC, b.IP, b.Name, b.Icon, b.Tags)
+			if err != nil {
+				http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(d)
+		case http.MethodPut:
+			var b deviceBody
+			if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+				http.Error(w, `{"error":"invalid JSON body"}`, http.StatusBadRequest)
+				return
+			}
+			if b.ID == "" {
+				b.ID = r.URL.Query().Get("id")
+			}
+			d, err := inv.Update(b.ID, b.Name, b.Icon, b.Tags)
+			if err != nil {
+				http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(d)
+		case http.MethodDelete:
+			id := r.URL.Query().Get("id")
+			if err := inv.Delete(id); err != nil {
+				http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"status": "deleted", "id": id})
+		default:
+			http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+		}
 	}
 }
 
-func InterfaceHistory(c *collector.Collector) http.HandlerFunc {
+// ClusterIngest accepts WebSocket connections from remote agents and
+// feeds their snapshots into the aggregator. Agents authenticate with
+// the shared cluster token as a Bearer token.
+func ClusterIngest(ag *cluster.Aggregator, token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if ag == nil || token == "" {
+			http.Error(w, "cluster mode disabled", http.StatusForbidden)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.Printf("cluster: upgrade failed: %v", err)
+			return
+		}
+		defer conn.Close()
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var peek struct {
+				Node string `json:"node"`
+			}
+			if err := json.Unmarshal(msg, &peek); err != nil || peek.Node == "" {
+				log.Printf("cluster: dropping malformed snapshot from %s", r.RemoteAddr)
+				continue
+			}
+			ag.Ingest(peek.Node, json.RawMessage(msg))
+		}
+	}
+}
+
+// ClusterNodes serves the latest snapshot from every node.
+func ClusterNodes(ag *cluster.Aggregator) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(c.GetHistory())
+		if ag == nil {
+			w.Write([]byte("null"))
+			return
+		}
+		json.NewEncoder(w).Encode(ag.GetAll())
 	}
 }
 
-func TopTalkersBandwidth(t *talkers.Tracker) http.HandlerFunc {
+// Federation proxies one named query (?q=summary|talkers|volume|
+// interfaces|dns) to every configured peer and returns the merged
+// per-peer results.
+func Federation(fed *cluster.Federator) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(t.TopByBandwidth(10))
+		if fed == nil {
+			w.Write([]byte("null"))
+			return
+		}
+		q := r.URL.Query().Get("q")
+		if q == "" {
+			q = "summary"
+		}
+		path, ok := cluster.QueryPath(q)
+		if !ok {
+			http.Error(w, `{"error":"unknown query"}`, http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(fed.Query(r.Context(), path))
 	}
 }
 
-func TopTalkersVolume(t *talkers.Tracker) http.HandlerFunc {
+// VersionInfo serves build metadata and update-check status. ck may be
+// nil (update check disabled).
+func VersionInfo(ck *version.Checker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ck.Get())
+	}
+}
+
+func NetBoxSummary(nb *netbox.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if nb == nil {
+			w.Write([]byte("null"))
+			return
+		}
+		json.NewEncoder(w).Encode(nb.GetSummary())
+	}
+}
+
+// WakeDevice sends a Wake-on-LAN magic packet to an inventory device:
+// POST /api/devices/wake?id=<device id>. The endpoint is only active when
+// WOL_TOKEN is configured; requests must carry it as a Bearer token.
+func WakeDevice(inv *inventory.Inventory, token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if inv == nil || token == "" {
+			http.Error(w, `{"error":"wake endpoint disabled"}`, http.StatusForbidden)
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		id := r.URL.Query().Get("id")
+		if id == "" {
+			http.Error(w, `{"error":"missing id parameter"}`, http.StatusBadRequest)
+			return
+		}
+		if err := inv.Wake(id); err != nil {
+			log.Printf("wol: wake %s: %v", id, err)
+			http.Error(w, `{"error":"wake failed"}`, http.StatusBadGateway)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"status": "sent", "id": id})
+	}
+}
+
+// PacketRing streams the rolling capture ring as a downloadable pcap.
+// Authenticated with a Bearer token; disabled when no token is configured.
+func PacketRing(ring *capture.Ring, token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if ring == nil || token == "" {
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, `{"error":"capture ring disabled"}`, http.StatusForbidden)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.tcpdump.pcap")
+		w.Header().Set("Content-Disposition",
+			fmt.Sprintf("attachment; filename=ring-%d.pcap", time.Now().Unix()))
+		if err := ring.WritePcap(w); err != nil {
+			log.Printf("capture: write ring pcap: %v", err)
+		}
+	}
+}
+
+// CaptureSessions manages on-demand targeted captures: POST starts one
+// (?device=, ?filter=, ?duration=), GET lists sessions or, with ?id=,
+// reports one session — add &pcap=1 to download its packets. Same Bearer
+// token scheme as the capture ring; disabled without a token.
+func CaptureSessions(mgr *capture.Manager, token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if mgr == nil || token == "" {
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, `{"error":"capture endpoint disabled"}`, http.StatusForbidden)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodPost:
+			w.Header().Set("Content-Type", "application/json")
+			duration := 30 * time.Second
+			if v := r.URL.Query().Get("duration"); v != "" {
+				d, err := time.ParseDuration(v)
+				if err != nil {
+					http.Error(w, `{"error":"invalid duration"}`, http.StatusBadRequest)
+					return
+				}
+				duration = d
+			}
+			s, err := mgr.Start(r.URL.Query().Get("device"), r.URL.Query().Get("filter"), duration)
+			if err != nil {
+				http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(s.Status())
+
+		case http.MethodGet:
+			id := r.URL.Query().Get("id")
+			if id == "" {
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(mgr.GetAll())
+				return
+			}
+			s := mgr.Get(id)
+			if s == nil {
+				w.Header().Set("Content-Type", "application/json")
+				http.Error(w, `{"error":"unknown session"}`, http.StatusNotFound)
+				return
+			}
+			if r.URL.Query().Get("pcap") == "" {
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(s.Status())
+				return
+			}
+			w.Header().Set("Content-Type", "application/vnd.tcpdump.pcap")
+			w.Header().Set("Content-Disposition",
+				fmt.Sprintf("attachment; filename=capture-%s.pcap", id))
+			if err := s.WritePcap(w); err != nil {
+				log.Printf("capture: write session pcap: %v", err)
+			}
+
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// deviceNamer supplies user-assigned display names (the device
+// inventory); applied to DNS clients and WiFi clients at serve time.
+var deviceNamer talkers.Namer
+
+// SetDeviceNamer configures the display-name source. Call once at startup.
+func SetDeviceNamer(n talkers.Namer) { deviceNamer = n }
+
+// namedDNS returns s with inventory names filled into TopClients,
+// copying the slice so the provider's cached summary stays untouched.
+func namedDNS(s *dns.Summary) *dns.Summary {
+	anon := privacy.Active("dns")
+	if s == nil || (deviceNamer == nil && !anon) || len(s.TopClients) == 0 {
+		return s
+	}
+	cp := *s
+	cp.TopClients = make([]dns.ClientStat, len(s.TopClients))
+	copy(cp.TopClients, s.TopClients)
+	for i := range cp.TopClients {
+		if anon {
+			cp.TopClients[i].IP = privacy.IP(cp.TopClients[i].IP)
+			cp.TopClients[i].Name = ""
+			continue
+		}
+		if name := deviceNamer.NameFor(cp.TopClients[i].IP); name != "" {
+			cp.TopClients[i].Name = name
+		}
+	}
+	return &cp
+}
+
+// namedWiFi returns s with inventory names overriding client hostnames.
+func namedWiFi(s *unifi.Summary) *unifi.Summary {
+	anon := privacy.Active("wifi")
+	if s == nil || (deviceNamer == nil && !anon) || len(s.Clients) == 0 {
+		return s
+	}
+	cp := *s
+	cp.Clients = make([]unifi.ClientInfo, len(s.Clients))
+	copy(cp.Clients, s.Clients)
+	for i := range cp.Clients {
+		if anon {
+			cp.Clients[i].IP = privacy.IP(cp.Clients[i].IP)
+			cp.Clients[i].MAC = privacy.MAC(cp.Clients[i].MAC)
+			cp.Clients[i].Hostname = ""
+			continue
+		}
+		if name := deviceNamer.NameFor(cp.Clients[i].IP); name != "" {
+			cp.Clients[i].Hostname = name
+		}
+	}
+	return &cp
+}
+
+// Overrides is the hostname/label override CRUD API: GET lists entries,
+// PUT sets {"key","label"} (key is an IP or CIDR), DELETE removes ?key=.
+func Overrides(m *overrides.Map) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(t.TopByVolume(10))
+		if m == nil {
+			w.Write([]byte("null"))
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(w).Encode(m.GetAll())
+		case http.MethodPut, http.MethodPost:
+			var e overrides.Entry
+			if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+				http.Error(w, `{"error":"invalid JSON body"}`, http.StatusBadRequest)
+				return
+			}
+			if err := m.Set(e.Key, e.Label); err != nil {
+				http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(e)
+		case http.MethodDelete:
+			key := r.URL.Query().Get("key")
+			if err := m.Delete(key); err != nil {
+				http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"status": "deleted", "key": key})
+		default:
+			http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+		}
 	}
 }
 
@@ -53,7 +1080,7 @@ func DNSSummary(dp dns.Provider) http.HandlerFunc {
 			w.Write([]byte("null"))
 			return
 		}
-		json.NewEncoder(w).Encode(dp.GetSummary())
+		json.NewEncoder(w).Encode(namedDNS(dp.GetSummary()))
 	}
 }
 
@@ -64,19 +1091,55 @@ func WiFiSummary(uf *unifi.Client) http.HandlerFunc {
 			w.Write([]byte("null"))
 			return
 		}
-		json.NewEncoder(w).Encode(uf.GetSummary())
+		json.NewEncoder(w).Encode(namedWiFi(uf.GetSummary()))
+	}
+}
+
+// WiFiHistory returns 24 hours of per-minute per-SSID and per-client
+// rate samples, so WiFi graphs match the interface graphs.
+func WiFiHistory(uf *unifi.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if uf == nil {
+			w.Write([]byte("null"))
+			return
+		}
+		h := uf.GetHistory()
+		if privacy.Active("wifi") && len(h.Clients) > 0 {
+			anon := make(map[string][]unifi.HistoryPoint, len(h.Clients))
+			for mac, pts := range h.Clients {
+				anon[privacy.MAC(mac)] = pts
+			}
+			h.Clients = anon
+		}
+		json.NewEncoder(w).Encode(h)
 	}
 }
 
-// MenuBarSummary returns a compact JSON snapshot for menu-bar widgets.
-func MenuBarSummary(c *collector.Collector, t *talkers.Tracker, dp dns.Provider, uf *unifi.Client) http.HandlerFunc {
+// MenuBarSummary serves the compact dashboard payload. Widgets shape it
+// per request — ?interfaces=name,... ("none" omits the section),
+// ?talkers=N adds the top N current talkers, ?dns=0 / ?wifi=0 drop
+// those sections — with SUMMARY_DEFAULTS supplying any absent parameter.
+func MenuBarSummary(c *collector.Collector, t *talkers.Tracker, dp dns.Provider, uf *unifi.Client, defaults url.Values) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
+		q := r.URL.Query()
+		opt := func(key string) string {
+			if v := q.Get(key); v != "" {
+				return v
+			}
+			return defaults.Get(key)
+		}
 		type ifaceBrief struct {
-			Name   string  `json:"name"`
-			RxRate float64 `json:"rx_rate"`
-			TxRate float64 `json:"tx_rate"`
-			State  string  `json:"state"`
+			Name        string  `json:"name"`
+			DisplayName string  `json:"display_name,omitempty"`
+			RxRate      float64 `json:"rx_rate"`
+			TxRate      float64 `json:"tx_rate"`
+			// Cumulative counters since capture start, so widgets can
+			// compute their own windows without the history API
+			RxBytes uint64 `json:"rx_bytes"`
+			TxBytes uint64 `json:"tx_bytes"`
+			State   string `json:"state"`
 		}
 		type dnsBrief struct {
 			Provider     string  `json:"provider_name"`
@@ -89,27 +1152,61 @@ func MenuBarSummary(c *collector.Collector, t *talkers.Tracker, dp dns.Provider,
 			APs     int `json:"aps"`
 			Clients int `json:"clients"`
 		}
+		type talkerBrief struct {
+			IP        string  `json:"ip"`
+			Name      string  `json:"name,omitempty"`
+			RateBytes float64 `json:"rate_bytes"`
+			// Direction split, so a brief consumer can tell an upload
+			// from a download without the full talker endpoint
+			RxRate float64 `json:"rx_rate"`
+			TxRate float64 `json:"tx_rate"`
+		}
 		type summary struct {
-			Interfaces []ifaceBrief `json:"interfaces"`
-			VPN        bool         `json:"vpn"`
-			VPNIface   string       `json:"vpn_iface,omitempty"`
-			DNS        *dnsBrief    `json:"dns,omitempty"`
-			WiFi       *wifiBrief   `json:"wifi,omitempty"`
-			Timestamp  int64        `json:"timestamp"`
+			Node       string        `json:"node,omitempty"`
+			Interfaces []ifaceBrief  `json:"interfaces"`
+			VPN        bool          `json:"vpn"`
+			VPNIface   string        `json:"vpn_iface,omitempty"`
+			DNS        *dnsBrief     `json:"dns,omitempty"`
+			WiFi       *wifiBrief    `json:"wifi,omitempty"`
+			Talkers    []talkerBrief `json:"talkers,omitempty"`
+			Timestamp  int64         `json:"timestamp"`
 		}
 
 		var out summary
+		out.Node = nodeLabel
 		out.Timestamp = time.Now().UnixMilli()
 
+		ifFilter := opt("interfaces")
 		for _, iface := range c.GetAll() {
+			if !ifaceWanted(ifFilter, iface.Name, iface.DisplayName) {
+				continue
+			}
 			out.Interfaces = append(out.Interfaces, ifaceBrief{
-				Name:   iface.Name,
-				RxRate: iface.RxRate,
-				TxRate: iface.TxRate,
-				State:  iface.OperState,
+				Name:        iface.Name,
+				DisplayName: iface.DisplayName,
+				RxRate:      iface.RxRate,
+				TxRate:      iface.TxRate,
+				RxBytes:     iface.RxBytes,
+				TxBytes:     iface.TxBytes,
+				State:       iface.OperState,
 			})
 		}
-		if dp != nil {
+		if n, _ := strconv.Atoi(opt("talkers")); n > 0 {
+			for _, ts := range t.TopByBandwidth(n) {
+				name := ts.Hostname
+				if name == ts.IP {
+					name = ""
+				}
+				out.Talkers = append(out.Talkers, talkerBrief{
+					IP:        ts.IP,
+					Name:      name,
+					RateBytes: ts.RateBytes,
+					RxRate:    ts.RxRate,
+					TxRate:    ts.TxRate,
+				})
+			}
+		}
+		if dp != nil && opt("dns") != "0" {
 			if ds := dp.GetSummary(); ds != nil {
 				out.DNS = &dnsBrief{
 					Provider:     ds.ProviderName,
@@ -120,7 +1217,7 @@ func MenuBarSummary(c *collector.Collector, t *talkers.Tracker, dp dns.Provider,
 				}
 			}
 		}
-		if uf != nil {
+		if uf != nil && opt("wifi") != "0" {
 			if ws := uf.GetSummary(); ws != nil {
 				totalClients := 0
 				for _, ap := range ws.APs {
@@ -137,7 +1234,104 @@ func MenuBarSummary(c *collector.Collector, t *talkers.Tracker, dp dns.Provider,
 	}
 }
 
-func WebSocket(c *collector.Collector, t *talkers.Tracker, dp dns.Provider, uf *unifi.Client) http.HandlerFunc {
+// ifaceWanted applies the summary interface filter: empty keeps all,
+// "none" drops the section, otherwise a comma-separated list of device
+// names or display names.
+func ifaceWanted(filter, name, displayName string) bool {
+	if filter == "" {
+		return true
+	}
+	if filter == "none" {
+		return false
+	}
+	for _, f := range strings.Split(filter, ",") {
+		f = strings.TrimSpace(f)
+		if f != "" && (f == name || f == displayName) {
+			return true
+		}
+	}
+	return false
+}
+
+// haAPIVersion is bumped only when the /api/ha key set changes shape;
+// Home Assistant REST sensors depend on these keys staying stable.
+const haAPIVersion = 1
+
+// HomeAssistant returns a flat, stable key/value structure shaped for
+// Home Assistant REST sensors, versioned independently of the richer API.
+func HomeAssistant(c *collector.Collector, t *talkers.Tracker, dp dns.Provider, uf *unifi.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		out := map[string]interface{}{
+			"version":         haAPIVersion,
+			"wan_rx_mbps":     0.0,
+			"wan_tx_mbps":     0.0,
+			"vpn_active":      false,
+			"dns_blocked_pct": 0.0,
+			"wifi_clients":    0,
+			"top_talker":      "",
+		}
+		if nodeLabel != "" {
+			out["node"] = nodeLabel
+		}
+		for _, iface := range c.GetAll() {
+			out["wan_rx_mbps"] = iface.RxRate * 8 / 1e6
+			out["wan_tx_mbps"] = iface.TxRate * 8 / 1e6
+			break
+		}
+		if top := t.TopByBandwidth(1); len(top) > 0 {
+			name := top[0].Hostname
+			if name == "" {
+				name = top[0].IP
+			}
+			out["top_talker"] = name
+		}
+		if dp != nil {
+			if ds := dp.GetSummary(); ds != nil {
+				out["dns_blocked_pct"] = ds.BlockedPercent
+			}
+		}
+		if uf != nil {
+			if ws := uf.GetSummary(); ws != nil {
+				out["wifi_clients"] = ws.TotalClients
+			}
+		}
+
+		json.NewEncoder(w).Encode(out)
+	}
+}
+
+// clampWSInterval parses a client-requested update interval, keeping it
+// within sane bounds; malformed input keeps the current interval.
+func clampWSInterval(v string, current time.Duration) time.Duration {
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return current
+	}
+	if d < 500*time.Millisecond {
+		return 500 * time.Millisecond
+	}
+	if d > time.Minute {
+		return time.Minute
+	}
+	return d
+}
+
+// Events returns retained event-bus entries, newest first. ?limit=
+// caps the list (default 100) and ?source= filters by publishing
+// subsystem.
+func Events(b *events.Bus) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		n := limitParam(r, 100)
+		json.NewEncoder(w).Encode(b.Recent(n, r.URL.Query().Get("source")))
+	}
+}
+
+// EventStream pushes each event-bus entry over a WebSocket as it is
+// published, one JSON object per message.
+func EventStream(b *events.Bus) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		conn, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
@@ -146,8 +1340,11 @@ func WebSocket(c *collector.Collector, t *talkers.Tracker, dp dns.Provider, uf *
 		}
 		defer conn.Close()
 
-		// Read pump — drain incoming messages so the connection
-		// can process control frames (close, ping/pong).
+		ch := b.Subscribe()
+		defer b.Unsubscribe(ch)
+
+		// Read pump — drain incoming messages so close and ping/pong
+		// control frames are processed.
 		doneCh := make(chan struct{})
 		go func() {
 			defer close(doneCh)
@@ -163,7 +1360,79 @@ func WebSocket(c *collector.Collector, t *talkers.Tracker, dp dns.Provider, uf *
 			}
 		}()
 
-		ticker := time.NewTicker(1 * time.Second)
+		pingTicker := time.NewTicker(30 * time.Second)
+		defer pingTicker.Stop()
+
+		for {
+			select {
+			case <-doneCh:
+				return
+			case <-pingTicker.C:
+				conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+				if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+					return
+				}
+			case e := <-ch:
+				conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+				if err := conn.WriteJSON(e); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+func WebSocket(c *collector.Collector, t *talkers.Tracker, dp dns.Provider, uf *unifi.Client, pb *prober.Prober, tr *tracer.Tracer, sp *snmp.Poller, wg *wireguard.Client, ov *openvpn.Client, dk *docker.Client, px *proxmox.Client, md *modem.Client, fw *opnsense.Client, su *suricata.Tailer, al *alerts.Engine) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.Printf("websocket upgrade: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		// Per-client update cadence: ?interval=5s at connect, or an
+		// {"interval":"5s"} text frame later — a wall dashboard has no
+		// business ticking at the main UI's 1-second rate.
+		interval := time.Second
+		if v := r.URL.Query().Get("interval"); v != "" {
+			interval = clampWSInterval(v, interval)
+		}
+		intervalCh := make(chan time.Duration, 1)
+
+		// ?limit= sizes the talker lists and breakdowns for this
+		// connection, same as on the REST endpoints
+		talkerN := limitParam(r, topTalkerLimit)
+		breakdownN := limitParam(r, breakdownLimit)
+
+		// Read pump — drain incoming messages so the connection can
+		// process control frames (close, ping/pong) and rate changes.
+		doneCh := make(chan struct{})
+		go func() {
+			defer close(doneCh)
+			conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+			conn.SetPongHandler(func(string) error {
+				conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+				return nil
+			})
+			for {
+				_, msg, err := conn.ReadMessage()
+				if err != nil {
+					return
+				}
+				var ctl struct {
+					Interval string `json:"interval"`
+				}
+				if json.Unmarshal(msg, &ctl) == nil && ctl.Interval != "" {
+					select {
+					case intervalCh <- clampWSInterval(ctl.Interval, interval):
+					default:
+					}
+				}
+			}
+		}()
+
+		ticker := time.NewTicker(interval)
 		defer ticker.Stop()
 
 		pingTicker := time.NewTicker(30 * time.Second)
@@ -173,6 +1442,8 @@ func WebSocket(c *collector.Collector, t *talkers.Tracker, dp dns.Provider, uf *
 			select {
 			case <-doneCh:
 				return
+			case d := <-intervalCh:
+				ticker.Reset(d)
 			case <-pingTicker.C:
 				conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
 				if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
@@ -184,17 +1455,56 @@ func WebSocket(c *collector.Collector, t *talkers.Tracker, dp dns.Provider, uf *
 					"sparklines":    c.GetSparklines(5*time.Minute, 50),
 					"protocols":     t.GetProtocolBreakdown(),
 					"ip_versions":   t.GetIPVersionBreakdown(),
-					"countries":     t.GetCountryBreakdown(),
-					"asns":          t.GetASNBreakdown(),
-					"top_bandwidth": t.TopByBandwidth(10),
-					"top_volume":    t.TopByVolume(10),
+					"dscp":          t.GetDSCPBreakdown(),
+					"countries":     t.GetCountryBreakdown(breakdownN),
+					"asns":          t.GetASNBreakdown(breakdownN),
+					"top_bandwidth": t.TopByBandwidth(talkerN),
+					"top_volume":    t.TopByVolume(talkerN),
+					"capture":       t.CaptureStatuses(),
 					"timestamp":     time.Now().UnixMilli(),
 				}
+				if nodeLabel != "" {
+					payload["node"] = nodeLabel
+				}
 				if dp != nil {
-					payload["dns"] = dp.GetSummary()
+					payload["dns"] = namedDNS(dp.GetSummary())
 				}
 				if uf != nil {
-					payload["wifi"] = uf.GetSummary()
+					payload["wifi"] = namedWiFi(uf.GetSummary())
+				}
+				if pb != nil {
+					payload["ping"] = pb.GetAll()
+				}
+				if tr != nil {
+					payload["paths"] = tr.GetAll()
+					payload["path_events"] = tr.GetEvents()
+				}
+				if sp != nil {
+					payload["snmp"] = sp.GetAll()
+				}
+				if wg != nil {
+					payload["wireguard"] = wg.GetAll()
+				}
+				if ov != nil {
+					payload["openvpn"] = ov.GetSummary()
+				}
+				if dk != nil {
+					payload["containers"] = dk.GetAll()
+				}
+				if px != nil {
+					payload["proxmox"] = px.GetAll()
+				}
+				if md != nil {
+					payload["modem"] = md.GetStatus()
+				}
+				if fw != nil && fw.Available() {
+					payload["firewall"] = fw.GetSummary()
+				}
+				if su != nil {
+					payload["ids_events"] = su.GetEvents()
+				}
+				if al != nil {
+					payload["alerts"] = al.GetAll()
 				}
 				conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
 				if err := conn.WriteJSON(payload); err != nil {
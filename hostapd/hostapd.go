@@ -0,0 +1,263 @@
+// Package hostapd polls station data from hostapd control sockets, for
+// setups where the monitor host is itself the access point. Results are
+// shaped into the same summary structures the UniFi integration
+// produces, so /api/wifi serves identically whichever backend fills it.
+package hostapd
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"bandwidth-monitor/unifi"
+)
+
+type Poller struct {
+	ifaces   []string
+	dir      string // control socket directory, usually /var/run/hostapd
+	interval time.Duration
+	sink     func(*unifi.Summary)
+	stopCh   chan struct{}
+
+	mu        sync.RWMutex
+	available bool
+
+	// rate tracking across polls, keyed by client MAC
+	lastPoll time.Time
+	prevCli  map[string]byteSnap
+}
+
+type byteSnap struct {
+	tx, rx int64
+}
+
+// New creates a poller for the given interfaces. sink receives each
+// completed summary (normally unifi.Client.SetSummary).
+func New(ifaces []string, dir string, interval time.Duration, sink func(*unifi.Summary)) *Poller {
+	return &Poller{
+		ifaces:   ifaces,
+		dir:      dir,
+		interval: interval,
+		sink:     sink,
+		stopCh:   make(chan struct{}),
+		prevCli:  make(map[string]byteSnap),
+	}
+}
+
+func (p *Poller) Run() {
+	p.poll()
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.poll()
+		case <-p.stopCh:
+			return
+		}
+	}
+}
+
+func (p *Poller) Stop() {
+	select {
+	case <-p.stopCh:
+	default:
+		close(p.stopCh)
+	}
+}
+
+func (p *Poller) Available() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.available
+}
+
+func (p *Poller) poll() {
+	now := time.Now()
+	dt := now.Sub(p.lastPoll).Seconds()
+	if p.lastPoll.IsZero() {
+		dt = 0
+	}
+
+	s := &unifi.Summary{}
+	ssids := make(map[string]*unifi.SSIDStat)
+	anyUp := false
+	for _, iface := range p.ifaces {
+		c, err := dial(filepath.Join(p.dir, iface))
+		if err != nil {
+			log.Printf("hostapd: %s: %v", iface, err)
+			continue
+		}
+		ap, clients := p.pollIface(c, iface, dt)
+		c.close()
+		anyUp = true
+
+		s.APs = append(s.APs, ap)
+		for _, cl := range clients {
+			ss, ok := ssids[cl.SSID]
+			if !ok {
+				ss = &unifi.SSIDStat{Name: cl.SSID}
+				ssids[cl.SSID] = ss
+			}
+			ss.NumClients++
+			ss.TxBytes += cl.TxBytes
+			ss.RxBytes += cl.RxBytes
+			ss.TxRate += cl.TxRate
+			ss.RxRate += cl.RxRate
+		}
+		s.Clients = append(s.Clients, clients...)
+	}
+	if !anyUp {
+		return
+	}
+
+	s.TotalAPs = len(s.APs)
+	s.TotalClients = len(s.Clients)
+	for _, ss := range ssids {
+		s.SSIDs = append(s.SSIDs, *ss)
+	}
+	sort.Slice(s.SSIDs, func(i, j int) bool { return s.SSIDs[i].Name < s.SSIDs[j].Name })
+	sort.Slice(s.Clients, func(i, j int) bool {
+		return s.Clients[i].TxBytes+s.Clients[i].RxBytes > s.Clients[j].TxBytes+s.Clients[j].RxBytes
+	})
+
+	p.lastPoll = now
+	p.mu.Lock()
+	p.available = true
+	p.mu.Unlock()
+	p.sink(s)
+}
+
+// pollIface reads STATUS plus the station list of one BSS.
+func (p *Poller) pollIface(c *ctrlConn, iface string, dt float64) (unifi.APInfo, []unifi.ClientInfo) {
+	status := parseKV(c.request("STATUS"))
+	channel, _ := strconv.Atoi(status["channel"])
+	freq, _ := strconv.Atoi(status["freq"])
+	radio := "2g"
+	switch {
+	case freq >= 5955:
+		radio = "6g"
+	case freq >= 5000:
+		radio = "5g"
+	}
+	ssid := status["ssid[0]"]
+
+	ap := unifi.APInfo{
+		Name:   iface,
+		Model:  "hostapd",
+		MAC:    status["bssid[0]"],
+		Status: "online",
+	}
+	if status["state"] != "ENABLED" {
+		ap.Status = strings.ToLower(status["state"])
+	}
+
+	var clients []unifi.ClientInfo
+	for reply := c.request("STA-FIRST"); ; {
+		mac, fields := parseSTA(reply)
+		if mac == "" {
+			break
+		}
+		tx, _ := strconv.ParseInt(fields["tx_bytes"], 10, 64)
+		rx, _ := strconv.ParseInt(fields["rx_bytes"], 10, 64)
+		signal, _ := strconv.Atoi(fields["signal"])
+		cl := unifi.ClientInfo{
+			MAC:     mac,
+			SSID:    ssid,
+			APMAC:   ap.MAC,
+			APName:  iface,
+			Signal:  signal,
+			Channel: channel,
+			Radio:   radio,
+			TxBytes: tx,
+			RxBytes: rx,
+		}
+		if prev, ok := p.prevCli[mac]; ok && dt > 0 && tx >= prev.tx && rx >= prev.rx {
+			cl.TxRate = float64(tx-prev.tx) / dt
+			cl.RxRate = float64(rx-prev.rx) / dt
+		}
+		p.prevCli[mac] = byteSnap{tx: tx, rx: rx}
+		clients = append(clients, cl)
+
+		ap.NumClients++
+		ap.TxBytes += tx
+		ap.RxBytes += rx
+		ap.TxRate += cl.TxRate
+		ap.RxRate += cl.RxRate
+
+		reply = c.request("STA-NEXT " + mac)
+	}
+	return ap, clients
+}
+
+// parseKV splits "key=value" lines into a map.
+func parseKV(s string) map[string]string {
+	out := make(map[string]string)
+	for _, line := range strings.Split(s, "\n") {
+		if k, v, ok := strings.Cut(line, "="); ok {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// parseSTA splits a STA-FIRST/STA-NEXT reply: the station MAC on the
+// first line, key=value fields after. Empty or FAIL means end of list.
+func parseSTA(s string) (string, map[string]string) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.HasPrefix(s, "FAIL") || strings.HasPrefix(s, "UNKNOWN") {
+		return "", nil
+	}
+	mac, rest, _ := strings.Cut(s, "\n")
+	if len(strings.Split(mac, ":")) != 6 {
+		return "", nil
+	}
+	return mac, parseKV(rest)
+}
+
+// ctrlConn is one wpa_ctrl-style datagram connection. The protocol
+// needs a bound local socket for replies, which must be unlinked again.
+type ctrlConn struct {
+	conn  *net.UnixConn
+	local string
+}
+
+func dial(path string) (*ctrlConn, error) {
+	local := filepath.Join(os.TempDir(), fmt.Sprintf("bwmon-wpa-%d-%s", os.Getpid(), filepath.Base(path)))
+	os.Remove(local)
+	conn, err := net.DialUnix("unixgram",
+		&net.UnixAddr{Name: local, Net: "unixgram"},
+		&net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		os.Remove(local)
+		return nil, err
+	}
+	return &ctrlConn{conn: conn, local: local}, nil
+}
+
+func (c *ctrlConn) close() {
+	c.conn.Close()
+	os.Remove(c.local)
+}
+
+// request sends one command and returns the reply, or "" on error —
+// callers treat an empty reply as end-of-data.
+func (c *ctrlConn) request(cmd string) string {
+	if _, err := c.conn.Write([]byte(cmd)); err != nil {
+		return ""
+	}
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 4096)
+	n, err := c.conn.Read(buf)
+	if err != nil {
+		return ""
+	}
+	return string(buf[:n])
+}
@@ -0,0 +1,362 @@
+// Package httpcheck runs synthetic HTTP(S) probes against key external
+// services (work VPN portal, cloud storage, a video CDN) and records
+// time-to-first-byte and availability over time. Next to the bandwidth
+// graphs this splits "slow internet" complaints into line capacity
+// versus remote-service problems.
+package httpcheck
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	checkTimeout  = 10 * time.Second
+	sampleWindow  = 120 // recent TTFB samples kept per target
+	historyMaxAge = 24 * time.Hour
+	historyBucket = 1 * time.Minute
+	maxBodyRead   = 1 << 20 // drain at most 1 MiB; we time delivery, not download it
+)
+
+// CheckStat is the live summary for a single checked service.
+type CheckStat struct {
+	Name       string  `json:"name"`
+	URL        string  `json:"url"`
+	Up         bool    `json:"up"`
+	StatusCode int     `json:"status_code,omitempty"`
+	Error      string  `json:"error,omitempty"`
+	DNSMs      float64 `json:"dns_ms"`
+	ConnectMs  float64 `json:"connect_ms"`
+	TLSMs      float64 `json:"tls_ms,omitempty"`
+	TTFBMs     float64 `json:"ttfb_ms"`
+	TotalMs    float64 `json:"total_ms"`
+	P50TTFBMs  float64 `json:"p50_ttfb_ms"`
+	P95TTFBMs  float64 `json:"p95_ttfb_ms"`
+	AvailPct   float64 `json:"avail_pct"`
+	Checks     uint64  `json:"checks"`
+	Failures   uint64  `json:"failures"`
+	Timestamp  int64   `json:"timestamp"`
+}
+
+// HistoryPoint is one per-minute aggregate for the 24-hour history ring.
+type HistoryPoint struct {
+	Timestamp int64   `json:"t"`
+	P50TTFBMs float64 `json:"p50"`
+	P95TTFBMs float64 `json:"p95"`
+	AvailPct  float64 `json:"avail"`
+}
+
+// target holds per-service check state, protected by Checker.mu.
+type target struct {
+	name string
+	url  string
+
+	checks   uint64
+	failures uint64
+	last     CheckStat
+	samples  []float64 // ring of recent TTFBs in ms
+
+	// per-minute accumulators, reset at bucket rotation
+	bucketChecks int
+	bucketOK     int
+	bucketTTFBs  []float64
+
+	history []HistoryPoint
+}
+
+// Checker drives periodic HTTP probes against a set of services.
+type Checker struct {
+	interval time.Duration
+	client   *http.Client
+
+	mu      sync.RWMutex
+	targets []*target
+
+	stopCh chan struct{}
+}
+
+// New creates a Checker from specs of the form "name=url" (a bare URL
+// uses its hostname as the name). Invalid specs are skipped with a log
+// line; checking starts when Run is called.
+func New(specs []string, interval time.Duration) *Checker {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	c := &Checker{
+		interval: interval,
+		client: &http.Client{
+			Timeout: checkTimeout,
+			// A health check should answer the question "is the service
+			// reachable", not follow it to a login page
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		stopCh: make(chan struct{}),
+	}
+	for _, spec := range specs {
+		name, raw, ok := strings.Cut(spec, "=")
+		if !ok || strings.Contains(name, "://") {
+			// bare URL (possibly with query parameters); name from host
+			raw, name = spec, ""
+		}
+		u, err := url.Parse(strings.TrimSpace(raw))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			fmt.Fprintf(os.Stderr, "httpcheck: skipping invalid target %q\n", spec)
+			continue
+		}
+		if name == "" {
+			name = u.Hostname()
+		}
+		c.targets = append(c.targets, &target{name: strings.TrimSpace(name), url: u.String()})
+	}
+	return c
+}
+
+// Run starts the check and history rotation loops. It blocks until
+// Stop() is called; intended to be launched as a goroutine.
+func (c *Checker) Run() {
+	if len(c.targets) == 0 {
+		return
+	}
+	go c.rotateLoop()
+
+	c.checkAll()
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stopCh:
+			return
+		case <-ticker.C:
+			c.checkAll()
+		}
+	}
+}
+
+// Stop signals the checker to shut down.
+func (c *Checker) Stop() {
+	select {
+	case <-c.stopCh:
+	default:
+		close(c.stopCh)
+	}
+}
+
+// Available reports whether at least one check has completed.
+func (c *Checker) Available() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, t := range c.targets {
+		if t.checks > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAll returns the current summary for every checked service.
+func (c *Checker) GetAll() []CheckStat {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	out := make([]CheckStat, 0, len(c.targets))
+	for _, t := range c.targets {
+		s := t.last
+		s.Name = t.name
+		s.URL = t.url
+		s.Checks = t.checks
+		s.Failures = t.failures
+		if t.checks > 0 {
+			s.AvailPct = float64(t.checks-t.failures) / float64(t.checks) * 100
+		}
+		if len(t.samples) > 0 {
+			sorted := append([]float64(nil), t.samples...)
+			sort.Float64s(sorted)
+			s.P50TTFBMs = percentile(sorted, 50)
+			s.P95TTFBMs = percentile(sorted, 95)
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// GetHistory returns the 24-hour per-minute history keyed by check name.
+func (c *Checker) GetHistory() map[string][]HistoryPoint {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	out := make(map[string][]HistoryPoint, len(c.targets))
+	for _, t := range c.targets {
+		cp := make([]HistoryPoint, len(t.history))
+		copy(cp, t.history)
+		out[t.name] = cp
+	}
+	return out
+}
+
+// ---------- internal ----------
+
+// checkAll probes every target concurrently and waits for all to finish.
+func (c *Checker) checkAll() {
+	var wg sync.WaitGroup
+	for _, t := range c.targets {
+		wg.Add(1)
+		go func(t *target) {
+			defer wg.Done()
+			c.check(t)
+		}(t)
+	}
+	wg.Wait()
+}
+
+// check performs one probe, timing each phase via httptrace.
+func (c *Checker) check(t *target) {
+	var dnsStart, connStart, tlsStart, start time.Time
+	var dnsMs, connMs, tlsMs, ttfbMs float64
+
+	trace := &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone:           func(httptrace.DNSDoneInfo) { dnsMs = msSince(dnsStart) },
+		ConnectStart:      func(string, string) { connStart = time.Now() },
+		ConnectDone:       func(string, string, error) { connMs = msSince(connStart) },
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			tlsMs = msSince(tlsStart)
+		},
+		GotFirstResponseByte: func() { ttfbMs = msSince(start) },
+	}
+
+	req, err := http.NewRequest("GET", t.url, nil)
+	if err != nil {
+		c.record(t, CheckStat{Error: err.Error()}, false)
+		return
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	start = time.Now()
+	resp, err := c.client.Do(req)
+	if err != nil {
+		c.record(t, CheckStat{Error: err.Error(), TotalMs: msSince(start)}, false)
+		return
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxBodyRead))
+	resp.Body.Close()
+
+	s := CheckStat{
+		StatusCode: resp.StatusCode,
+		DNSMs:      dnsMs,
+		ConnectMs:  connMs,
+		TLSMs:      tlsMs,
+		TTFBMs:     ttfbMs,
+		TotalMs:    msSince(start),
+	}
+	// Redirects and auth challenges still prove the service answers;
+	// only 5xx counts as down
+	c.record(t, s, resp.StatusCode < 500)
+}
+
+// record stores one check result under lock.
+func (c *Checker) record(t *target, s CheckStat, up bool) {
+	s.Up = up
+	s.Timestamp = time.Now().UnixMilli()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	wasUp := t.last.Up || t.checks == 0
+	t.checks++
+	t.bucketChecks++
+	if up {
+		t.bucketOK++
+		t.samples = append(t.samples, s.TTFBMs)
+		if len(t.samples) > sampleWindow {
+			t.samples = t.samples[len(t.samples)-sampleWindow:]
+		}
+		t.bucketTTFBs = append(t.bucketTTFBs, s.TTFBMs)
+	} else {
+		t.failures++
+	}
+	if up != wasUp {
+		if up {
+			fmt.Fprintf(os.Stderr, "httpcheck: %s recovered\n", t.name)
+		} else {
+			fmt.Fprintf(os.Stderr, "httpcheck: %s down (%s)\n", t.name, s.Error)
+		}
+	}
+	t.last = s
+}
+
+// rotateLoop flushes per-minute accumulators into each target's history
+// ring and prunes entries older than 24 hours.
+func (c *Checker) rotateLoop() {
+	ticker := time.NewTicker(historyBucket)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		c.mu.Lock()
+		for _, t := range c.targets {
+			if t.bucketChecks == 0 {
+				continue
+			}
+			hp := HistoryPoint{
+				Timestamp: now.UnixMilli(),
+				AvailPct:  float64(t.bucketOK) / float64(t.bucketChecks) * 100,
+			}
+			if len(t.bucketTTFBs) > 0 {
+				sort.Float64s(t.bucketTTFBs)
+				hp.P50TTFBMs = percentile(t.bucketTTFBs, 50)
+				hp.P95TTFBMs = percentile(t.bucketTTFBs, 95)
+			}
+			t.history = append(t.history, hp)
+			cutoff := now.Add(-historyMaxAge).UnixMilli()
+			idx := 0
+			for idx < len(t.history) && t.history[idx].Timestamp < cutoff {
+				idx++
+			}
+			t.history = t.history[idx:]
+			t.bucketChecks = 0
+			t.bucketOK = 0
+			t.bucketTTFBs = t.bucketTTFBs[:0]
+		}
+		c.mu.Unlock()
+	}
+}
+
+func msSince(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(time.Since(t).Microseconds()) / 1000
+}
+
+// percentile returns the pth percentile of an already-sorted slice.
+func percentile(sorted []float64, pct float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(math.Ceil(pct/100*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
@@ -0,0 +1,102 @@
+// Package httpclient builds the http.Client every polling integration
+// uses, so proxy settings, timeouts, TLS verification and the
+// User-Agent are configured in one place instead of per package.
+//
+// Configuration comes from the environment, read once at first use:
+//
+//	HTTP_TIMEOUT    overrides every integration's default timeout
+//	HTTP_INSECURE   disables TLS certificate verification globally
+//	HTTP_USER_AGENT replaces the default bandwidth-monitor/<version>
+//
+// The standard HTTP_PROXY / HTTPS_PROXY / NO_PROXY variables are
+// honored through the shared transport.
+package httpclient
+
+import (
+	"crypto/tls"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"bandwidth-monitor/version"
+)
+
+var (
+	setupOnce sync.Once
+	override  time.Duration // HTTP_TIMEOUT; 0 keeps the caller's default
+	userAgent string
+	verifying *http.Transport
+	insecure  *http.Transport
+)
+
+func setup() {
+	if v := os.Getenv("HTTP_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Printf("HTTP_TIMEOUT: invalid duration %q: %v", v, err)
+		} else {
+			override = d
+		}
+	}
+	userAgent = os.Getenv("HTTP_USER_AGENT")
+	if userAgent == "" {
+		userAgent = "bandwidth-monitor/" + version.Version
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	transport := func(skipVerify bool) *http.Transport {
+		return &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+			IdleConnTimeout:     90 * time.Second,
+			TLSClientConfig:     &tls.Config{InsecureSkipVerify: skipVerify},
+		}
+	}
+	skipAll, _ := strconv.ParseBool(os.Getenv("HTTP_INSECURE"))
+	if skipAll {
+		log.Printf("httpclient: TLS certificate verification disabled (HTTP_INSECURE)")
+	}
+	verifying = transport(skipAll)
+	insecure = transport(true)
+}
+
+// New returns a client with the given default timeout (HTTP_TIMEOUT
+// outranks it) on the shared verifying transport. The returned client
+// is owned by the caller: setting a Jar or CheckRedirect on it is fine,
+// the transport underneath stays shared.
+func New(timeout time.Duration) *http.Client {
+	setupOnce.Do(setup)
+	if override > 0 {
+		timeout = override
+	}
+	return &http.Client{Timeout: timeout, Transport: &uaTransport{rt: verifying}}
+}
+
+// NewInsecure is New without TLS certificate verification, for
+// appliances that only speak self-signed HTTPS (UniFi, Proxmox, ...).
+func NewInsecure(timeout time.Duration) *http.Client {
+	setupOnce.Do(setup)
+	if override > 0 {
+		timeout = override
+	}
+	return &http.Client{Timeout: timeout, Transport: &uaTransport{rt: insecure}}
+}
+
+// uaTransport stamps the configured User-Agent on requests that don't
+// set their own.
+type uaTransport struct {
+	rt http.RoundTripper
+}
+
+func (t *uaTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", userAgent)
+	}
+	return t.rt.RoundTrip(req)
+}
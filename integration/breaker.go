@@ -0,0 +1,107 @@
+package integration
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	breakerThreshold  = 3                // consecutive failures before opening
+	breakerMinBackoff = 30 * time.Second // first open period
+	breakerMaxBackoff = 10 * time.Minute // doubling caps here
+)
+
+// Breaker is a shared circuit breaker for polling integrations. A
+// poller asks Allow before each attempt and reports the outcome; after
+// breakerThreshold consecutive failures the circuit opens and attempts
+// are skipped for an exponentially growing backoff, so a dead or
+// unreachable backend is probed occasionally instead of being hammered
+// (and logged) on every tick. One success closes the circuit and
+// resets the backoff.
+type Breaker struct {
+	name string
+
+	mu        sync.Mutex
+	failures  int
+	backoff   time.Duration
+	openUntil time.Time
+	lastErr   string
+}
+
+// BreakerStatus is the externally visible circuit state, reported by
+// the integrations health endpoint.
+type BreakerStatus struct {
+	State     string `json:"state"` // closed, open or half-open
+	Failures  int    `json:"failures,omitempty"`
+	LastError string `json:"last_error,omitempty"`
+	RetryAt   int64  `json:"retry_at,omitempty"` // unix ms, while open
+}
+
+// NewBreaker creates a closed breaker; name prefixes its log lines.
+func NewBreaker(name string) *Breaker {
+	return &Breaker{name: name}
+}
+
+// Allow reports whether the poller should attempt its backend now.
+// While the circuit is open it returns false until the backoff expires,
+// then lets one probe through (half-open).
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return time.Now().After(b.openUntil)
+}
+
+// Success records a completed poll, closing the circuit.
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	if b.failures >= breakerThreshold {
+		log.Printf("integration: %s recovered, circuit closed", b.name)
+	}
+	b.failures = 0
+	b.backoff = 0
+	b.openUntil = time.Time{}
+	b.lastErr = ""
+	b.mu.Unlock()
+}
+
+// Failure records a failed poll; the threshold'th consecutive failure
+// (and every failed probe after it) opens the circuit.
+func (b *Breaker) Failure(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if err != nil {
+		b.lastErr = err.Error()
+	}
+	if b.failures < breakerThreshold {
+		return
+	}
+	if b.backoff == 0 {
+		b.backoff = breakerMinBackoff
+	} else if b.backoff < breakerMaxBackoff {
+		b.backoff *= 2
+		if b.backoff > breakerMaxBackoff {
+			b.backoff = breakerMaxBackoff
+		}
+	}
+	b.openUntil = time.Now().Add(b.backoff)
+	log.Printf("integration: %s circuit open after %d failure(s), next attempt in %s", b.name, b.failures, b.backoff)
+}
+
+// Status reports the current circuit state.
+func (b *Breaker) Status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := BreakerStatus{Failures: b.failures, LastError: b.lastErr}
+	switch {
+	case b.openUntil.IsZero():
+		s.State = "closed"
+	case time.Now().After(b.openUntil):
+		s.State = "half-open"
+	default:
+		s.State = "open"
+		s.RetryAt = b.openUntil.UnixMilli()
+	}
+	return s
+}
@@ -0,0 +1,89 @@
+// Package integration defines the lifecycle contract every polling
+// integration already follows — construct, Run in a goroutine, Stop on
+// shutdown — and a registry so setupIntegrations manages them uniformly
+// instead of hand-maintaining a list of Stop calls. New integrations
+// only need to satisfy Integration and register themselves.
+package integration
+
+import "sync"
+
+// Integration is the minimal lifecycle every integration implements.
+// Run blocks until Stop is called; Stop must be safe to call once.
+type Integration interface {
+	Run()
+	Stop()
+}
+
+// Availabler is optionally implemented by integrations that can report
+// whether they have produced data yet.
+type Availabler interface {
+	Available() bool
+}
+
+// Healther is optionally implemented by integrations that guard their
+// backend with a Breaker and can report its circuit state.
+type Healther interface {
+	Health() BreakerStatus
+}
+
+// Status describes one registered integration.
+type Status struct {
+	Name      string         `json:"name"`
+	Available *bool          `json:"available,omitempty"` // omitted when not reported
+	Health    *BreakerStatus `json:"health,omitempty"`
+}
+
+type entry struct {
+	name string
+	i    Integration
+}
+
+// Registry tracks started integrations for status reporting and
+// shutdown.
+type Registry struct {
+	mu      sync.Mutex
+	entries []entry
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Start registers the integration under name and launches its Run loop.
+func (r *Registry) Start(name string, i Integration) {
+	r.mu.Lock()
+	r.entries = append(r.entries, entry{name: name, i: i})
+	r.mu.Unlock()
+	go i.Run()
+}
+
+// StopAll stops every registered integration, newest first.
+func (r *Registry) StopAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		r.entries[i].i.Stop()
+	}
+	r.entries = nil
+}
+
+// Statuses reports every registered integration in registration order.
+func (r *Registry) Statuses() []Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]Status, 0, len(r.entries))
+	for _, e := range r.entries {
+		st := Status{Name: e.name}
+		if a, ok := e.i.(Availabler); ok {
+			v := a.Available()
+			st.Available = &v
+		}
+		if h, ok := e.i.(Healther); ok {
+			hs := h.Health()
+			st.Health = &hs
+		}
+		out = append(out, st)
+	}
+	return out
+}
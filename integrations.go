@@ -0,0 +1,752 @@
+//go:build !agent
+
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"bandwidth-monitor/adguard"
+	"bandwidth-monitor/alerts"
+	"bandwidth-monitor/availability"
+	"bandwidth-monitor/budget"
+	"bandwidth-monitor/cluster"
+	"bandwidth-monitor/collector"
+	"bandwidth-monitor/demo"
+	"bandwidth-monitor/discovery"
+	"bandwidth-monitor/dns"
+	"bandwidth-monitor/dnslog"
+	"bandwidth-monitor/docker"
+	"bandwidth-monitor/edgeos"
+	"bandwidth-monitor/events"
+	"bandwidth-monitor/flowcollect"
+	"bandwidth-monitor/handler"
+	"bandwidth-monitor/hostapd"
+	"bandwidth-monitor/httpcheck"
+	"bandwidth-monitor/integration"
+	"bandwidth-monitor/inventory"
+	"bandwidth-monitor/knot"
+	"bandwidth-monitor/modem"
+	"bandwidth-monitor/mqtt"
+	"bandwidth-monitor/netbox"
+	"bandwidth-monitor/nextdns"
+	"bandwidth-monitor/openvpn"
+	"bandwidth-monitor/openwrt"
+	"bandwidth-monitor/opnsense"
+	"bandwidth-monitor/overrides"
+	"bandwidth-monitor/powerdns"
+	"bandwidth-monitor/prober"
+	"bandwidth-monitor/proxmox"
+	"bandwidth-monitor/snmp"
+	"bandwidth-monitor/suricata"
+	"bandwidth-monitor/talkers"
+	"bandwidth-monitor/tracer"
+	"bandwidth-monitor/unifi"
+	"bandwidth-monitor/wireguard"
+	"bandwidth-monitor/zeek"
+)
+
+//go:embed static/*
+var staticFiles embed.FS
+
+// overlayFS serves files from an on-disk override directory when they
+// exist there, falling back to the embedded static assets, so users can
+// customize the dashboard without rebuilding the binary.
+type overlayFS struct {
+	overlay http.FileSystem
+	base    http.FileSystem
+}
+
+func (o overlayFS) Open(name string) (http.File, error) {
+	if f, err := o.overlay.Open(name); err == nil {
+		return f, nil
+	}
+	return o.base.Open(name)
+}
+
+// setupIntegrations wires every optional integration, its API routes, the
+// WebSocket stream and the embedded UI. The agent build profile replaces
+// this with a stub, compiling all of it out. Returns the DNS provider
+// (shared with the agent push exporter) and a shutdown func.
+func setupIntegrations(mux *http.ServeMux, localNets []*net.IPNet, statsCollector *collector.Collector, talkerTracker *talkers.Tracker, eventBus *events.Bus) (dns.Provider, func()) {
+	// Every integration registers here as it starts; the registry owns
+	// shutdown order and feeds /api/integrations
+	reg := integration.NewRegistry()
+
+	adguardURL := env("ADGUARD_URL", "")
+	adguardUser := env("ADGUARD_USER", "")
+	adguardPass := env("ADGUARD_PASS", "")
+	nextdnsProfile := env("NEXTDNS_PROFILE", "")
+	nextdnsAPIKey := env("NEXTDNS_API_KEY", "")
+	unifiURL := env("UNIFI_URL", "")
+	unifiUser := env("UNIFI_USER", "")
+	unifiPass := env("UNIFI_PASS", "")
+	unifiSite := env("UNIFI_SITE", "default")
+
+	var dnsProvider dns.Provider
+	var unifiClient *unifi.Client
+	var adguardClient *adguard.Client
+
+	// Demo mode: fabricate collector, talker, DNS and WiFi data so the UI
+	// runs anywhere without root, pcap or configured integrations
+	var demoGen *demo.Generator
+	if ok, _ := strconv.ParseBool(env("DEMO", "false")); ok {
+		demoGen = demo.New(statsCollector, talkerTracker, localNets)
+		reg.Start("demo", demoGen)
+		dnsProvider = demoGen
+		unifiClient = demoGen.WiFi()
+		log.Printf("Demo mode enabled: serving synthetic data")
+	}
+
+	// DNS providers: every configured backend runs; a failover chain in
+	// priority order decides which one answers /api/dns, so a dead
+	// preferred backend hands over to the next without a restart. The
+	// default order matches the old hard-coded precedence; DNS_PROVIDERS
+	// reorders it.
+	if dnsProvider == nil {
+		// TOP_LIMIT sizes the providers' top-domain/client lists too, so
+		// the DNS panel matches the talker lists (invalid values already
+		// logged by the core parser)
+		topLimit, _ := strconv.Atoi(os.Getenv("TOP_LIMIT"))
+
+		configured := make(map[string]dns.Provider)
+		if adguardURL != "" {
+			ac := adguard.New(adguardURL, adguardUser, adguardPass, 10*time.Second)
+			if topLimit > 0 {
+				ac.SetTopLimit(topLimit)
+			}
+			reg.Start("adguard", ac)
+			configured["adguard"] = ac
+			adguardClient = ac
+			log.Printf("DNS integration: AdGuard Home (%s)", adguardURL)
+		}
+		if nextdnsProfile != "" && nextdnsAPIKey != "" {
+			nc := nextdns.New(nextdnsProfile, nextdnsAPIKey, 30*time.Second)
+			if topLimit > 0 {
+				nc.SetTopLimit(topLimit)
+			}
+			reg.Start("nextdns", nc)
+			configured["nextdns"] = nc
+			log.Printf("DNS integration: NextDNS (profile %s)", nextdnsProfile)
+		}
+		if u := env("KNOT_STATS_URL", ""); u != "" {
+			kc := knot.New(u, 30*time.Second)
+			reg.Start("knot", kc)
+			configured["knot"] = kc
+			log.Printf("DNS integration: Knot Resolver (%s)", u)
+		}
+		if u := env("POWERDNS_URL", ""); u != "" && os.Getenv("POWERDNS_API_KEY") != "" {
+			pc := powerdns.New(u, os.Getenv("POWERDNS_API_KEY"), 30*time.Second)
+			reg.Start("powerdns", pc)
+			configured["powerdns"] = pc
+			log.Printf("DNS integration: PowerDNS Recursor (%s)", u)
+		}
+
+		var ordered []dns.Provider
+		var names []string
+		seen := make(map[string]bool)
+		order := strings.Split(env("DNS_PROVIDERS", "adguard,nextdns,knot,powerdns"), ",")
+		// Configured backends missing from DNS_PROVIDERS still join the
+		// tail in default order rather than silently dropping
+		order = append(order, "adguard", "nextdns", "knot", "powerdns")
+		for _, name := range order {
+			name = strings.ToLower(strings.TrimSpace(name))
+			p, ok := configured[name]
+			if !ok || seen[name] {
+				continue
+			}
+			seen[name] = true
+			ordered = append(ordered, p)
+			names = append(names, name)
+		}
+		if len(ordered) > 0 {
+			dnsProvider = dns.NewChain(ordered...)
+			if len(ordered) > 1 {
+				log.Printf("DNS failover chain: %s", strings.Join(names, " > "))
+			}
+		}
+	}
+
+	// DNS query-log analytics: tail AdGuard's query log for the aggregates
+	// its stats API can't answer — per-client hourly query counts and
+	// domain first-seen timestamps. Opt-in; needs the query log enabled
+	// on the AdGuard side.
+	var dnsLog *dnslog.Logger
+	if ok, _ := strconv.ParseBool(env("DNS_QUERYLOG", "false")); ok && adguardURL != "" {
+		dnsLog = dnslog.New(adguardURL, adguardUser, adguardPass, env("DNS_QUERYLOG_FILE", "dnslog.json"), 30*time.Second)
+		reg.Start("dnslog", dnsLog)
+		log.Printf("DNS query-log analytics enabled (%s)", adguardURL)
+	}
+
+	// Latency prober: smokeping-style ICMP probes to gateway/ISP/resolver
+	var pingProber *prober.Prober
+	if raw := os.Getenv("PING_TARGETS"); raw != "" {
+		var targets []string
+		for _, h := range strings.Split(raw, ",") {
+			if h = strings.TrimSpace(h); h != "" {
+				targets = append(targets, h)
+			}
+		}
+		interval := 1 * time.Second
+		if v := os.Getenv("PING_INTERVAL"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				log.Printf("PING_INTERVAL: invalid duration %q: %v", v, err)
+			} else {
+				interval = d
+			}
+		}
+		pingProber = prober.New(targets, interval)
+		reg.Start("prober", pingProber)
+		log.Printf("Latency prober enabled: %d target(s) every %s", len(targets), interval)
+	}
+
+	// Synthetic HTTP checks: TTFB and availability of key external
+	// services, so slow-internet reports can be split into line capacity
+	// versus remote-service problems
+	var httpChecker *httpcheck.Checker
+	if raw := os.Getenv("HTTP_CHECKS"); raw != "" {
+		var specs []string
+		for _, s := range strings.Split(raw, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				specs = append(specs, s)
+			}
+		}
+		interval := 30 * time.Second
+		if v := os.Getenv("HTTP_CHECK_INTERVAL"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				log.Printf("HTTP_CHECK_INTERVAL: invalid duration %q: %v", v, err)
+			} else {
+				interval = d
+			}
+		}
+		httpChecker = httpcheck.New(specs, interval)
+		reg.Start("httpcheck", httpChecker)
+		log.Printf("HTTP checks enabled: %d target(s) every %s", len(specs), interval)
+	}
+
+	// Outage / availability tracking rides on the prober
+	var availMonitor *availability.Monitor
+	if pingProber != nil {
+		availMonitor = availability.New(pingProber)
+		reg.Start("availability", availMonitor)
+	}
+
+	// Path tracer: periodic traceroutes flagging route changes and hop latency spikes
+	var pathTracer *tracer.Tracer
+	if raw := os.Getenv("TRACE_TARGETS"); raw != "" {
+		var targets []string
+		for _, h := range strings.Split(raw, ",") {
+			if h = strings.TrimSpace(h); h != "" {
+				targets = append(targets, h)
+			}
+		}
+		interval := 5 * time.Minute
+		if v := os.Getenv("TRACE_INTERVAL"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				log.Printf("TRACE_INTERVAL: invalid duration %q: %v", v, err)
+			} else {
+				interval = d
+			}
+		}
+		pathTracer = tracer.New(targets, interval)
+		reg.Start("tracer", pathTracer)
+		log.Printf("Path tracer enabled: %d target(s) every %s", len(targets), interval)
+	}
+
+	// SNMP poller: per-port counters from managed switches/routers
+	var snmpPoller *snmp.Poller
+	if raw := os.Getenv("SNMP_TARGETS"); raw != "" {
+		var targets []string
+		for _, t := range strings.Split(raw, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				targets = append(targets, t)
+			}
+		}
+		interval := 30 * time.Second
+		if v := os.Getenv("SNMP_INTERVAL"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				log.Printf("SNMP_INTERVAL: invalid duration %q: %v", v, err)
+			} else {
+				interval = d
+			}
+		}
+		var v3 *snmp.V3Config
+		if u := os.Getenv("SNMP_V3_USER"); u != "" {
+			v3 = &snmp.V3Config{
+				User:      u,
+				AuthProto: env("SNMP_V3_AUTH_PROTO", "SHA"),
+				AuthPass:  os.Getenv("SNMP_V3_AUTH_PASS"),
+				PrivProto: env("SNMP_V3_PRIV_PROTO", "AES"),
+				PrivPass:  os.Getenv("SNMP_V3_PRIV_PASS"),
+			}
+		}
+		snmpPoller = snmp.New(targets, v3, interval)
+		reg.Start("snmp", snmpPoller)
+		log.Printf("SNMP poller enabled: %d target(s) every %s", len(targets), interval)
+	}
+
+	// WireGuard peer stats via wgctrl (netlink)
+	var wgClient *wireguard.Client
+	if ok, _ := strconv.ParseBool(env("WIREGUARD_STATS", "false")); ok {
+		interval := 10 * time.Second
+		if v := os.Getenv("WIREGUARD_INTERVAL"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				log.Printf("WIREGUARD_INTERVAL: invalid duration %q: %v", v, err)
+			} else {
+				interval = d
+			}
+		}
+		wgClient = wireguard.New(interval)
+		reg.Start("wireguard", wgClient)
+		log.Printf("WireGuard peer stats enabled (every %s)", interval)
+	}
+
+	// OpenVPN server status (status file or management interface)
+	var ovpnClient *openvpn.Client
+	ovpnStatus := env("OPENVPN_STATUS_FILE", "")
+	ovpnMgmt := env("OPENVPN_MGMT_ADDR", "")
+	if ovpnStatus != "" || ovpnMgmt != "" {
+		interval := 10 * time.Second
+		if v := os.Getenv("OPENVPN_INTERVAL"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				log.Printf("OPENVPN_INTERVAL: invalid duration %q: %v", v, err)
+			} else {
+				interval = d
+			}
+		}
+		ovpnClient = openvpn.New(ovpnStatus, ovpnMgmt, interval)
+		reg.Start("openvpn", ovpnClient)
+		if ovpnStatus != "" {
+			log.Printf("OpenVPN integration enabled: status file %s", ovpnStatus)
+		} else {
+			log.Printf("OpenVPN integration enabled: management interface %s", ovpnMgmt)
+		}
+	}
+
+	// Docker container network stats (auto-enabled when the socket exists)
+	var dockerClient *docker.Client
+	dockerSocket := env("DOCKER_SOCKET", "/var/run/docker.sock")
+	if _, err := os.Stat(dockerSocket); err == nil {
+		interval := 10 * time.Second
+		if v := os.Getenv("DOCKER_INTERVAL"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				log.Printf("DOCKER_INTERVAL: invalid duration %q: %v", v, err)
+			} else {
+				interval = d
+			}
+		}
+		dockerClient = docker.New(dockerSocket, interval)
+		reg.Start("docker", dockerClient)
+		log.Printf("Docker container stats enabled: %s", dockerSocket)
+	}
+
+	// Proxmox VE per-VM/CT network counters
+	var proxmoxClient *proxmox.Client
+	if u := env("PROXMOX_URL", ""); u != "" && os.Getenv("PROXMOX_TOKEN") != "" {
+		interval := 15 * time.Second
+		if v := os.Getenv("PROXMOX_INTERVAL"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				log.Printf("PROXMOX_INTERVAL: invalid duration %q: %v", v, err)
+			} else {
+				interval = d
+			}
+		}
+		proxmoxClient = proxmox.New(u, os.Getenv("PROXMOX_TOKEN"), interval)
+		reg.Start("proxmox", proxmoxClient)
+		log.Printf("Proxmox integration enabled: %s", u)
+	}
+
+	// DOCSIS cable modem status scraping
+	var modemClient *modem.Client
+	if u := env("MODEM_URL", ""); u != "" {
+		interval := 60 * time.Second
+		if v := os.Getenv("MODEM_INTERVAL"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				log.Printf("MODEM_INTERVAL: invalid duration %q: %v", v, err)
+			} else {
+				interval = d
+			}
+		}
+		mc, err := modem.New(u, env("MODEM_TYPE", "arris"), interval)
+		if err != nil {
+			log.Printf("Modem integration: %v", err)
+		} else {
+			modemClient = mc
+			reg.Start("modem", modemClient)
+			log.Printf("Modem status scraping enabled: %s (%s)", u, env("MODEM_TYPE", "arris"))
+		}
+	}
+
+	// EdgeOS / VyOS router polling
+	var edgeosClient *edgeos.Client
+	if u := env("EDGEOS_URL", ""); u != "" {
+		interval := 15 * time.Second
+		if v := os.Getenv("EDGEOS_INTERVAL"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				log.Printf("EDGEOS_INTERVAL: invalid duration %q: %v", v, err)
+			} else {
+				interval = d
+			}
+		}
+		edgeosClient = edgeos.New(u, env("EDGEOS_USER", "ubnt"), os.Getenv("EDGEOS_PASS"), interval)
+		reg.Start("edgeos", edgeosClient)
+		log.Printf("EdgeOS integration enabled: %s", u)
+	}
+
+	// OpenWrt router polling over ubus-over-HTTP
+	var openwrtClient *openwrt.Client
+	if u := env("OPENWRT_URL", ""); u != "" {
+		interval := 15 * time.Second
+		if v := os.Getenv("OPENWRT_INTERVAL"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				log.Printf("OPENWRT_INTERVAL: invalid duration %q: %v", v, err)
+			} else {
+				interval = d
+			}
+		}
+		openwrtClient = openwrt.New(u, env("OPENWRT_USER", "root"), os.Getenv("OPENWRT_PASS"), interval)
+		reg.Start("openwrt", openwrtClient)
+		log.Printf("OpenWrt integration enabled: %s", u)
+	}
+
+	// OPNsense / pfSense firewall polling
+	var opnsenseClient *opnsense.Client
+	if u := env("OPNSENSE_URL", ""); u != "" {
+		interval := 15 * time.Second
+		if v := os.Getenv("OPNSENSE_INTERVAL"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				log.Printf("OPNSENSE_INTERVAL: invalid duration %q: %v", v, err)
+			} else {
+				interval = d
+			}
+		}
+		flavor := env("OPNSENSE_FLAVOR", opnsense.FlavorOPNsense)
+		opnsenseClient = opnsense.New(u, flavor, os.Getenv("OPNSENSE_KEY"), os.Getenv("OPNSENSE_SECRET"), interval)
+		reg.Start("opnsense", opnsenseClient)
+		log.Printf("OPNsense integration enabled (%s): %s", flavor, u)
+	}
+
+	if unifiClient == nil && unifiURL != "" {
+		unifiClient = unifi.New(unifiURL, unifiUser, unifiPass, unifiSite, 15*time.Second)
+		reg.Start("unifi", unifiClient)
+		log.Printf("UniFi controller integration enabled: %s", unifiURL)
+	}
+
+	// Local hostapd AP: when this box is the access point, read station
+	// data straight off the control sockets and serve it through the
+	// same /api/wifi structures the UniFi integration fills
+	if raw := os.Getenv("HOSTAPD_IFACES"); raw != "" && unifiClient == nil {
+		interval := 15 * time.Second
+		if v := os.Getenv("HOSTAPD_INTERVAL"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				log.Printf("HOSTAPD_INTERVAL: invalid duration %q: %v", v, err)
+			} else {
+				interval = d
+			}
+		}
+		var ifaces []string
+		for _, f := range strings.Split(raw, ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				ifaces = append(ifaces, f)
+			}
+		}
+		// The summary lives in a unifi.Client shell so /api/wifi and its
+		// history tracking work unchanged; the shell never polls itself
+		unifiClient = unifi.New("https://hostapd.invalid", "", "", "default", time.Hour)
+		reg.Start("hostapd", hostapd.New(ifaces, env("HOSTAPD_DIR", "/var/run/hostapd"), interval, unifiClient.SetSummary))
+		log.Printf("hostapd integration enabled: %s", strings.Join(ifaces, ", "))
+	}
+
+	// Device inventory built from periodic ARP sweeps of LOCAL_NETS
+	var deviceInventory *inventory.Inventory
+	if ok, _ := strconv.ParseBool(env("INVENTORY_SWEEP", "false")); ok {
+		interval := 5 * time.Minute
+		if v := os.Getenv("INVENTORY_INTERVAL"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				log.Printf("INVENTORY_INTERVAL: invalid duration %q: %v", v, err)
+			} else {
+				interval = d
+			}
+		}
+		deviceInventory = inventory.New(
+			env("INVENTORY_FILE", "devices.json"),
+			localNets,
+			env("OUI_FILE", ""),
+			interval,
+		)
+		deviceInventory.SetEventBus(eventBus)
+		reg.Start("inventory", deviceInventory)
+		// User-assigned device names take precedence everywhere IPs appear
+		handler.SetDeviceNamer(deviceInventory)
+		log.Printf("Device inventory enabled (sweep every %s)", interval)
+	}
+
+	// SSDP/mDNS service discovery: devices announce what they are
+	// (Chromecast, printer, camera); attach those services and instance
+	// names to the inventory
+	if ok, _ := strconv.ParseBool(env("SERVICE_DISCOVERY", "false")); ok && deviceInventory != nil {
+		active, _ := strconv.ParseBool(env("SERVICE_DISCOVERY_ACTIVE", "false"))
+		interval := 5 * time.Minute
+		if v := os.Getenv("SERVICE_DISCOVERY_INTERVAL"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				log.Printf("SERVICE_DISCOVERY_INTERVAL: invalid duration %q: %v", v, err)
+			} else {
+				interval = d
+			}
+		}
+		reg.Start("discovery", discovery.New(deviceInventory, active, interval))
+		log.Printf("Service discovery enabled (active=%v)", active)
+	}
+
+	// When AdGuard Home also serves DHCP, its leases carry hostnames and
+	// MACs the ARP sweep can't see; merge them into the inventory (which
+	// in turn names DNS client stats and talkers)
+	if ok, _ := strconv.ParseBool(env("ADGUARD_DHCP", "false")); ok && adguardClient != nil && deviceInventory != nil {
+		adguardClient.SetLeaseSink(deviceInventory)
+		log.Printf("AdGuard DHCP lease enrichment enabled")
+	}
+
+	// Static hostname/label overrides for remote IPs and CIDRs; these
+	// outrank inventory names and reverse DNS in all talker outputs
+	overrideMap := overrides.New(env("OVERRIDES_FILE", "overrides.json"))
+	if spec := os.Getenv("HOST_OVERRIDES"); spec != "" {
+		if err := overrideMap.LoadSpec(spec); err != nil {
+			log.Printf("HOST_OVERRIDES: %v", err)
+		}
+	}
+	if deviceInventory != nil {
+		talkerTracker.SetNamer(talkers.ChainNamers(overrideMap, deviceInventory))
+	} else {
+		talkerTracker.SetNamer(overrideMap)
+	}
+
+	// NetBox source-of-truth enrichment for devices and prefixes
+	var netboxClient *netbox.Client
+	if u := env("NETBOX_URL", ""); u != "" && os.Getenv("NETBOX_TOKEN") != "" {
+		interval := 15 * time.Minute
+		if v := os.Getenv("NETBOX_INTERVAL"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				log.Printf("NETBOX_INTERVAL: invalid duration %q: %v", v, err)
+			} else {
+				interval = d
+			}
+		}
+		netboxClient = netbox.New(u, os.Getenv("NETBOX_TOKEN"), interval)
+		reg.Start("netbox", netboxClient)
+		if deviceInventory != nil {
+			deviceInventory.SetEnricher(netboxClient)
+		}
+		log.Printf("NetBox enrichment enabled: %s", u)
+	}
+
+	// Zeek conn.log ingestion into the talkers bucket pipeline
+	var zeekTailer *zeek.Tailer
+	if p := env("ZEEK_CONN", ""); p != "" {
+		zeekTailer = zeek.New(p, talkerTracker)
+		reg.Start("zeek", zeekTailer)
+		log.Printf("Zeek conn.log ingestion enabled: %s", p)
+	}
+
+	// Flow-collector mode: switches and routers export sFlow/NetFlow
+	// here instead of this box capturing packets itself
+	if addr := env("FLOW_LISTEN", ""); addr != "" {
+		reg.Start("flowcollect", flowcollect.New(addr, talkerTracker))
+		log.Printf("Flow collector listening on %s (NetFlow v5/v9, IPFIX, sFlow v5)", addr)
+	}
+
+	// Suricata EVE JSON ingestion
+	var suricataTailer *suricata.Tailer
+	if p := env("SURICATA_EVE", ""); p != "" {
+		suricataTailer = suricata.New(p, talkerTracker)
+		reg.Start("suricata", suricataTailer)
+		log.Printf("Suricata EVE ingestion enabled: %s", p)
+	}
+
+	// Multi-node: aggregator accepts snapshots from remote agents when
+	// CLUSTER_TOKEN is set; federation queries peers on demand.
+	var clusterAgg *cluster.Aggregator
+	clusterToken := os.Getenv("CLUSTER_TOKEN")
+	if clusterToken != "" {
+		clusterAgg = cluster.NewAggregator()
+		log.Printf("Cluster aggregator enabled on /api/cluster/ws")
+	}
+	var federator *cluster.Federator
+	if spec := env("FEDERATION_PEERS", ""); spec != "" {
+		f, err := cluster.NewFederator(spec)
+		if err != nil {
+			log.Printf("FEDERATION_PEERS: %v", err)
+		} else {
+			federator = f
+			log.Printf("Federation enabled with %d peer(s)", len(f.Peers()))
+		}
+	}
+
+	// Alerting engine: user-defined rules over the live collectors with
+	// webhook/ntfy/Slack/Discord delivery
+	var alertEngine *alerts.Engine
+	if spec := env("ALERT_RULES", ""); spec != "" {
+		rules, err := alerts.ParseRules(spec)
+		if err != nil {
+			log.Printf("ALERT_RULES: %v", err)
+		} else {
+			interval := 30 * time.Second
+			if v := env("ALERT_INTERVAL", ""); v != "" {
+				d, err := time.ParseDuration(v)
+				if err != nil {
+					log.Printf("Invalid ALERT_INTERVAL %q, using 30s", v)
+				} else {
+					interval = d
+				}
+			}
+			alertEngine = alerts.New(rules, statsCollector, talkerTracker, interval)
+			alertEngine.SetDNSProvider(dnsProvider)
+			alertEngine.SetUniFi(unifiClient)
+			alertEngine.SetEventBus(eventBus)
+			alertEngine.SetNotify(env("ALERT_WEBHOOK", ""), env("ALERT_NTFY", ""),
+				env("ALERT_SLACK", ""), env("ALERT_DISCORD", ""))
+			reg.Start("alerts", alertEngine)
+			log.Printf("Alerting enabled with %d rule(s)", len(rules))
+		}
+	}
+
+	// Per-device daily budgets: usage from the talker per-client
+	// accounting (needs TALKERS_CLIENTS), with optional block
+	// enforcement through the UniFi controller or AdGuard
+	var budgetTracker *budget.Tracker
+	if spec := env("BUDGETS", ""); spec != "" {
+		budgets, err := budget.ParseBudgets(spec)
+		if err != nil {
+			log.Printf("BUDGETS: %v", err)
+		} else {
+			budgetTracker = budget.New(budgets, talkerTracker)
+			budgetTracker.SetEventBus(eventBus)
+			switch enforce := env("BUDGET_ENFORCE", ""); enforce {
+			case "":
+			case "unifi":
+				if unifiClient != nil {
+					budgetTracker.SetEnforcer(unifiClient)
+				} else {
+					log.Printf("BUDGET_ENFORCE=unifi needs a UniFi controller configured, ignoring")
+				}
+			case "adguard":
+				if adguardClient != nil {
+					budgetTracker.SetEnforcer(adguardClient)
+				} else {
+					log.Printf("BUDGET_ENFORCE=adguard needs ADGUARD_URL, ignoring")
+				}
+			default:
+				log.Printf("Unknown BUDGET_ENFORCE %q, ignoring", enforce)
+			}
+			reg.Start("budget", budgetTracker)
+			log.Printf("Daily device budgets enabled for %d device(s)", len(budgets))
+		}
+	}
+
+	// MQTT: push the headline numbers to a broker with Home Assistant
+	// discovery, so the monitor appears as HA sensors without templates
+	if broker := env("MQTT_BROKER", ""); broker != "" {
+		interval := 30 * time.Second
+		if v := env("MQTT_INTERVAL", ""); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				log.Printf("Invalid MQTT_INTERVAL %q, using 30s", v)
+			} else {
+				interval = d
+			}
+		}
+		pub := mqtt.New(broker, env("MQTT_USER", ""), env("MQTT_PASS", ""),
+			env("MQTT_PREFIX", ""), statsCollector, interval)
+		pub.SetNode(env("NODE_LABEL", ""))
+		pub.SetDNSProvider(dnsProvider)
+		pub.SetUniFi(unifiClient)
+		reg.Start("mqtt", pub)
+		log.Printf("MQTT publishing to %s every %s", broker, interval)
+	}
+
+	mux.HandleFunc("/api/dns", handler.DNSSummary(dnsProvider))
+	mux.HandleFunc("/api/dns/analytics", handler.DNSAnalytics(dnsLog))
+	mux.HandleFunc("/api/wifi", handler.WiFiSummary(unifiClient))
+	mux.HandleFunc("/api/wifi/history", handler.WiFiHistory(unifiClient))
+	// Default shaping for /api/summary; per-request query params override
+	summaryDefaults := url.Values{}
+	if spec := os.Getenv("SUMMARY_DEFAULTS"); spec != "" {
+		v, err := url.ParseQuery(spec)
+		if err != nil {
+			log.Printf("SUMMARY_DEFAULTS: %v", err)
+		} else {
+			summaryDefaults = v
+		}
+	}
+	mux.HandleFunc("/api/summary", handler.MenuBarSummary(statsCollector, talkerTracker, dnsProvider, unifiClient, summaryDefaults))
+	mux.HandleFunc("/api/ping", handler.PingStats(pingProber))
+	mux.HandleFunc("/api/ping/history", handler.PingHistory(pingProber))
+	mux.HandleFunc("/api/checks", handler.HTTPChecks(httpChecker))
+	mux.HandleFunc("/api/checks/history", handler.HTTPCheckHistory(httpChecker))
+	mux.HandleFunc("/api/paths", handler.Paths(pathTracer))
+	mux.HandleFunc("/api/paths/events", handler.PathEvents(pathTracer))
+	mux.HandleFunc("/api/snmp", handler.SNMPDevices(snmpPoller))
+	mux.HandleFunc("/api/wireguard", handler.WireGuardStats(wgClient))
+	mux.HandleFunc("/api/openvpn", handler.OpenVPNStats(ovpnClient))
+	mux.HandleFunc("/api/containers", handler.ContainerStats(dockerClient))
+	mux.HandleFunc("/api/proxmox", handler.ProxmoxGuests(proxmoxClient))
+	mux.HandleFunc("/api/modem", handler.ModemStatus(modemClient))
+	mux.HandleFunc("/api/edgeos", handler.EdgeOSStats(edgeosClient))
+	mux.HandleFunc("/api/openwrt", handler.OpenWrtStats(openwrtClient))
+	mux.HandleFunc("/api/opnsense", handler.OPNsenseStats(opnsenseClient))
+	mux.HandleFunc("/api/modem/history", handler.ModemHistory(modemClient))
+	mux.HandleFunc("/api/devices", handler.Devices(deviceInventory))
+	mux.HandleFunc("/api/overrides", handler.Overrides(overrideMap))
+	mux.HandleFunc("/api/netbox", handler.NetBoxSummary(netboxClient))
+	mux.HandleFunc("/api/cluster", handler.ClusterNodes(clusterAgg))
+	mux.HandleFunc("/api/cluster/ws", handler.ClusterIngest(clusterAgg, clusterToken))
+	mux.HandleFunc("/api/federation", handler.Federation(federator))
+	mux.HandleFunc("/api/devices/wake", handler.WakeDevice(deviceInventory, os.Getenv("WOL_TOKEN")))
+	mux.HandleFunc("/api/availability", handler.Availability(availMonitor))
+	mux.HandleFunc("/api/ha", handler.HomeAssistant(statsCollector, talkerTracker, dnsProvider, unifiClient))
+	mux.HandleFunc("/api/events", handler.IDSEvents(suricataTailer))
+	mux.HandleFunc("/api/integrations", handler.Integrations(reg))
+	mux.HandleFunc("/api/alerts", handler.Alerts(alertEngine))
+	mux.HandleFunc("/api/alerts/mutes", handler.AlertMutes(alertEngine))
+	mux.HandleFunc("/api/budgets", handler.Budgets(budgetTracker))
+	mux.HandleFunc("/api/ws", handler.WebSocket(statsCollector, talkerTracker, dnsProvider, unifiClient, pingProber, pathTracer, snmpPoller, wgClient, ovpnClient, dockerClient, proxmoxClient, modemClient, opnsenseClient, suricataTailer, alertEngine))
+	staticSub, err := fs.Sub(staticFiles, "static")
+	if err != nil {
+		log.Fatalf("Failed to create sub filesystem: %v", err)
+	}
+	staticFS := http.FS(staticSub)
+	if dir := env("STATIC_DIR", ""); dir != "" {
+		if st, err := os.Stat(dir); err != nil || !st.IsDir() {
+			log.Printf("STATIC_DIR: %s is not a directory (using embedded assets only)", dir)
+		} else {
+			staticFS = overlayFS{overlay: http.Dir(dir), base: staticFS}
+			log.Printf("Static asset overrides enabled: %s", dir)
+		}
+	}
+	mux.Handle("/", http.FileServer(staticFS))
+
+	return dnsProvider, reg.StopAll
+}
@@ -0,0 +1,22 @@
+//go:build agent
+
+package main
+
+import (
+	"log"
+	"net"
+	"net/http"
+
+	"bandwidth-monitor/collector"
+	"bandwidth-monitor/dns"
+	"bandwidth-monitor/events"
+	"bandwidth-monitor/talkers"
+)
+
+// setupIntegrations is a stub for the minimal agent profile (built with
+// -tags agent): no embedded UI, no WebSocket, no integrations — just
+// capture/collection and the push exporter, for low-flash devices.
+func setupIntegrations(mux *http.ServeMux, localNets []*net.IPNet, statsCollector *collector.Collector, talkerTracker *talkers.Tracker, eventBus *events.Bus) (dns.Provider, func()) {
+	log.Println("Agent build profile: UI, WebSocket and integrations disabled")
+	return nil, func() {}
+}
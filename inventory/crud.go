@@ -0,0 +1,105 @@
+package inventory
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Create adds a manual device entry (e.g. one the sweep cannot reach).
+// At least one of mac or ip is required; a MAC becomes the ID as usual.
+func (inv *Inventory) Create(mac, ip, name, icon string, tags []string) (*Device, error) {
+	mac = strings.ToLower(strings.TrimSpace(mac))
+	ip = strings.TrimSpace(ip)
+	if mac == "" && ip == "" {
+		return nil, fmt.Errorf("mac or ip required")
+	}
+	id := mac
+	if id == "" {
+		id = ip
+	}
+
+	inv.mu.Lock()
+	if _, exists := inv.devices[id]; exists {
+		inv.mu.Unlock()
+		return nil, fmt.Errorf("device %q already exists", id)
+	}
+	now := time.Now().UnixMilli()
+	d := &Device{
+		ID:        id,
+		MAC:       mac,
+		IP:        ip,
+		Name:      name,
+		Icon:      icon,
+		Tags:      tags,
+		Vendor:    inv.vendorFor(mac),
+		FirstSeen: now,
+		LastSeen:  now,
+	}
+	inv.devices[id] = d
+	inv.dirty = true
+	cp := *d
+	inv.mu.Unlock()
+
+	inv.save()
+	return &cp, nil
+}
+
+// Update assigns the user-editable fields (name, icon, tags) of a device;
+// empty values clear them.
+func (inv *Inventory) Update(id, name, icon string, tags []string) (*Device, error) {
+	inv.mu.Lock()
+	d, ok := inv.devices[id]
+	if !ok {
+		inv.mu.Unlock()
+		return nil, fmt.Errorf("unknown device %q", id)
+	}
+	d.Name = name
+	d.Icon = icon
+	d.Tags = tags
+	inv.dirty = true
+	cp := *d
+	inv.enrich(&cp)
+	inv.mu.Unlock()
+
+	inv.save()
+	return &cp, nil
+}
+
+// Delete removes a device from the inventory (it will reappear on the
+// next sweep if still active, minus any user-assigned fields).
+func (inv *Inventory) Delete(id string) error {
+	inv.mu.Lock()
+	if _, ok := inv.devices[id]; !ok {
+		inv.mu.Unlock()
+		return fmt.Errorf("unknown device %q", id)
+	}
+	delete(inv.devices, id)
+	inv.dirty = true
+	inv.mu.Unlock()
+
+	inv.save()
+	return nil
+}
+
+// NameFor returns the display name for ip: the user-assigned device name
+// first, then the enricher's. Returns "" when neither knows the IP.
+func (inv *Inventory) NameFor(ip string) string {
+	inv.mu.RLock()
+	defer inv.mu.RUnlock()
+	for _, d := range inv.devices {
+		if !d.hasIP(ip) {
+			continue
+		}
+		if d.Name != "" {
+			return d.Name
+		}
+		break
+	}
+	if inv.enricher != nil {
+		if name, _, _ := inv.enricher.NameForIP(ip); name != "" {
+			return name
+		}
+	}
+	return ""
+}
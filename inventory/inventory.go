@@ -0,0 +1,438 @@
+// Package inventory maintains a persistent device inventory (IP, MAC,
+// vendor, first/last seen) built from periodic ARP sweeps of LOCAL_NETS.
+// Other subsystems (talker attribution, alerts, reports) reference devices
+// by their stable ID.
+package inventory
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"bandwidth-monitor/events"
+)
+
+const maxSweepHosts = 1024 // cap per-net sweep so a /16 doesn't flood the LAN
+
+// Device is one discovered LAN device. ID is the MAC address when known,
+// otherwise the IP. IPv6 lists the addresses currently correlated to the
+// MAC via the neighbour table (privacy temporaries rotate, so the set is
+// replaced on every sweep). Name, Icon and Tags are user-assigned and persisted;
+// Role and Tenant come from an external Enricher (e.g. NetBox), which
+// also supplies Name when the user has not set one.
+type Device struct {
+	ID     string   `json:"id"`
+	MAC    string   `json:"mac,omitempty"`
+	IP     string   `json:"ip"`
+	IPv6   []string `json:"ipv6,omitempty"`
+	Vendor string   `json:"vendor,omitempty"`
+	Name   string   `json:"name,omitempty"`
+	Icon   string   `json:"icon,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+	Role   string   `json:"role,omitempty"`
+	Tenant string   `json:"tenant,omitempty"`
+	// Services advertised over SSDP/mDNS (e.g. "_googlecast._tcp",
+	// "upnp:dial"), filled by the discovery listener.
+	Services  []string `json:"services,omitempty"`
+	FirstSeen int64    `json:"first_seen"`
+	LastSeen  int64    `json:"last_seen"`
+}
+
+// hasIP reports whether ip is the device's IPv4 address or one of its
+// correlated IPv6 addresses.
+func (d *Device) hasIP(ip string) bool {
+	if d.IP == ip {
+		return true
+	}
+	for _, v6 := range d.IPv6 {
+		if v6 == ip {
+			return true
+		}
+	}
+	return false
+}
+
+// Enricher supplies source-of-truth naming for a device IP.
+type Enricher interface {
+	NameForIP(ip string) (name, role, tenant string)
+}
+
+// Inventory tracks devices and persists them to a JSON file across restarts.
+type Inventory struct {
+	path      string
+	localNets []*net.IPNet
+	interval  time.Duration
+	oui       map[string]string // OUI prefix (lowercase "aa:bb:cc") → vendor
+
+	mu       sync.RWMutex
+	devices  map[string]*Device // keyed by ID
+	dirty    bool
+	enricher Enricher
+	bus      *events.Bus
+
+	stopCh chan struct{}
+}
+
+// New creates an Inventory persisted at path. ouiFile is an optional IEEE
+// OUI database ("XX-XX-XX   (hex)  Vendor" lines) for vendor names.
+func New(path string, localNets []*net.IPNet, ouiFile string, sweepInterval time.Duration) *Inventory {
+	if sweepInterval <= 0 {
+		sweepInterval = 5 * time.Minute
+	}
+	inv := &Inventory{
+		path:      path,
+		localNets: localNets,
+		interval:  sweepInterval,
+		devices:   make(map[string]*Device),
+		stopCh:    make(chan struct{}),
+	}
+	inv.load()
+	if ouiFile != "" {
+		inv.oui = loadOUI(ouiFile)
+	}
+	return inv
+}
+
+// SetEventBus attaches the event bus that newly discovered devices are
+// published to. Call before Run.
+func (inv *Inventory) SetEventBus(b *events.Bus) {
+	inv.bus = b
+}
+
+// Run sweeps immediately and then on every interval tick, persisting
+// changes between sweeps. Call in a goroutine.
+func (inv *Inventory) Run() {
+	inv.sweep()
+	inv.save()
+
+	ticker := time.NewTicker(inv.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			inv.sweep()
+			inv.save()
+		case <-inv.stopCh:
+			inv.save()
+			return
+		}
+	}
+}
+
+// Stop terminates the sweep loop (persisting one final time).
+func (inv *Inventory) Stop() {
+	select {
+	case <-inv.stopCh:
+	default:
+		close(inv.stopCh)
+	}
+}
+
+// SetEnricher attaches an external naming source applied to devices
+// returned by GetAll and Lookup.
+func (inv *Inventory) SetEnricher(e Enricher) {
+	inv.mu.Lock()
+	inv.enricher = e
+	inv.mu.Unlock()
+}
+
+// GetAll returns all known devices sorted by IP.
+func (inv *Inventory) GetAll() []Device {
+	inv.mu.RLock()
+	defer inv.mu.RUnlock()
+
+	out := make([]Device, 0, len(inv.devices))
+	for _, d := range inv.devices {
+		cp := *d
+		inv.enrich(&cp)
+		out = append(out, cp)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].IP < out[j].IP })
+	return out
+}
+
+// Lookup returns the device currently holding ip (v4 or any of its
+// correlated IPv6 addresses), or nil.
+func (inv *Inventory) Lookup(ip string) *Device {
+	inv.mu.RLock()
+	defer inv.mu.RUnlock()
+	for _, d := range inv.devices {
+		if d.hasIP(ip) {
+			cp := *d
+			inv.enrich(&cp)
+			return &cp
+		}
+	}
+	return nil
+}
+
+// enrich fills Role/Tenant (and Name, unless user-assigned) on a device
+// copy; callers hold inv.mu.
+func (inv *Inventory) enrich(d *Device) {
+	if inv.enricher == nil || d.IP == "" {
+		return
+	}
+	name, role, tenant := inv.enricher.NameForIP(d.IP)
+	if d.Name == "" {
+		d.Name = name
+	}
+	d.Role, d.Tenant = role, tenant
+}
+
+// MergeLease merges a DHCP lease (e.g. from AdGuard Home's built-in
+// server) into the inventory. The lease hostname fills Name only when
+// the user hasn't assigned one — leases name devices ARP sweeps can
+// only see as bare MACs.
+func (inv *Inventory) MergeLease(mac, ip, hostname string) {
+	mac = strings.ToLower(mac)
+	if mac == "" || ip == "" {
+		return
+	}
+	now := time.Now().UnixMilli()
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	d, ok := inv.devices[mac]
+	if !ok {
+		d = &Device{
+			ID:        mac,
+			MAC:       mac,
+			FirstSeen: now,
+			Vendor:    inv.vendorFor(mac),
+		}
+		inv.devices[mac] = d
+		log.Printf("inventory: new device %s (%s) via DHCP lease", ip, mac)
+		inv.bus.Publish("inventory", "device_new", ip, fmt.Sprintf("new device %s (%s) via DHCP lease", ip, mac))
+	}
+	d.IP = ip
+	if d.Name == "" && hostname != "" {
+		d.Name = hostname
+	}
+	d.LastSeen = now
+	inv.dirty = true
+}
+
+// MergeService attaches a discovered service (and, when the device has
+// no name yet, the advertised instance name) to the device holding ip.
+// Unknown IPs are ignored — the ARP sweep finds the device first.
+func (inv *Inventory) MergeService(ip, service, name string) {
+	if ip == "" {
+		return
+	}
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	for _, d := range inv.devices {
+		if !d.hasIP(ip) {
+			continue
+		}
+		if name != "" && d.Name == "" {
+			d.Name = name
+			inv.dirty = true
+		}
+		if service != "" && !slices.Contains(d.Services, service) {
+			d.Services = append(d.Services, service)
+			sort.Strings(d.Services)
+			inv.dirty = true
+		}
+		return
+	}
+}
+
+// ---------- internal ----------
+
+// sweep probes every host in the configured IPv4 LOCAL_NETS (a UDP send
+// forces kernel ARP resolution) and then harvests the neighbour table.
+func (inv *Inventory) sweep() {
+	for _, n := range inv.localNets {
+		if n.IP.To4() == nil {
+			continue // a /64 can't be probed host-by-host; harvestND covers IPv6
+		}
+		ones, bits := n.Mask.Size()
+		if bits-ones > 10 {
+			log.Printf("inventory: skipping sweep of %s (too large)", n)
+			continue
+		}
+		count := 0
+		for ip := n.IP.Mask(n.Mask); n.Contains(ip) && count < maxSweepHosts; incIP(ip) {
+			count++
+			// Fire-and-forget: the point is the ARP request, not the datagram
+			conn, err := net.DialTimeout("udp4", net.JoinHostPort(ip.String(), "9"), 100*time.Millisecond)
+			if err != nil {
+				continue
+			}
+			conn.Write([]byte{})
+			conn.Close()
+		}
+	}
+	// Give the kernel a moment to finish resolution
+	time.Sleep(2 * time.Second)
+	inv.harvestARP()
+	inv.harvestND()
+}
+
+// harvestARP merges /proc/net/arp entries into the device map.
+func (inv *Inventory) harvestARP() {
+	f, err := os.Open("/proc/net/arp")
+	if err != nil {
+		log.Printf("inventory: read ARP table: %v", err)
+		return
+	}
+	defer f.Close()
+
+	now := time.Now().UnixMilli()
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // header
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		ip, mac := fields[0], strings.ToLower(fields[3])
+		if mac == "00:00:00:00:00:00" {
+			continue // incomplete entry
+		}
+		if !inv.isLocal(ip) {
+			continue
+		}
+		id := mac
+		d, ok := inv.devices[id]
+		if !ok {
+			d = &Device{
+				ID:        id,
+				MAC:       mac,
+				FirstSeen: now,
+				Vendor:    inv.vendorFor(mac),
+			}
+			inv.devices[id] = d
+			inv.dirty = true
+			log.Printf("inventory: new device %s (%s)", ip, mac)
+			inv.bus.Publish("inventory", "device_new", ip, fmt.Sprintf("new device %s (%s)", ip, mac))
+		}
+		if d.IP != ip {
+			d.IP = ip
+			inv.dirty = true
+		}
+		d.LastSeen = now
+		inv.dirty = true
+	}
+}
+
+func (inv *Inventory) isLocal(ipStr string) bool {
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return false
+	}
+	for _, n := range inv.localNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (inv *Inventory) vendorFor(mac string) string {
+	if inv.oui == nil || len(mac) < 8 {
+		return ""
+	}
+	return inv.oui[mac[:8]]
+}
+
+// load reads the persisted inventory, ignoring a missing file.
+func (inv *Inventory) load() {
+	b, err := os.ReadFile(inv.path)
+	if err != nil {
+		return
+	}
+	var devices []Device
+	if err := json.Unmarshal(b, &devices); err != nil {
+		log.Printf("inventory: parse %s: %v", inv.path, err)
+		return
+	}
+	for i := range devices {
+		d := devices[i]
+		inv.devices[d.ID] = &d
+	}
+	log.Printf("inventory: loaded %d device(s) from %s", len(devices), inv.path)
+}
+
+// save persists the inventory when it changed since the last save.
+func (inv *Inventory) save() {
+	inv.mu.Lock()
+	if !inv.dirty {
+		inv.mu.Unlock()
+		return
+	}
+	devices := make([]Device, 0, len(inv.devices))
+	for _, d := range inv.devices {
+		devices = append(devices, *d)
+	}
+	inv.dirty = false
+	inv.mu.Unlock()
+
+	sort.Slice(devices, func(i, j int) bool { return devices[i].ID < devices[j].ID })
+	b, err := json.MarshalIndent(devices, "", "  ")
+	if err != nil {
+		return
+	}
+	tmp := inv.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		log.Printf("inventory: write %s: %v", tmp, err)
+		return
+	}
+	if err := os.Rename(tmp, inv.path); err != nil {
+		log.Printf("inventory: rename %s: %v", tmp, err)
+	}
+}
+
+// loadOUI parses the IEEE OUI database format.
+func loadOUI(path string) map[string]string {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Printf("inventory: open OUI file: %v", err)
+		return nil
+	}
+	defer f.Close()
+
+	out := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.Contains(line, "(hex)") {
+			continue
+		}
+		parts := strings.SplitN(line, "(hex)", 2)
+		prefix := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(parts[0]), "-", ":"))
+		vendor := strings.TrimSpace(parts[1])
+		if len(prefix) == 8 && vendor != "" {
+			out[prefix] = vendor
+		}
+	}
+	log.Printf("inventory: loaded %d OUI vendor prefixes", len(out))
+	return out
+}
+
+// incIP steps an IPv4 address in place.
+func incIP(ip net.IP) {
+	for i := len(ip) - 1; i >= 0; i-- {
+		ip[i]++
+		if ip[i] != 0 {
+			break
+		}
+	}
+}
+
+// String returns a debug string.
+func (inv *Inventory) String() string {
+	inv.mu.RLock()
+	defer inv.mu.RUnlock()
+	return fmt.Sprintf("Inventory[%d devices]", len(inv.devices))
+}
@@ -0,0 +1,115 @@
+package inventory
+
+import (
+	"encoding/binary"
+	"log"
+	"net"
+	"sort"
+	"syscall"
+	"time"
+)
+
+// rtnetlink neighbour attributes and states (linux/neighbour.h).
+const (
+	ndaDst    = 1
+	ndaLladdr = 2
+
+	// Any state except INCOMPLETE, FAILED and NOARP counts as a live
+	// neighbour: REACHABLE | STALE | DELAY | PROBE | PERMANENT.
+	nudValid = 0x02 | 0x04 | 0x08 | 0x10 | 0x80
+)
+
+// harvestND dumps the kernel's IPv6 neighbour table over rtnetlink and
+// groups each MAC's global addresses — including rotating RFC 4941 privacy
+// temporaries — under the device identity the ARP sweep established, so
+// per-device views don't fragment one laptop into ten address rows.
+func (inv *Inventory) harvestND() {
+	tab, err := syscall.NetlinkRIB(syscall.RTM_GETNEIGH, syscall.AF_INET6)
+	if err != nil {
+		log.Printf("inventory: dump IPv6 neighbours: %v", err)
+		return
+	}
+	msgs, err := syscall.ParseNetlinkMessage(tab)
+	if err != nil {
+		log.Printf("inventory: parse IPv6 neighbours: %v", err)
+		return
+	}
+
+	byMAC := make(map[string][]string)
+	for _, m := range msgs {
+		if m.Header.Type != syscall.RTM_NEWNEIGH {
+			continue
+		}
+		// struct ndmsg: family, pad, pad2, ifindex(4), state(2), flags, type
+		if len(m.Data) < 12 {
+			continue
+		}
+		if binary.NativeEndian.Uint16(m.Data[8:10])&nudValid == 0 {
+			continue
+		}
+		var ip net.IP
+		var mac string
+		for b := m.Data[12:]; len(b) >= 4; {
+			alen := int(binary.NativeEndian.Uint16(b[0:2]))
+			atyp := binary.NativeEndian.Uint16(b[2:4])
+			if alen < 4 || alen > len(b) {
+				break
+			}
+			switch val := b[4:alen]; atyp {
+			case ndaDst:
+				ip = net.IP(append([]byte(nil), val...))
+			case ndaLladdr:
+				if len(val) == 6 {
+					mac = net.HardwareAddr(val).String()
+				}
+			}
+			b = b[(alen+3)&^3:]
+		}
+		if ip == nil || mac == "" || ip.IsLinkLocalUnicast() {
+			continue
+		}
+		if !inv.isLocal(ip.String()) {
+			continue
+		}
+		byMAC[mac] = append(byMAC[mac], ip.String())
+	}
+
+	now := time.Now().UnixMilli()
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	for mac, ips := range byMAC {
+		d, ok := inv.devices[mac]
+		if !ok {
+			// IPv6-only neighbour the ARP sweep never saw
+			d = &Device{
+				ID:        mac,
+				MAC:       mac,
+				FirstSeen: now,
+				Vendor:    inv.vendorFor(mac),
+			}
+			inv.devices[mac] = d
+			inv.dirty = true
+			log.Printf("inventory: new device %s (%s)", ips[0], mac)
+		}
+		sort.Strings(ips)
+		if !equalStrings(d.IPv6, ips) {
+			// Replace rather than merge: expired temporaries age out of
+			// the kernel table and should age out of the device too.
+			d.IPv6 = ips
+			inv.dirty = true
+		}
+		d.LastSeen = now
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package inventory
+
+// The IPv6 neighbour table comes from rtnetlink, a Linux kernel
+// interface; elsewhere devices keep whatever addresses the ARP sweep
+// and capture attribution find.
+func (inv *Inventory) harvestND() {}
@@ -0,0 +1,44 @@
+package inventory
+
+import (
+	"fmt"
+	"net"
+)
+
+// Wake sends a Wake-on-LAN magic packet (UDP broadcast, port 9) for the
+// device with the given inventory ID. The device must have a known MAC.
+func (inv *Inventory) Wake(id string) error {
+	inv.mu.RLock()
+	d, ok := inv.devices[id]
+	inv.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown device %q", id)
+	}
+	if d.MAC == "" {
+		return fmt.Errorf("device %q has no known MAC", id)
+	}
+
+	hw, err := net.ParseMAC(d.MAC)
+	if err != nil {
+		return fmt.Errorf("parse MAC %q: %w", d.MAC, err)
+	}
+
+	// Magic packet: 6 bytes of 0xFF followed by the MAC repeated 16 times
+	payload := make([]byte, 0, 102)
+	for i := 0; i < 6; i++ {
+		payload = append(payload, 0xFF)
+	}
+	for i := 0; i < 16; i++ {
+		payload = append(payload, hw...)
+	}
+
+	conn, err := net.Dial("udp4", "255.255.255.255:9")
+	if err != nil {
+		return fmt.Errorf("open broadcast socket: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write(payload); err != nil {
+		return fmt.Errorf("send magic packet: %w", err)
+	}
+	return nil
+}
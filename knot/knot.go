@@ -0,0 +1,178 @@
+// Package knot polls Knot Resolver's built-in HTTP stats endpoint (the
+// http/webmgmt module's /stats) and reshapes its counters into the
+// shared dns.Summary. A plain recursive resolver does no blocking, so
+// the blocked fields stay zero; cache hit rate and rcode counts fill
+// the resolver-specific fields instead. Knot's counters are cumulative
+// since resolver start, and so are the totals reported here.
+package knot
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"bandwidth-monitor/dns"
+	"bandwidth-monitor/httpclient"
+)
+
+// Client polls a Knot Resolver stats endpoint.
+type Client struct {
+	baseURL    string
+	interval   time.Duration
+	httpClient *http.Client
+
+	mu    sync.RWMutex
+	stats map[string]float64
+
+	stopCh chan struct{}
+}
+
+// New creates a Knot Resolver stats client. baseURL is the management
+// HTTP endpoint, e.g. http://127.0.0.1:8453.
+func New(baseURL string, pollInterval time.Duration) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		interval:   pollInterval,
+		httpClient: httpclient.New(15 * time.Second),
+		stopCh:     make(chan struct{}),
+	}
+}
+
+// Run starts the polling loop. Call in a goroutine.
+func (c *Client) Run() {
+	c.poll()
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.poll()
+		case <-c.stopCh:
+			return
+		}
+	}
+}
+
+// Stop terminates the polling loop.
+func (c *Client) Stop() {
+	select {
+	case <-c.stopCh:
+	default:
+		close(c.stopCh)
+	}
+}
+
+func (c *Client) poll() {
+	resp, err := c.httpClient.Get(c.baseURL + "/stats")
+	if err != nil {
+		log.Printf("knot: fetch /stats: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("knot: /stats returned %d", resp.StatusCode)
+		return
+	}
+
+	// Values are numeric, but decode loosely so a future non-numeric
+	// metric doesn't break the whole poll
+	var raw map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		log.Printf("knot: decode /stats: %v", err)
+		return
+	}
+	m := make(map[string]float64, len(raw))
+	for k, v := range raw {
+		if f, ok := v.(float64); ok {
+			m[k] = f
+		}
+	}
+
+	c.mu.Lock()
+	c.stats = m
+	c.mu.Unlock()
+}
+
+// latencyBuckets maps Knot's answer-time histogram to bucket midpoints
+// (milliseconds) for the weighted-mean latency estimate.
+var latencyBuckets = []struct {
+	key string
+	ms  float64
+}{
+	{"answer.1ms", 0.5},
+	{"answer.10ms", 5},
+	{"answer.50ms", 30},
+	{"answer.100ms", 75},
+	{"answer.250ms", 175},
+	{"answer.500ms", 375},
+	{"answer.1000ms", 750},
+	{"answer.1500ms", 1250},
+	{"answer.slow", 2000},
+}
+
+// rcodeMetrics maps Knot's per-rcode answer counters to rcode names.
+var rcodeMetrics = map[string]string{
+	"answer.noerror":  "NOERROR",
+	"answer.nodata":   "NODATA",
+	"answer.nxdomain": "NXDOMAIN",
+	"answer.servfail": "SERVFAIL",
+}
+
+// GetSummary returns a frontend-friendly summary, or nil if no data yet.
+func (c *Client) GetSummary() *dns.Summary {
+	c.mu.RLock()
+	m := c.stats
+	c.mu.RUnlock()
+	if m == nil {
+		return nil
+	}
+
+	total := m["answer.total"]
+	hitPct := 0.0
+	if total > 0 {
+		hitPct = m["answer.cached"] / total * 100
+	}
+
+	rcodes := make(map[string]int)
+	for metric, name := range rcodeMetrics {
+		if v, ok := m[metric]; ok && v > 0 {
+			rcodes[name] = int(v)
+		}
+	}
+
+	// Knot only exposes latency as histogram buckets; a midpoint-weighted
+	// mean is close enough for a dashboard
+	var weighted, counted float64
+	for _, b := range latencyBuckets {
+		weighted += m[b.key] * b.ms
+		counted += m[b.key]
+	}
+	avgMs := 0.0
+	if counted > 0 {
+		avgMs = weighted / counted
+	}
+
+	return &dns.Summary{
+		ProviderName:    "Knot Resolver",
+		TotalQueries:    int(total),
+		AvgLatencyMs:    avgMs,
+		CacheHitPercent: hitPct,
+		Rcodes:          rcodes,
+	}
+}
+
+// Available returns true if the client has fetched data at least once.
+func (c *Client) Available() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.stats != nil
+}
+
+// String returns a debug string.
+func (c *Client) String() string {
+	return fmt.Sprintf("Knot[%s]", c.baseURL)
+}
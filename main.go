@@ -1,9 +1,9 @@
 package main
 
 import (
-	"embed"
+	"crypto/tls"
+	"flag"
 	"fmt"
-	"io/fs"
 	"log"
 	"net"
 	"net/http"
@@ -14,18 +14,29 @@ import (
 	"syscall"
 	"time"
 
-	"bandwidth-monitor/adguard"
+	"bandwidth-monitor/capture"
+	"bandwidth-monitor/cluster"
 	"bandwidth-monitor/collector"
-	"bandwidth-monitor/dns"
+	"bandwidth-monitor/config"
+	"bandwidth-monitor/events"
+	"bandwidth-monitor/flowexport"
+	"bandwidth-monitor/flowlog"
 	"bandwidth-monitor/geoip"
 	"bandwidth-monitor/handler"
-	"bandwidth-monitor/nextdns"
+	"bandwidth-monitor/mtu"
+	"bandwidth-monitor/nftacct"
+	"bandwidth-monitor/persist"
+	"bandwidth-monitor/privacy"
+	"bandwidth-monitor/procmon"
+	"bandwidth-monitor/quota"
+	"bandwidth-monitor/schedule"
+	"bandwidth-monitor/systemd"
 	"bandwidth-monitor/talkers"
-	"bandwidth-monitor/unifi"
-)
+	"bandwidth-monitor/version"
+	"bandwidth-monitor/wan"
 
-//go:embed static/*
-var staticFiles embed.FS
+	"golang.org/x/crypto/acme/autocert"
+)
 
 // env returns the value of the environment variable named by key,
 // or fallback if the variable is empty/unset.
@@ -37,6 +48,31 @@ func env(key, fallback string) string {
 }
 
 func main() {
+	configPath := flag.String("config", "", "KEY=VALUE config file (same format as env.example); environment variables override file values")
+	flag.Parse()
+
+	log.Printf("Bandwidth Monitor %s", version.String())
+
+	if *configPath != "" {
+		if err := config.Load(*configPath); err != nil {
+			log.Fatalf("config: %v", err)
+		}
+		log.Printf("Config loaded from %s", *configPath)
+	}
+
+	// Align daily rollovers, report schedules and bucket labeling with the
+	// user's timezone rather than wherever the server happens to run.
+	// TIMEZONE outranks the system TZ; both use IANA names.
+	if tz := env("TIMEZONE", ""); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			log.Printf("TIMEZONE: invalid zone %q: %v", tz, err)
+		} else {
+			time.Local = loc
+			log.Printf("Timezone: %s", tz)
+		}
+	}
+
 	listenAddr := env("LISTEN", ":8080")
 	captureDevice := env("DEVICE", "")
 	promiscuous := env("PROMISCUOUS", "true")
@@ -95,15 +131,6 @@ func main() {
 
 	geoCountry := env("GEO_COUNTRY", "GeoLite2-Country.mmdb")
 	geoASN := env("GEO_ASN", "GeoLite2-ASN.mmdb")
-	adguardURL := env("ADGUARD_URL", "")
-	adguardUser := env("ADGUARD_USER", "")
-	adguardPass := env("ADGUARD_PASS", "")
-	nextdnsProfile := env("NEXTDNS_PROFILE", "")
-	nextdnsAPIKey := env("NEXTDNS_API_KEY", "")
-	unifiURL := env("UNIFI_URL", "")
-	unifiUser := env("UNIFI_USER", "")
-	unifiPass := env("UNIFI_PASS", "")
-	unifiSite := env("UNIFI_SITE", "default")
 
 	geoDB, err := geoip.Open(geoCountry, geoASN)
 	if err != nil {
@@ -116,67 +143,576 @@ func main() {
 		log.Println("GeoIP: no MMDB files found (continuing without geo)")
 	}
 
-	statsCollector := collector.New(captureDevice, promiscuousBool, localNets)
-	go statsCollector.Run()
+	// Privacy mode: anonymize addresses and suppress hostnames before any
+	// traffic is recorded
+	if m := env("PRIVACY_MODE", ""); m != "" {
+		if err := privacy.Configure(m, os.Getenv("PRIVACY_SUBSYSTEMS")); err != nil {
+			log.Printf("PRIVACY_MODE: %v", err)
+		} else {
+			log.Printf("Privacy mode: %s", m)
+		}
+	}
 
+	// Internal event bus: subsystems publish interface flaps, new
+	// devices and capture errors; retained for /api/events and streamed
+	// at /api/events/ws
+	var eventsRetention time.Duration
+	if v := os.Getenv("EVENTS_RETENTION"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Printf("EVENTS_RETENTION: invalid duration %q: %v", v, err)
+		} else {
+			eventsRetention = d
+		}
+	}
+	eventBus := events.New(eventsRetention)
+
+	statsCollector := collector.New(captureDevice, promiscuousBool, localNets)
+	statsCollector.SetEventBus(eventBus)
+	// Friendly interface labels: "iface=label" pairs, same shape as
+	// HOST_OVERRIDES. SNMP ports get theirs from ifAlias instead.
+	if raw := os.Getenv("IFACE_NAMES"); raw != "" {
+		for _, pair := range strings.Split(raw, ",") {
+			name, label, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok {
+				log.Printf("IFACE_NAMES: skipping malformed entry %q", pair)
+				continue
+			}
+			if name == captureDevice {
+				statsCollector.SetDisplayName(strings.TrimSpace(label))
+			}
+		}
+	}
 	talkerTracker := talkers.New(captureDevice, promiscuousBool, localNets, geoDB)
+	talkerTracker.SetEventBus(eventBus)
+
+	// Capture tuning: snaplen and a BPF filter applied to both capture
+	// paths, e.g. "not port 873" to exclude backups or "vlan 30" to
+	// scope capture to one segment
+	var capSnapLen int32
+	if v := os.Getenv("CAPTURE_SNAPLEN"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			log.Printf("CAPTURE_SNAPLEN: invalid value %q", v)
+		} else {
+			capSnapLen = int32(n)
+		}
+	}
+	if v := os.Getenv("CAPTURE_BACKEND"); v != "" {
+		if err := talkerTracker.SetCaptureBackend(v); err != nil {
+			log.Printf("CAPTURE_BACKEND: %v", err)
+		} else {
+			log.Printf("Capture backend: %s", v)
+		}
+	}
+	capBPF := os.Getenv("CAPTURE_BPF")
+	if capSnapLen > 0 || capBPF != "" {
+		statsCollector.SetCaptureOptions(capSnapLen, capBPF)
+		talkerTracker.SetCaptureOptions(capSnapLen, capBPF)
+		log.Printf("Capture options: snaplen=%s filter=%q",
+			env("CAPTURE_SNAPLEN", "default"), env("CAPTURE_BPF", "ip or ip6"))
+	}
+
+	// History sizing: defaults are 1m talker buckets and 24h retention
+	// for both talkers and interface samples; a box with RAM to spare
+	// can keep a week, a small router an hour
+	if v := os.Getenv("HISTORY_RETENTION"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Printf("HISTORY_RETENTION: invalid duration %q: %v", v, err)
+		} else {
+			statsCollector.SetHistoryRetention(d)
+			log.Printf("Interface history retention: %s", d)
+		}
+	}
+	var talkerBucket, talkerRetention time.Duration
+	if v := os.Getenv("TALKERS_BUCKET"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Printf("TALKERS_BUCKET: invalid duration %q: %v", v, err)
+		} else {
+			talkerBucket = d
+		}
+	}
+	if v := os.Getenv("TALKERS_RETENTION"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Printf("TALKERS_RETENTION: invalid duration %q: %v", v, err)
+		} else {
+			talkerRetention = d
+		}
+	}
+	if talkerBucket > 0 || talkerRetention > 0 {
+		talkerTracker.SetRetention(talkerBucket, talkerRetention)
+		log.Printf("Talker accounting: bucket %s, retention %s",
+			env("TALKERS_BUCKET", "1m"), env("TALKERS_RETENTION", "24h"))
+	}
+
+	// Persistent flow history: every finished talker bucket is appended
+	// to a JSONL audit trail with time-based retention
+	var flowLog *flowlog.Logger
+	if p := os.Getenv("FLOW_LOG"); p != "" {
+		retention := 7 * 24 * time.Hour
+		if v := os.Getenv("FLOW_RETENTION"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				log.Printf("FLOW_RETENTION: invalid duration %q: %v", v, err)
+			} else {
+				retention = d
+			}
+		}
+		flowLog = flowlog.New(p, retention)
+		go flowLog.Run()
+		talkerTracker.SetFlowLog(flowLog)
+		log.Printf("Flow history enabled: %s (retention %s)", p, retention)
+	}
+
+	// NetFlow v9 export: finished talker buckets go out as flow records
+	// so existing pipelines (ElastiFlow, nfcapd) can ingest them
+	var flowExporter *flowexport.Exporter
+	if addr := os.Getenv("FLOW_EXPORT_ADDR"); addr != "" {
+		ex, err := flowexport.New(addr)
+		if err != nil {
+			log.Printf("FLOW_EXPORT_ADDR: %v", err)
+		} else {
+			flowExporter = ex
+			talkerTracker.SetFlowExporter(ex)
+			log.Printf("Flow export enabled: NetFlow v9 to %s", addr)
+		}
+	}
+
+	// First/last-seen retention: how long a remote host's contact record
+	// survives without traffic (drives the "new destination" badge)
+	if v := os.Getenv("SEEN_RETENTION"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Printf("SEEN_RETENTION: invalid duration %q: %v", v, err)
+		} else {
+			talkerTracker.SetSeenRetention(d)
+		}
+	}
+
+	// VPN kill-switch verification: devices/subnets that must route via
+	// the tunnel are flagged (and alertable via vpn_leak) when their
+	// traffic shows up in cleartext on the raw WAN interface
+	if raw := os.Getenv("VPN_PROTECTED_NETS"); raw != "" {
+		wan := os.Getenv("VPN_WAN_DEVICE")
+		if wan == "" {
+			log.Printf("VPN_PROTECTED_NETS set without VPN_WAN_DEVICE, ignoring")
+		} else {
+			var nets []*net.IPNet
+			for _, cidr := range strings.Split(raw, ",") {
+				cidr = strings.TrimSpace(cidr)
+				if cidr == "" {
+					continue
+				}
+				_, ipnet, err := net.ParseCIDR(cidr)
+				if err != nil {
+					log.Printf("VPN_PROTECTED_NETS: invalid CIDR %q: %v", cidr, err)
+					continue
+				}
+				nets = append(nets, ipnet)
+			}
+			if len(nets) > 0 {
+				talkerTracker.ConfigureLeakCheck(wan, nets)
+				log.Printf("VPN kill-switch verification: %d protected network(s), WAN %s", len(nets), wan)
+			}
+		}
+	}
+
+	// State snapshots: periodically persist talker buckets and interface
+	// history so a restart doesn't wipe the 24-hour view
+	var stateStore *persist.Store
+	if p := os.Getenv("PERSIST_FILE"); p != "" {
+		interval := 5 * time.Minute
+		if v := os.Getenv("PERSIST_INTERVAL"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				log.Printf("PERSIST_INTERVAL: invalid duration %q: %v", v, err)
+			} else {
+				interval = d
+			}
+		}
+		stateStore = persist.New(p, interval)
+		stateStore.Attach("collector", statsCollector)
+		stateStore.Attach("talkers", talkerTracker)
+		stateStore.Attach("seen", talkerTracker.SeenStore())
+		stateStore.Attach("events", eventBus)
+		go stateStore.Run()
+		log.Printf("State persistence enabled: %s (snapshot every %s)", p, interval)
+	}
+
+	// LAN-to-LAN matrix: account east-west traffic between internal
+	// subnets instead of discarding it (needs a mirror that sees it)
+	if ok, _ := strconv.ParseBool(env("LAN_MATRIX", "false")); ok {
+		talkerTracker.EnableLANMatrix()
+		log.Printf("LAN-to-LAN traffic matrix enabled")
+	}
+
+	// Per-client attribution: keep the (local device, remote host)
+	// dimension so /api/talkers/clients can drill into who talked where
+	if ok, _ := strconv.ParseBool(env("TALKERS_CLIENTS", "false")); ok {
+		talkerTracker.EnableClientTracking()
+		log.Printf("Per-client talker attribution enabled")
+	}
+
+	// DNS sniffing: learn IP→domain from captured DNS responses so talkers
+	// are labelled with the name clients actually queried
+	if ok, _ := strconv.ParseBool(env("DNS_SNIFF", "false")); ok {
+		talkerTracker.EnableDNSSniff()
+		log.Printf("DNS-answer sniffing enabled")
+	}
+
+	// ARP watching: learn IP→MAC bindings from captured ARP traffic and
+	// flag conflicts and gratuitous-ARP storms as security events
+	if ok, _ := strconv.ParseBool(env("ARP_WATCH", "false")); ok {
+		talkerTracker.EnableARPWatch()
+		log.Printf("ARP anomaly detection enabled")
+	}
+
+	// SNI inspection: read server names out of TLS/QUIC ClientHellos so
+	// cloud IPs with useless PTR records still get a meaningful label
+	if ok, _ := strconv.ParseBool(env("SNI_INSPECT", "false")); ok {
+		talkerTracker.EnableSNIInspection()
+		log.Printf("TLS/QUIC SNI inspection enabled")
+	}
+
+	// Reverse-DNS override: send PTR lookups to a specific server (e.g. a
+	// local AdGuard/Pi-hole) instead of the system resolver
+	if addr := os.Getenv("RDNS_RESOLVER"); addr != "" {
+		talkerTracker.SetResolver(addr)
+		log.Printf("Reverse DNS lookups via %s", addr)
+	}
+
+	// Enrichment budget: cap reverse-DNS and uncached geo lookups per
+	// second so a scan surfacing thousands of fresh IPs can't saturate
+	// the resolver; over-budget stats are served with pending set
+	if v := os.Getenv("ENRICH_BUDGET"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			talkerTracker.SetEnrichBudget(n)
+			log.Printf("Enrichment budget: %d lookups/s", n)
+		} else {
+			log.Printf("Invalid ENRICH_BUDGET %q, ignoring", v)
+		}
+	}
+
+	// Enrichment pipeline: which stages decorate served talker stats, in
+	// order (e.g. drop "names" to avoid reverse-DNS lookups entirely)
+	if spec := os.Getenv("ENRICHERS"); spec != "" {
+		if err := talkerTracker.SetEnrichers(spec); err != nil {
+			log.Printf("ENRICHERS: %v", err)
+		} else {
+			log.Printf("Talker enrichment pipeline: %s", spec)
+		}
+	}
+
+	// Scheduled capture pauses, e.g. CAPTURE_PAUSE=01:00-03:00 for a
+	// nightly backup window that would otherwise dominate every statistic
+	if spec := os.Getenv("CAPTURE_PAUSE"); spec != "" {
+		windows, err := schedule.Parse(spec)
+		if err != nil {
+			log.Printf("CAPTURE_PAUSE: %v", err)
+		} else {
+			statsCollector.SetPauseWindows(windows)
+			talkerTracker.SetPauseWindows(windows)
+			log.Printf("Capture pause windows: %s", spec)
+		}
+	}
+
+	// Monthly data-cap tracking: accumulate per-interface cycle totals
+	// against configured caps, with 80/90/100% alerts
+	var quotaTracker *quota.Tracker
+	if spec := os.Getenv("QUOTA_CAPS"); spec != "" {
+		caps, err := quota.ParseCaps(spec)
+		if err != nil {
+			log.Printf("QUOTA_CAPS: %v", err)
+		} else {
+			cycleDay := 1
+			if v := os.Getenv("QUOTA_CYCLE_DAY"); v != "" {
+				n, err := strconv.Atoi(v)
+				if err != nil || n < 1 || n > 28 {
+					log.Printf("QUOTA_CYCLE_DAY: invalid day %q (want 1-28)", v)
+				} else {
+					cycleDay = n
+				}
+			}
+			quotaTracker = quota.New(env("QUOTA_FILE", "quota.json"), caps, cycleDay, statsCollector)
+			quotaTracker.SetEventBus(eventBus)
+			go quotaTracker.Run()
+			log.Printf("Quota tracking enabled for %d interface(s), cycle day %d", len(caps), cycleDay)
+		}
+	}
+
+	// Multi-WAN awareness: watch which interface holds the default
+	// route, recording failovers so graphs can be annotated with the
+	// uplink the traffic actually used
+	var wanMonitor *wan.Monitor
+	if ok, _ := strconv.ParseBool(env("WAN_FAILOVER", "false")); ok {
+		wanMonitor = wan.New(0)
+		wanMonitor.SetEventBus(eventBus)
+		if stateStore != nil {
+			stateStore.Attach("wan", wanMonitor)
+		}
+		go wanMonitor.Run()
+		log.Printf("Multi-WAN failover tracking enabled")
+	}
+
+	go statsCollector.Run()
 	go talkerTracker.Run()
 
-	// DNS provider: AdGuard Home or NextDNS (mutually exclusive, AdGuard takes priority)
-	var dnsProvider dns.Provider
-	if adguardURL != "" {
-		ac := adguard.New(adguardURL, adguardUser, adguardPass, 10*time.Second)
-		go ac.Run()
-		dnsProvider = ac
-		log.Printf("DNS integration: AdGuard Home (%s)", adguardURL)
-	} else if nextdnsProfile != "" && nextdnsAPIKey != "" {
-		nc := nextdns.New(nextdnsProfile, nextdnsAPIKey, 30*time.Second)
-		go nc.Run()
-		dnsProvider = nc
-		log.Printf("DNS integration: NextDNS (profile %s)", nextdnsProfile)
+	// Optional authentication: basic auth (AUTH_USER/AUTH_PASS) and/or
+	// bearer tokens (API_TOKENS) in front of /api/* and the WebSockets;
+	// AUTH_STATIC=true puts the dashboard assets behind the same gate
+	authStatic, _ := strconv.ParseBool(env("AUTH_STATIC", "false"))
+	if handler.ConfigureAuth(os.Getenv("AUTH_USER"), os.Getenv("AUTH_PASS"), os.Getenv("API_TOKENS"), authStatic) {
+		scope := "/api/*"
+		if authStatic {
+			scope = "all routes"
+		}
+		log.Printf("Authentication enabled (%s)", scope)
+	}
+
+	// Node/site label attached to every API payload and response header
+	nodeLabel := env("NODE_LABEL", "")
+	if nodeLabel != "" {
+		handler.SetNodeLabel(nodeLabel)
+		log.Printf("Node label: %s", nodeLabel)
 	}
 
-	var unifiClient *unifi.Client
-	if unifiURL != "" {
-		unifiClient = unifi.New(unifiURL, unifiUser, unifiPass, unifiSite, 15*time.Second)
-		go unifiClient.Run()
-		log.Printf("UniFi controller integration enabled: %s", unifiURL)
+	// Global top-N cap for talker lists and breakdowns; individual
+	// requests can still override it with ?limit=
+	if v := os.Getenv("TOP_LIMIT"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			log.Printf("TOP_LIMIT: invalid value %q", v)
+		} else {
+			handler.SetTopLimit(n)
+			log.Printf("Top-N limit: %d", n)
+		}
 	}
 
+	// Core API: capture/collection endpoints present in every profile
 	mux := http.NewServeMux()
 	mux.HandleFunc("/api/interfaces", handler.InterfaceStats(statsCollector))
 	mux.HandleFunc("/api/interfaces/history", handler.InterfaceHistory(statsCollector))
-	mux.HandleFunc("/api/talkers/bandwidth", handler.TopTalkersBandwidth(talkerTracker))
-	mux.HandleFunc("/api/talkers/volume", handler.TopTalkersVolume(talkerTracker))
-	mux.HandleFunc("/api/dns", handler.DNSSummary(dnsProvider))
-	mux.HandleFunc("/api/wifi", handler.WiFiSummary(unifiClient))
-	mux.HandleFunc("/api/summary", handler.MenuBarSummary(statsCollector, talkerTracker, dnsProvider, unifiClient))
-	mux.HandleFunc("/api/ws", handler.WebSocket(statsCollector, talkerTracker, dnsProvider, unifiClient))
-	staticSub, err := fs.Sub(staticFiles, "static")
-	if err != nil {
-		log.Fatalf("Failed to create sub filesystem: %v", err)
+	mux.HandleFunc("/api/talkers/bandwidth", handler.Cached(handler.CacheShort, handler.TopTalkersBandwidth(talkerTracker)))
+	mux.HandleFunc("/api/talkers/volume", handler.Cached(handler.CacheShort, handler.TopTalkersVolume(talkerTracker)))
+	mux.HandleFunc("/api/talkers/dscp", handler.Cached(handler.CacheShort, handler.DSCPBreakdown(talkerTracker)))
+	mux.HandleFunc("/api/talkers/hops", handler.Cached(handler.CacheShort, handler.HopDistribution(talkerTracker)))
+	mux.HandleFunc("/api/talkers/protocols/series", handler.Cached(handler.CacheShort, handler.ProtocolSeries(talkerTracker)))
+	mux.HandleFunc("/api/talkers/ipversions/series", handler.Cached(handler.CacheShort, handler.IPVersionSeries(talkerTracker)))
+	mux.HandleFunc("/api/flows/history", handler.Cached(handler.CacheLong, handler.FlowHistory(flowLog)))
+	mux.HandleFunc("/api/talkers/matrix", handler.Cached(handler.CacheShort, handler.LANMatrix(talkerTracker)))
+	mux.HandleFunc("/api/talkers/clients", handler.Cached(handler.CacheShort, handler.TalkerClients(talkerTracker)))
+	mux.HandleFunc("/api/talkers/icmp", handler.Cached(handler.CacheShort, handler.ICMPSources(talkerTracker)))
+	mux.HandleFunc("/api/talkers/dualstack", handler.Cached(handler.CacheLong, handler.DualStack(talkerTracker)))
+	mux.HandleFunc("/api/vpn/leaks", handler.VPNLeaks(talkerTracker))
+	mux.HandleFunc("/api/talkers/arp", handler.ARPAnomalies(talkerTracker))
+	mux.HandleFunc("/api/dns/ipmap", handler.DNSIPMap(talkerTracker))
+	mux.HandleFunc("/api/quota", handler.Quota(quotaTracker))
+	mux.HandleFunc("/api/wan", handler.WANStatus(wanMonitor))
+	mux.HandleFunc("/api/capture/status", handler.CaptureStatus(talkerTracker))
+	mux.HandleFunc("/api/capture/stats", handler.CaptureStatus(talkerTracker))
+	mux.HandleFunc("/api/capture/control", handler.CaptureControl(talkerTracker, os.Getenv("PCAP_TOKEN")))
+
+	// Build info, with optional daily update check against GitHub releases
+	var updateChecker *version.Checker
+	if ok, _ := strconv.ParseBool(env("UPDATE_CHECK", "false")); ok {
+		updateChecker = version.NewChecker()
+		go updateChecker.Run()
+		log.Printf("Update check enabled (daily)")
+	}
+	mux.HandleFunc("/api/version", handler.VersionInfo(updateChecker))
+	mux.HandleFunc("/api/self", handler.SelfMetrics())
+
+	// Rolling header capture for on-demand pcap downloads. Needs both a
+	// window and a token; the endpoint stays disabled otherwise.
+	var captureRing *capture.Ring
+	if v := os.Getenv("PCAP_RING"); v != "" && captureDevice != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Printf("PCAP_RING: invalid duration %q: %v", v, err)
+		} else {
+			captureRing = capture.New(captureDevice, promiscuousBool, d)
+			go captureRing.Run()
+		}
 	}
-	mux.Handle("/", http.FileServer(http.FS(staticSub)))
+	mux.HandleFunc("/api/capture/ring", handler.PacketRing(captureRing, os.Getenv("PCAP_TOKEN")))
+
+	// On-demand targeted captures share the ring's token
+	captureMgr := capture.NewManager(captureDevice, promiscuousBool)
+	mux.HandleFunc("/api/capture/sessions", handler.CaptureSessions(captureMgr, os.Getenv("PCAP_TOKEN")))
+
+	// Path-MTU problem detection (ICMP frag-needed, low MSS, fragmentation)
+	var mtuDetector *mtu.Detector
+	if ok, _ := strconv.ParseBool(env("MTU_DETECT", "false")); ok && captureDevice != "" {
+		mtuDetector = mtu.New(captureDevice, promiscuousBool)
+		go mtuDetector.Run()
+		log.Printf("MTU problem detection enabled on %s", captureDevice)
+	}
+	mux.HandleFunc("/api/mtu/events", handler.MTUEvents(mtuDetector))
+
+	// Per-process attribution: socket→pid mapping for hosts that
+	// terminate their own traffic (workstation/server, not a mirror)
+	var procMonitor *procmon.Monitor
+	if ok, _ := strconv.ParseBool(env("PROCESS_MONITOR", "false")); ok && captureDevice != "" {
+		procMonitor = procmon.New(captureDevice, localNets)
+		go procMonitor.Run()
+		log.Printf("Per-process attribution enabled on %s", captureDevice)
+	}
+	mux.HandleFunc("/api/processes", handler.Processes(procMonitor))
+
+	// Gateway accounting: per-LAN-device totals from nftables counters,
+	// a capture-free complement (or alternative) to pcap on routers
+	var natCounters *nftacct.Collector
+	if ok, _ := strconv.ParseBool(env("NFT_ACCOUNTING", "false")); ok && len(localNets) > 0 {
+		interval := 10 * time.Second
+		if v := os.Getenv("NFT_INTERVAL"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				log.Printf("NFT_INTERVAL: invalid duration %q: %v", v, err)
+			} else {
+				interval = d
+			}
+		}
+		natCounters = nftacct.New(localNets, interval)
+		go natCounters.Run()
+		log.Printf("nftables accounting enabled (poll every %s)", interval)
+	}
+	mux.HandleFunc("/api/lan/counters", handler.LANCounters(natCounters))
+
+	mux.HandleFunc("/api/events", handler.Events(eventBus))
+	mux.HandleFunc("/api/events/ws", handler.EventStream(eventBus))
+
+	// Everything beyond capture/collection (integrations, WebSocket,
+	// embedded UI) lives behind the build profile; the agent profile
+	// compiles it all out.
+	dnsProvider, stopIntegrations := setupIntegrations(mux, localNets, statsCollector, talkerTracker, eventBus)
+
+	// Agent push exporter: available in every profile
+	var clusterAgent *cluster.Agent
+	if u := env("AGENT_PUSH_URL", ""); u != "" {
+		interval := 10 * time.Second
+		if v := os.Getenv("AGENT_INTERVAL"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				log.Printf("AGENT_INTERVAL: invalid duration %q: %v", v, err)
+			} else {
+				interval = d
+			}
+		}
+		node := env("AGENT_NODE", nodeLabel)
+		if node == "" {
+			node, _ = os.Hostname()
+		}
+		clusterAgent = cluster.NewAgent(u, os.Getenv("AGENT_TOKEN"), node, interval, statsCollector, talkerTracker, dnsProvider)
+		go clusterAgent.Run()
+		log.Printf("Agent mode enabled: pushing to %s as %q every %s", u, node, interval)
+	}
+
+	// systemd supervision: readiness, watchdog heartbeats and shutdown
+	// notification (all no-ops outside systemd)
+	watchdogStop := make(chan struct{})
+	go systemd.RunWatchdog(watchdogStop)
 
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		<-sigCh
 		fmt.Println("\nShutting down...")
+		systemd.Stopping()
+		close(watchdogStop)
+		if updateChecker != nil {
+			updateChecker.Stop()
+		}
 		statsCollector.Stop()
 		talkerTracker.Stop()
-		if dnsProvider != nil {
-			dnsProvider.Stop()
+		if quotaTracker != nil {
+			quotaTracker.Stop()
+		}
+		if wanMonitor != nil {
+			wanMonitor.Stop()
+		}
+		if stateStore != nil {
+			stateStore.Stop()
 		}
-		if unifiClient != nil {
-			unifiClient.Stop()
+		if flowLog != nil {
+			flowLog.Stop()
 		}
+		if flowExporter != nil {
+			flowExporter.Stop()
+		}
+		if captureRing != nil {
+			captureRing.Stop()
+		}
+		captureMgr.StopAll()
+		if mtuDetector != nil {
+			mtuDetector.Stop()
+		}
+		if procMonitor != nil {
+			procMonitor.Stop()
+		}
+		if natCounters != nil {
+			natCounters.Stop()
+		}
+		if clusterAgent != nil {
+			clusterAgent.Stop()
+		}
+		stopIntegrations()
 		os.Exit(0)
 	}()
 
-	log.Printf("Bandwidth Monitor starting on %s", listenAddr)
-	log.Printf("Open http://localhost%s in your browser", listenAddr)
-	if err := http.ListenAndServe(listenAddr, mux); err != nil {
+	// TLS termination: a static certificate pair, or ACME-managed
+	// certificates for a public hostname (TLS-ALPN challenge, so the
+	// listener must be reachable on 443). Without either, plain HTTP as
+	// before — front with a reverse proxy if that matters.
+	var tlsCfg *tls.Config
+	certFile, keyFile := os.Getenv("LISTEN_TLS_CERT"), os.Getenv("LISTEN_TLS_KEY")
+	if hosts := os.Getenv("ACME_HOSTS"); hosts != "" {
+		var allowed []string
+		for _, h := range strings.Split(hosts, ",") {
+			if h = strings.TrimSpace(h); h != "" {
+				allowed = append(allowed, h)
+			}
+		}
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(allowed...),
+			Cache:      autocert.DirCache(env("ACME_CACHE", "acme-cache")),
+		}
+		tlsCfg = m.TLSConfig()
+		log.Printf("TLS: ACME-managed certificates for %s", strings.Join(allowed, ", "))
+	} else if certFile != "" && keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			log.Fatalf("LISTEN_TLS_CERT: %v", err)
+		}
+		tlsCfg = &tls.Config{Certificates: []tls.Certificate{cert}}
+		log.Printf("TLS: certificate %s", certFile)
+	} else if certFile != "" || keyFile != "" {
+		log.Fatalf("LISTEN_TLS_CERT and LISTEN_TLS_KEY must be set together")
+	}
+
+	// Prefer a socket-activated listener when systemd passed one
+	ln := systemd.Listener()
+	if ln == nil {
+		ln, err = net.Listen("tcp", listenAddr)
+		if err != nil {
+			log.Fatalf("Listen on %s: %v", listenAddr, err)
+		}
+	} else {
+		log.Printf("Using systemd socket-activated listener on %s", ln.Addr())
+	}
+	scheme := "http"
+	if tlsCfg != nil {
+		ln = tls.NewListener(ln, tlsCfg)
+		scheme = "https"
+	}
+
+	log.Printf("Bandwidth Monitor starting on %s", ln.Addr())
+	log.Printf("Open %s://localhost%s in your browser", scheme, listenAddr)
+	systemd.Ready()
+	if err := http.Serve(ln, handler.WithNodeLabel(handler.RequireAuth(mux))); err != nil {
 		log.Fatalf("Server failed: %v", err)
 	}
 }
@@ -0,0 +1,326 @@
+// Package modem scrapes DOCSIS cable modem status pages (Arris,
+// Technicolor, Netgear) for downstream/upstream power, SNR and
+// corrected/uncorrectable error counts — invaluable when diagnosing ISP
+// issues alongside throughput.
+package modem
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"bandwidth-monitor/httpclient"
+)
+
+const (
+	historyMaxAge = 24 * time.Hour
+	snrAlertDB    = 30 // log when a downstream channel drops below this SNR
+)
+
+// DownChannel is one downstream DOCSIS channel.
+type DownChannel struct {
+	ID            int     `json:"id"`
+	FreqMHz       float64 `json:"freq_mhz"`
+	PowerDBmV     float64 `json:"power_dbmv"`
+	SNRdB         float64 `json:"snr_db"`
+	Corrected     uint64  `json:"corrected"`
+	Uncorrectable uint64  `json:"uncorrectable"`
+}
+
+// UpChannel is one upstream DOCSIS channel.
+type UpChannel struct {
+	ID        int     `json:"id"`
+	FreqMHz   float64 `json:"freq_mhz"`
+	PowerDBmV float64 `json:"power_dbmv"`
+}
+
+// Status is a full modem snapshot.
+type Status struct {
+	Downstream []DownChannel `json:"downstream"`
+	Upstream   []UpChannel   `json:"upstream"`
+	Timestamp  int64         `json:"timestamp"`
+}
+
+// HistoryPoint is a per-poll aggregate for the 24-hour history ring.
+type HistoryPoint struct {
+	Timestamp     int64   `json:"t"`
+	AvgPowerDBmV  float64 `json:"power"`
+	AvgSNRdB      float64 `json:"snr"`
+	Uncorrectable uint64  `json:"uncorrectable"` // total across channels
+}
+
+// Scraper parses one vendor's status page into a Status.
+type Scraper interface {
+	Name() string
+	// Path is the status page path fetched relative to the modem base URL.
+	Path() string
+	Parse(body string) (*Status, error)
+}
+
+// Client polls a modem status page on a fixed interval.
+type Client struct {
+	baseURL    string
+	scraper    Scraper
+	interval   time.Duration
+	httpClient *http.Client
+
+	mu      sync.RWMutex
+	status  *Status
+	history []HistoryPoint
+
+	stopCh chan struct{}
+}
+
+// New creates a modem scraping client. kind selects the vendor scraper:
+// "arris", "technicolor" or "netgear".
+func New(baseURL, kind string, pollInterval time.Duration) (*Client, error) {
+	var s Scraper
+	switch strings.ToLower(kind) {
+	case "arris":
+		s = arrisScraper{}
+	case "technicolor":
+		s = technicolorScraper{}
+	case "netgear":
+		s = netgearScraper{}
+	default:
+		return nil, fmt.Errorf("modem: unknown modem type %q", kind)
+	}
+	if pollInterval <= 0 {
+		pollInterval = 60 * time.Second
+	}
+	return &Client{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		scraper:  s,
+		interval: pollInterval,
+		// Modems serve their status pages over self-signed HTTPS
+		httpClient: httpclient.NewInsecure(15 * time.Second),
+		stopCh:     make(chan struct{}),
+	}, nil
+}
+
+// Run starts the polling loop. Call in a goroutine.
+func (c *Client) Run() {
+	c.poll()
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.poll()
+		case <-c.stopCh:
+			return
+		}
+	}
+}
+
+// Stop terminates the polling loop.
+func (c *Client) Stop() {
+	select {
+	case <-c.stopCh:
+	default:
+		close(c.stopCh)
+	}
+}
+
+// GetStatus returns the latest snapshot, or nil if no data yet.
+func (c *Client) GetStatus() *Status {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.status
+}
+
+// GetHistory returns the 24-hour aggregate history.
+func (c *Client) GetHistory() []HistoryPoint {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	cp := make([]HistoryPoint, len(c.history))
+	copy(cp, c.history)
+	return cp
+}
+
+// Available returns true if the client has scraped successfully at least once.
+func (c *Client) Available() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.status != nil
+}
+
+// ---------- internal ----------
+
+func (c *Client) poll() {
+	resp, err := c.httpClient.Get(c.baseURL + c.scraper.Path())
+	if err != nil {
+		log.Printf("modem: fetch %s status: %v", c.scraper.Name(), err)
+		return
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		log.Printf("modem: read status: %v", err)
+		return
+	}
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("modem: unexpected status %d", resp.StatusCode)
+		return
+	}
+
+	st, err := c.scraper.Parse(string(body))
+	if err != nil {
+		log.Printf("modem: parse %s status: %v", c.scraper.Name(), err)
+		return
+	}
+	now := time.Now()
+	st.Timestamp = now.UnixMilli()
+
+	hp := HistoryPoint{Timestamp: st.Timestamp}
+	for _, ch := range st.Downstream {
+		hp.AvgPowerDBmV += ch.PowerDBmV
+		hp.AvgSNRdB += ch.SNRdB
+		hp.Uncorrectable += ch.Uncorrectable
+		if ch.SNRdB > 0 && ch.SNRdB < snrAlertDB {
+			log.Printf("modem: downstream channel %d SNR %.1f dB below %d dB", ch.ID, ch.SNRdB, snrAlertDB)
+		}
+	}
+	if n := len(st.Downstream); n > 0 {
+		hp.AvgPowerDBmV /= float64(n)
+		hp.AvgSNRdB /= float64(n)
+	}
+
+	c.mu.Lock()
+	c.status = st
+	c.history = append(c.history, hp)
+	cutoff := now.Add(-historyMaxAge).UnixMilli()
+	idx := 0
+	for idx < len(c.history) && c.history[idx].Timestamp < cutoff {
+		idx++
+	}
+	c.history = c.history[idx:]
+	c.mu.Unlock()
+}
+
+// ---------- vendor scrapers ----------
+
+// cells extracts the text of every <td> in a table row.
+var (
+	rowRe  = regexp.MustCompile(`(?is)<tr[^>]*>(.*?)</tr>`)
+	cellRe = regexp.MustCompile(`(?is)<t[dh][^>]*>(.*?)</t[dh]>`)
+	tagRe  = regexp.MustCompile(`(?s)<[^>]*>`)
+	numRe  = regexp.MustCompile(`-?\d+(?:\.\d+)?`)
+)
+
+func tableRows(body string) [][]string {
+	var rows [][]string
+	for _, m := range rowRe.FindAllStringSubmatch(body, -1) {
+		var cells []string
+		for _, c := range cellRe.FindAllStringSubmatch(m[1], -1) {
+			cells = append(cells, strings.TrimSpace(tagRe.ReplaceAllString(c[1], "")))
+		}
+		if len(cells) > 0 {
+			rows = append(rows, cells)
+		}
+	}
+	return rows
+}
+
+func num(s string) float64 {
+	m := numRe.FindString(s)
+	if m == "" {
+		return 0
+	}
+	f, _ := strconv.ParseFloat(m, 64)
+	return f
+}
+
+// parseChannelRows interprets generic status-page table rows. Downstream
+// rows carry at least frequency, power, SNR and the two error counters;
+// upstream rows carry frequency and power. Vendors differ only in column
+// order, which each scraper maps via the index arguments.
+func parseChannelRows(rows [][]string, down map[string]int, up map[string]int, upMarker string) *Status {
+	st := &Status{}
+	inUpstream := false
+	for _, cells := range rows {
+		joined := strings.ToLower(strings.Join(cells, " "))
+		if upMarker != "" && strings.Contains(joined, upMarker) {
+			inUpstream = true
+		}
+		if !inUpstream && len(cells) > down["uncorr"] && num(cells[down["freq"]]) > 0 {
+			st.Downstream = append(st.Downstream, DownChannel{
+				ID:            int(num(cells[down["id"]])),
+				FreqMHz:       toMHz(num(cells[down["freq"]])),
+				PowerDBmV:     num(cells[down["power"]]),
+				SNRdB:         num(cells[down["snr"]]),
+				Corrected:     uint64(num(cells[down["corr"]])),
+				Uncorrectable: uint64(num(cells[down["uncorr"]])),
+			})
+		} else if inUpstream && len(cells) > up["power"] && num(cells[up["freq"]]) > 0 {
+			st.Upstream = append(st.Upstream, UpChannel{
+				ID:        int(num(cells[up["id"]])),
+				FreqMHz:   toMHz(num(cells[up["freq"]])),
+				PowerDBmV: num(cells[up["power"]]),
+			})
+		}
+	}
+	if len(st.Downstream) == 0 && len(st.Upstream) == 0 {
+		return nil
+	}
+	return st
+}
+
+// toMHz normalizes frequencies reported in Hz to MHz.
+func toMHz(f float64) float64 {
+	if f > 1e6 {
+		return f / 1e6
+	}
+	return f
+}
+
+type arrisScraper struct{}
+
+func (arrisScraper) Name() string { return "arris" }
+func (arrisScraper) Path() string { return "/cgi-bin/status" }
+func (arrisScraper) Parse(body string) (*Status, error) {
+	st := parseChannelRows(tableRows(body),
+		map[string]int{"id": 0, "freq": 4, "power": 5, "snr": 6, "corr": 7, "uncorr": 8},
+		map[string]int{"id": 0, "freq": 6, "power": 7},
+		"upstream bonded channels")
+	if st == nil {
+		return nil, fmt.Errorf("no channel tables found")
+	}
+	return st, nil
+}
+
+type technicolorScraper struct{}
+
+func (technicolorScraper) Name() string { return "technicolor" }
+func (technicolorScraper) Path() string { return "/cgi-bin/dashboard" }
+func (technicolorScraper) Parse(body string) (*Status, error) {
+	st := parseChannelRows(tableRows(body),
+		map[string]int{"id": 0, "freq": 1, "power": 2, "snr": 3, "corr": 5, "uncorr": 6},
+		map[string]int{"id": 0, "freq": 1, "power": 2},
+		"upstream")
+	if st == nil {
+		return nil, fmt.Errorf("no channel tables found")
+	}
+	return st, nil
+}
+
+type netgearScraper struct{}
+
+func (netgearScraper) Name() string { return "netgear" }
+func (netgearScraper) Path() string { return "/DocsisStatus.asp" }
+func (netgearScraper) Parse(body string) (*Status, error) {
+	st := parseChannelRows(tableRows(body),
+		map[string]int{"id": 0, "freq": 4, "power": 5, "snr": 6, "corr": 7, "uncorr": 8},
+		map[string]int{"id": 0, "freq": 5, "power": 6},
+		"upstream bonded channels")
+	if st == nil {
+		return nil, fmt.Errorf("no channel tables found")
+	}
+	return st, nil
+}
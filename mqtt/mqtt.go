@@ -0,0 +1,137 @@
+// Package mqtt publishes the monitor's headline numbers — per-interface
+// rates, VPN state, DNS block percentage, WiFi client count — to an
+// MQTT broker with Home Assistant discovery topics, so the monitor
+// shows up as HA sensors without custom templates. It speaks just
+// enough MQTT 3.1.1 itself (CONNECT, PUBLISH QoS 0): a client library
+// would be overkill for fire-and-forget telemetry.
+package mqtt
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+const dialTimeout = 10 * time.Second
+
+// conn is one broker connection; the publisher owns it and replaces it
+// after any error.
+type conn struct {
+	nc net.Conn
+}
+
+// dialBroker connects and performs the MQTT handshake. keepalive is
+// advertised to the broker; the publisher's regular publishes keep the
+// session alive, so it must comfortably exceed the publish interval.
+func dialBroker(addr, clientID, user, pass string, keepalive time.Duration) (*conn, error) {
+	nc, err := net.DialTimeout("tcp", addr, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &conn{nc: nc}
+	if err := c.connect(clientID, user, pass, keepalive); err != nil {
+		nc.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *conn) connect(clientID, user, pass string, keepalive time.Duration) error {
+	flags := byte(0x02) // clean session
+	if user != "" {
+		flags |= 0x80
+	}
+	if pass != "" {
+		flags |= 0x40
+	}
+	var body []byte
+	body = appendString(body, "MQTT")
+	body = append(body, 4, flags) // protocol level 4 = MQTT 3.1.1
+	secs := int(keepalive.Seconds())
+	body = append(body, byte(secs>>8), byte(secs))
+	body = appendString(body, clientID)
+	if user != "" {
+		body = appendString(body, user)
+	}
+	if pass != "" {
+		body = appendString(body, pass)
+	}
+	if err := c.writePacket(0x10, body); err != nil {
+		return err
+	}
+
+	// CONNACK: fixed header, session-present flag, return code
+	c.nc.SetReadDeadline(time.Now().Add(dialTimeout))
+	ack := make([]byte, 4)
+	if _, err := readFull(c.nc, ack); err != nil {
+		return fmt.Errorf("read CONNACK: %w", err)
+	}
+	if ack[0] != 0x20 || ack[1] != 0x02 {
+		return fmt.Errorf("unexpected CONNACK header %#x %#x", ack[0], ack[1])
+	}
+	if ack[3] != 0 {
+		return fmt.Errorf("connection refused, code %d", ack[3])
+	}
+	return nil
+}
+
+// publish sends one QoS 0 message; retain keeps it on the broker so HA
+// picks up discovery configs published before it subscribed.
+func (c *conn) publish(topic string, payload []byte, retain bool) error {
+	header := byte(0x30)
+	if retain {
+		header |= 0x01
+	}
+	body := appendString(nil, topic)
+	body = append(body, payload...)
+	return c.writePacket(header, body)
+}
+
+func (c *conn) close() {
+	// DISCONNECT is a courtesy; the close is what matters
+	c.writePacket(0xE0, nil)
+	c.nc.Close()
+}
+
+func (c *conn) writePacket(header byte, body []byte) error {
+	pkt := append([]byte{header}, encodeLength(len(body))...)
+	pkt = append(pkt, body...)
+	c.nc.SetWriteDeadline(time.Now().Add(dialTimeout))
+	_, err := c.nc.Write(pkt)
+	return err
+}
+
+// encodeLength encodes the remaining-length varint (7 bits per byte,
+// high bit continues).
+func encodeLength(n int) []byte {
+	var out []byte
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		out = append(out, b)
+		if n == 0 {
+			return out
+		}
+	}
+}
+
+// appendString appends an MQTT length-prefixed UTF-8 string.
+func appendString(dst []byte, s string) []byte {
+	dst = append(dst, byte(len(s)>>8), byte(len(s)))
+	return append(dst, s...)
+}
+
+func readFull(nc net.Conn, buf []byte) (int, error) {
+	read := 0
+	for read < len(buf) {
+		n, err := nc.Read(buf[read:])
+		read += n
+		if err != nil {
+			return read, err
+		}
+	}
+	return read, nil
+}
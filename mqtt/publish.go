@@ -0,0 +1,265 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"bandwidth-monitor/collector"
+	"bandwidth-monitor/dns"
+	"bandwidth-monitor/integration"
+	"bandwidth-monitor/unifi"
+)
+
+const (
+	defaultInterval = 30 * time.Second
+	discoveryPrefix = "homeassistant"
+)
+
+// Publisher pushes a snapshot to the broker on an interval.
+type Publisher struct {
+	broker   string
+	user     string
+	pass     string
+	prefix   string // state topic prefix
+	node     string // NODE_LABEL, distinguishes multi-node setups
+	interval time.Duration
+
+	src     *collector.Collector
+	dnsProv dns.Provider
+	unifi   *unifi.Client
+	breaker *integration.Breaker
+
+	conn       *conn
+	discovered map[string]bool // config topics already published this connection
+
+	stopCh chan struct{}
+}
+
+// New creates the publisher; broker is host:port (":1883" is appended
+// when the port is missing) and prefix roots the state topics.
+func New(broker, user, pass, prefix string, src *collector.Collector, interval time.Duration) *Publisher {
+	if !strings.Contains(broker, ":") {
+		broker += ":1883"
+	}
+	if prefix == "" {
+		prefix = "bandwidth-monitor"
+	}
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	return &Publisher{
+		broker:   broker,
+		user:     user,
+		pass:     pass,
+		prefix:   prefix,
+		interval: interval,
+		src:      src,
+		breaker:  integration.NewBreaker("mqtt"),
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// SetNode attaches the node label distinguishing this instance's
+// topics and unique IDs in multi-node setups. Call before Run.
+func (p *Publisher) SetNode(label string) { p.node = label }
+
+// SetDNSProvider attaches the DNS stats source for the block-percentage
+// sensor. Call before Run.
+func (p *Publisher) SetDNSProvider(dp dns.Provider) { p.dnsProv = dp }
+
+// SetUniFi attaches the controller client for the WiFi client-count
+// sensor. Call before Run.
+func (p *Publisher) SetUniFi(c *unifi.Client) { p.unifi = c }
+
+// Run publishes immediately and then on every tick. Call in a
+// goroutine.
+func (p *Publisher) Run() {
+	p.publish()
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.publish()
+		case <-p.stopCh:
+			if p.conn != nil {
+				p.conn.close()
+				p.conn = nil
+			}
+			return
+		}
+	}
+}
+
+// Stop terminates the publish loop.
+func (p *Publisher) Stop() {
+	select {
+	case <-p.stopCh:
+	default:
+		close(p.stopCh)
+	}
+}
+
+// Health reports the breaker's circuit state for /api/integrations.
+func (p *Publisher) Health() integration.BreakerStatus {
+	return p.breaker.Status()
+}
+
+func (p *Publisher) publish() {
+	if !p.breaker.Allow() {
+		return
+	}
+	if err := p.publishOnce(); err != nil {
+		log.Printf("mqtt: %v", err)
+		p.breaker.Failure(err)
+		if p.conn != nil {
+			p.conn.close()
+			p.conn = nil
+		}
+		return
+	}
+	p.breaker.Success()
+}
+
+// sensor is one Home Assistant entity: a discovery config published
+// retained once per connection, and a state value on every tick.
+type sensor struct {
+	component string // "sensor" or "binary_sensor"
+	id        string // topic/unique-id suffix
+	name      string
+	unit      string
+	value     string
+}
+
+func (p *Publisher) publishOnce() error {
+	if p.conn == nil {
+		c, err := dialBroker(p.broker, p.clientID(), p.user, p.pass, 4*p.interval)
+		if err != nil {
+			return fmt.Errorf("connect %s: %w", p.broker, err)
+		}
+		p.conn = c
+		// Re-announce everything on a fresh connection; retained
+		// discovery publishes are idempotent
+		p.discovered = make(map[string]bool)
+	}
+	for _, s := range p.sensors() {
+		if err := p.announce(s); err != nil {
+			return err
+		}
+		topic := p.prefix + "/" + p.nodeID() + "/" + s.id
+		if err := p.conn.publish(topic, []byte(s.value), false); err != nil {
+			return fmt.Errorf("publish %s: %w", topic, err)
+		}
+	}
+	return nil
+}
+
+func (p *Publisher) sensors() []sensor {
+	var out []sensor
+	vpnUp := false
+	for _, iface := range p.src.GetAll() {
+		if isVPNIface(iface.Name) && iface.OperState == "up" {
+			vpnUp = true
+		}
+		out = append(out,
+			sensor{"sensor", iface.Name + "_rx_mbps", iface.Name + " RX rate", "Mbit/s",
+				fmt.Sprintf("%.3f", iface.RxRate*8/1e6)},
+			sensor{"sensor", iface.Name + "_tx_mbps", iface.Name + " TX rate", "Mbit/s",
+				fmt.Sprintf("%.3f", iface.TxRate*8/1e6)},
+		)
+	}
+	vpn := sensor{component: "binary_sensor", id: "vpn_active", name: "VPN active", value: "OFF"}
+	if vpnUp {
+		vpn.value = "ON"
+	}
+	out = append(out, vpn)
+	if p.dnsProv != nil {
+		if ds := p.dnsProv.GetSummary(); ds != nil {
+			out = append(out, sensor{"sensor", "dns_blocked_pct", "DNS blocked", "%",
+				fmt.Sprintf("%.1f", ds.BlockedPercent)})
+		}
+	}
+	if p.unifi != nil {
+		if ws := p.unifi.GetSummary(); ws != nil {
+			out = append(out, sensor{"sensor", "wifi_clients", "WiFi clients", "",
+				fmt.Sprintf("%d", ws.TotalClients)})
+		}
+	}
+	return out
+}
+
+// announce publishes the retained discovery config once per connection.
+func (p *Publisher) announce(s sensor) error {
+	topic := discoveryPrefix + "/" + s.component + "/" + p.nodeID() + "/" + s.id + "/config"
+	if p.discovered[topic] {
+		return nil
+	}
+	cfg := map[string]interface{}{
+		"name":        s.name,
+		"state_topic": p.prefix + "/" + p.nodeID() + "/" + s.id,
+		"unique_id":   p.nodeID() + "_" + s.id,
+		"device": map[string]interface{}{
+			"identifiers": []string{p.nodeID()},
+			"name":        p.deviceName(),
+		},
+	}
+	if s.unit != "" {
+		cfg["unit_of_measurement"] = s.unit
+		cfg["state_class"] = "measurement"
+	}
+	payload, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	if err := p.conn.publish(topic, payload, true); err != nil {
+		return fmt.Errorf("publish %s: %w", topic, err)
+	}
+	p.discovered[topic] = true
+	return nil
+}
+
+func (p *Publisher) clientID() string {
+	return "bandwidth-monitor-" + p.nodeID()
+}
+
+// nodeID sanitizes the node label (or hostname) into the topic-safe
+// discriminator used in unique IDs and state topics.
+func (p *Publisher) nodeID() string {
+	label := p.node
+	if label == "" {
+		label, _ = os.Hostname()
+	}
+	if label == "" {
+		label = "default"
+	}
+	var b strings.Builder
+	for _, r := range strings.ToLower(label) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return "bwmon_" + b.String()
+}
+
+func (p *Publisher) deviceName() string {
+	if p.node != "" {
+		return "Bandwidth Monitor (" + p.node + ")"
+	}
+	return "Bandwidth Monitor"
+}
+
+// isVPNIface matches the interface names the common VPN stacks create.
+func isVPNIface(name string) bool {
+	for _, prefix := range []string{"wg", "tun", "tap", "tailscale"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
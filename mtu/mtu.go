@@ -0,0 +1,230 @@
+// Package mtu watches the capture path for signs of path-MTU trouble —
+// ICMP fragmentation-needed storms, suspiciously low TCP MSS values and
+// excessive IPv4 fragmentation — a common pain on PPPoE and WireGuard
+// links where a missing MSS clamp breaks things intermittently.
+package mtu
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+	"github.com/gopacket/gopacket/pcap"
+)
+
+const (
+	snapshotLen int32 = 128
+	capTimeout        = 100 * time.Millisecond
+	maxEvents         = 200
+
+	lowMSS        = 1000            // below any sane link MTU — likely breakage
+	dedupeWindow  = 5 * time.Minute // one event per destination per window
+	fragInterval  = time.Minute     // fragmentation counter flush cadence
+	fragThreshold = 100             // fragments per interval worth reporting
+)
+
+// Event is one detected path-MTU finding.
+type Event struct {
+	Timestamp int64  `json:"timestamp"`
+	Kind      string `json:"kind"` // frag-needed, pkt-too-big, low-mss, fragmentation
+	Dest      string `json:"dest,omitempty"`
+	Detail    string `json:"detail"`
+}
+
+// Detector sniffs ICMP errors and TCP SYNs on the capture device.
+type Detector struct {
+	device      string
+	promiscuous bool
+
+	mu       sync.Mutex
+	events   []Event
+	lastSeen map[string]time.Time // kind+dest → last event, for dedupe
+	fragPkts int
+
+	stopCh chan struct{}
+}
+
+// New creates a Detector for the given capture device.
+func New(device string, promiscuous bool) *Detector {
+	return &Detector{
+		device:      device,
+		promiscuous: promiscuous,
+		lastSeen:    make(map[string]time.Time),
+		stopCh:      make(chan struct{}),
+	}
+}
+
+// Run opens a narrowly-filtered capture handle and watches for trouble.
+// Call in a goroutine.
+func (d *Detector) Run() {
+	handle, err := pcap.OpenLive(d.device, snapshotLen, d.promiscuous, capTimeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mtu: cannot open %s: %v\n", d.device, err)
+		return
+	}
+	defer handle.Close()
+
+	// Only ICMP errors, TCP SYNs and IPv4 fragments reach userspace
+	filter := "icmp or icmp6 or (tcp[tcpflags] & tcp-syn != 0) or (ip[6:2] & 0x3fff != 0)"
+	if err := handle.SetBPFFilter(filter); err != nil {
+		fmt.Fprintf(os.Stderr, "mtu: BPF filter error: %v\n", err)
+	}
+
+	go d.fragFlushLoop()
+
+	for {
+		select {
+		case <-d.stopCh:
+			return
+		default:
+		}
+		data, _, err := handle.ReadPacketData()
+		if err != nil {
+			if err == pcap.NextErrorTimeoutExpired {
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "mtu: read error on %s: %v\n", d.device, err)
+			return
+		}
+		pkt := gopacket.NewPacket(data, handle.LinkType(), gopacket.DecodeOptions{
+			Lazy:   true,
+			NoCopy: true,
+		})
+		d.inspect(pkt)
+	}
+}
+
+// Stop terminates the detector.
+func (d *Detector) Stop() {
+	select {
+	case <-d.stopCh:
+	default:
+		close(d.stopCh)
+	}
+}
+
+// GetEvents returns the retained findings, newest last.
+func (d *Detector) GetEvents() []Event {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]Event, len(d.events))
+	copy(out, d.events)
+	return out
+}
+
+// ---------- internal ----------
+
+func (d *Detector) inspect(pkt gopacket.Packet) {
+	if l := pkt.Layer(layers.LayerTypeICMPv4); l != nil {
+		icmp := l.(*layers.ICMPv4)
+		if icmp.TypeCode.Type() == layers.ICMPv4TypeDestinationUnreachable &&
+			icmp.TypeCode.Code() == layers.ICMPv4CodeFragmentationNeeded {
+			// Next-hop MTU sits in the unused field; the embedded
+			// original header names the destination that needs it
+			dest := ""
+			if p := icmp.Payload; len(p) >= 20 {
+				dest = fmt.Sprintf("%d.%d.%d.%d", p[16], p[17], p[18], p[19])
+			}
+			d.emit("frag-needed", dest, fmt.Sprintf("ICMP fragmentation needed, next-hop MTU %d", icmp.Seq))
+		}
+		return
+	}
+	if l := pkt.Layer(layers.LayerTypeICMPv6); l != nil {
+		icmp := l.(*layers.ICMPv6)
+		if icmp.TypeCode.Type() == layers.ICMPv6TypePacketTooBig {
+			mtuVal := uint32(0)
+			if len(icmp.Payload) >= 4 {
+				mtuVal = binary.BigEndian.Uint32(icmp.Payload[:4])
+			}
+			dest := ""
+			if p := icmp.Payload; len(p) >= 44 {
+				// 4 bytes MTU, then the embedded original IPv6 header
+				dest = net.IP(p[28:44]).String()
+			}
+			d.emit("pkt-too-big", dest, fmt.Sprintf("ICMPv6 packet too big, MTU %d", mtuVal))
+		}
+		return
+	}
+
+	if ipLayer := pkt.Layer(layers.LayerTypeIPv4); ipLayer != nil {
+		ip := ipLayer.(*layers.IPv4)
+		if ip.Flags&layers.IPv4MoreFragments != 0 || ip.FragOffset > 0 {
+			d.mu.Lock()
+			d.fragPkts++
+			d.mu.Unlock()
+			return
+		}
+	}
+
+	if l := pkt.Layer(layers.LayerTypeTCP); l != nil {
+		tcp := l.(*layers.TCP)
+		if !tcp.SYN {
+			return
+		}
+		for _, opt := range tcp.Options {
+			if opt.OptionType == layers.TCPOptionKindMSS && len(opt.OptionData) == 2 {
+				if mss := binary.BigEndian.Uint16(opt.OptionData); mss < lowMSS {
+					dest := ""
+					if ipLayer := pkt.Layer(layers.LayerTypeIPv4); ipLayer != nil {
+						dest = ipLayer.(*layers.IPv4).DstIP.String()
+					} else if ipLayer := pkt.Layer(layers.LayerTypeIPv6); ipLayer != nil {
+						dest = ipLayer.(*layers.IPv6).DstIP.String()
+					}
+					d.emit("low-mss", dest, fmt.Sprintf("TCP SYN advertises MSS %d", mss))
+				}
+				return
+			}
+		}
+	}
+}
+
+// emit records an event unless the same kind+dest fired recently.
+func (d *Detector) emit(kind, dest, detail string) {
+	now := time.Now()
+	key := kind + "|" + dest
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if last, ok := d.lastSeen[key]; ok && now.Sub(last) < dedupeWindow {
+		return
+	}
+	d.lastSeen[key] = now
+	d.append(Event{Timestamp: now.UnixMilli(), Kind: kind, Dest: dest, Detail: detail})
+}
+
+// append assumes d.mu is held.
+func (d *Detector) append(e Event) {
+	d.events = append(d.events, e)
+	if len(d.events) > maxEvents {
+		d.events = d.events[len(d.events)-maxEvents:]
+	}
+}
+
+// fragFlushLoop converts the raw fragment counter into at most one
+// aggregated event per interval.
+func (d *Detector) fragFlushLoop() {
+	ticker := time.NewTicker(fragInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			d.mu.Lock()
+			n := d.fragPkts
+			d.fragPkts = 0
+			if n >= fragThreshold {
+				d.append(Event{
+					Timestamp: time.Now().UnixMilli(),
+					Kind:      "fragmentation",
+					Detail:    fmt.Sprintf("%d fragmented IPv4 packets in the last %s", n, fragInterval),
+				})
+			}
+			d.mu.Unlock()
+		case <-d.stopCh:
+			return
+		}
+	}
+}
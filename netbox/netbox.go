@@ -0,0 +1,285 @@
+// Package netbox periodically syncs IP addresses and prefixes from a
+// NetBox instance so devices and talker attribution can use the org's
+// source-of-truth naming (device names, roles, tenants).
+package netbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"bandwidth-monitor/httpclient"
+)
+
+const pageSize = 500 // NetBox pagination limit per request
+
+type Client struct {
+	baseURL    string
+	token      string
+	interval   time.Duration
+	httpClient *http.Client
+
+	mu       sync.RWMutex
+	ips      map[string]*IPInfo // keyed by bare IP (no prefix length)
+	prefixes []Prefix
+
+	stopCh chan struct{}
+}
+
+// IPInfo is the NetBox record for one IP address.
+type IPInfo struct {
+	Address string `json:"address"`
+	DNSName string `json:"dns_name,omitempty"`
+	Device  string `json:"device,omitempty"`
+	Role    string `json:"role,omitempty"`
+	Tenant  string `json:"tenant,omitempty"`
+}
+
+// Prefix is one NetBox prefix with its metadata.
+type Prefix struct {
+	Prefix      string `json:"prefix"`
+	Description string `json:"description,omitempty"`
+	Role        string `json:"role,omitempty"`
+	Tenant      string `json:"tenant,omitempty"`
+	Site        string `json:"site,omitempty"`
+
+	net *net.IPNet
+}
+
+type Summary struct {
+	IPCount  int      `json:"ip_count"`
+	Prefixes []Prefix `json:"prefixes"`
+}
+
+func New(baseURL, token string, syncInterval time.Duration) *Client {
+	if syncInterval <= 0 {
+		syncInterval = 15 * time.Minute
+	}
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		token:      token,
+		interval:   syncInterval,
+		httpClient: httpclient.New(30 * time.Second),
+		ips:        make(map[string]*IPInfo),
+		stopCh:     make(chan struct{}),
+	}
+}
+
+func (c *Client) Run() {
+	c.sync()
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.sync()
+		case <-c.stopCh:
+			return
+		}
+	}
+}
+
+func (c *Client) Stop() {
+	select {
+	case <-c.stopCh:
+	default:
+		close(c.stopCh)
+	}
+}
+
+func (c *Client) GetSummary() *Summary {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return &Summary{IPCount: len(c.ips), Prefixes: c.prefixes}
+}
+
+// NameForIP returns the NetBox name, role and tenant for ip. The IP record
+// wins; a covering prefix supplies role/tenant fallbacks. Implements
+// inventory.Enricher.
+func (c *Client) NameForIP(ip string) (name, role, tenant string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if info, ok := c.ips[ip]; ok {
+		name = info.Device
+		if name == "" {
+			name = info.DNSName
+		}
+		role, tenant = info.Role, info.Tenant
+	}
+	if role != "" && tenant != "" {
+		return
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return
+	}
+	// Prefixes are sorted longest-mask-first, so the first match is the
+	// most specific.
+	for _, p := range c.prefixes {
+		if p.net != nil && p.net.Contains(parsed) {
+			if role == "" {
+				role = p.Role
+			}
+			if tenant == "" {
+				tenant = p.Tenant
+			}
+			break
+		}
+	}
+	return
+}
+
+// ---------- sync ----------
+
+// rawNamed is the {"name": ...} shape NetBox uses for nested objects.
+type rawNamed struct {
+	Name string `json:"name"`
+}
+
+type rawIP struct {
+	Address        string    `json:"address"`
+	DNSName        string    `json:"dns_name"`
+	Role           *rawNamed `json:"role"`
+	Tenant         *rawNamed `json:"tenant"`
+	AssignedObject *struct {
+		Device         *rawNamed `json:"device"`
+		VirtualMachine *rawNamed `json:"virtual_machine"`
+	} `json:"assigned_object"`
+}
+
+type rawPrefix struct {
+	Prefix      string    `json:"prefix"`
+	Description string    `json:"description"`
+	Role        *rawNamed `json:"role"`
+	Tenant      *rawNamed `json:"tenant"`
+	Site        *rawNamed `json:"site"`
+}
+
+func (c *Client) sync() {
+	ips, err := c.fetchIPs()
+	if err != nil {
+		log.Printf("netbox: fetch ip-addresses: %v", err)
+		return
+	}
+	prefixes, err := c.fetchPrefixes()
+	if err != nil {
+		log.Printf("netbox: fetch prefixes: %v", err)
+		return
+	}
+
+	c.mu.Lock()
+	c.ips = ips
+	c.prefixes = prefixes
+	c.mu.Unlock()
+	log.Printf("netbox: synced %d IP(s), %d prefix(es)", len(ips), len(prefixes))
+}
+
+func (c *Client) fetchIPs() (map[string]*IPInfo, error) {
+	out := make(map[string]*IPInfo)
+	url := c.baseURL + fmt.Sprintf("/api/ipam/ip-addresses/?limit=%d", pageSize)
+	for url != "" {
+		var page struct {
+			Next    string  `json:"next"`
+			Results []rawIP `json:"results"`
+		}
+		if err := c.get(url, &page); err != nil {
+			return nil, err
+		}
+		for _, r := range page.Results {
+			ip := r.Address
+			if i := strings.IndexByte(ip, '/'); i >= 0 {
+				ip = ip[:i]
+			}
+			info := &IPInfo{Address: r.Address, DNSName: r.DNSName}
+			if r.Role != nil {
+				info.Role = r.Role.Name
+			}
+			if r.Tenant != nil {
+				info.Tenant = r.Tenant.Name
+			}
+			if ao := r.AssignedObject; ao != nil {
+				if ao.Device != nil {
+					info.Device = ao.Device.Name
+				} else if ao.VirtualMachine != nil {
+					info.Device = ao.VirtualMachine.Name
+				}
+			}
+			out[ip] = info
+		}
+		url = page.Next
+	}
+	return out, nil
+}
+
+func (c *Client) fetchPrefixes() ([]Prefix, error) {
+	var out []Prefix
+	url := c.baseURL + fmt.Sprintf("/api/ipam/prefixes/?limit=%d", pageSize)
+	for url != "" {
+		var page struct {
+			Next    string      `json:"next"`
+			Results []rawPrefix `json:"results"`
+		}
+		if err := c.get(url, &page); err != nil {
+			return nil, err
+		}
+		for _, r := range page.Results {
+			p := Prefix{Prefix: r.Prefix, Description: r.Description}
+			if r.Role != nil {
+				p.Role = r.Role.Name
+			}
+			if r.Tenant != nil {
+				p.Tenant = r.Tenant.Name
+			}
+			if r.Site != nil {
+				p.Site = r.Site.Name
+			}
+			if _, n, err := net.ParseCIDR(r.Prefix); err == nil {
+				p.net = n
+			}
+			out = append(out, p)
+		}
+		url = page.Next
+	}
+	// Longest mask first so NameForIP finds the most specific prefix
+	sort.Slice(out, func(i, j int) bool { return maskLen(out[i].net) > maskLen(out[j].net) })
+	return out, nil
+}
+
+func maskLen(n *net.IPNet) int {
+	if n == nil {
+		return -1
+	}
+	ones, _ := n.Mask.Size()
+	return ones
+}
+
+func (c *Client) get(url string, v interface{}) error {
+	req, _ := http.NewRequest("GET", url, nil)
+	req.Header.Set("Authorization", "Token "+c.token)
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("GET %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("status %d: %s", resp.StatusCode, string(body))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decode: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) String() string {
+	return fmt.Sprintf("NetBox[%s]", c.baseURL)
+}
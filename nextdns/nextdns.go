@@ -7,22 +7,43 @@ import (
 	"log"
 	"net/http"
 	"sort"
+	"strconv"
 	"sync"
 	"time"
 
 	"bandwidth-monitor/dns"
+	"bandwidth-monitor/httpclient"
+	"bandwidth-monitor/integration"
 )
 
-const apiBase = "https://api.nextdns.io"
+const (
+	apiBase = "https://api.nextdns.io"
+
+	// The NextDNS API rate limits aggressively; the top-domain/client
+	// lists churn slowly, so refresh them only every topEvery polls.
+	topEvery   = 5
+	backoffMax = 15 * time.Minute
+)
 
 // Client polls the NextDNS analytics API.
 type Client struct {
-	profile  string
-	apiKey   string
-	interval time.Duration
+	profile    string
+	apiKey     string
+	interval   time.Duration
+	topLimit   int // entries requested for top-domain/client lists
+	httpClient *http.Client
+	breaker    *integration.Breaker
 
-	mu    sync.RWMutex
-	stats *snapshot
+	mu        sync.RWMutex
+	stats     *snapshot
+	throttled bool
+
+	// rate-limit state, touched by the poll goroutine only
+	etags   map[string]string
+	cache   map[string]json.RawMessage // last 200 body per path, replayed on 304
+	backoff time.Duration
+	retryAt time.Time
+	pollN   int
 
 	stopCh chan struct{}
 }
@@ -58,10 +79,23 @@ type statusTSEntry struct {
 // New creates a NextDNS API client.
 func New(profile, apiKey string, pollInterval time.Duration) *Client {
 	return &Client{
-		profile:  profile,
-		apiKey:   apiKey,
-		interval: pollInterval,
-		stopCh:   make(chan struct{}),
+		profile:    profile,
+		apiKey:     apiKey,
+		interval:   pollInterval,
+		topLimit:   10,
+		httpClient: httpclient.New(15 * time.Second),
+		breaker:    integration.NewBreaker("nextdns"),
+		etags:      make(map[string]string),
+		cache:      make(map[string]json.RawMessage),
+		stopCh:     make(chan struct{}),
+	}
+}
+
+// SetTopLimit changes how many entries the top-domain and top-client
+// queries ask for (default 10). Call before Run.
+func (c *Client) SetTopLimit(n int) {
+	if n > 0 {
+		c.topLimit = n
 	}
 }
 
@@ -90,34 +124,113 @@ func (c *Client) Stop() {
 }
 
 func (c *Client) poll() {
-	snap := &snapshot{}
-	var ok bool
-
-	if snap.status, ok = fetchJSON[[]statusEntry](c, "/analytics/status?from=-24h&limit=500"); !ok {
-		return
+	if time.Now().Before(c.retryAt) {
+		return // still backing off a rate limit
 	}
-	if snap.domains, ok = fetchJSON[[]domainEntry](c, "/analytics/domains?from=-24h&limit=10"); !ok {
+	if !c.breaker.Allow() {
 		return
 	}
-	if snap.blocked, ok = fetchJSON[[]domainEntry](c, "/analytics/domains?from=-24h&status=blocked&limit=10"); !ok {
+	if err := c.pollOnce(); err != nil {
+		// fetchJSON logged the details already; if it was a 429 the
+		// rate-limit backoff paces retries, so don't also open the
+		// circuit for it
+		if time.Now().Before(c.retryAt) {
+			return
+		}
+		c.breaker.Failure(err)
 		return
 	}
-	if snap.clients, ok = fetchJSON[[]clientEntry](c, "/analytics/ips?from=-24h&limit=10"); !ok {
-		return
+	c.breaker.Success()
+}
+
+// Health reports the circuit-breaker state for the health endpoint.
+func (c *Client) Health() integration.BreakerStatus {
+	return c.breaker.Status()
+}
+
+func (c *Client) pollOnce() error {
+	c.pollN++
+
+	snap := &snapshot{}
+	var ok bool
+
+	if snap.status, ok = fetchJSON[[]statusEntry](c, "/analytics/status?from=-24h&limit=500"); !ok {
+		return fmt.Errorf("status fetch failed")
+	}
+
+	c.mu.RLock()
+	prev := c.stats
+	c.mu.RUnlock()
+	if prev != nil && c.pollN%topEvery != 1 {
+		// Carry the previous top lists; they get their own, slower
+		// refresh cadence to stay under the rate limit
+		snap.domains, snap.blocked, snap.clients = prev.domains, prev.blocked, prev.clients
+	} else {
+		if snap.domains, ok = fetchJSON[[]domainEntry](c, fmt.Sprintf("/analytics/domains?from=-24h&limit=%d", c.topLimit)); !ok {
+			return fmt.Errorf("top domains fetch failed")
+		}
+		if snap.blocked, ok = fetchJSON[[]domainEntry](c, fmt.Sprintf("/analytics/domains?from=-24h&status=blocked&limit=%d", c.topLimit)); !ok {
+			return fmt.Errorf("blocked domains fetch failed")
+		}
+		if snap.clients, ok = fetchJSON[[]clientEntry](c, fmt.Sprintf("/analytics/ips?from=-24h&limit=%d", c.topLimit)); !ok {
+			return fmt.Errorf("top clients fetch failed")
+		}
 	}
 	if snap.statusTS, ok = fetchJSON[[]statusTSEntry](c, "/analytics/status;series?from=-24h&interval=1800"); !ok {
-		return
+		return fmt.Errorf("status series fetch failed")
 	}
 
 	c.mu.Lock()
 	c.stats = snap
 	c.mu.Unlock()
+	return nil
+}
+
+// noteThrottled records a 429: exponential backoff doubling up to
+// backoffMax, stretched further if Retry-After asks for more.
+func (c *Client) noteThrottled(retryAfter string) {
+	if c.backoff == 0 {
+		c.backoff = c.interval
+	} else {
+		c.backoff *= 2
+	}
+	if c.backoff > backoffMax {
+		c.backoff = backoffMax
+	}
+	delay := c.backoff
+	if s, err := strconv.Atoi(retryAfter); err == nil && s > 0 {
+		if d := time.Duration(s) * time.Second; d > delay {
+			delay = d
+		}
+	}
+	c.retryAt = time.Now().Add(delay)
+
+	c.mu.Lock()
+	first := !c.throttled
+	c.throttled = true
+	c.mu.Unlock()
+	if first {
+		log.Printf("nextdns: rate limited, backing off %s", delay)
+	}
+}
+
+// noteHealthy resets the backoff after a successful request.
+func (c *Client) noteHealthy() {
+	c.backoff = 0
+	c.mu.Lock()
+	was := c.throttled
+	c.throttled = false
+	c.mu.Unlock()
+	if was {
+		log.Printf("nextdns: rate limit cleared")
+	}
 }
 
 // GetSummary returns a frontend-friendly summary, or nil if no data yet.
 func (c *Client) GetSummary() *dns.Summary {
 	c.mu.RLock()
 	snap := c.stats
+	throttled := c.throttled
 	c.mu.RUnlock()
 	if snap == nil {
 		return nil
@@ -160,8 +273,14 @@ func (c *Client) GetSummary() *dns.Summary {
 		}
 	}
 
+	health := ""
+	if throttled {
+		health = "throttled"
+	}
+
 	return &dns.Summary{
 		ProviderName:   "NextDNS",
+		Health:         health,
 		TotalQueries:   totalQueries,
 		BlockedTotal:   blockedTotal,
 		BlockedPercent: blockedPct,
@@ -200,26 +319,55 @@ func fetchJSON[T any](c *Client, path string) (T, bool) {
 		return zero, false
 	}
 	req.Header.Set("X-Api-Key", c.apiKey)
+	if etag := c.etags[path]; etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
 
-	client := &http.Client{Timeout: 15 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		log.Printf("nextdns: fetch %s: %v", path, err)
 		return zero, false
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		raw, ok := c.cache[path]
+		if !ok {
+			return zero, false
+		}
+		var r apiResponse[T]
+		if err := json.Unmarshal(raw, &r); err != nil {
+			return zero, false
+		}
+		c.noteHealthy()
+		return r.Data, true
+	case http.StatusTooManyRequests:
+		io.Copy(io.Discard, resp.Body)
+		c.noteThrottled(resp.Header.Get("Retry-After"))
+		return zero, false
+	default:
 		body, _ := io.ReadAll(resp.Body)
 		log.Printf("nextdns: %s returned %d: %s", path, resp.StatusCode, string(body))
 		return zero, false
 	}
 
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Printf("nextdns: read %s: %v", path, err)
+		return zero, false
+	}
 	var r apiResponse[T]
-	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+	if err := json.Unmarshal(body, &r); err != nil {
 		log.Printf("nextdns: decode %s: %v", path, err)
 		return zero, false
 	}
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		c.etags[path] = etag
+		c.cache[path] = body
+	}
+	c.noteHealthy()
 	return r.Data, true
 }
 
@@ -0,0 +1,254 @@
+// Package openvpn reads the OpenVPN server status (status file or
+// management interface) and lists connected clients with per-client byte
+// counts and connect time, for people still running OpenVPN servers
+// alongside WireGuard.
+package openvpn
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ClientStat is one connected OpenVPN client.
+type ClientStat struct {
+	CommonName     string  `json:"common_name"`
+	RealAddress    string  `json:"real_address"`
+	VirtualAddress string  `json:"virtual_address,omitempty"`
+	RxBytes        int64   `json:"rx_bytes"` // bytes received from the client
+	TxBytes        int64   `json:"tx_bytes"` // bytes sent to the client
+	RxRate         float64 `json:"rx_rate"`
+	TxRate         float64 `json:"tx_rate"`
+	ConnectedSince int64   `json:"connected_since"` // unix ms
+}
+
+// Summary is the latest OpenVPN server snapshot.
+type Summary struct {
+	Clients   []ClientStat `json:"clients"`
+	Timestamp int64        `json:"timestamp"`
+}
+
+type byteSnap struct {
+	rx int64
+	tx int64
+}
+
+// Client polls an OpenVPN status source on a fixed interval. Exactly one
+// of statusFile / mgmtAddr should be set.
+type Client struct {
+	statusFile string
+	mgmtAddr   string
+	interval   time.Duration
+
+	mu       sync.RWMutex
+	summary  *Summary
+	prev     map[string]byteSnap // keyed by common name + real address
+	lastPoll time.Time
+
+	stopCh chan struct{}
+}
+
+// New creates an OpenVPN status client. statusFile is the path written by
+// the server's `status` directive; mgmtAddr is a host:port management
+// interface (used when statusFile is empty).
+func New(statusFile, mgmtAddr string, pollInterval time.Duration) *Client {
+	if pollInterval <= 0 {
+		pollInterval = 10 * time.Second
+	}
+	return &Client{
+		statusFile: statusFile,
+		mgmtAddr:   mgmtAddr,
+		interval:   pollInterval,
+		prev:       make(map[string]byteSnap),
+		stopCh:     make(chan struct{}),
+	}
+}
+
+// Run starts the polling loop. Call in a goroutine.
+func (c *Client) Run() {
+	c.poll()
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.poll()
+		case <-c.stopCh:
+			return
+		}
+	}
+}
+
+// Stop terminates the polling loop.
+func (c *Client) Stop() {
+	select {
+	case <-c.stopCh:
+	default:
+		close(c.stopCh)
+	}
+}
+
+// GetSummary returns the latest snapshot, or nil if no data yet.
+func (c *Client) GetSummary() *Summary {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.summary
+}
+
+// Available returns true if the client has successfully read status at least once.
+func (c *Client) Available() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.summary != nil
+}
+
+// ---------- internal ----------
+
+func (c *Client) poll() {
+	var (
+		raw string
+		err error
+	)
+	if c.statusFile != "" {
+		var b []byte
+		b, err = os.ReadFile(c.statusFile)
+		raw = string(b)
+	} else {
+		raw, err = c.fetchMgmtStatus()
+	}
+	if err != nil {
+		log.Printf("openvpn: read status: %v", err)
+		return
+	}
+
+	clients := parseStatus(raw)
+
+	now := time.Now()
+	dt := now.Sub(c.lastPoll).Seconds()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	newPrev := make(map[string]byteSnap, len(clients))
+	for i := range clients {
+		cl := &clients[i]
+		key := cl.CommonName + "/" + cl.RealAddress
+		if prev, ok := c.prev[key]; ok && dt > 0 && !c.lastPoll.IsZero() {
+			if cl.RxBytes >= prev.rx {
+				cl.RxRate = float64(cl.RxBytes-prev.rx) / dt
+			}
+			if cl.TxBytes >= prev.tx {
+				cl.TxRate = float64(cl.TxBytes-prev.tx) / dt
+			}
+		}
+		newPrev[key] = byteSnap{rx: cl.RxBytes, tx: cl.TxBytes}
+	}
+
+	c.summary = &Summary{Clients: clients, Timestamp: now.UnixMilli()}
+	c.prev = newPrev
+	c.lastPoll = now
+}
+
+// fetchMgmtStatus connects to the management interface and issues "status 2".
+func (c *Client) fetchMgmtStatus() (string, error) {
+	conn, err := net.DialTimeout("tcp", c.mgmtAddr, 5*time.Second)
+	if err != nil {
+		return "", fmt.Errorf("dial %s: %w", c.mgmtAddr, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if _, err := io.WriteString(conn, "status 2\n"); err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "END" {
+			break
+		}
+		sb.WriteString(line)
+		sb.WriteByte('\n')
+	}
+	return sb.String(), scanner.Err()
+}
+
+// parseStatus handles both the version-1 status file layout
+// ("Common Name,Real Address,...") and version 2/3 CLIENT_LIST records.
+func parseStatus(raw string) []ClientStat {
+	var clients []ClientStat
+	inV1List := false
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimRight(line, "\r")
+		// Version 2 uses commas, version 3 uses tabs
+		fields := strings.Split(line, ",")
+		if strings.ContainsRune(line, '\t') {
+			fields = strings.Split(line, "\t")
+		}
+
+		if fields[0] == "CLIENT_LIST" && len(fields) >= 8 {
+			cl := ClientStat{
+				CommonName:     fields[1],
+				RealAddress:    fields[2],
+				VirtualAddress: fields[3],
+			}
+			cl.RxBytes, _ = strconv.ParseInt(fields[5], 10, 64)
+			cl.TxBytes, _ = strconv.ParseInt(fields[6], 10, 64)
+			// Prefer the unix timestamp column when present
+			if len(fields) >= 9 {
+				if ts, err := strconv.ParseInt(fields[8], 10, 64); err == nil && ts > 0 {
+					cl.ConnectedSince = ts * 1000
+				}
+			}
+			if cl.ConnectedSince == 0 {
+				cl.ConnectedSince = parseStatusTime(fields[7])
+			}
+			clients = append(clients, cl)
+			continue
+		}
+
+		// Version 1 layout: header line then one CSV row per client until
+		// the ROUTING TABLE section.
+		if line == "Common Name,Real Address,Bytes Received,Bytes Sent,Connected Since" {
+			inV1List = true
+			continue
+		}
+		if strings.HasPrefix(line, "ROUTING TABLE") {
+			inV1List = false
+			continue
+		}
+		if inV1List && len(fields) == 5 {
+			cl := ClientStat{
+				CommonName:  fields[0],
+				RealAddress: fields[1],
+			}
+			cl.RxBytes, _ = strconv.ParseInt(fields[2], 10, 64)
+			cl.TxBytes, _ = strconv.ParseInt(fields[3], 10, 64)
+			cl.ConnectedSince = parseStatusTime(fields[4])
+			clients = append(clients, cl)
+		}
+	}
+	return clients
+}
+
+// parseStatusTime parses OpenVPN's human-readable timestamp format.
+func parseStatusTime(s string) int64 {
+	for _, layout := range []string{
+		"Mon Jan 2 15:04:05 2006",
+		"2006-01-02 15:04:05",
+	} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t.UnixMilli()
+		}
+	}
+	return 0
+}
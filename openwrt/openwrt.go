@@ -0,0 +1,401 @@
+// Package openwrt polls a remote OpenWrt router over ubus-over-HTTP
+// (the /ubus JSON-RPC endpoint the LuCI web UI uses) for interface
+// counters, DHCP leases and wireless station data — for people whose
+// monitor box isn't the router. Needs the uhttpd-mod-ubus package on
+// the router and an rpcd login with read access to network.device,
+// iwinfo and luci-rpc.
+package openwrt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"bandwidth-monitor/httpclient"
+	"bandwidth-monitor/integration"
+)
+
+// nullSession is the anonymous session ID used for the login call.
+const nullSession = "00000000000000000000000000000000"
+
+type Client struct {
+	baseURL    string
+	user       string
+	pass       string
+	interval   time.Duration
+	httpClient *http.Client
+	breaker    *integration.Breaker
+
+	mu      sync.RWMutex
+	summary *Summary
+	stopCh  chan struct{}
+
+	// rpc state, touched by the poll goroutine only
+	session string
+	rpcID   int
+
+	// rate tracking
+	lastPoll time.Time
+	prevIf   map[string]byteSnap
+}
+
+type byteSnap struct {
+	tx uint64
+	rx uint64
+}
+
+type InterfaceStat struct {
+	Name     string  `json:"name"`
+	Up       bool    `json:"up"`
+	RxBytes  uint64  `json:"rx_bytes"`
+	TxBytes  uint64  `json:"tx_bytes"`
+	RxErrors uint64  `json:"rx_errors"`
+	TxErrors uint64  `json:"tx_errors"`
+	RxRate   float64 `json:"rx_rate"` // bytes/sec since last poll
+	TxRate   float64 `json:"tx_rate"`
+}
+
+type DHCPLease struct {
+	Hostname string `json:"hostname,omitempty"`
+	IP       string `json:"ip"`
+	MAC      string `json:"mac"`
+	Expires  int64  `json:"expires"` // seconds until expiry
+}
+
+// Station is one associated wireless client, from iwinfo.
+type Station struct {
+	Device string `json:"device"` // radio interface, e.g. wlan0
+	MAC    string `json:"mac"`
+	Signal int    `json:"signal"` // dBm
+	Noise  int    `json:"noise,omitempty"`
+	RxRate int64  `json:"rx_rate"` // PHY rate, kbit/s
+	TxRate int64  `json:"tx_rate"`
+}
+
+type Summary struct {
+	Interfaces []InterfaceStat `json:"interfaces"`
+	Leases     []DHCPLease     `json:"leases,omitempty"`
+	Stations   []Station       `json:"stations,omitempty"`
+}
+
+func New(baseURL, user, pass string, pollInterval time.Duration) *Client {
+	// Routers speak self-signed HTTPS when they speak it at all
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		user:       user,
+		pass:       pass,
+		interval:   pollInterval,
+		httpClient: httpclient.NewInsecure(15 * time.Second),
+		breaker:    integration.NewBreaker("openwrt"),
+		stopCh:     make(chan struct{}),
+	}
+}
+
+func (c *Client) Run() {
+	c.poll()
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.poll()
+		case <-c.stopCh:
+			return
+		}
+	}
+}
+
+func (c *Client) Stop() {
+	select {
+	case <-c.stopCh:
+	default:
+		close(c.stopCh)
+	}
+}
+
+func (c *Client) GetSummary() *Summary {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.summary
+}
+
+func (c *Client) Available() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.summary != nil
+}
+
+// Health reports the breaker's circuit state for /api/integrations.
+func (c *Client) Health() integration.BreakerStatus {
+	return c.breaker.Status()
+}
+
+func (c *Client) poll() {
+	if !c.breaker.Allow() {
+		return
+	}
+	if err := c.pollOnce(); err != nil {
+		log.Printf("openwrt: %v", err)
+		c.breaker.Failure(err)
+		return
+	}
+	c.breaker.Success()
+}
+
+func (c *Client) pollOnce() error {
+	if c.session == "" {
+		if err := c.login(); err != nil {
+			return fmt.Errorf("login failed: %w", err)
+		}
+	}
+	devices, err := c.fetchDevices()
+	if err != nil {
+		// If the session expired, re-login once and retry
+		log.Printf("openwrt: fetch devices: %v (re-authenticating)", err)
+		c.session = ""
+		if err := c.login(); err != nil {
+			return fmt.Errorf("re-login failed: %w", err)
+		}
+		devices, err = c.fetchDevices()
+		if err != nil {
+			return fmt.Errorf("fetch devices after re-login: %w", err)
+		}
+	}
+
+	now := time.Now()
+	dt := now.Sub(c.lastPoll).Seconds()
+	if c.lastPoll.IsZero() {
+		dt = 0
+	}
+
+	sum := &Summary{Interfaces: c.buildInterfaces(devices, dt)}
+
+	// Leases and stations are best-effort: luci-rpc needs the luci
+	// package and iwinfo needs a radio, neither a given on every build
+	if leases, err := c.fetchLeases(); err == nil {
+		sum.Leases = leases
+	}
+	if stations, err := c.fetchStations(); err == nil {
+		sum.Stations = stations
+	}
+
+	newIf := make(map[string]byteSnap, len(sum.Interfaces))
+	for _, ifc := range sum.Interfaces {
+		newIf[ifc.Name] = byteSnap{tx: ifc.TxBytes, rx: ifc.RxBytes}
+	}
+
+	c.mu.Lock()
+	c.summary = sum
+	c.prevIf = newIf
+	c.lastPoll = now
+	c.mu.Unlock()
+	return nil
+}
+
+// login opens an rpcd session; the returned ID authenticates every
+// subsequent call.
+func (c *Client) login() error {
+	var out struct {
+		Session string `json:"ubus_rpc_session"`
+	}
+	args := map[string]interface{}{
+		"username": c.user,
+		"password": c.pass,
+		"timeout":  300,
+	}
+	if err := c.call(nullSession, "session", "login", args, &out); err != nil {
+		return err
+	}
+	if out.Session == "" {
+		return fmt.Errorf("no session in login reply")
+	}
+	c.session = out.Session
+	return nil
+}
+
+// call performs one ubus JSON-RPC invocation. ubus wraps its own status
+// code inside the JSON-RPC result: [status, data].
+func (c *Client) call(session, object, method string, args, out interface{}) error {
+	if args == nil {
+		args = struct{}{}
+	}
+	c.rpcID++
+	payload, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      c.rpcID,
+		"method":  "call",
+		"params":  [4]interface{}{session, object, method, args},
+	})
+	if err != nil {
+		return err
+	}
+	url := c.baseURL + "/ubus"
+	resp, err := c.httpClient.Post(url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("POST %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var rpc struct {
+		Result []json.RawMessage `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rpc); err != nil {
+		return fmt.Errorf("decode %s %s: %w", object, method, err)
+	}
+	if rpc.Error != nil {
+		return fmt.Errorf("%s %s: rpc error %d: %s", object, method, rpc.Error.Code, rpc.Error.Message)
+	}
+	if len(rpc.Result) == 0 {
+		return fmt.Errorf("%s %s: empty result", object, method)
+	}
+	var status int
+	if err := json.Unmarshal(rpc.Result[0], &status); err != nil {
+		return fmt.Errorf("%s %s: bad status: %w", object, method, err)
+	}
+	if status != 0 {
+		return fmt.Errorf("%s %s: ubus status %d", object, method, status)
+	}
+	if out != nil && len(rpc.Result) > 1 {
+		if err := json.Unmarshal(rpc.Result[1], out); err != nil {
+			return fmt.Errorf("%s %s: decode payload: %w", object, method, err)
+		}
+	}
+	return nil
+}
+
+// rawDevice matches one entry of network.device status.
+type rawDevice struct {
+	Up         bool `json:"up"`
+	Statistics struct {
+		RxBytes  uint64 `json:"rx_bytes"`
+		TxBytes  uint64 `json:"tx_bytes"`
+		RxErrors uint64 `json:"rx_errors"`
+		TxErrors uint64 `json:"tx_errors"`
+	} `json:"statistics"`
+}
+
+func (c *Client) fetchDevices() (map[string]rawDevice, error) {
+	var out map[string]rawDevice
+	if err := c.call(c.session, "network.device", "status", nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *Client) buildInterfaces(devices map[string]rawDevice, dt float64) []InterfaceStat {
+	stats := make([]InterfaceStat, 0, len(devices))
+	for name, d := range devices {
+		if name == "lo" {
+			continue
+		}
+		st := InterfaceStat{
+			Name:     name,
+			Up:       d.Up,
+			RxBytes:  d.Statistics.RxBytes,
+			TxBytes:  d.Statistics.TxBytes,
+			RxErrors: d.Statistics.RxErrors,
+			TxErrors: d.Statistics.TxErrors,
+		}
+		if prev, ok := c.prevIf[name]; ok && dt > 0 {
+			if st.RxBytes >= prev.rx {
+				st.RxRate = float64(st.RxBytes-prev.rx) / dt
+			}
+			if st.TxBytes >= prev.tx {
+				st.TxRate = float64(st.TxBytes-prev.tx) / dt
+			}
+		}
+		stats = append(stats, st)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
+
+func (c *Client) fetchLeases() ([]DHCPLease, error) {
+	var out struct {
+		DHCPLeases  []rawLease `json:"dhcp_leases"`
+		DHCP6Leases []rawLease `json:"dhcp6_leases"`
+	}
+	if err := c.call(c.session, "luci-rpc", "getDHCPLeases", nil, &out); err != nil {
+		return nil, err
+	}
+	leases := make([]DHCPLease, 0, len(out.DHCPLeases)+len(out.DHCP6Leases))
+	for _, l := range append(out.DHCPLeases, out.DHCP6Leases...) {
+		ip := l.IPAddr
+		if ip == "" {
+			ip = l.IP6Addr
+		}
+		if ip == "" {
+			continue
+		}
+		leases = append(leases, DHCPLease{
+			Hostname: l.Hostname,
+			IP:       ip,
+			MAC:      l.MACAddr,
+			Expires:  l.Expires,
+		})
+	}
+	return leases, nil
+}
+
+type rawLease struct {
+	Hostname string `json:"hostname"`
+	IPAddr   string `json:"ipaddr"`
+	IP6Addr  string `json:"ip6addr"`
+	MACAddr  string `json:"macaddr"`
+	Expires  int64  `json:"expires"`
+}
+
+func (c *Client) fetchStations() ([]Station, error) {
+	var devs struct {
+		Devices []string `json:"devices"`
+	}
+	if err := c.call(c.session, "iwinfo", "devices", nil, &devs); err != nil {
+		return nil, err
+	}
+	var stations []Station
+	for _, dev := range devs.Devices {
+		var out struct {
+			Results []struct {
+				MAC    string `json:"mac"`
+				Signal int    `json:"signal"`
+				Noise  int    `json:"noise"`
+				RX     struct {
+					Rate int64 `json:"rate"`
+				} `json:"rx"`
+				TX struct {
+					Rate int64 `json:"rate"`
+				} `json:"tx"`
+			} `json:"results"`
+		}
+		args := map[string]interface{}{"device": dev}
+		if err := c.call(c.session, "iwinfo", "assoclist", args, &out); err != nil {
+			continue // radio without stations answers fine; a vanished one shouldn't kill the rest
+		}
+		for _, r := range out.Results {
+			stations = append(stations, Station{
+				Device: dev,
+				MAC:    r.MAC,
+				Signal: r.Signal,
+				Noise:  r.Noise,
+				RxRate: r.RX.Rate,
+				TxRate: r.TX.Rate,
+			})
+		}
+	}
+	return stations, nil
+}
@@ -0,0 +1,372 @@
+// Package opnsense polls an OPNsense firewall's REST API for WAN
+// interface counters, gateway latency/loss as measured by dpinger, and
+// the pf state-table size. The pfSense REST plugin (pfsense-api v2)
+// speaks a different dialect of the same idea, so a flavor switch maps
+// the handful of endpoints; both authenticate with an API key.
+package opnsense
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"bandwidth-monitor/httpclient"
+	"bandwidth-monitor/integration"
+)
+
+// Flavors understood by the client.
+const (
+	FlavorOPNsense = "opnsense"
+	FlavorPfSense  = "pfsense"
+)
+
+type Client struct {
+	baseURL    string
+	flavor     string
+	key        string // API key (OPNsense: key, pfSense: X-API-Key)
+	secret     string // API secret (OPNsense only, basic-auth password)
+	interval   time.Duration
+	httpClient *http.Client
+	breaker    *integration.Breaker
+
+	mu      sync.RWMutex
+	summary *Summary
+	stopCh  chan struct{}
+
+	// rate tracking
+	lastPoll time.Time
+	prevIf   map[string]byteSnap
+}
+
+type byteSnap struct {
+	tx uint64
+	rx uint64
+}
+
+type InterfaceStat struct {
+	Name    string  `json:"name"`
+	Device  string  `json:"device,omitempty"`
+	RxBytes uint64  `json:"rx_bytes"`
+	TxBytes uint64  `json:"tx_bytes"`
+	RxRate  float64 `json:"rx_rate"` // bytes/sec since last poll
+	TxRate  float64 `json:"tx_rate"`
+}
+
+// GatewayStat is one dpinger-monitored gateway.
+type GatewayStat struct {
+	Name     string  `json:"name"`
+	Address  string  `json:"address,omitempty"`
+	Status   string  `json:"status"`
+	DelayMs  float64 `json:"delay_ms"`
+	StdDevMs float64 `json:"stddev_ms"`
+	LossPct  float64 `json:"loss_pct"`
+}
+
+type Summary struct {
+	Interfaces []InterfaceStat `json:"interfaces"`
+	Gateways   []GatewayStat   `json:"gateways"`
+	// States is the pf state-table size; -1 when the firewall didn't
+	// report it (older releases, pfSense plugin).
+	States int `json:"states"`
+}
+
+// New creates the client. flavor selects the API dialect (default
+// opnsense); secret is unused for pfSense.
+func New(baseURL, flavor, key, secret string, pollInterval time.Duration) *Client {
+	if flavor == "" {
+		flavor = FlavorOPNsense
+	}
+	// Firewalls almost always present self-signed certificates
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		flavor:     flavor,
+		key:        key,
+		secret:     secret,
+		interval:   pollInterval,
+		httpClient: httpclient.NewInsecure(15 * time.Second),
+		breaker:    integration.NewBreaker(flavor),
+		stopCh:     make(chan struct{}),
+	}
+}
+
+func (c *Client) Run() {
+	c.poll()
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.poll()
+		case <-c.stopCh:
+			return
+		}
+	}
+}
+
+func (c *Client) Stop() {
+	select {
+	case <-c.stopCh:
+	default:
+		close(c.stopCh)
+	}
+}
+
+func (c *Client) GetSummary() *Summary {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.summary
+}
+
+func (c *Client) Available() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.summary != nil
+}
+
+// Health reports the breaker's circuit state for /api/integrations.
+func (c *Client) Health() integration.BreakerStatus {
+	return c.breaker.Status()
+}
+
+func (c *Client) poll() {
+	if !c.breaker.Allow() {
+		return
+	}
+	if err := c.pollOnce(); err != nil {
+		log.Printf("%s: %v", c.flavor, err)
+		c.breaker.Failure(err)
+		return
+	}
+	c.breaker.Success()
+}
+
+func (c *Client) pollOnce() error {
+	var (
+		sum *Summary
+		err error
+	)
+	if c.flavor == FlavorPfSense {
+		sum, err = c.pollPfSense()
+	} else {
+		sum, err = c.pollOPNsense()
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	dt := now.Sub(c.lastPoll).Seconds()
+	if !c.lastPoll.IsZero() && dt > 0 {
+		for i := range sum.Interfaces {
+			ifc := &sum.Interfaces[i]
+			if prev, ok := c.prevIf[ifc.Name]; ok {
+				if ifc.RxBytes >= prev.rx {
+					ifc.RxRate = float64(ifc.RxBytes-prev.rx) / dt
+				}
+				if ifc.TxBytes >= prev.tx {
+					ifc.TxRate = float64(ifc.TxBytes-prev.tx) / dt
+				}
+			}
+		}
+	}
+	newIf := make(map[string]byteSnap, len(sum.Interfaces))
+	for _, ifc := range sum.Interfaces {
+		newIf[ifc.Name] = byteSnap{tx: ifc.TxBytes, rx: ifc.RxBytes}
+	}
+
+	c.mu.Lock()
+	c.summary = sum
+	c.prevIf = newIf
+	c.lastPoll = now
+	c.mu.Unlock()
+	return nil
+}
+
+// get fetches one endpoint with the flavor's authentication and decodes
+// the JSON body into out.
+func (c *Client) get(path string, out interface{}) error {
+	url := c.baseURL + path
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	if c.flavor == FlavorPfSense {
+		req.Header.Set("X-API-Key", c.key)
+	} else {
+		req.SetBasicAuth(c.key, c.secret)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("GET %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("GET %s: status %d: %s", path, resp.StatusCode, string(body))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Client) pollOPNsense() (*Summary, error) {
+	sum := &Summary{States: -1}
+
+	// Gateway status is the one endpoint that must work; counters and
+	// pf statistics are best-effort extras
+	var gw struct {
+		Items []struct {
+			Name    string `json:"name"`
+			Address string `json:"address"`
+			Status  string `json:"status_translated"`
+			Loss    string `json:"loss"`
+			Delay   string `json:"delay"`
+			StdDev  string `json:"stddev"`
+		} `json:"items"`
+	}
+	if err := c.get("/api/routes/gateway/status", &gw); err != nil {
+		return nil, err
+	}
+	for _, g := range gw.Items {
+		sum.Gateways = append(sum.Gateways, GatewayStat{
+			Name:     g.Name,
+			Address:  g.Address,
+			Status:   g.Status,
+			DelayMs:  parseUnit(g.Delay),
+			StdDevMs: parseUnit(g.StdDev),
+			LossPct:  parseUnit(g.Loss),
+		})
+	}
+
+	var traffic struct {
+		Interfaces map[string]struct {
+			Device  string      `json:"device"`
+			RxBytes json.Number `json:"bytes received"`
+			TxBytes json.Number `json:"bytes transmitted"`
+		} `json:"interfaces"`
+	}
+	if err := c.get("/api/diagnostics/traffic/interface", &traffic); err == nil {
+		for name, ti := range traffic.Interfaces {
+			rx, _ := strconv.ParseUint(ti.RxBytes.String(), 10, 64)
+			tx, _ := strconv.ParseUint(ti.TxBytes.String(), 10, 64)
+			sum.Interfaces = append(sum.Interfaces, InterfaceStat{
+				Name:    name,
+				Device:  ti.Device,
+				RxBytes: rx,
+				TxBytes: tx,
+			})
+		}
+	}
+
+	// pfctl -si output shape varies between releases; hunt for the
+	// state-table counter instead of pinning a schema
+	var pf json.RawMessage
+	if err := c.get("/api/diagnostics/firewall/pfStatistics", &pf); err == nil {
+		if n, ok := findInt(pf, "current entries", "current-entries"); ok {
+			sum.States = n
+		}
+	}
+
+	sortInterfaces(sum.Interfaces)
+	return sum, nil
+}
+
+func (c *Client) pollPfSense() (*Summary, error) {
+	sum := &Summary{States: -1}
+
+	var gw struct {
+		Data []struct {
+			Name   string  `json:"name"`
+			Status string  `json:"status"`
+			Delay  float64 `json:"delay"`
+			StdDev float64 `json:"stddev"`
+			Loss   float64 `json:"loss"`
+		} `json:"data"`
+	}
+	if err := c.get("/api/v2/status/gateways", &gw); err != nil {
+		return nil, err
+	}
+	for _, g := range gw.Data {
+		sum.Gateways = append(sum.Gateways, GatewayStat{
+			Name:     g.Name,
+			Status:   g.Status,
+			DelayMs:  g.Delay,
+			StdDevMs: g.StdDev,
+			LossPct:  g.Loss,
+		})
+	}
+
+	var ifs struct {
+		Data []struct {
+			Name     string      `json:"name"`
+			Descr    string      `json:"descr"`
+			InBytes  json.Number `json:"inbytes"`
+			OutBytes json.Number `json:"outbytes"`
+		} `json:"data"`
+	}
+	if err := c.get("/api/v2/status/interfaces", &ifs); err == nil {
+		for _, fi := range ifs.Data {
+			name := fi.Descr
+			if name == "" {
+				name = fi.Name
+			}
+			rx, _ := strconv.ParseUint(fi.InBytes.String(), 10, 64)
+			tx, _ := strconv.ParseUint(fi.OutBytes.String(), 10, 64)
+			sum.Interfaces = append(sum.Interfaces, InterfaceStat{
+				Name:    name,
+				Device:  fi.Name,
+				RxBytes: rx,
+				TxBytes: tx,
+			})
+		}
+	}
+
+	sortInterfaces(sum.Interfaces)
+	return sum, nil
+}
+
+func sortInterfaces(ifs []InterfaceStat) {
+	sort.Slice(ifs, func(i, j int) bool { return ifs[i].Name < ifs[j].Name })
+}
+
+// parseUnit parses dpinger's unit-suffixed strings ("12.3 ms",
+// "0.0 %"); unmonitored gateways report "~", which maps to zero.
+func parseUnit(s string) float64 {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		s = s[:i]
+	}
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
+
+// findInt walks arbitrarily nested JSON looking for the first of keys
+// holding a number.
+func findInt(raw json.RawMessage, keys ...string) (int, bool) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return 0, false
+	}
+	for _, k := range keys {
+		if v, ok := obj[k]; ok {
+			var n float64
+			if err := json.Unmarshal(v, &n); err == nil {
+				return int(n), true
+			}
+		}
+	}
+	for _, v := range obj {
+		if n, ok := findInt(v, keys...); ok {
+			return n, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,216 @@
+// Package overrides implements a static hostname/label map for remote
+// IPs and CIDRs (e.g. 203.0.113.10 → "Office VPN endpoint") that takes
+// precedence over reverse DNS in all talker outputs. Entries come from
+// the HOST_OVERRIDES env spec and the /api/overrides CRUD API, the
+// latter persisted to a JSON file.
+package overrides
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Map resolves IPs to configured labels. Exact IPs win over CIDRs;
+// among CIDRs the longest prefix wins.
+type Map struct {
+	path string
+
+	mu    sync.RWMutex
+	exact map[string]string // IP → label
+	cidrs []cidrEntry       // sorted longest-prefix-first
+}
+
+type cidrEntry struct {
+	net   *net.IPNet
+	label string
+}
+
+// Entry is one override as served by the API.
+type Entry struct {
+	Key   string `json:"key"` // IP or CIDR
+	Label string `json:"label"`
+}
+
+// New creates a Map persisted at path ("" disables persistence).
+func New(path string) *Map {
+	m := &Map{path: path, exact: make(map[string]string)}
+	m.load()
+	return m
+}
+
+// LoadSpec merges a "key=label,key=label" spec (keys are IPs or CIDRs),
+// as given in HOST_OVERRIDES.
+func (m *Map) LoadSpec(spec string) error {
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, label, ok := strings.Cut(entry, "=")
+		if !ok || label == "" {
+			return fmt.Errorf("invalid override %q (want key=label)", entry)
+		}
+		if err := m.set(strings.TrimSpace(key), strings.TrimSpace(label)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NameFor returns the label configured for ip, or "". Implements
+// talkers.Namer.
+func (m *Map) NameFor(ip string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if label, ok := m.exact[ip]; ok {
+		return label
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	for _, c := range m.cidrs {
+		if c.net.Contains(parsed) {
+			return c.label
+		}
+	}
+	return ""
+}
+
+// GetAll returns every override sorted by key.
+func (m *Map) GetAll() []Entry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	out := make([]Entry, 0, len(m.exact)+len(m.cidrs))
+	for ip, label := range m.exact {
+		out = append(out, Entry{Key: ip, Label: label})
+	}
+	for _, c := range m.cidrs {
+		out = append(out, Entry{Key: c.net.String(), Label: c.label})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// Set adds or replaces an override and persists the map.
+func (m *Map) Set(key, label string) error {
+	if err := m.set(key, label); err != nil {
+		return err
+	}
+	m.save()
+	return nil
+}
+
+// Delete removes an override and persists the map.
+func (m *Map) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.exact[key]; ok {
+		delete(m.exact, key)
+		m.saveLocked()
+		return nil
+	}
+	for i, c := range m.cidrs {
+		if c.net.String() == key {
+			m.cidrs = append(m.cidrs[:i], m.cidrs[i+1:]...)
+			m.saveLocked()
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown override %q", key)
+}
+
+func (m *Map) set(key, label string) error {
+	if label == "" {
+		return fmt.Errorf("override %q: empty label", key)
+	}
+	if _, ipnet, err := net.ParseCIDR(key); err == nil {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for i, c := range m.cidrs {
+			if c.net.String() == ipnet.String() {
+				m.cidrs[i].label = label
+				return nil
+			}
+		}
+		m.cidrs = append(m.cidrs, cidrEntry{net: ipnet, label: label})
+		sort.Slice(m.cidrs, func(i, j int) bool {
+			oi, _ := m.cidrs[i].net.Mask.Size()
+			oj, _ := m.cidrs[j].net.Mask.Size()
+			return oi > oj
+		})
+		return nil
+	}
+	if net.ParseIP(key) == nil {
+		return fmt.Errorf("override %q: not an IP or CIDR", key)
+	}
+	m.mu.Lock()
+	m.exact[key] = label
+	m.mu.Unlock()
+	return nil
+}
+
+// load reads the persisted overrides, ignoring a missing file.
+func (m *Map) load() {
+	if m.path == "" {
+		return
+	}
+	b, err := os.ReadFile(m.path)
+	if err != nil {
+		return
+	}
+	var entries []Entry
+	if err := json.Unmarshal(b, &entries); err != nil {
+		log.Printf("overrides: parse %s: %v", m.path, err)
+		return
+	}
+	for _, e := range entries {
+		if err := m.set(e.Key, e.Label); err != nil {
+			log.Printf("overrides: %v", err)
+		}
+	}
+	log.Printf("overrides: loaded %d entr(ies) from %s", len(entries), m.path)
+}
+
+func (m *Map) save() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.saveLocked()
+}
+
+// saveLocked persists the map; callers hold m.mu.
+func (m *Map) saveLocked() {
+	if m.path == "" {
+		return
+	}
+	entries := make([]Entry, 0, len(m.exact)+len(m.cidrs))
+	for ip, label := range m.exact {
+		entries = append(entries, Entry{Key: ip, Label: label})
+	}
+	for _, c := range m.cidrs {
+		entries = append(entries, Entry{Key: c.net.String(), Label: c.label})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+
+	b, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return
+	}
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		log.Printf("overrides: write %s: %v", tmp, err)
+		return
+	}
+	if err := os.Rename(tmp, m.path); err != nil {
+		log.Printf("overrides: rename %s: %v", tmp, err)
+	}
+}
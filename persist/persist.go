@@ -0,0 +1,124 @@
+// Package persist snapshots in-memory accounting state (talker buckets,
+// interface history) to a JSON file on an interval and restores it on
+// the next start, so restarting the daemon doesn't wipe the 24-hour
+// view. Sources serialize themselves; the store only shuttles opaque
+// blobs keyed by subsystem name.
+package persist
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+const defaultInterval = 5 * time.Minute
+
+// Source is a subsystem whose state can be snapshotted and restored.
+type Source interface {
+	ExportState() ([]byte, error)
+	ImportState([]byte) error
+}
+
+// Store owns the snapshot file and the save loop.
+type Store struct {
+	path     string
+	interval time.Duration
+
+	mu      sync.Mutex
+	sources map[string]Source
+	loaded  map[string]json.RawMessage
+
+	stopCh chan struct{}
+}
+
+// New creates a Store backed by path, reading any existing snapshot so
+// Attach can restore it.
+func New(path string, interval time.Duration) *Store {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	s := &Store{
+		path:     path,
+		interval: interval,
+		sources:  make(map[string]Source),
+		stopCh:   make(chan struct{}),
+	}
+	if b, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(b, &s.loaded); err != nil {
+			log.Printf("persist: parse %s: %v", path, err)
+		}
+	}
+	return s
+}
+
+// Attach registers src under name and restores its saved state, if any.
+// Call for every subsystem before its Run starts.
+func (s *Store) Attach(name string, src Source) {
+	s.mu.Lock()
+	s.sources[name] = src
+	raw := s.loaded[name]
+	s.mu.Unlock()
+	if raw == nil {
+		return
+	}
+	if err := src.ImportState(raw); err != nil {
+		log.Printf("persist: restore %s: %v", name, err)
+		return
+	}
+	log.Printf("persist: restored %s state from %s", name, s.path)
+}
+
+// Run saves every interval and once more at shutdown. Call in a
+// goroutine.
+func (s *Store) Run() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.save()
+		case <-s.stopCh:
+			s.save()
+			return
+		}
+	}
+}
+
+// Stop terminates the save loop, flushing a final snapshot.
+func (s *Store) Stop() {
+	select {
+	case <-s.stopCh:
+	default:
+		close(s.stopCh)
+	}
+}
+
+// save snapshots every source to the file via temp+rename.
+func (s *Store) save() {
+	s.mu.Lock()
+	snap := make(map[string]json.RawMessage, len(s.sources))
+	for name, src := range s.sources {
+		b, err := src.ExportState()
+		if err != nil {
+			log.Printf("persist: export %s: %v", name, err)
+			continue
+		}
+		snap[name] = b
+	}
+	s.mu.Unlock()
+
+	b, err := json.Marshal(snap)
+	if err != nil {
+		return
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		log.Printf("persist: write %s: %v", tmp, err)
+		return
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		log.Printf("persist: rename %s: %v", tmp, err)
+	}
+}
@@ -0,0 +1,172 @@
+// Package powerdns polls the PowerDNS Recursor REST API and converts
+// its statistics into the shared dns.Summary. Like Knot, the Recursor
+// is a plain resolver with no blocking, so only query totals, cache
+// efficiency, latency and rcode counts are meaningful — all cumulative
+// since resolver start.
+package powerdns
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"bandwidth-monitor/dns"
+	"bandwidth-monitor/httpclient"
+)
+
+// Client polls a PowerDNS Recursor API.
+type Client struct {
+	baseURL    string
+	apiKey     string
+	interval   time.Duration
+	httpClient *http.Client
+
+	mu    sync.RWMutex
+	stats map[string]float64
+
+	stopCh chan struct{}
+}
+
+// statEntry is one element of the statistics array. Value is raw
+// because scalar statistics carry a string while ring/map statistics
+// carry structured data we don't use.
+type statEntry struct {
+	Name  string          `json:"name"`
+	Value json.RawMessage `json:"value"`
+}
+
+// New creates a Recursor API client. baseURL is the webserver address,
+// e.g. http://127.0.0.1:8082.
+func New(baseURL, apiKey string, pollInterval time.Duration) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		interval:   pollInterval,
+		httpClient: httpclient.New(15 * time.Second),
+		stopCh:     make(chan struct{}),
+	}
+}
+
+// Run starts the polling loop. Call in a goroutine.
+func (c *Client) Run() {
+	c.poll()
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.poll()
+		case <-c.stopCh:
+			return
+		}
+	}
+}
+
+// Stop terminates the polling loop.
+func (c *Client) Stop() {
+	select {
+	case <-c.stopCh:
+	default:
+		close(c.stopCh)
+	}
+}
+
+func (c *Client) poll() {
+	url := c.baseURL + "/api/v1/servers/localhost/statistics"
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		log.Printf("powerdns: build request: %v", err)
+		return
+	}
+	req.Header.Set("X-API-Key", c.apiKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		log.Printf("powerdns: fetch statistics: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		log.Printf("powerdns: statistics returned %d: %s", resp.StatusCode, string(body))
+		return
+	}
+
+	var entries []statEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		log.Printf("powerdns: decode statistics: %v", err)
+		return
+	}
+
+	m := make(map[string]float64, len(entries))
+	for _, e := range entries {
+		var s string
+		if err := json.Unmarshal(e.Value, &s); err != nil {
+			continue // ring/map statistic
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			m[e.Name] = f
+		}
+	}
+
+	c.mu.Lock()
+	c.stats = m
+	c.mu.Unlock()
+}
+
+// rcodeMetrics maps the Recursor's per-rcode answer counters to rcode names.
+var rcodeMetrics = map[string]string{
+	"noerror-answers":  "NOERROR",
+	"nxdomain-answers": "NXDOMAIN",
+	"servfail-answers": "SERVFAIL",
+}
+
+// GetSummary returns a frontend-friendly summary, or nil if no data yet.
+func (c *Client) GetSummary() *dns.Summary {
+	c.mu.RLock()
+	m := c.stats
+	c.mu.RUnlock()
+	if m == nil {
+		return nil
+	}
+
+	hits, misses := m["cache-hits"], m["cache-misses"]
+	hitPct := 0.0
+	if hits+misses > 0 {
+		hitPct = hits / (hits + misses) * 100
+	}
+
+	rcodes := make(map[string]int)
+	for metric, name := range rcodeMetrics {
+		if v, ok := m[metric]; ok && v > 0 {
+			rcodes[name] = int(v)
+		}
+	}
+
+	return &dns.Summary{
+		ProviderName:    "PowerDNS Recursor",
+		TotalQueries:    int(m["questions"]),
+		AvgLatencyMs:    m["qa-latency"] / 1000, // reported in microseconds
+		CacheHitPercent: hitPct,
+		Rcodes:          rcodes,
+	}
+}
+
+// Available returns true if the client has fetched data at least once.
+func (c *Client) Available() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.stats != nil
+}
+
+// String returns a debug string.
+func (c *Client) String() string {
+	return fmt.Sprintf("PowerDNS[%s]", c.baseURL)
+}
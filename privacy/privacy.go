@@ -0,0 +1,88 @@
+// Package privacy anonymizes addresses and suppresses hostnames in stored
+// data and API output, for households and offices where monitoring the
+// link is acceptable but tracking individual people is not.
+//
+// Configure is called once at startup before any traffic is recorded;
+// afterwards the package is read-only and safe for concurrent use.
+package privacy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Anonymization modes.
+const (
+	ModeTruncate = "truncate" // zero host bits: v4 → /24, v6 → /48
+	ModeHash     = "hash"     // replace the address with a short digest
+)
+
+var (
+	mode       string
+	subsystems map[string]bool // nil = privacy off, empty = all subsystems
+)
+
+// Configure enables anonymization. subsystemList is a comma-separated
+// subset of "talkers", "dns", "wifi"; empty means every subsystem.
+func Configure(m, subsystemList string) error {
+	switch m {
+	case ModeTruncate, ModeHash:
+	default:
+		return fmt.Errorf("unknown privacy mode %q (want %q or %q)", m, ModeTruncate, ModeHash)
+	}
+	mode = m
+	subsystems = make(map[string]bool)
+	for _, s := range strings.Split(subsystemList, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			subsystems[s] = true
+		}
+	}
+	return nil
+}
+
+// Active reports whether anonymization applies to the given subsystem.
+func Active(subsystem string) bool {
+	if subsystems == nil {
+		return false
+	}
+	return len(subsystems) == 0 || subsystems[subsystem]
+}
+
+// IP anonymizes an address according to the configured mode. Truncation
+// keeps enough of the prefix for GeoIP/ASN attribution to stay useful;
+// hashing keeps nothing but a stable aggregation key.
+func IP(ip string) string {
+	switch mode {
+	case ModeTruncate:
+		parsed := net.ParseIP(ip)
+		if parsed == nil {
+			return ip
+		}
+		if v4 := parsed.To4(); v4 != nil {
+			return v4.Mask(net.CIDRMask(24, 32)).String()
+		}
+		return parsed.Mask(net.CIDRMask(48, 128)).String()
+	case ModeHash:
+		sum := sha256.Sum256([]byte(ip))
+		return "ip-" + hex.EncodeToString(sum[:4])
+	}
+	return ip
+}
+
+// MAC reduces a hardware address to its OUI prefix (vendor survives,
+// identity doesn't), or a digest in hash mode.
+func MAC(mac string) string {
+	switch mode {
+	case ModeTruncate:
+		if len(mac) >= 8 {
+			return mac[:8]
+		}
+	case ModeHash:
+		sum := sha256.Sum256([]byte(mac))
+		return "mac-" + hex.EncodeToString(sum[:4])
+	}
+	return mac
+}
@@ -0,0 +1,331 @@
+// Package prober sends high-frequency ICMP echo probes to configurable
+// targets (gateway, ISP hop, public resolver) and records latency
+// percentiles, jitter and packet loss — bandwidth graphs alone can't show
+// bufferbloat or a degrading line.
+package prober
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	probeTimeout   = 2 * time.Second
+	sampleWindow   = 300 // recent RTT samples kept per target (~5 min at 1s)
+	historyMaxAge  = 24 * time.Hour
+	historyBucket  = 1 * time.Minute
+	lossAlertRatio = 0.5 // log when a target crosses 50% loss in a bucket
+)
+
+// TargetStat is the live probe summary for a single target.
+type TargetStat struct {
+	Target    string  `json:"target"`
+	IP        string  `json:"ip"`
+	Sent      uint64  `json:"sent"`
+	Received  uint64  `json:"received"`
+	LossPct   float64 `json:"loss_pct"`
+	LastMs    float64 `json:"last_ms"`
+	MinMs     float64 `json:"min_ms"`
+	AvgMs     float64 `json:"avg_ms"`
+	MaxMs     float64 `json:"max_ms"`
+	P50Ms     float64 `json:"p50_ms"`
+	P95Ms     float64 `json:"p95_ms"`
+	P99Ms     float64 `json:"p99_ms"`
+	JitterMs  float64 `json:"jitter_ms"` // mean absolute difference of consecutive RTTs
+	Degraded  bool    `json:"degraded"`
+	Timestamp int64   `json:"timestamp"`
+}
+
+// HistoryPoint is one per-minute aggregate for the 24-hour history ring.
+type HistoryPoint struct {
+	Timestamp int64   `json:"t"`
+	P50Ms     float64 `json:"p50"`
+	P95Ms     float64 `json:"p95"`
+	LossPct   float64 `json:"loss"`
+}
+
+// target holds per-target probe state, protected by Prober.mu.
+type target struct {
+	host string
+	addr *net.IPAddr
+
+	sent     uint64
+	received uint64
+	lastRTT  float64
+	samples  []float64 // ring of recent RTTs in ms
+
+	// per-minute accumulators, reset at bucket rotation
+	bucketSent int
+	bucketRecv int
+	bucketRTTs []float64
+
+	history  []HistoryPoint
+	degraded bool
+}
+
+// Prober drives ICMP echo probes against a set of targets.
+// Requires root or CAP_NET_RAW, same as the capture subsystems.
+type Prober struct {
+	interval time.Duration
+
+	mu      sync.RWMutex
+	targets []*target
+
+	stopCh chan struct{}
+}
+
+// New creates a Prober for the given hosts. Hosts that fail to resolve are
+// skipped with a log line; probing starts when Run is called.
+func New(hosts []string, interval time.Duration) *Prober {
+	if interval <= 0 {
+		interval = 1 * time.Second
+	}
+	p := &Prober{
+		interval: interval,
+		stopCh:   make(chan struct{}),
+	}
+	for _, h := range hosts {
+		addr, err := net.ResolveIPAddr("ip", h)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "prober: cannot resolve %s: %v\n", h, err)
+			continue
+		}
+		p.targets = append(p.targets, &target{host: h, addr: addr})
+	}
+	return p
+}
+
+// Run starts one probe loop per target plus the history rotation loop.
+// It blocks until Stop() is called; intended to be launched as a goroutine.
+func (p *Prober) Run() {
+	if len(p.targets) == 0 {
+		return
+	}
+	for i := range p.targets {
+		go p.probeLoop(p.targets[i], i)
+	}
+	go p.rotateLoop()
+	<-p.stopCh
+}
+
+// Stop signals the prober to shut down.
+func (p *Prober) Stop() {
+	select {
+	case <-p.stopCh:
+	default:
+		close(p.stopCh)
+	}
+}
+
+// GetAll returns the current summary for every target.
+func (p *Prober) GetAll() []TargetStat {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	now := time.Now().UnixMilli()
+	out := make([]TargetStat, 0, len(p.targets))
+	for _, t := range p.targets {
+		s := TargetStat{
+			Target:    t.host,
+			IP:        t.addr.IP.String(),
+			Sent:      t.sent,
+			Received:  t.received,
+			LastMs:    t.lastRTT,
+			Degraded:  t.degraded,
+			Timestamp: now,
+		}
+		if t.sent > 0 {
+			s.LossPct = float64(t.sent-t.received) / float64(t.sent) * 100
+		}
+		if len(t.samples) > 0 {
+			sorted := append([]float64(nil), t.samples...)
+			sort.Float64s(sorted)
+			s.MinMs = sorted[0]
+			s.MaxMs = sorted[len(sorted)-1]
+			s.P50Ms = percentile(sorted, 50)
+			s.P95Ms = percentile(sorted, 95)
+			s.P99Ms = percentile(sorted, 99)
+			var sum, jitter float64
+			for i, v := range t.samples {
+				sum += v
+				if i > 0 {
+					jitter += math.Abs(v - t.samples[i-1])
+				}
+			}
+			s.AvgMs = sum / float64(len(t.samples))
+			if len(t.samples) > 1 {
+				s.JitterMs = jitter / float64(len(t.samples)-1)
+			}
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// GetHistory returns the 24-hour per-minute history keyed by target host.
+func (p *Prober) GetHistory() map[string][]HistoryPoint {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	out := make(map[string][]HistoryPoint, len(p.targets))
+	for _, t := range p.targets {
+		cp := make([]HistoryPoint, len(t.history))
+		copy(cp, t.history)
+		out[t.host] = cp
+	}
+	return out
+}
+
+// ---------- internal ----------
+
+// probeLoop sends one echo per interval and records the RTT (or a loss).
+func (p *Prober) probeLoop(t *target, id int) {
+	network, proto := "ip4:icmp", 1
+	var typ icmp.Type = ipv4.ICMPTypeEcho
+	if t.addr.IP.To4() == nil {
+		network, proto = "ip6:ipv6-icmp", 58
+		typ = ipv6.ICMPTypeEchoRequest
+	}
+
+	conn, err := icmp.ListenPacket(network, "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "prober: cannot open ICMP socket for %s: %v\n", t.host, err)
+		fmt.Fprintln(os.Stderr, "prober: latency probing requires root or CAP_NET_RAW")
+		return
+	}
+	defer conn.Close()
+
+	echoID := os.Getpid()&0xffff ^ id
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	seq := 0
+	buf := make([]byte, 1500)
+	for {
+		select {
+		case <-p.stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		seq = (seq + 1) & 0xffff
+		msg := icmp.Message{
+			Type: typ,
+			Body: &icmp.Echo{ID: echoID, Seq: seq, Data: []byte("bandwidth-monitor")},
+		}
+		wire, err := msg.Marshal(nil)
+		if err != nil {
+			continue
+		}
+
+		sentAt := time.Now()
+		if _, err := conn.WriteTo(wire, t.addr); err != nil {
+			continue
+		}
+		p.mu.Lock()
+		t.sent++
+		t.bucketSent++
+		p.mu.Unlock()
+
+		conn.SetReadDeadline(sentAt.Add(probeTimeout))
+		for {
+			n, peer, err := conn.ReadFrom(buf)
+			if err != nil {
+				break // timeout = loss
+			}
+			rm, err := icmp.ParseMessage(proto, buf[:n])
+			if err != nil {
+				continue
+			}
+			echo, ok := rm.Body.(*icmp.Echo)
+			if !ok || echo.ID != echoID || echo.Seq != seq {
+				continue // reply for another probe on the same socket
+			}
+			if ipPeer, ok := peer.(*net.IPAddr); ok && !ipPeer.IP.Equal(t.addr.IP) {
+				continue
+			}
+			rtt := float64(time.Since(sentAt).Microseconds()) / 1000
+			p.mu.Lock()
+			t.received++
+			t.bucketRecv++
+			t.lastRTT = rtt
+			t.samples = append(t.samples, rtt)
+			if len(t.samples) > sampleWindow {
+				t.samples = t.samples[len(t.samples)-sampleWindow:]
+			}
+			t.bucketRTTs = append(t.bucketRTTs, rtt)
+			p.mu.Unlock()
+			break
+		}
+	}
+}
+
+// rotateLoop flushes per-minute accumulators into each target's history
+// ring and prunes entries older than 24 hours.
+func (p *Prober) rotateLoop() {
+	ticker := time.NewTicker(historyBucket)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		p.mu.Lock()
+		for _, t := range p.targets {
+			hp := HistoryPoint{Timestamp: now.UnixMilli()}
+			if t.bucketSent > 0 {
+				hp.LossPct = float64(t.bucketSent-t.bucketRecv) / float64(t.bucketSent) * 100
+			}
+			if len(t.bucketRTTs) > 0 {
+				sort.Float64s(t.bucketRTTs)
+				hp.P50Ms = percentile(t.bucketRTTs, 50)
+				hp.P95Ms = percentile(t.bucketRTTs, 95)
+			}
+			wasDegraded := t.degraded
+			t.degraded = t.bucketSent > 0 &&
+				float64(t.bucketRecv) < float64(t.bucketSent)*(1-lossAlertRatio)
+			if t.degraded && !wasDegraded {
+				fmt.Fprintf(os.Stderr, "prober: %s degraded (%.0f%% loss over last minute)\n",
+					t.host, hp.LossPct)
+			}
+			t.history = append(t.history, hp)
+			cutoff := now.Add(-historyMaxAge).UnixMilli()
+			idx := 0
+			for idx < len(t.history) && t.history[idx].Timestamp < cutoff {
+				idx++
+			}
+			t.history = t.history[idx:]
+			t.bucketSent = 0
+			t.bucketRecv = 0
+			t.bucketRTTs = t.bucketRTTs[:0]
+		}
+		p.mu.Unlock()
+	}
+}
+
+// percentile returns the pth percentile of an already-sorted slice.
+func percentile(sorted []float64, pct float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(math.Ceil(pct/100*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
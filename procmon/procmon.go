@@ -0,0 +1,367 @@
+// Package procmon maps local traffic to the processes that own the
+// sockets — nethogs functionality inside the same dashboard. It pairs a
+// packet capture with the /proc/net socket tables (local port → inode)
+// and /proc/<pid>/fd (inode → pid), so it is only meaningful when the
+// daemon runs on the host whose traffic it sees, not on a router
+// mirroring other machines' packets.
+package procmon
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+	"github.com/gopacket/gopacket/pcap"
+)
+
+const (
+	snapshotLen  int32 = 128
+	capTimeout         = 100 * time.Millisecond
+	refreshEvery       = 5 * time.Second // socket/pid table rebuild
+	rateEvery          = 2 * time.Second
+	idleDrop           = 60 * time.Second // drop processes with no traffic for this long
+)
+
+// ProcStat is the per-process traffic summary.
+type ProcStat struct {
+	PID     int     `json:"pid"`
+	Name    string  `json:"name"`
+	RxBytes uint64  `json:"rx_bytes"`
+	TxBytes uint64  `json:"tx_bytes"`
+	RxRate  float64 `json:"rx_rate"`
+	TxRate  float64 `json:"tx_rate"`
+	Sockets int     `json:"sockets"`
+}
+
+type portKey struct {
+	proto string // "tcp" or "udp"
+	port  uint16
+}
+
+type procAccum struct {
+	name     string
+	rxBytes  uint64
+	txBytes  uint64
+	prevRx   uint64
+	prevTx   uint64
+	rxRate   float64
+	txRate   float64
+	sockets  int
+	lastSeen time.Time
+}
+
+// Monitor attributes captured traffic to local processes.
+type Monitor struct {
+	device    string
+	localNets []*net.IPNet
+
+	mu    sync.RWMutex
+	procs map[int]*procAccum // keyed by pid
+
+	// port table, replaced wholesale by the refresh loop
+	portMu sync.RWMutex
+	ports  map[portKey]int // local port → pid
+	names  map[int]string  // pid → comm
+	counts map[int]int     // pid → socket count
+
+	stopCh chan struct{}
+}
+
+// New creates a Monitor capturing on device. Direction is classified
+// against localNets, same as the collector.
+func New(device string, localNets []*net.IPNet) *Monitor {
+	return &Monitor{
+		device:    device,
+		localNets: localNets,
+		procs:     make(map[int]*procAccum),
+		ports:     make(map[portKey]int),
+		names:     make(map[int]string),
+		stopCh:    make(chan struct{}),
+	}
+}
+
+// Run starts the capture, socket-table refresh and rate loops. Call in
+// a goroutine; requires root (both for pcap and to read other
+// processes' /proc/<pid>/fd).
+func (m *Monitor) Run() {
+	m.refreshPorts()
+	go m.refreshLoop()
+	go m.rateLoop()
+
+	handle, err := pcap.OpenLive(m.device, snapshotLen, false, capTimeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "procmon: cannot open %s: %v\n", m.device, err)
+		<-m.stopCh
+		return
+	}
+	defer handle.Close()
+
+	src := gopacket.NewPacketSource(handle, handle.LinkType())
+	src.NoCopy = true
+	for {
+		select {
+		case <-m.stopCh:
+			return
+		case pkt, ok := <-src.Packets():
+			if !ok {
+				return
+			}
+			m.processPacket(pkt)
+		}
+	}
+}
+
+// Stop terminates all loops.
+func (m *Monitor) Stop() {
+	select {
+	case <-m.stopCh:
+	default:
+		close(m.stopCh)
+	}
+}
+
+// GetAll returns per-process stats sorted by current rate.
+func (m *Monitor) GetAll() []ProcStat {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]ProcStat, 0, len(m.procs))
+	for pid, p := range m.procs {
+		out = append(out, ProcStat{
+			PID:     pid,
+			Name:    p.name,
+			RxBytes: p.rxBytes,
+			TxBytes: p.txBytes,
+			RxRate:  p.rxRate,
+			TxRate:  p.txRate,
+			Sockets: p.sockets,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].RxRate+out[i].TxRate > out[j].RxRate+out[j].TxRate
+	})
+	return out
+}
+
+// ---------- packet path ----------
+
+func (m *Monitor) processPacket(pkt gopacket.Packet) {
+	var srcIP, dstIP net.IP
+	var pktLen uint64
+	if ip4 := pkt.Layer(layers.LayerTypeIPv4); ip4 != nil {
+		ip := ip4.(*layers.IPv4)
+		srcIP, dstIP, pktLen = ip.SrcIP, ip.DstIP, uint64(ip.Length)
+	} else if ip6 := pkt.Layer(layers.LayerTypeIPv6); ip6 != nil {
+		ip := ip6.(*layers.IPv6)
+		srcIP, dstIP, pktLen = ip.SrcIP, ip.DstIP, uint64(ip.Length)+40
+	} else {
+		return
+	}
+
+	var proto string
+	var srcPort, dstPort uint16
+	if tl := pkt.Layer(layers.LayerTypeTCP); tl != nil {
+		t := tl.(*layers.TCP)
+		proto, srcPort, dstPort = "tcp", uint16(t.SrcPort), uint16(t.DstPort)
+	} else if ul := pkt.Layer(layers.LayerTypeUDP); ul != nil {
+		u := ul.(*layers.UDP)
+		proto, srcPort, dstPort = "udp", uint16(u.SrcPort), uint16(u.DstPort)
+	} else {
+		return
+	}
+
+	// The local side's port identifies the socket (and so the process)
+	var localPort uint16
+	var tx bool
+	switch {
+	case m.isLocal(srcIP):
+		localPort, tx = srcPort, true
+	case m.isLocal(dstIP):
+		localPort, tx = dstPort, false
+	default:
+		return
+	}
+
+	m.portMu.RLock()
+	pid, ok := m.ports[portKey{proto, localPort}]
+	name := m.names[pid]
+	sockets := m.counts[pid]
+	m.portMu.RUnlock()
+	if !ok {
+		return // forwarded or short-lived; nothing local owns the port
+	}
+
+	m.mu.Lock()
+	p, exists := m.procs[pid]
+	if !exists {
+		p = &procAccum{name: name}
+		m.procs[pid] = p
+	}
+	p.name = name
+	p.sockets = sockets
+	if tx {
+		p.txBytes += pktLen
+	} else {
+		p.rxBytes += pktLen
+	}
+	p.lastSeen = time.Now()
+	m.mu.Unlock()
+}
+
+func (m *Monitor) isLocal(ip net.IP) bool {
+	for _, n := range m.localNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ---------- rate loop ----------
+
+func (m *Monitor) rateLoop() {
+	ticker := time.NewTicker(rateEvery)
+	defer ticker.Stop()
+	last := time.Now()
+	for {
+		select {
+		case <-m.stopCh:
+			return
+		case now := <-ticker.C:
+			dt := now.Sub(last).Seconds()
+			last = now
+			if dt <= 0 {
+				continue
+			}
+			m.mu.Lock()
+			for pid, p := range m.procs {
+				p.rxRate = float64(p.rxBytes-p.prevRx) / dt
+				p.txRate = float64(p.txBytes-p.prevTx) / dt
+				p.prevRx, p.prevTx = p.rxBytes, p.txBytes
+				if now.Sub(p.lastSeen) > idleDrop {
+					delete(m.procs, pid)
+				}
+			}
+			m.mu.Unlock()
+		}
+	}
+}
+
+// ---------- socket table ----------
+
+func (m *Monitor) refreshLoop() {
+	ticker := time.NewTicker(refreshEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stopCh:
+			return
+		case <-ticker.C:
+			m.refreshPorts()
+		}
+	}
+}
+
+// refreshPorts rebuilds the port → pid mapping: /proc/net tables give
+// local port → socket inode, /proc/<pid>/fd gives inode → pid.
+func (m *Monitor) refreshPorts() {
+	inodes := make(map[uint64]portKey)
+	for _, t := range []struct{ path, proto string }{
+		{"/proc/net/tcp", "tcp"},
+		{"/proc/net/tcp6", "tcp"},
+		{"/proc/net/udp", "udp"},
+		{"/proc/net/udp6", "udp"},
+	} {
+		readSocketTable(t.path, t.proto, inodes)
+	}
+
+	ports := make(map[portKey]int)
+	names := make(map[int]string)
+	counts := make(map[int]int)
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		fds, err := os.ReadDir("/proc/" + e.Name() + "/fd")
+		if err != nil {
+			continue // gone, or not ours to read
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink("/proc/" + e.Name() + "/fd/" + fd.Name())
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			inode, err := strconv.ParseUint(link[8:len(link)-1], 10, 64)
+			if err != nil {
+				continue
+			}
+			key, ok := inodes[inode]
+			if !ok {
+				continue
+			}
+			ports[key] = pid
+			counts[pid]++
+			if _, ok := names[pid]; !ok {
+				names[pid] = commName(pid)
+			}
+		}
+	}
+
+	m.portMu.Lock()
+	m.ports = ports
+	m.names = names
+	m.counts = counts
+	m.portMu.Unlock()
+}
+
+// readSocketTable collects local port → inode from one /proc/net table.
+func readSocketTable(path, proto string, out map[uint64]portKey) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		_, portHex, ok := strings.Cut(fields[1], ":")
+		if !ok {
+			continue
+		}
+		port, err := strconv.ParseUint(portHex, 16, 16)
+		if err != nil {
+			continue
+		}
+		inode, err := strconv.ParseUint(fields[9], 10, 64)
+		if err != nil || inode == 0 {
+			continue
+		}
+		out[inode] = portKey{proto, uint16(port)}
+	}
+}
+
+// commName reads a process's short name.
+func commName(pid int) string {
+	b, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/comm")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
@@ -0,0 +1,311 @@
+// Package quota tracks monthly data-cap usage per interface. Interface
+// counters are sampled periodically and the deltas accumulated into the
+// current billing cycle, which rolls over on a configurable day of the
+// month. Totals persist across restarts in their own small JSON file —
+// losing a month of accounting to a reboot would defeat the point.
+// /api/quota reports used/remaining bytes, a linear projection to cycle
+// end, and alerts fire once per cycle when usage crosses 80/90/100%.
+package quota
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"bandwidth-monitor/collector"
+	"bandwidth-monitor/events"
+)
+
+const sampleEvery = 30 * time.Second
+
+// ParseCaps parses the QUOTA_CAPS syntax: comma-separated iface=size
+// pairs where size takes decimal G/T suffixes (gigabytes/terabytes) or
+// a plain byte count, e.g. "eth0=1.2T" or "wan0=500G,lte0=50G".
+func ParseCaps(spec string) (map[string]uint64, error) {
+	caps := make(map[string]uint64)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		iface, size, ok := strings.Cut(pair, "=")
+		if !ok || iface == "" {
+			return nil, fmt.Errorf("%q: want iface=size", pair)
+		}
+		mult := 1.0
+		switch {
+		case strings.HasSuffix(size, "T"):
+			mult, size = 1e12, strings.TrimSuffix(size, "T")
+		case strings.HasSuffix(size, "G"):
+			mult, size = 1e9, strings.TrimSuffix(size, "G")
+		}
+		v, err := strconv.ParseFloat(size, 64)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("%q: invalid size", pair)
+		}
+		caps[iface] = uint64(v * mult)
+	}
+	if len(caps) == 0 {
+		return nil, fmt.Errorf("no caps configured")
+	}
+	return caps, nil
+}
+
+// thresholds are the cap percentages that trigger one alert each per
+// cycle.
+var thresholds = []float64{80, 90, 100}
+
+// Status is the externally visible state for one capped interface.
+type Status struct {
+	Interface  string  `json:"interface"`
+	CapBytes   uint64  `json:"cap_bytes"`
+	UsedBytes  uint64  `json:"used_bytes"`
+	Remaining  uint64  `json:"remaining_bytes"`
+	UsedPct    float64 `json:"used_pct"`
+	CycleStart int64   `json:"cycle_start"` // unix ms
+	CycleEnd   int64   `json:"cycle_end"`
+	// ProjectedBytes extrapolates the cycle's average rate to cycle
+	// end; ProjectedOverage is how far past the cap that lands (zero
+	// when on track).
+	ProjectedBytes   uint64 `json:"projected_bytes"`
+	ProjectedOverage uint64 `json:"projected_overage"`
+}
+
+// ifaceState is the persisted per-interface accounting.
+type ifaceState struct {
+	Used       uint64    `json:"used"`
+	CycleStart time.Time `json:"cycle_start"`
+	Alerted    []float64 `json:"alerted,omitempty"` // thresholds already fired this cycle
+
+	// last observed cumulative counters, for delta/reset detection;
+	// not meaningful across restarts
+	lastRx, lastTx uint64
+	primed         bool
+}
+
+// Tracker samples interface counters and maintains cycle totals.
+type Tracker struct {
+	path     string
+	caps     map[string]uint64 // iface → cap in bytes
+	cycleDay int               // day of month the cycle starts, 1-28
+	src      *collector.Collector
+	bus      *events.Bus
+
+	mu     sync.Mutex
+	state  map[string]*ifaceState
+	dirty  bool
+	stopCh chan struct{}
+}
+
+// New creates the tracker; caps maps interface names to their cap in
+// bytes and cycleDay is the billing-cycle start day (clamped to 1-28 so
+// every month has one).
+func New(path string, caps map[string]uint64, cycleDay int, src *collector.Collector) *Tracker {
+	if cycleDay < 1 {
+		cycleDay = 1
+	}
+	if cycleDay > 28 {
+		cycleDay = 28
+	}
+	t := &Tracker{
+		path:     path,
+		caps:     caps,
+		cycleDay: cycleDay,
+		src:      src,
+		state:    make(map[string]*ifaceState),
+		stopCh:   make(chan struct{}),
+	}
+	t.load()
+	return t
+}
+
+// SetEventBus attaches the bus that threshold crossings are published
+// to. Call before Run.
+func (t *Tracker) SetEventBus(b *events.Bus) { t.bus = b }
+
+// Run samples immediately and then on every tick, persisting changed
+// totals. Call in a goroutine.
+func (t *Tracker) Run() {
+	t.sample()
+	ticker := time.NewTicker(sampleEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			t.sample()
+			t.save()
+		case <-t.stopCh:
+			t.save()
+			return
+		}
+	}
+}
+
+// Stop terminates the sample loop, persisting one final time.
+func (t *Tracker) Stop() {
+	select {
+	case <-t.stopCh:
+	default:
+		close(t.stopCh)
+	}
+}
+
+// cycleStart returns the start of the billing cycle containing now.
+func (t *Tracker) cycleStart(now time.Time) time.Time {
+	start := time.Date(now.Year(), now.Month(), t.cycleDay, 0, 0, 0, 0, now.Location())
+	if start.After(now) {
+		start = start.AddDate(0, -1, 0)
+	}
+	return start
+}
+
+func (t *Tracker) sample() {
+	now := time.Now()
+	start := t.cycleStart(now)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, st := range t.src.GetAll() {
+		limit, capped := t.caps[st.Name]
+		if !capped {
+			continue
+		}
+		s, ok := t.state[st.Name]
+		if !ok {
+			s = &ifaceState{CycleStart: start}
+			t.state[st.Name] = s
+		}
+		if start.After(s.CycleStart) {
+			// New billing cycle: reset the total and the fired alerts
+			s.Used = 0
+			s.CycleStart = start
+			s.Alerted = nil
+			t.dirty = true
+		}
+		if s.primed {
+			// Counter resets (reboot, driver reload) show as the
+			// cumulative value going backwards; count from zero again
+			if st.RxBytes >= s.lastRx {
+				s.Used += st.RxBytes - s.lastRx
+			} else {
+				s.Used += st.RxBytes
+			}
+			if st.TxBytes >= s.lastTx {
+				s.Used += st.TxBytes - s.lastTx
+			} else {
+				s.Used += st.TxBytes
+			}
+			t.dirty = true
+		}
+		s.lastRx, s.lastTx = st.RxBytes, st.TxBytes
+		s.primed = true
+
+		t.checkThresholds(st.Name, s, limit)
+	}
+}
+
+// checkThresholds fires each crossed threshold once per cycle. Caller
+// holds t.mu.
+func (t *Tracker) checkThresholds(iface string, s *ifaceState, limit uint64) {
+	pct := float64(s.Used) / float64(limit) * 100
+	for _, th := range thresholds {
+		if pct < th {
+			break
+		}
+		fired := false
+		for _, a := range s.Alerted {
+			if a == th {
+				fired = true
+				break
+			}
+		}
+		if fired {
+			continue
+		}
+		s.Alerted = append(s.Alerted, th)
+		t.dirty = true
+		msg := fmt.Sprintf("%s at %.1f%% of its %d GB cap", iface, pct, limit/1e9)
+		log.Printf("quota: %s", msg)
+		t.bus.Publish("quota", fmt.Sprintf("quota_%d", int(th)), iface, msg)
+	}
+}
+
+// GetAll reports every capped interface, sorted by name.
+func (t *Tracker) GetAll() []Status {
+	now := time.Now()
+	t.mu.Lock()
+	out := make([]Status, 0, len(t.state))
+	for iface, s := range t.state {
+		limit := t.caps[iface]
+		if limit == 0 {
+			// Stale persisted entry for an interface no longer capped
+			continue
+		}
+		cycleEnd := s.CycleStart.AddDate(0, 1, 0)
+		st := Status{
+			Interface:  iface,
+			CapBytes:   limit,
+			UsedBytes:  s.Used,
+			UsedPct:    float64(s.Used) / float64(limit) * 100,
+			CycleStart: s.CycleStart.UnixMilli(),
+			CycleEnd:   cycleEnd.UnixMilli(),
+		}
+		if s.Used < limit {
+			st.Remaining = limit - s.Used
+		}
+		elapsed := now.Sub(s.CycleStart)
+		if total := cycleEnd.Sub(s.CycleStart); elapsed > time.Hour && total > 0 {
+			st.ProjectedBytes = uint64(float64(s.Used) * float64(total) / float64(elapsed))
+			if st.ProjectedBytes > limit {
+				st.ProjectedOverage = st.ProjectedBytes - limit
+			}
+		}
+		out = append(out, st)
+	}
+	t.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Interface < out[j].Interface })
+	return out
+}
+
+func (t *Tracker) load() {
+	b, err := os.ReadFile(t.path)
+	if err != nil {
+		return
+	}
+	var saved map[string]*ifaceState
+	if err := json.Unmarshal(b, &saved); err != nil {
+		log.Printf("quota: parse %s: %v", t.path, err)
+		return
+	}
+	t.state = saved
+	log.Printf("quota: loaded totals for %d interface(s) from %s", len(saved), t.path)
+}
+
+// save persists the cycle totals when they changed since the last save.
+func (t *Tracker) save() {
+	t.mu.Lock()
+	if !t.dirty {
+		t.mu.Unlock()
+		return
+	}
+	b, err := json.MarshalIndent(t.state, "", "  ")
+	t.dirty = false
+	t.mu.Unlock()
+	if err != nil {
+		return
+	}
+	tmp := t.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		log.Printf("quota: write %s: %v", tmp, err)
+		return
+	}
+	if err := os.Rename(tmp, t.path); err != nil {
+		log.Printf("quota: rename %s: %v", tmp, err)
+	}
+}
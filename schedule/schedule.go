@@ -0,0 +1,139 @@
+// Package schedule parses recurring daily time windows used to pause
+// packet capture — e.g. during nightly backups that would otherwise
+// dominate every statistic and burn CPU.
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// dayNames maps the accepted three-letter day abbreviations.
+var dayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday,
+	"wed": time.Wednesday, "thu": time.Thursday, "fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+const allDays = 0x7f
+
+// window is one recurring slot: a day-of-week bitmask (Sunday = bit 0)
+// and start/end as minutes since midnight. end <= start means the window
+// crosses midnight into the following day.
+type window struct {
+	days  uint8
+	start int
+	end   int
+}
+
+// Windows is a parsed set of recurring time windows.
+type Windows struct {
+	windows []window
+}
+
+// Parse reads a comma-separated window list. Each entry is
+// "HH:MM-HH:MM", optionally preceded by a day or day range:
+//
+//	22:30-02:00            every night, crossing midnight
+//	Sat 03:00-05:00        Saturdays only
+//	Mon-Fri 01:00-01:30    weekday range
+func Parse(spec string) (*Windows, error) {
+	var ws Windows
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		win := window{days: allDays}
+		fields := strings.Fields(entry)
+		timePart := fields[len(fields)-1]
+		if len(fields) == 2 {
+			days, err := parseDays(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("window %q: %w", entry, err)
+			}
+			win.days = days
+		} else if len(fields) != 1 {
+			return nil, fmt.Errorf("window %q: expected \"[days] HH:MM-HH:MM\"", entry)
+		}
+		from, to, ok := strings.Cut(timePart, "-")
+		if !ok {
+			return nil, fmt.Errorf("window %q: missing \"-\" between times", entry)
+		}
+		var err error
+		if win.start, err = parseClock(from); err != nil {
+			return nil, fmt.Errorf("window %q: %w", entry, err)
+		}
+		if win.end, err = parseClock(to); err != nil {
+			return nil, fmt.Errorf("window %q: %w", entry, err)
+		}
+		ws.windows = append(ws.windows, win)
+	}
+	if len(ws.windows) == 0 {
+		return nil, fmt.Errorf("no windows in %q", spec)
+	}
+	return &ws, nil
+}
+
+// Active reports whether t falls within any window.
+func (ws *Windows) Active(t time.Time) bool {
+	day := t.Weekday()
+	minute := t.Hour()*60 + t.Minute()
+	for _, win := range ws.windows {
+		if win.start < win.end {
+			if win.hasDay(day) && minute >= win.start && minute < win.end {
+				return true
+			}
+			continue
+		}
+		// Crosses midnight: the tail belongs to the day the window started
+		if win.hasDay(day) && minute >= win.start {
+			return true
+		}
+		if win.hasDay((day+6)%7) && minute < win.end {
+			return true
+		}
+	}
+	return false
+}
+
+func (win window) hasDay(d time.Weekday) bool {
+	return win.days&(1<<uint(d)) != 0
+}
+
+// parseDays handles "Sat" and "Mon-Fri" (range wraps, so "Fri-Mon" works).
+func parseDays(s string) (uint8, error) {
+	from, to, isRange := strings.Cut(s, "-")
+	start, ok := dayNames[strings.ToLower(from)]
+	if !ok {
+		return 0, fmt.Errorf("unknown day %q", from)
+	}
+	if !isRange {
+		return 1 << uint(start), nil
+	}
+	end, ok := dayNames[strings.ToLower(to)]
+	if !ok {
+		return 0, fmt.Errorf("unknown day %q", to)
+	}
+	var days uint8
+	for d := start; ; d = (d + 1) % 7 {
+		days |= 1 << uint(d)
+		if d == end {
+			break
+		}
+	}
+	return days, nil
+}
+
+// parseClock converts "HH:MM" to minutes since midnight.
+func parseClock(s string) (int, error) {
+	var h, m int
+	if _, err := fmt.Sscanf(s, "%d:%d", &h, &m); err != nil {
+		return 0, fmt.Errorf("bad time %q (want HH:MM)", s)
+	}
+	if h < 0 || h > 23 || m < 0 || m > 59 {
+		return 0, fmt.Errorf("time %q out of range", s)
+	}
+	return h*60 + m, nil
+}
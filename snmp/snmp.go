@@ -0,0 +1,336 @@
+// Package snmp polls managed switches and routers (ifTable/ifXTable) so
+// devices that can't run the agent still contribute per-port counters,
+// rendered alongside the local interfaces with a device/port hierarchy.
+package snmp
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// ifXTable / ifTable columns walked on every poll.
+const (
+	oidIfName        = ".1.3.6.1.2.1.31.1.1.1.1"
+	oidIfAlias       = ".1.3.6.1.2.1.31.1.1.1.18"
+	oidIfHCInOctets  = ".1.3.6.1.2.1.31.1.1.1.6"
+	oidIfHCOutOctets = ".1.3.6.1.2.1.31.1.1.1.10"
+	oidIfDescr       = ".1.3.6.1.2.1.2.2.1.2"
+	oidIfInOctets    = ".1.3.6.1.2.1.2.2.1.10"
+	oidIfOutOctets   = ".1.3.6.1.2.1.2.2.1.16"
+	oidIfOperStatus  = ".1.3.6.1.2.1.2.2.1.8"
+)
+
+// PortStat mirrors the collector's InterfaceStat shape for one SNMP port.
+type PortStat struct {
+	Index int    `json:"index"`
+	Name  string `json:"name"`
+	Alias string `json:"alias,omitempty"`
+	// DisplayName carries the operator-assigned ifAlias ("WAN - Fiber")
+	// under the same key the local interfaces use, so the UI renders
+	// friendly names uniformly.
+	DisplayName string  `json:"display_name,omitempty"`
+	OperState   string  `json:"oper_state"`
+	RxBytes     uint64  `json:"rx_bytes"`
+	TxBytes     uint64  `json:"tx_bytes"`
+	RxRate      float64 `json:"rx_rate"` // bytes/sec in
+	TxRate      float64 `json:"tx_rate"` // bytes/sec out
+}
+
+// DeviceStat groups the polled ports of one SNMP device.
+type DeviceStat struct {
+	Host      string     `json:"host"`
+	Ports     []PortStat `json:"ports"`
+	Timestamp int64      `json:"timestamp"`
+}
+
+// V3Config carries SNMPv3 USM credentials shared by all v3 targets.
+type V3Config struct {
+	User      string
+	AuthProto string // "MD5" or "SHA"
+	AuthPass  string
+	PrivProto string // "DES" or "AES"
+	PrivPass  string
+}
+
+type device struct {
+	host      string
+	community string // empty = SNMPv3
+
+	ports    []PortStat
+	prev     map[int]byteSnap
+	lastPoll time.Time
+	polledAt int64
+}
+
+type byteSnap struct {
+	rx uint64
+	tx uint64
+}
+
+// Poller polls a set of SNMP devices on a fixed interval.
+type Poller struct {
+	interval time.Duration
+	v3       *V3Config
+
+	mu      sync.RWMutex
+	devices []*device
+
+	stopCh chan struct{}
+}
+
+// New creates a Poller. Each target is "community@host[:port]" for v2c, or
+// a bare "host[:port]" which uses the shared SNMPv3 credentials (if any).
+func New(targets []string, v3 *V3Config, pollInterval time.Duration) *Poller {
+	if pollInterval <= 0 {
+		pollInterval = 30 * time.Second
+	}
+	p := &Poller{
+		interval: pollInterval,
+		v3:       v3,
+		stopCh:   make(chan struct{}),
+	}
+	for _, t := range targets {
+		d := &device{prev: make(map[int]byteSnap)}
+		if at := strings.LastIndex(t, "@"); at >= 0 {
+			d.community = t[:at]
+			d.host = t[at+1:]
+		} else {
+			d.host = t
+			if v3 == nil || v3.User == "" {
+				log.Printf("snmp: target %s has no community and no SNMPv3 credentials — skipped", t)
+				continue
+			}
+		}
+		p.devices = append(p.devices, d)
+	}
+	return p
+}
+
+// Run starts the polling loop. Call in a goroutine.
+func (p *Poller) Run() {
+	if len(p.devices) == 0 {
+		return
+	}
+	p.pollAll()
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.pollAll()
+		case <-p.stopCh:
+			return
+		}
+	}
+}
+
+// Stop terminates the polling loop.
+func (p *Poller) Stop() {
+	select {
+	case <-p.stopCh:
+	default:
+		close(p.stopCh)
+	}
+}
+
+// GetAll returns the latest port stats grouped per device.
+func (p *Poller) GetAll() []DeviceStat {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	out := make([]DeviceStat, 0, len(p.devices))
+	for _, d := range p.devices {
+		ports := make([]PortStat, len(d.ports))
+		copy(ports, d.ports)
+		out = append(out, DeviceStat{Host: d.host, Ports: ports, Timestamp: d.polledAt})
+	}
+	return out
+}
+
+// ---------- internal ----------
+
+func (p *Poller) pollAll() {
+	for _, d := range p.devices {
+		select {
+		case <-p.stopCh:
+			return
+		default:
+		}
+		if err := p.pollDevice(d); err != nil {
+			log.Printf("snmp: poll %s: %v", d.host, err)
+		}
+	}
+}
+
+func (p *Poller) pollDevice(d *device) error {
+	conn, err := p.connect(d)
+	if err != nil {
+		return err
+	}
+	defer conn.Conn.Close()
+
+	names := walkStrings(conn, oidIfName)
+	if len(names) == 0 {
+		names = walkStrings(conn, oidIfDescr)
+	}
+	aliases := walkStrings(conn, oidIfAlias)
+	oper := walkUints(conn, oidIfOperStatus)
+
+	rx := walkUints(conn, oidIfHCInOctets)
+	tx := walkUints(conn, oidIfHCOutOctets)
+	if len(rx) == 0 {
+		// Device without ifXTable — fall back to 32-bit counters
+		rx = walkUints(conn, oidIfInOctets)
+		tx = walkUints(conn, oidIfOutOctets)
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("walk returned no interfaces")
+	}
+
+	now := time.Now()
+	dt := now.Sub(d.lastPoll).Seconds()
+
+	var indexes []int
+	for idx := range names {
+		indexes = append(indexes, idx)
+	}
+	// Stable port order by ifIndex
+	for i := 0; i < len(indexes); i++ {
+		for j := i + 1; j < len(indexes); j++ {
+			if indexes[j] < indexes[i] {
+				indexes[i], indexes[j] = indexes[j], indexes[i]
+			}
+		}
+	}
+
+	ports := make([]PortStat, 0, len(indexes))
+	newPrev := make(map[int]byteSnap, len(indexes))
+	for _, idx := range indexes {
+		ps := PortStat{
+			Index:       idx,
+			Name:        names[idx],
+			Alias:       aliases[idx],
+			DisplayName: aliases[idx],
+			OperState:   operState(oper[idx]),
+			RxBytes:     rx[idx],
+			TxBytes:     tx[idx],
+		}
+		if prev, ok := d.prev[idx]; ok && dt > 0 && !d.lastPoll.IsZero() {
+			if ps.RxBytes >= prev.rx {
+				ps.RxRate = float64(ps.RxBytes-prev.rx) / dt
+			}
+			if ps.TxBytes >= prev.tx {
+				ps.TxRate = float64(ps.TxBytes-prev.tx) / dt
+			}
+		}
+		newPrev[idx] = byteSnap{rx: ps.RxBytes, tx: ps.TxBytes}
+		ports = append(ports, ps)
+	}
+
+	p.mu.Lock()
+	d.ports = ports
+	d.prev = newPrev
+	d.lastPoll = now
+	d.polledAt = now.UnixMilli()
+	p.mu.Unlock()
+	return nil
+}
+
+// connect builds and opens a gosnmp connection for v2c or v3.
+func (p *Poller) connect(d *device) (*gosnmp.GoSNMP, error) {
+	host, port := d.host, uint16(161)
+	if h, pStr, err := net.SplitHostPort(d.host); err == nil {
+		if n, err := strconv.Atoi(pStr); err == nil {
+			host, port = h, uint16(n)
+		}
+	}
+	conn := &gosnmp.GoSNMP{
+		Target:  host,
+		Port:    port,
+		Timeout: 5 * time.Second,
+		Retries: 1,
+	}
+	if d.community != "" {
+		conn.Version = gosnmp.Version2c
+		conn.Community = d.community
+	} else {
+		conn.Version = gosnmp.Version3
+		conn.SecurityModel = gosnmp.UserSecurityModel
+		usm := &gosnmp.UsmSecurityParameters{UserName: p.v3.User}
+		conn.MsgFlags = gosnmp.NoAuthNoPriv
+		if p.v3.AuthPass != "" {
+			conn.MsgFlags = gosnmp.AuthNoPriv
+			usm.AuthenticationPassphrase = p.v3.AuthPass
+			usm.AuthenticationProtocol = gosnmp.SHA
+			if strings.EqualFold(p.v3.AuthProto, "MD5") {
+				usm.AuthenticationProtocol = gosnmp.MD5
+			}
+		}
+		if p.v3.PrivPass != "" {
+			conn.MsgFlags = gosnmp.AuthPriv
+			usm.PrivacyPassphrase = p.v3.PrivPass
+			usm.PrivacyProtocol = gosnmp.AES
+			if strings.EqualFold(p.v3.PrivProto, "DES") {
+				usm.PrivacyProtocol = gosnmp.DES
+			}
+		}
+		conn.SecurityParameters = usm
+	}
+	if err := conn.Connect(); err != nil {
+		return nil, fmt.Errorf("connect: %w", err)
+	}
+	return conn, nil
+}
+
+// walkStrings walks a string-valued column, keyed by ifIndex.
+func walkStrings(conn *gosnmp.GoSNMP, oid string) map[int]string {
+	out := make(map[int]string)
+	conn.BulkWalk(oid, func(pdu gosnmp.SnmpPDU) error {
+		idx := lastIndex(pdu.Name)
+		if b, ok := pdu.Value.([]byte); ok {
+			out[idx] = string(b)
+		} else if s, ok := pdu.Value.(string); ok {
+			out[idx] = s
+		}
+		return nil
+	})
+	return out
+}
+
+// walkUints walks an integer/counter column, keyed by ifIndex.
+func walkUints(conn *gosnmp.GoSNMP, oid string) map[int]uint64 {
+	out := make(map[int]uint64)
+	conn.BulkWalk(oid, func(pdu gosnmp.SnmpPDU) error {
+		out[lastIndex(pdu.Name)] = gosnmp.ToBigInt(pdu.Value).Uint64()
+		return nil
+	})
+	return out
+}
+
+// lastIndex extracts the trailing ifIndex from a column instance OID.
+func lastIndex(oid string) int {
+	dot := strings.LastIndex(oid, ".")
+	if dot < 0 {
+		return 0
+	}
+	n, _ := strconv.Atoi(oid[dot+1:])
+	return n
+}
+
+func operState(v uint64) string {
+	switch v {
+	case 1:
+		return "up"
+	case 2:
+		return "down"
+	default:
+		return "unknown"
+	}
+}
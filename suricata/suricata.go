@@ -0,0 +1,214 @@
+// Package suricata tails a Suricata eve.json file and ingests alert and
+// flow records, correlating IDS alerts with the talkers data (same IPs)
+// so suspicious hosts stand out in the dashboard.
+package suricata
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	maxEvents    = 500
+	pollInterval = 1 * time.Second
+)
+
+// Event is one ingested alert (or notable flow) from eve.json.
+type Event struct {
+	Type        string `json:"type"` // "alert" or "flow"
+	Timestamp   int64  `json:"timestamp"`
+	SrcIP       string `json:"src_ip"`
+	DestIP      string `json:"dest_ip"`
+	SrcPort     int    `json:"src_port,omitempty"`
+	DestPort    int    `json:"dest_port,omitempty"`
+	Proto       string `json:"proto,omitempty"`
+	Signature   string `json:"signature,omitempty"`
+	Category    string `json:"category,omitempty"`
+	Severity    int    `json:"severity,omitempty"`
+	Bytes       uint64 `json:"bytes,omitempty"`
+	KnownTalker bool   `json:"known_talker"` // IP currently appears in the talkers data
+}
+
+// TalkerIndex reports whether an IP is currently tracked as a talker;
+// implemented by talkers.Tracker.
+type TalkerIndex interface {
+	HasTalker(ip string) bool
+}
+
+// eveRecord is the subset of an EVE line we decode.
+type eveRecord struct {
+	Timestamp string `json:"timestamp"`
+	EventType string `json:"event_type"`
+	SrcIP     string `json:"src_ip"`
+	DestIP    string `json:"dest_ip"`
+	SrcPort   int    `json:"src_port"`
+	DestPort  int    `json:"dest_port"`
+	Proto     string `json:"proto"`
+	Alert     struct {
+		Signature string `json:"signature"`
+		Category  string `json:"category"`
+		Severity  int    `json:"severity"`
+	} `json:"alert"`
+	Flow struct {
+		BytesToServer uint64 `json:"bytes_toserver"`
+		BytesToClient uint64 `json:"bytes_toclient"`
+	} `json:"flow"`
+}
+
+// Tailer follows an eve.json file (surviving log rotation) and keeps a
+// bounded ring of recent events.
+type Tailer struct {
+	path    string
+	talkers TalkerIndex
+
+	mu     sync.RWMutex
+	events []Event
+
+	stopCh chan struct{}
+}
+
+// New creates a Tailer for the given eve.json path. talkers may be nil.
+func New(path string, talkers TalkerIndex) *Tailer {
+	return &Tailer{
+		path:    path,
+		talkers: talkers,
+		stopCh:  make(chan struct{}),
+	}
+}
+
+// Run tails the file until Stop() is called. Call in a goroutine.
+func (t *Tailer) Run() {
+	var (
+		f      *os.File
+		reader *bufio.Reader
+		size   int64
+	)
+	defer func() {
+		if f != nil {
+			f.Close()
+		}
+	}()
+
+	open := func(seekEnd bool) bool {
+		var err error
+		f, err = os.Open(t.path)
+		if err != nil {
+			return false
+		}
+		if seekEnd {
+			f.Seek(0, io.SeekEnd)
+		}
+		fi, _ := f.Stat()
+		if fi != nil {
+			size = fi.Size()
+		}
+		reader = bufio.NewReader(f)
+		return true
+	}
+	if !open(true) {
+		log.Printf("suricata: cannot open %s yet — waiting for it to appear", t.path)
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.stopCh:
+			return
+		case <-ticker.C:
+		}
+		if f == nil {
+			if !open(false) {
+				continue
+			}
+		}
+		// Detect truncation / rotation
+		if fi, err := os.Stat(t.path); err != nil || fi.Size() < size {
+			f.Close()
+			f = nil
+			if !open(false) {
+				continue
+			}
+		}
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(line) > 0 {
+				size += int64(len(line))
+				t.ingest(line)
+			}
+			if err != nil {
+				break // EOF — wait for more data
+			}
+		}
+	}
+}
+
+// Stop terminates the tailer.
+func (t *Tailer) Stop() {
+	select {
+	case <-t.stopCh:
+	default:
+		close(t.stopCh)
+	}
+}
+
+// GetEvents returns recent ingested events, newest last.
+func (t *Tailer) GetEvents() []Event {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	cp := make([]Event, len(t.events))
+	copy(cp, t.events)
+	return cp
+}
+
+// ---------- internal ----------
+
+func (t *Tailer) ingest(line []byte) {
+	var rec eveRecord
+	if err := json.Unmarshal(line, &rec); err != nil {
+		return
+	}
+	// Alerts always; flows only when they carried traffic worth noting
+	if rec.EventType != "alert" && rec.EventType != "flow" {
+		return
+	}
+	ev := Event{
+		Type:     rec.EventType,
+		SrcIP:    rec.SrcIP,
+		DestIP:   rec.DestIP,
+		SrcPort:  rec.SrcPort,
+		DestPort: rec.DestPort,
+		Proto:    rec.Proto,
+	}
+	if ts, err := time.Parse("2006-01-02T15:04:05.999999-0700", rec.Timestamp); err == nil {
+		ev.Timestamp = ts.UnixMilli()
+	} else {
+		ev.Timestamp = time.Now().UnixMilli()
+	}
+	switch rec.EventType {
+	case "alert":
+		ev.Signature = rec.Alert.Signature
+		ev.Category = rec.Alert.Category
+		ev.Severity = rec.Alert.Severity
+	case "flow":
+		ev.Bytes = rec.Flow.BytesToServer + rec.Flow.BytesToClient
+		if ev.Bytes == 0 {
+			return
+		}
+	}
+	if t.talkers != nil {
+		ev.KnownTalker = t.talkers.HasTalker(rec.SrcIP) || t.talkers.HasTalker(rec.DestIP)
+	}
+
+	t.mu.Lock()
+	t.events = append(t.events, ev)
+	if len(t.events) > maxEvents {
+		t.events = t.events[len(t.events)-maxEvents:]
+	}
+	t.mu.Unlock()
+}
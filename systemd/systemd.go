@@ -0,0 +1,112 @@
+// Package systemd implements the sd_notify protocol (readiness and
+// watchdog heartbeats) and socket activation over the raw NOTIFY_SOCKET /
+// LISTEN_FDS interfaces, so the daemon can run as Type=notify without
+// pulling in libsystemd.
+package systemd
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// listenFdsStart is the first file descriptor passed by socket activation.
+const listenFdsStart = 3
+
+// Notify sends one sd_notify state message ("READY=1", "STOPPING=1",
+// "WATCHDOG=1", ...). It is a no-op outside systemd (NOTIFY_SOCKET unset).
+func Notify(state string) error {
+	sock := os.Getenv("NOTIFY_SOCKET")
+	if sock == "" {
+		return nil
+	}
+	// An abstract socket address starts with "@" in the env var but "\x00"
+	// on the wire.
+	if sock[0] == '@' {
+		sock = "\x00" + sock[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: sock, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("dial %s: %w", os.Getenv("NOTIFY_SOCKET"), err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return fmt.Errorf("notify %q: %w", state, err)
+	}
+	return nil
+}
+
+// Ready signals startup completion to systemd (Type=notify).
+func Ready() {
+	if err := Notify("READY=1"); err != nil {
+		log.Printf("systemd: %v", err)
+	}
+}
+
+// Stopping signals that shutdown has begun.
+func Stopping() {
+	if err := Notify("STOPPING=1"); err != nil {
+		log.Printf("systemd: %v", err)
+	}
+}
+
+// WatchdogInterval returns the heartbeat interval (half the configured
+// WatchdogSec) and whether the watchdog is armed for this process.
+func WatchdogInterval() (time.Duration, bool) {
+	usec, err := strconv.ParseUint(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec == 0 {
+		return 0, false
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, false
+	}
+	return time.Duration(usec) * time.Microsecond / 2, true
+}
+
+// RunWatchdog sends WATCHDOG=1 heartbeats until stopCh closes. Call in a
+// goroutine; returns immediately when no watchdog is armed.
+func RunWatchdog(stopCh <-chan struct{}) {
+	interval, ok := WatchdogInterval()
+	if !ok {
+		return
+	}
+	log.Printf("systemd: watchdog armed, heartbeat every %s", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := Notify("WATCHDOG=1"); err != nil {
+				log.Printf("systemd: %v", err)
+			}
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// Listener returns the socket-activated listener when systemd passed
+// exactly one (LISTEN_FDS=1), or nil to fall back to a regular listen.
+func Listener() net.Listener {
+	if pid := os.Getenv("LISTEN_PID"); pid != strconv.Itoa(os.Getpid()) {
+		return nil
+	}
+	nfds, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || nfds < 1 {
+		return nil
+	}
+	if nfds > 1 {
+		log.Printf("systemd: %d activated sockets passed, using the first", nfds)
+	}
+	f := os.NewFile(uintptr(listenFdsStart), "LISTEN_FD_3")
+	ln, err := net.FileListener(f)
+	f.Close()
+	if err != nil {
+		log.Printf("systemd: activated socket unusable: %v", err)
+		return nil
+	}
+	return ln
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package talkers
+
+import "errors"
+
+// TPACKET_V3 rings are a Linux kernel feature; other platforms stay on
+// the pcap backend.
+func openAFPacket(device string, snap int32, filter string) (packetSource, error, error) {
+	return nil, nil, errors.New("afpacket backend requires Linux")
+}
@@ -0,0 +1,132 @@
+package talkers
+
+import (
+	"sort"
+	"time"
+)
+
+// Incrementally-maintained window aggregates. The country/ASN
+// breakdowns used to walk every retained bucket and geo-look-up every
+// IP on every request (and every WebSocket tick). Instead the rotation
+// goroutine folds each finished bucket in, subtracts the ones that aged
+// out and regroups the snapshots once per bucket, so serving a
+// breakdown is just handing back a slice. The in-progress bucket joins
+// the totals when it finishes, so breakdowns lag live traffic by at
+// most one bucket.
+
+// hostWindow is one IP's running total across the retained buckets.
+type hostWindow struct {
+	bytes uint64
+	refs  int // retained buckets containing this IP
+}
+
+// updateAggregates applies one rotation: subtract expired buckets, add
+// the freshly finished one, regroup. Runs outside t.mu — retired
+// buckets are never written again.
+func (t *Tracker) updateAggregates(finished *bucket, expired []*bucket) {
+	t.aggMu.Lock()
+	defer t.aggMu.Unlock()
+
+	for _, b := range expired {
+		for ip, acc := range b.hosts {
+			hw, ok := t.aggHosts[ip]
+			if !ok {
+				continue
+			}
+			hw.bytes -= acc.bytes
+			hw.refs--
+			if hw.refs <= 0 {
+				delete(t.aggHosts, ip)
+			}
+		}
+	}
+	if finished != nil {
+		for ip, acc := range finished.hosts {
+			hw, ok := t.aggHosts[ip]
+			if !ok {
+				hw = &hostWindow{}
+				t.aggHosts[ip] = hw
+			}
+			hw.bytes += acc.bytes
+			hw.refs++
+		}
+	}
+	t.rebuildBreakdowns()
+}
+
+// rebuildAggregates recomputes the per-IP window totals from the
+// retained buckets, used after ImportState restores a snapshot.
+func (t *Tracker) rebuildAggregates() {
+	t.mu.RLock()
+	buckets := t.buckets
+	t.mu.RUnlock()
+
+	t.aggMu.Lock()
+	defer t.aggMu.Unlock()
+
+	t.aggHosts = make(map[string]*hostWindow)
+	for _, b := range buckets {
+		for ip, acc := range b.hosts {
+			hw, ok := t.aggHosts[ip]
+			if !ok {
+				hw = &hostWindow{}
+				t.aggHosts[ip] = hw
+			}
+			hw.bytes += acc.bytes
+			hw.refs++
+		}
+	}
+	t.rebuildBreakdowns()
+}
+
+// rebuildBreakdowns regroups the per-IP window totals by country and
+// ASN. Caller holds t.aggMu.
+func (t *Tracker) rebuildBreakdowns() {
+	if t.geoDB == nil || !t.geoDB.Available() {
+		return
+	}
+
+	countries := make(map[string]*CountryStat)
+	asns := make(map[uint]*ASNStat)
+	now := time.Now()
+	for ip, hw := range t.aggHosts {
+		geo := t.geoDB.Lookup(ip)
+		cc, cname := "XX", "Unknown"
+		if geo != nil && geo.Country != "" {
+			cc, cname = geo.Country, geo.CountryName
+		}
+		c, ok := countries[cc]
+		if !ok {
+			c = &CountryStat{Country: cc, CountryName: cname}
+			countries[cc] = c
+		}
+		c.Bytes += hw.bytes
+		c.Connections++
+
+		if geo != nil && geo.ASN != 0 {
+			a, ok := asns[geo.ASN]
+			if !ok {
+				a = &ASNStat{ASN: geo.ASN, ASOrg: geo.ASOrg}
+				asns[geo.ASN] = a
+			}
+			a.Bytes += hw.bytes
+			a.Connections++
+			t.observeSeenASN(geo.ASN, now)
+		}
+	}
+
+	cl := make([]CountryStat, 0, len(countries))
+	for _, c := range countries {
+		cl = append(cl, *c)
+	}
+	sort.Slice(cl, func(i, j int) bool { return cl[i].Bytes > cl[j].Bytes })
+
+	al := make([]ASNStat, 0, len(asns))
+	for _, a := range asns {
+		al = append(al, *a)
+	}
+	sort.Slice(al, func(i, j int) bool { return al[i].Bytes > al[j].Bytes })
+
+	t.aggCountries = cl
+	t.aggASNs = al
+}
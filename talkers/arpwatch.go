@@ -0,0 +1,199 @@
+package talkers
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"bandwidth-monitor/events"
+
+	"github.com/gopacket/gopacket/layers"
+)
+
+// ARP anomaly watching: the capture already sees the segment's ARP
+// traffic, so instead of discarding it an arpwatch-style IP→MAC table is
+// maintained from sender bindings. An IP suddenly claimed by a different
+// MAC is the classic spoofing signature (or a flapping bond, which is
+// worth knowing about too), and a burst of gratuitous ARPs is either an
+// attack priming caches or a misbehaving device. Both are kept in a
+// small anomaly log and published to the event bus.
+
+const (
+	arpTableMax       = 4096
+	arpEntryTTL       = time.Hour // forget bindings not refreshed within this
+	arpStormWindow    = time.Minute
+	arpStormThreshold = 60 // gratuitous ARPs per MAC per window
+	arpAlertCooldown  = 10 * time.Minute
+	arpAnomalyMax     = 200
+)
+
+// ARPAnomaly is one detected ARP irregularity. Kind is "conflict" (an
+// IP changed MACs) or "storm" (gratuitous-ARP burst from one MAC).
+type ARPAnomaly struct {
+	Timestamp int64  `json:"timestamp"` // unix ms
+	Device    string `json:"device"`
+	Kind      string `json:"kind"`
+	IP        string `json:"ip,omitempty"`
+	MAC       string `json:"mac"`
+	PrevMAC   string `json:"prev_mac,omitempty"`
+	Count     int    `json:"count,omitempty"`
+}
+
+// arpEntry is the learned binding for one sender IP.
+type arpEntry struct {
+	mac       string
+	lastSeen  time.Time
+	lastAlert time.Time
+}
+
+// arpStorm counts gratuitous announcements per sender MAC.
+type arpStorm struct {
+	windowStart time.Time
+	count       int
+	lastAlert   time.Time
+}
+
+// arpState lives behind its own mutex — ARP packets never touch the
+// bucket accounting under t.mu.
+type arpState struct {
+	mu      sync.RWMutex
+	table   map[string]*arpEntry
+	storms  map[string]*arpStorm
+	anomaly []ARPAnomaly
+}
+
+// EnableARPWatch turns on ARP anomaly detection (and widens the default
+// capture filter to admit ARP frames). Call before Run.
+func (t *Tracker) EnableARPWatch() {
+	t.watchARP = true
+	t.arp = &arpState{
+		table:  make(map[string]*arpEntry),
+		storms: make(map[string]*arpStorm),
+	}
+}
+
+// inspectARP feeds one captured ARP packet through the table.
+func (t *Tracker) inspectARP(device string, arp *layers.ARP) {
+	// Ethernet/IPv4 ARP only; IPv6 neighbor discovery is ICMPv6
+	if arp.HwAddressSize != 6 || arp.ProtAddressSize != 4 {
+		return
+	}
+	senderMAC := net.HardwareAddr(arp.SourceHwAddress).String()
+	senderIP := net.IP(arp.SourceProtAddress).String()
+	targetIP := net.IP(arp.DstProtAddress).String()
+	now := time.Now()
+
+	st := t.arp
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	// Probes (DAD) carry a zero sender address and bind nothing
+	if senderIP != "0.0.0.0" {
+		if e, ok := st.table[senderIP]; ok {
+			if e.mac != senderMAC {
+				prev := e.mac
+				e.mac = senderMAC
+				if now.Sub(e.lastAlert) >= arpAlertCooldown {
+					e.lastAlert = now
+					st.record(t.bus, ARPAnomaly{
+						Timestamp: now.UnixMilli(),
+						Device:    device,
+						Kind:      "conflict",
+						IP:        senderIP,
+						MAC:       senderMAC,
+						PrevMAC:   prev,
+					})
+				}
+			}
+			e.lastSeen = now
+		} else {
+			if len(st.table) >= arpTableMax {
+				st.pruneTable(now)
+			}
+			if len(st.table) < arpTableMax {
+				st.table[senderIP] = &arpEntry{mac: senderMAC, lastSeen: now}
+			}
+		}
+	}
+
+	// Gratuitous: the sender announces its own binding unprompted
+	if senderIP == targetIP && senderIP != "0.0.0.0" {
+		s, ok := st.storms[senderMAC]
+		if !ok {
+			if len(st.storms) >= arpTableMax {
+				st.pruneStorms(now)
+			}
+			s = &arpStorm{windowStart: now}
+			st.storms[senderMAC] = s
+		}
+		if now.Sub(s.windowStart) > arpStormWindow {
+			s.windowStart = now
+			s.count = 0
+		}
+		s.count++
+		if s.count == arpStormThreshold && now.Sub(s.lastAlert) >= arpAlertCooldown {
+			s.lastAlert = now
+			st.record(t.bus, ARPAnomaly{
+				Timestamp: now.UnixMilli(),
+				Device:    device,
+				Kind:      "storm",
+				IP:        senderIP,
+				MAC:       senderMAC,
+				Count:     s.count,
+			})
+		}
+	}
+}
+
+// record appends one anomaly and publishes it; caller holds st.mu.
+func (st *arpState) record(bus *events.Bus, a ARPAnomaly) {
+	st.anomaly = append(st.anomaly, a)
+	if over := len(st.anomaly) - arpAnomalyMax; over > 0 {
+		st.anomaly = append(st.anomaly[:0], st.anomaly[over:]...)
+	}
+	switch a.Kind {
+	case "conflict":
+		bus.Publish("talkers", "arp_conflict", a.IP,
+			fmt.Sprintf("%s changed from %s to %s", a.IP, a.PrevMAC, a.MAC))
+	case "storm":
+		bus.Publish("talkers", "arp_storm", a.MAC,
+			fmt.Sprintf("%d gratuitous ARPs for %s from %s within %s", a.Count, a.IP, a.MAC, arpStormWindow))
+	}
+}
+
+// pruneTable drops bindings not refreshed within arpEntryTTL; caller
+// holds st.mu.
+func (st *arpState) pruneTable(now time.Time) {
+	cutoff := now.Add(-arpEntryTTL)
+	for ip, e := range st.table {
+		if e.lastSeen.Before(cutoff) {
+			delete(st.table, ip)
+		}
+	}
+}
+
+// pruneStorms drops counters whose window has long passed; caller holds
+// st.mu.
+func (st *arpState) pruneStorms(now time.Time) {
+	for mac, s := range st.storms {
+		if now.Sub(s.windowStart) > arpStormWindow {
+			delete(st.storms, mac)
+		}
+	}
+}
+
+// GetARPAnomalies reports the retained anomalies, most recent first.
+// Empty (not nil) when watching and clean; nil when never enabled.
+func (t *Tracker) GetARPAnomalies() []ARPAnomaly {
+	if t.arp == nil {
+		return nil
+	}
+	t.arp.mu.RLock()
+	defer t.arp.mu.RUnlock()
+	out := make([]ARPAnomaly, 0, len(t.arp.anomaly))
+	for i := len(t.arp.anomaly) - 1; i >= 0; i-- {
+		out = append(out, t.arp.anomaly[i])
+	}
+	return out
+}
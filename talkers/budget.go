@@ -0,0 +1,79 @@
+package talkers
+
+import (
+	"sync"
+	"time"
+)
+
+// Enrichment budget: one token bucket shared by every lookup that costs
+// something — PTR queries against the resolver and uncached MMDB reads.
+// A port scan can surface thousands of fresh IPs in one bucket; without
+// a ceiling those all turn into resolver queries and geo-cache churn at
+// once. Over-budget stats are served with Pending set and pick up their
+// enrichment on a later poll.
+type enrichBudget struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	tokens float64
+	last   time.Time
+}
+
+func newEnrichBudget(perSec int) *enrichBudget {
+	return &enrichBudget{
+		rate:   float64(perSec),
+		tokens: float64(perSec),
+		last:   time.Now(),
+	}
+}
+
+func (b *enrichBudget) refill(now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.rate {
+		b.tokens = b.rate // burst at most one second's worth
+	}
+	b.last = now
+}
+
+// allow takes a token if one is available; used on serve paths that
+// must not block.
+func (b *enrichBudget) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(time.Now())
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// wait blocks until a token is available or stop closes; used by the
+// resolver workers, which have nothing better to do.
+func (b *enrichBudget) wait(stop <-chan struct{}) bool {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.refill(now)
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return true
+		}
+		need := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		select {
+		case <-stop:
+			return false
+		case <-time.After(need):
+		}
+	}
+}
+
+// SetEnrichBudget caps reverse-DNS and uncached geo lookups at perSec
+// across the tracker; non-positive disables the cap. Call before Run.
+func (t *Tracker) SetEnrichBudget(perSec int) {
+	if perSec > 0 {
+		t.budget = newEnrichBudget(perSec)
+	}
+}
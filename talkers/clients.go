@@ -0,0 +1,199 @@
+package talkers
+
+import (
+	"sort"
+	"time"
+)
+
+// ClientStat is one internal device's external traffic over the
+// retained window — the LAN-side view the remote-talker accounting
+// normally throws away.
+type ClientStat struct {
+	IP         string `json:"ip"`
+	Hostname   string `json:"hostname"`
+	TotalBytes uint64 `json:"total_bytes"`
+	RxBytes    uint64 `json:"rx_bytes"`
+	TxBytes    uint64 `json:"tx_bytes"`
+	Packets    uint64 `json:"packets"`
+	// Remotes counts the distinct external hosts this device talked to.
+	Remotes int `json:"remotes"`
+}
+
+// clientAccum accumulates one internal device inside a bucket; remotes
+// is the drill-down dimension, keyed by external address.
+type clientAccum struct {
+	hostAccum
+	remotes map[string]*hostAccum
+}
+
+// EnableClientTracking turns on the (local device, remote host)
+// dimension behind /api/talkers/clients. Costs RAM proportional to the
+// distinct pairs seen per bucket, hence opt-in. Call before Run.
+func (t *Tracker) EnableClientTracking() {
+	t.trackClients = true
+}
+
+// recordClient accounts one internal-to-external packet under the
+// internal side. upload is true when the local device sent it. Caller
+// holds t.mu.
+func (t *Tracker) recordClient(local, remote string, pktLen uint64, upload bool) {
+	acc, ok := t.current.clients[local]
+	if !ok {
+		acc = &clientAccum{remotes: make(map[string]*hostAccum)}
+		t.current.clients[local] = acc
+	}
+	rem, ok := acc.remotes[remote]
+	if !ok {
+		rem = &hostAccum{}
+		acc.remotes[remote] = rem
+	}
+	for _, h := range [2]*hostAccum{&acc.hostAccum, rem} {
+		h.bytes += pktLen
+		h.packets++
+		if upload {
+			h.txBytes += pktLen
+		} else {
+			h.rxBytes += pktLen
+		}
+	}
+}
+
+// TopClients returns the internal devices generating the most external
+// traffic over the retained window, capped at n entries (<=0 returns
+// everything). Nil unless TALKERS_CLIENTS is on.
+func (t *Tracker) TopClients(n int) []ClientStat {
+	if !t.trackClients {
+		return nil
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	type agg struct {
+		hostAccum
+		remotes map[string]struct{}
+	}
+	totals := make(map[string]*agg)
+	add := func(b *bucket) {
+		for ip, acc := range b.clients {
+			a, ok := totals[ip]
+			if !ok {
+				a = &agg{remotes: make(map[string]struct{})}
+				totals[ip] = a
+			}
+			a.bytes += acc.bytes
+			a.rxBytes += acc.rxBytes
+			a.txBytes += acc.txBytes
+			a.packets += acc.packets
+			for r := range acc.remotes {
+				a.remotes[r] = struct{}{}
+			}
+		}
+	}
+	for _, b := range t.buckets {
+		add(b)
+	}
+	if t.current != nil {
+		add(t.current)
+	}
+
+	list := make([]ClientStat, 0, len(totals))
+	for ip, a := range totals {
+		list = append(list, ClientStat{
+			IP:         ip,
+			Hostname:   t.resolveIP(ip),
+			TotalBytes: a.bytes,
+			RxBytes:    a.rxBytes,
+			TxBytes:    a.txBytes,
+			Packets:    a.packets,
+			Remotes:    len(a.remotes),
+		})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].TotalBytes > list[j].TotalBytes
+	})
+	if n > 0 && len(list) > n {
+		list = list[:n]
+	}
+	return list
+}
+
+// ClientUsageSince sums each internal device's external traffic across
+// buckets starting at or after since — the per-device view the daily
+// budget tracker checks against. Nil unless TALKERS_CLIENTS is on.
+func (t *Tracker) ClientUsageSince(since time.Time) map[string]uint64 {
+	if !t.trackClients {
+		return nil
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	totals := make(map[string]uint64)
+	add := func(b *bucket) {
+		if b.timestamp.Before(since) {
+			return
+		}
+		for ip, acc := range b.clients {
+			totals[ip] += acc.bytes
+		}
+	}
+	for _, b := range t.buckets {
+		add(b)
+	}
+	if t.current != nil {
+		add(t.current)
+	}
+	return totals
+}
+
+// ClientRemotes returns the external hosts one internal device talked
+// to, largest first, capped at n entries (<=0 returns everything). The
+// remotes are enriched like any other talker list.
+func (t *Tracker) ClientRemotes(clientIP string, n int) []TalkerStat {
+	if !t.trackClients {
+		return nil
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	totals := make(map[string]*TalkerStat)
+	add := func(b *bucket) {
+		acc, ok := b.clients[clientIP]
+		if !ok {
+			return
+		}
+		for ip, rem := range acc.remotes {
+			if _, ok := totals[ip]; !ok {
+				totals[ip] = &TalkerStat{IP: ip}
+			}
+			totals[ip].TotalBytes += rem.bytes
+			totals[ip].RxBytes += rem.rxBytes
+			totals[ip].TxBytes += rem.txBytes
+			totals[ip].Packets += rem.packets
+		}
+	}
+	for _, b := range t.buckets {
+		add(b)
+	}
+	if t.current != nil {
+		add(t.current)
+	}
+
+	list := make([]TalkerStat, 0, len(totals))
+	for _, s := range totals {
+		t.enrich(s)
+		if info, ok := t.ttls[s.IP]; ok {
+			s.Hops = info.hops
+		}
+		list = append(list, *s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].TotalBytes > list[j].TotalBytes
+	})
+	if n > 0 && len(list) > n {
+		list = list[:n]
+	}
+	return list
+}
@@ -0,0 +1,76 @@
+package talkers
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// When capturing on several interfaces of the same router, each routed
+// packet is seen once per interface and would be counted twice. The
+// deduper remembers recent packet signatures for a short window and
+// drops repeat sightings. IPv4 signatures deliberately exclude the
+// addresses (NAT rewrites them between LAN and WAN) and rely on the IP
+// ID, which NAT preserves; IPv6 has no ID, so the flow label and the
+// addresses stand in — NAT66 setups are rare enough not to design for.
+const (
+	dedupeTTL      = 500 * time.Millisecond
+	dedupeSweepAt  = 8192
+	dedupeMaxEntry = 65536
+)
+
+type dedupe struct {
+	mu   sync.Mutex
+	seen map[uint64]int64 // signature → expiry, unix nanos
+	n    int              // inserts since last sweep
+}
+
+func newDedupe() *dedupe {
+	return &dedupe{seen: make(map[uint64]int64)}
+}
+
+// duplicate reports whether this signature was seen within the window,
+// recording it either way.
+func (d *dedupe) duplicate(sig uint64) bool {
+	now := time.Now().UnixNano()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if deadline, ok := d.seen[sig]; ok && deadline > now {
+		return true
+	}
+	d.seen[sig] = now + int64(dedupeTTL)
+	d.n++
+	if d.n >= dedupeSweepAt || len(d.seen) > dedupeMaxEntry {
+		for k, deadline := range d.seen {
+			if deadline <= now {
+				delete(d.seen, k)
+			}
+		}
+		d.n = 0
+	}
+	return false
+}
+
+// sigV4 builds an IPv4 packet signature from NAT-stable header fields.
+func sigV4(id uint16, length uint64, proto uint8) uint64 {
+	var b [11]byte
+	binary.BigEndian.PutUint16(b[0:], id)
+	binary.BigEndian.PutUint64(b[2:], length)
+	b[10] = proto
+	h := fnv.New64a()
+	h.Write(b[:])
+	return h.Sum64()
+}
+
+// sigV6 builds an IPv6 packet signature from flow label and addresses.
+func sigV6(srcIP, dstIP string, flowLabel uint32, length uint64) uint64 {
+	var b [12]byte
+	binary.BigEndian.PutUint32(b[0:], flowLabel)
+	binary.BigEndian.PutUint64(b[4:], length)
+	h := fnv.New64a()
+	h.Write([]byte(srcIP))
+	h.Write([]byte(dstIP))
+	h.Write(b[:])
+	return h.Sum64()
+}
@@ -0,0 +1,111 @@
+package talkers
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+// DNS-answer sniffing: the capture already sees the LAN's plain-text DNS
+// responses, so A/AAAA answers are parsed on the wire into an IP→domain
+// map. The map labels talkers with the name clients actually asked for
+// ("cdn.netflix.com") instead of whatever the PTR record says, and is
+// served raw at /api/dns/ipmap. Entries live for the answer's TTL (with
+// a floor, since CDNs hand out 30s TTLs). Responses longer than the
+// capture snaplen decode partially or not at all; short answers — the
+// common case — fit.
+
+const (
+	dnsMapMax    = 65536
+	dnsMapMinTTL = 10 * time.Minute
+)
+
+type dnsMapEntry struct {
+	domain  string
+	expires time.Time
+}
+
+// DNSMapping is one learned IP→domain association.
+type DNSMapping struct {
+	IP      string    `json:"ip"`
+	Domain  string    `json:"domain"`
+	Expires time.Time `json:"expires"`
+}
+
+// EnableDNSSniff turns on parsing of captured DNS responses. Call
+// before Run.
+func (t *Tracker) EnableDNSSniff() {
+	t.sniffDNS = true
+	t.dnsMap = make(map[string]dnsMapEntry)
+}
+
+// sniffDNSAnswer records the A/AAAA answers of a captured response.
+func (t *Tracker) sniffDNSAnswer(pkt gopacket.Packet) {
+	l := pkt.Layer(layers.LayerTypeDNS)
+	if l == nil {
+		return
+	}
+	d := l.(*layers.DNS)
+	if !d.QR || len(d.Answers) == 0 {
+		return
+	}
+
+	now := time.Now()
+	t.dnsMapMu.Lock()
+	defer t.dnsMapMu.Unlock()
+	for _, a := range d.Answers {
+		if a.Type != layers.DNSTypeA && a.Type != layers.DNSTypeAAAA {
+			continue
+		}
+		if a.IP == nil || len(a.Name) == 0 {
+			continue
+		}
+		ttl := time.Duration(a.TTL) * time.Second
+		if ttl < dnsMapMinTTL {
+			ttl = dnsMapMinTTL
+		}
+		t.dnsMap[a.IP.String()] = dnsMapEntry{domain: string(a.Name), expires: now.Add(ttl)}
+	}
+
+	// Bound the map: drop expired entries when it gets large. If the LAN
+	// genuinely resolves >64k live IPs the map just stays at the cap.
+	if len(t.dnsMap) > dnsMapMax {
+		for ip, e := range t.dnsMap {
+			if now.After(e.expires) {
+				delete(t.dnsMap, ip)
+			}
+		}
+	}
+}
+
+// sniffedDomain returns the domain last seen resolving to ip, or "".
+func (t *Tracker) sniffedDomain(ip string) string {
+	if !t.sniffDNS {
+		return ""
+	}
+	t.dnsMapMu.RLock()
+	e, ok := t.dnsMap[ip]
+	t.dnsMapMu.RUnlock()
+	if ok && time.Now().Before(e.expires) {
+		return e.domain
+	}
+	return ""
+}
+
+// DNSIPMap returns the live learned mappings, sorted by IP.
+func (t *Tracker) DNSIPMap() []DNSMapping {
+	now := time.Now()
+	t.dnsMapMu.RLock()
+	out := make([]DNSMapping, 0, len(t.dnsMap))
+	for ip, e := range t.dnsMap {
+		if now.After(e.expires) {
+			continue
+		}
+		out = append(out, DNSMapping{IP: ip, Domain: e.domain, Expires: e.expires})
+	}
+	t.dnsMapMu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].IP < out[j].IP })
+	return out
+}
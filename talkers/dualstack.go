@@ -0,0 +1,76 @@
+package talkers
+
+import (
+	"sort"
+	"strings"
+)
+
+// Dual-stack breakdown: group window totals by destination name (SNI,
+// sniffed DNS, inventory or PTR) falling back to the AS organization,
+// and split each group's bytes by IP family. Destinations served over
+// both families show directly whether the network prefers IPv6 —
+// happy-eyeballs failures surface as a big name stuck at 0% v6.
+
+// FamilySplit is one destination's v4/v6 byte split over the retained
+// window.
+type FamilySplit struct {
+	Label      string  `json:"label"` // domain or AS organization
+	V4Bytes    uint64  `json:"v4_bytes"`
+	V6Bytes    uint64  `json:"v6_bytes"`
+	TotalBytes uint64  `json:"total_bytes"`
+	V6Percent  float64 `json:"v6_pct"`
+	// DualStack marks destinations actually seen over both families;
+	// the rest are listed for context but prove nothing about
+	// preference.
+	DualStack bool `json:"dual_stack"`
+}
+
+// GetDualStackBreakdown returns per-destination family splits sorted by
+// total bytes, capped at limit entries (<=0 returns everything). Hosts
+// with neither a name nor an AS organization can't be grouped and are
+// skipped.
+func (t *Tracker) GetDualStackBreakdown(limit int) []FamilySplit {
+	t.aggMu.RLock()
+	totals := make(map[string]uint64, len(t.aggHosts))
+	for ip, hw := range t.aggHosts {
+		totals[ip] = hw.bytes
+	}
+	t.aggMu.RUnlock()
+
+	groups := make(map[string]*FamilySplit)
+	for ip, bytes := range totals {
+		label := t.resolveIP(ip)
+		if label == ip {
+			if geo := t.geoDB.Lookup(ip); geo != nil && geo.ASOrg != "" {
+				label = geo.ASOrg
+			} else {
+				continue
+			}
+		}
+		g, ok := groups[label]
+		if !ok {
+			g = &FamilySplit{Label: label}
+			groups[label] = g
+		}
+		if strings.Contains(ip, ":") {
+			g.V6Bytes += bytes
+		} else {
+			g.V4Bytes += bytes
+		}
+	}
+
+	out := make([]FamilySplit, 0, len(groups))
+	for _, g := range groups {
+		g.TotalBytes = g.V4Bytes + g.V6Bytes
+		if g.TotalBytes > 0 {
+			g.V6Percent = float64(g.V6Bytes) / float64(g.TotalBytes) * 100
+		}
+		g.DualStack = g.V4Bytes > 0 && g.V6Bytes > 0
+		out = append(out, *g)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].TotalBytes > out[j].TotalBytes })
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package talkers
+
+import "errors"
+
+// Socket-filter programs and AF_PACKET are Linux kernel features; other
+// platforms stay on the pcap backend.
+func (t *Tracker) openEBPF(device string) (packetSource, error, error) {
+	return nil, nil, errors.New("ebpf backend requires Linux")
+}
@@ -0,0 +1,229 @@
+package talkers
+
+import (
+	"encoding/binary"
+
+	"golang.org/x/sys/unix"
+)
+
+// The kernel side of the ebpf backend, assembled by hand. Socket-filter
+// programs read the fixed __sk_buff UAPI and use only stable helpers
+// (bpf_skb_load_bytes, the map operations), so the bytecode is portable
+// across kernels without a compiler or CO-RE relocation step.
+//
+// The program parses Ethernet/IPv4/IPv6 headers into a flowKey on the
+// stack, then atomically bumps the flow's byte and packet counters in
+// the hash map (inserting on first sight) and returns 0 so the packet
+// is never queued to the socket.
+
+// eBPF helper function numbers (uapi/linux/bpf.h).
+const (
+	hlpMapLookupElem = 1
+	hlpMapUpdateElem = 2
+	hlpSkbLoadBytes  = 26
+)
+
+// Opcodes used below, composed from the instruction-class constants.
+const (
+	opMov64Imm = unix.BPF_ALU64 | unix.BPF_MOV | unix.BPF_K
+	opMov64Reg = unix.BPF_ALU64 | unix.BPF_MOV | unix.BPF_X
+	opAdd64Imm = unix.BPF_ALU64 | unix.BPF_ADD | unix.BPF_K
+	opAnd64Imm = unix.BPF_ALU64 | unix.BPF_AND | unix.BPF_K
+	opLsh64Imm = unix.BPF_ALU64 | unix.BPF_LSH | unix.BPF_K
+	opLdxB     = unix.BPF_LDX | unix.BPF_MEM | unix.BPF_B
+	opLdxH     = unix.BPF_LDX | unix.BPF_MEM | unix.BPF_H
+	opLdxW     = unix.BPF_LDX | unix.BPF_MEM | unix.BPF_W
+	opLdxDW    = unix.BPF_LDX | unix.BPF_MEM | unix.BPF_DW
+	opStB      = unix.BPF_ST | unix.BPF_MEM | unix.BPF_B
+	opStW      = unix.BPF_ST | unix.BPF_MEM | unix.BPF_W
+	opStDW     = unix.BPF_ST | unix.BPF_MEM | unix.BPF_DW
+	opStxB     = unix.BPF_STX | unix.BPF_MEM | unix.BPF_B
+	opStxH     = unix.BPF_STX | unix.BPF_MEM | unix.BPF_H
+	opStxW     = unix.BPF_STX | unix.BPF_MEM | unix.BPF_W
+	opStxDW    = unix.BPF_STX | unix.BPF_MEM | unix.BPF_DW
+	opXaddDW   = unix.BPF_STX | unix.BPF_XADD | unix.BPF_DW
+	opJeqImm   = unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K
+	opJneImm   = unix.BPF_JMP | unix.BPF_JNE | unix.BPF_K
+	opJa       = unix.BPF_JMP | unix.BPF_JA
+	opCall     = unix.BPF_JMP | unix.BPF_CALL
+	opExit     = unix.BPF_JMP | unix.BPF_EXIT
+)
+
+// ebpfAsm is a minimal assembler: instructions append to buf, forward
+// jumps name a label and are patched in finish.
+type ebpfAsm struct {
+	buf    []byte
+	labels map[string]int
+	fixups []ebpfFixup
+}
+
+type ebpfFixup struct {
+	insn  int
+	label string
+}
+
+func newEBPFAsm() *ebpfAsm {
+	return &ebpfAsm{labels: make(map[string]int)}
+}
+
+func (a *ebpfAsm) count() int { return len(a.buf) / 8 }
+
+func (a *ebpfAsm) ins(op, dst, src int, off int16, imm int32) {
+	var b [8]byte
+	b[0] = byte(op)
+	b[1] = byte(dst) | byte(src)<<4
+	binary.LittleEndian.PutUint16(b[2:], uint16(off))
+	binary.LittleEndian.PutUint32(b[4:], uint32(imm))
+	a.buf = append(a.buf, b[:]...)
+}
+
+func (a *ebpfAsm) label(name string) { a.labels[name] = a.count() }
+
+// jump emits a conditional or unconditional jump to a label; the
+// offset is resolved in finish.
+func (a *ebpfAsm) jump(op, dst int, imm int32, label string) {
+	a.fixups = append(a.fixups, ebpfFixup{insn: a.count(), label: label})
+	a.ins(op, dst, 0, 0, imm)
+}
+
+// loadMapFd emits the two-slot BPF_LD_IMM64 that the kernel rewrites
+// into a map pointer at load time.
+func (a *ebpfAsm) loadMapFd(dst, fd int) {
+	a.ins(unix.BPF_LD|unix.BPF_IMM|unix.BPF_DW, dst, unix.BPF_PSEUDO_MAP_FD, 0, int32(fd))
+	a.ins(0, 0, 0, 0, 0)
+}
+
+func (a *ebpfAsm) finish() []byte {
+	for _, f := range a.fixups {
+		off := a.labels[f.label] - f.insn - 1
+		binary.LittleEndian.PutUint16(a.buf[f.insn*8+2:], uint16(int16(off)))
+	}
+	return a.buf
+}
+
+// Stack frame (offsets from r10):
+//
+//	-40..-1    flowKey (family, proto, port, pad, saddr, daddr)
+//	-56..-41   flowVal template for inserts
+//	-60..-57   scratch (ethertype, then L4 ports)
+//	-104..-65  scratch for the IP header (20 or 40 bytes)
+//
+// r6 holds the skb, r7 skb->len, r8 a data scratch, r9 the L4 offset.
+func ebpfAssemble(mapFd int) []byte {
+	a := newEBPFAsm()
+
+	a.ins(opMov64Reg, 6, 1, 0, 0) // r6 = skb
+	a.ins(opLdxW, 7, 6, 0, 0)     // r7 = skb->len (__sk_buff offset 0)
+
+	// Zero the key so map lookups see fully initialized stack
+	for off := int16(-40); off < 0; off += 8 {
+		a.ins(opStDW, 10, 0, off, 0)
+	}
+
+	// Ethertype from the MAC header (packet sockets see the full frame)
+	a.ins(opMov64Reg, 1, 6, 0, 0)
+	a.ins(opMov64Imm, 2, 0, 0, 12)
+	a.ins(opMov64Reg, 3, 10, 0, 0)
+	a.ins(opAdd64Imm, 3, 0, 0, -60)
+	a.ins(opMov64Imm, 4, 0, 0, 2)
+	a.ins(opCall, 0, 0, 0, hlpSkbLoadBytes)
+	a.jump(opJneImm, 0, 0, "out")
+	a.ins(opLdxH, 8, 10, -60, 0)
+	// Ethertypes are big-endian on the wire and loaded host-endian
+	a.jump(opJeqImm, 8, 0x0008, "v4") // ETH_P_IP
+	a.jump(opJeqImm, 8, 0xdd86, "v6") // ETH_P_IPV6
+	a.jump(opJa, 0, 0, "out")
+
+	// IPv4: fixed 20-byte header part at frame offset 14
+	a.label("v4")
+	a.ins(opMov64Reg, 1, 6, 0, 0)
+	a.ins(opMov64Imm, 2, 0, 0, 14)
+	a.ins(opMov64Reg, 3, 10, 0, 0)
+	a.ins(opAdd64Imm, 3, 0, 0, -104)
+	a.ins(opMov64Imm, 4, 0, 0, 20)
+	a.ins(opCall, 0, 0, 0, hlpSkbLoadBytes)
+	a.jump(opJneImm, 0, 0, "out")
+	a.ins(opStB, 10, 0, -40, 4)   // key.family = 4
+	a.ins(opLdxB, 8, 10, -95, 0)  // protocol (hdr+9)
+	a.ins(opStxB, 10, 8, -39, 0)  // key.proto
+	a.ins(opLdxW, 8, 10, -92, 0)  // saddr (hdr+12)
+	a.ins(opStxW, 10, 8, -32, 0)  // key.saddr[0:4]
+	a.ins(opLdxW, 8, 10, -88, 0)  // daddr (hdr+16)
+	a.ins(opStxW, 10, 8, -16, 0)  // key.daddr[0:4]
+	a.ins(opLdxB, 9, 10, -104, 0) // version/IHL byte
+	a.ins(opAnd64Imm, 9, 0, 0, 0x0f)
+	a.ins(opLsh64Imm, 9, 0, 0, 2)
+	a.ins(opAdd64Imm, 9, 0, 0, 14) // r9 = L4 offset
+	a.jump(opJa, 0, 0, "l4")
+
+	// IPv6: fixed 40-byte header at frame offset 14 (extension-header
+	// chains keep the next-header value as the flow's protocol)
+	a.label("v6")
+	a.ins(opMov64Reg, 1, 6, 0, 0)
+	a.ins(opMov64Imm, 2, 0, 0, 14)
+	a.ins(opMov64Reg, 3, 10, 0, 0)
+	a.ins(opAdd64Imm, 3, 0, 0, -104)
+	a.ins(opMov64Imm, 4, 0, 0, 40)
+	a.ins(opCall, 0, 0, 0, hlpSkbLoadBytes)
+	a.jump(opJneImm, 0, 0, "out")
+	a.ins(opStB, 10, 0, -40, 6)   // key.family = 6
+	a.ins(opLdxB, 8, 10, -98, 0)  // next header (hdr+6)
+	a.ins(opStxB, 10, 8, -39, 0)  // key.proto
+	a.ins(opLdxDW, 8, 10, -96, 0) // saddr (hdr+8)
+	a.ins(opStxDW, 10, 8, -32, 0)
+	a.ins(opLdxDW, 8, 10, -88, 0)
+	a.ins(opStxDW, 10, 8, -24, 0)
+	a.ins(opLdxDW, 8, 10, -80, 0) // daddr (hdr+24)
+	a.ins(opStxDW, 10, 8, -16, 0)
+	a.ins(opLdxDW, 8, 10, -72, 0)
+	a.ins(opStxDW, 10, 8, -8, 0)
+	a.ins(opMov64Imm, 9, 0, 0, 54) // r9 = L4 offset (14 + 40)
+
+	// Destination port for TCP/UDP; other protocols stay portless
+	a.label("l4")
+	a.ins(opLdxB, 8, 10, -39, 0)
+	a.jump(opJeqImm, 8, 6, "ports")  // TCP
+	a.jump(opJeqImm, 8, 17, "ports") // UDP
+	a.jump(opJa, 0, 0, "update")
+
+	a.label("ports")
+	a.ins(opMov64Reg, 1, 6, 0, 0)
+	a.ins(opMov64Reg, 2, 9, 0, 0)
+	a.ins(opMov64Reg, 3, 10, 0, 0)
+	a.ins(opAdd64Imm, 3, 0, 0, -60)
+	a.ins(opMov64Imm, 4, 0, 0, 4)
+	a.ins(opCall, 0, 0, 0, hlpSkbLoadBytes)
+	a.jump(opJneImm, 0, 0, "update") // truncated transport header
+	a.ins(opLdxH, 8, 10, -58, 0)     // dst port, network order
+	a.ins(opStxH, 10, 8, -38, 0)     // key.port
+
+	// Bump the flow's counters, inserting it on first sight
+	a.label("update")
+	a.loadMapFd(1, mapFd)
+	a.ins(opMov64Reg, 2, 10, 0, 0)
+	a.ins(opAdd64Imm, 2, 0, 0, -40)
+	a.ins(opCall, 0, 0, 0, hlpMapLookupElem)
+	a.jump(opJeqImm, 0, 0, "insert")
+	a.ins(opXaddDW, 0, 7, 0, 0) // bytes += skb->len
+	a.ins(opMov64Imm, 8, 0, 0, 1)
+	a.ins(opXaddDW, 0, 8, 8, 0) // packets++
+	a.jump(opJa, 0, 0, "out")
+
+	a.label("insert")
+	a.ins(opStxDW, 10, 7, -56, 0) // value.bytes = skb->len
+	a.ins(opStDW, 10, 0, -48, 1)  // value.packets = 1
+	a.loadMapFd(1, mapFd)
+	a.ins(opMov64Reg, 2, 10, 0, 0)
+	a.ins(opAdd64Imm, 2, 0, 0, -40)
+	a.ins(opMov64Reg, 3, 10, 0, 0)
+	a.ins(opAdd64Imm, 3, 0, 0, -56)
+	a.ins(opMov64Imm, 4, 0, 0, unix.BPF_ANY)
+	a.ins(opCall, 0, 0, 0, hlpMapUpdateElem)
+
+	// Return 0: the packet is counted, never queued to the socket
+	a.label("out")
+	a.ins(opMov64Imm, 0, 0, 0, 0)
+	a.ins(opExit, 0, 0, 0, 0)
+
+	return a.finish()
+}
@@ -0,0 +1,96 @@
+package talkers
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Enricher is one stage of the talker enrichment pipeline. Stages run
+// in order over each TalkerStat as it is served; a stage should fill
+// only fields that are still empty so earlier stages win. Custom stages
+// can be attached with AddEnricher without touching the tracker core.
+type Enricher interface {
+	// Name identifies the stage in the ENRICHERS config list.
+	Name() string
+	Enrich(s *TalkerStat)
+}
+
+// nameEnricher fills Hostname from the configured namers, falling back
+// to reverse DNS.
+type nameEnricher struct{ t *Tracker }
+
+func (e nameEnricher) Name() string { return "names" }
+func (e nameEnricher) Enrich(s *TalkerStat) {
+	if s.Hostname == "" {
+		name, pending := e.t.resolveState(s.IP)
+		s.Hostname = name
+		if pending {
+			s.Pending = true
+		}
+	}
+}
+
+// geoEnricher fills the country and ASN fields from the MMDBs.
+type geoEnricher struct{ t *Tracker }
+
+func (e geoEnricher) Name() string { return "geo" }
+func (e geoEnricher) Enrich(s *TalkerStat) {
+	if s.Country == "" && s.ASN == 0 {
+		e.t.enrichGeo(s)
+	}
+}
+
+// builtinEnricher maps a config name to its stage, or nil.
+func (t *Tracker) builtinEnricher(name string) Enricher {
+	switch name {
+	case "names":
+		return nameEnricher{t}
+	case "geo":
+		return geoEnricher{t}
+	}
+	return nil
+}
+
+// defaultEnrichers is the pipeline used when ENRICHERS is unset,
+// matching the tracker's historical behavior.
+func (t *Tracker) defaultEnrichers() []Enricher {
+	return []Enricher{nameEnricher{t}, geoEnricher{t}}
+}
+
+// SetEnrichers replaces the pipeline with the named built-in stages in
+// the given order. Call before Run.
+func (t *Tracker) SetEnrichers(spec string) error {
+	var pipeline []Enricher
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		e := t.builtinEnricher(name)
+		if e == nil {
+			return fmt.Errorf("unknown enricher %q", name)
+		}
+		pipeline = append(pipeline, e)
+	}
+	t.enrichers = pipeline
+	return nil
+}
+
+// AddEnricher appends a custom stage to the pipeline. Call before Run.
+func (t *Tracker) AddEnricher(e Enricher) {
+	if t.enrichers == nil {
+		t.enrichers = t.defaultEnrichers()
+	}
+	t.enrichers = append(t.enrichers, e)
+}
+
+// enrich runs the pipeline over one stat.
+func (t *Tracker) enrich(s *TalkerStat) {
+	pipeline := t.enrichers
+	if pipeline == nil {
+		pipeline = t.defaultEnrichers()
+	}
+	for _, e := range pipeline {
+		e.Enrich(s)
+	}
+}
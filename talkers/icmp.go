@@ -0,0 +1,106 @@
+package talkers
+
+import (
+	"sort"
+	"time"
+)
+
+// ICMP visibility: instead of one opaque "ICMP" slice in the protocol
+// breakdown, captured ICMP is bucketed by the types worth separating
+// (echo, unreachable, redirect, time-exceeded), and every ICMP packet
+// is also accounted per source so a ping flood or a misconfigured
+// gateway spraying redirects stands out at /api/talkers/icmp.
+
+// icmpV4Name buckets an ICMPv4 type into a protocol-breakdown label.
+func icmpV4Name(t uint8) string {
+	switch t {
+	case 0, 8:
+		return "ICMP Echo"
+	case 3:
+		return "ICMP Unreachable"
+	case 5:
+		return "ICMP Redirect"
+	case 11:
+		return "ICMP Time Exceeded"
+	default:
+		return "ICMP Other"
+	}
+}
+
+// icmpV6Name does the same for ICMPv6; NDP gets its own label so
+// ordinary neighbour chatter doesn't drown the interesting types.
+func icmpV6Name(t uint8) string {
+	switch t {
+	case 128, 129:
+		return "ICMP Echo"
+	case 1:
+		return "ICMP Unreachable"
+	case 2:
+		return "ICMP Too Big"
+	case 3:
+		return "ICMP Time Exceeded"
+	case 137:
+		return "ICMP Redirect"
+	case 133, 134, 135, 136:
+		return "ICMP NDP"
+	default:
+		return "ICMP Other"
+	}
+}
+
+// ICMPSource is one host's ICMP traffic over the retained window.
+type ICMPSource struct {
+	IP       string `json:"ip"`
+	Hostname string `json:"hostname"`
+	Bytes    uint64 `json:"bytes"`
+	Packets  uint64 `json:"packets"`
+	// RatePPS is the packet rate over the in-progress bucket — the
+	// number that makes a flood obvious.
+	RatePPS float64 `json:"rate_pps"`
+}
+
+// ICMPSources returns the hosts originating the most ICMP, capped at n
+// entries (<=0 returns everything). Sorted by window packet count.
+func (t *Tracker) ICMPSources(n int) []ICMPSource {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	totals := make(map[string]*ICMPSource)
+	add := func(b *bucket) {
+		for ip, acc := range b.icmpSrc {
+			s, ok := totals[ip]
+			if !ok {
+				s = &ICMPSource{IP: ip}
+				totals[ip] = s
+			}
+			s.Bytes += acc.bytes
+			s.Packets += acc.packets
+		}
+	}
+	for _, b := range t.buckets {
+		add(b)
+	}
+	if t.current != nil {
+		add(t.current)
+		elapsed := time.Since(t.current.timestamp).Seconds()
+		if elapsed < 1 {
+			elapsed = 1
+		}
+		for ip, acc := range t.current.icmpSrc {
+			totals[ip].RatePPS = float64(acc.packets) / elapsed
+		}
+	}
+
+	list := make([]ICMPSource, 0, len(totals))
+	for _, s := range totals {
+		s.Hostname = t.resolveIP(s.IP)
+		list = append(list, *s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Packets > list[j].Packets
+	})
+	if n > 0 && len(list) > n {
+		list = list[:n]
+	}
+	return list
+}
@@ -0,0 +1,95 @@
+package talkers
+
+import (
+	"net"
+	"sort"
+	"time"
+)
+
+// VPN kill-switch verification: devices or subnets that are supposed to
+// route through a tunnel must never show up in cleartext on the raw WAN
+// interface — if they do, the kill switch failed and traffic is
+// leaking. The check rides the existing per-device capture attribution:
+// every packet accounted from the WAN device is matched against the
+// protected networks.
+
+// LeakStat is one protected local address seen egressing the raw WAN.
+type LeakStat struct {
+	IP        string `json:"ip"`
+	Bytes     uint64 `json:"bytes"`
+	Packets   uint64 `json:"packets"`
+	FirstSeen int64  `json:"first_seen"` // unix ms
+	LastSeen  int64  `json:"last_seen"`
+}
+
+// leakInfo is the internal accumulator behind LeakStat.
+type leakInfo struct {
+	bytes   uint64
+	packets uint64
+	first   time.Time
+	last    time.Time
+}
+
+// ConfigureLeakCheck arms the kill-switch verification: traffic from
+// nets observed on wanDevice is recorded as a leak (and published to
+// the event bus on first detection per address). Call before Run.
+func (t *Tracker) ConfigureLeakCheck(wanDevice string, nets []*net.IPNet) {
+	t.leakWAN = wanDevice
+	t.leakNets = nets
+	t.leaks = make(map[string]*leakInfo)
+}
+
+// checkLeak matches one accounted packet against the protected
+// networks. Caller holds t.mu; device is the capture interface that saw
+// the packet.
+func (t *Tracker) checkLeak(device, srcIP, dstIP string, pktLen uint64) {
+	if t.leakWAN == "" || device != t.leakWAN {
+		return
+	}
+	for _, ipStr := range [2]string{srcIP, dstIP} {
+		ip := net.ParseIP(ipStr)
+		if ip == nil {
+			continue
+		}
+		for _, n := range t.leakNets {
+			if !n.Contains(ip) {
+				continue
+			}
+			now := time.Now()
+			info, ok := t.leaks[ipStr]
+			if !ok {
+				info = &leakInfo{first: now}
+				t.leaks[ipStr] = info
+				t.bus.Publish("talkers", "vpn_leak", ipStr,
+					"protected address "+ipStr+" seen in cleartext on "+device)
+			}
+			info.bytes += pktLen
+			info.packets++
+			info.last = now
+			break
+		}
+	}
+}
+
+// GetLeaks reports every protected address caught leaking, most recent
+// first. Empty (not nil) when the check is armed and clean; nil when it
+// was never configured.
+func (t *Tracker) GetLeaks() []LeakStat {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.leaks == nil {
+		return nil
+	}
+	out := make([]LeakStat, 0, len(t.leaks))
+	for ip, info := range t.leaks {
+		out = append(out, LeakStat{
+			IP:        ip,
+			Bytes:     info.bytes,
+			Packets:   info.packets,
+			FirstSeen: info.first.UnixMilli(),
+			LastSeen:  info.last.UnixMilli(),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].LastSeen > out[j].LastSeen })
+	return out
+}
@@ -0,0 +1,95 @@
+package talkers
+
+import (
+	"net"
+	"sort"
+	"strings"
+)
+
+// MatrixCell is traffic between one ordered pair of internal subnets —
+// the east-west view that the remote-talker accounting throws away.
+type MatrixCell struct {
+	Src     string `json:"src"`
+	Dst     string `json:"dst"`
+	Bytes   uint64 `json:"bytes"`
+	Packets uint64 `json:"packets"`
+}
+
+// matrixAcc accumulates one subnet-pair cell inside a bucket.
+type matrixAcc struct {
+	bytes   uint64
+	packets uint64
+}
+
+// EnableLANMatrix turns on LAN-to-LAN subnet accounting. Only useful
+// when capturing on a mirror that sees east-west traffic. Call before
+// Run.
+func (t *Tracker) EnableLANMatrix() {
+	t.lanMatrix = true
+}
+
+// subnetFor maps an internal IP to its LOCAL_NETS CIDR, falling back to
+// the enclosing /24 (or /64) for private ranges outside LOCAL_NETS.
+func (t *Tracker) subnetFor(ipStr string) string {
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return "unknown"
+	}
+	for _, n := range t.localNets {
+		if n.Contains(ip) {
+			return n.String()
+		}
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String() + "/24"
+	}
+	return ip.Mask(net.CIDRMask(64, 128)).String() + "/64"
+}
+
+// recordLAN accounts one local-to-local packet. Caller holds t.mu.
+func (t *Tracker) recordLAN(srcIP, dstIP string, pktLen uint64) {
+	key := t.subnetFor(srcIP) + "|" + t.subnetFor(dstIP)
+	acc, ok := t.current.lanPairs[key]
+	if !ok {
+		acc = &matrixAcc{}
+		t.current.lanPairs[key] = acc
+	}
+	acc.bytes += pktLen
+	acc.packets++
+}
+
+// GetLANMatrix returns the subnet-to-subnet traffic matrix over the
+// retained window, largest cells first. Nil unless LAN_MATRIX is on.
+func (t *Tracker) GetLANMatrix() []MatrixCell {
+	if !t.lanMatrix {
+		return nil
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	totals := make(map[string]*matrixAcc)
+	add := func(b *bucket) {
+		for key, acc := range b.lanPairs {
+			if _, ok := totals[key]; !ok {
+				totals[key] = &matrixAcc{}
+			}
+			totals[key].bytes += acc.bytes
+			totals[key].packets += acc.packets
+		}
+	}
+	for _, b := range t.buckets {
+		add(b)
+	}
+	if t.current != nil {
+		add(t.current)
+	}
+
+	out := make([]MatrixCell, 0, len(totals))
+	for key, acc := range totals {
+		src, dst, _ := strings.Cut(key, "|")
+		out = append(out, MatrixCell{Src: src, Dst: dst, Bytes: acc.bytes, Packets: acc.packets})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Bytes > out[j].Bytes })
+	return out
+}
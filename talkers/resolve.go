@@ -0,0 +1,151 @@
+package talkers
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"bandwidth-monitor/privacy"
+)
+
+// Reverse DNS for talker hostnames. Lookups run on a small fixed worker
+// pool fed from a bounded queue, so a burst of new IPs never fans out
+// into thousands of goroutines. Answers are cached with separate TTLs
+// for hits and misses — a host that gains a PTR record later is picked
+// up once its negative entry expires, instead of being labelled by IP
+// forever.
+
+const (
+	resolveWorkers = 4
+	resolveQueue   = 512
+	resolvePending = 30 * time.Second // suppress re-queueing while a lookup is in flight
+	dnsPositiveTTL = 1 * time.Hour
+	dnsNegativeTTL = 5 * time.Minute
+)
+
+type dnsEntry struct {
+	name    string // "" means the lookup failed (negative entry)
+	expires time.Time
+	pending bool // a worker has been asked and hasn't answered yet
+}
+
+// SetResolver sends PTR lookups to a specific DNS server (host or
+// host:port, e.g. a local AdGuard instance) instead of the system
+// resolver. Call before Run.
+func (t *Tracker) SetResolver(addr string) {
+	if addr == "" {
+		return
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "53")
+	}
+	d := &net.Dialer{Timeout: 5 * time.Second}
+	t.resolver = &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+}
+
+// resolveIP returns the best display name for an IP without blocking:
+// the namer wins, then a cached PTR answer; unknown or expired entries
+// are queued for the worker pool and the IP itself is returned until an
+// answer lands.
+func (t *Tracker) resolveIP(ip string) string {
+	name, _ := t.resolveState(ip)
+	return name
+}
+
+// resolveState additionally reports whether a lookup is still in flight
+// (queued, or deferred by the enrichment budget) — a completed negative
+// lookup is not pending.
+func (t *Tracker) resolveState(ip string) (string, bool) {
+	// Hostnames are suppressed in privacy mode (ip is already anonymized)
+	if privacy.Active("talkers") {
+		return ip, false
+	}
+
+	t.namerMu.RLock()
+	n := t.namer
+	t.namerMu.RUnlock()
+	if n != nil {
+		if name := n.NameFor(ip); name != "" {
+			return name, false
+		}
+	}
+
+	// Names observed on the wire beat the PTR record: an SNI from a live
+	// flow first, then a domain a client actually resolved to this IP
+	if name := t.sniName(ip); name != "" {
+		return name, false
+	}
+	if name := t.sniffedDomain(ip); name != "" {
+		return name, false
+	}
+
+	now := time.Now()
+	t.dnsCacheMu.RLock()
+	e, ok := t.dnsCache[ip]
+	t.dnsCacheMu.RUnlock()
+	if ok && now.Before(e.expires) {
+		if e.name != "" {
+			return e.name, false
+		}
+		return ip, e.pending
+	}
+
+	// Expired or never seen: mark it pending (keeping any stale name so
+	// the UI doesn't flicker back to the bare IP) and hand it to the
+	// pool. A full queue just means the retry waits for the next call.
+	t.dnsCacheMu.Lock()
+	if e2, ok := t.dnsCache[ip]; ok && now.Before(e2.expires) {
+		t.dnsCacheMu.Unlock()
+		if e2.name != "" {
+			return e2.name, false
+		}
+		return ip, e2.pending
+	}
+	t.dnsCache[ip] = dnsEntry{name: e.name, expires: now.Add(resolvePending), pending: true}
+	t.dnsCacheMu.Unlock()
+
+	select {
+	case t.resolveQ <- ip:
+	default:
+	}
+
+	if e.name != "" {
+		return e.name, false
+	}
+	return ip, true
+}
+
+func (t *Tracker) resolveWorker() {
+	for {
+		select {
+		case <-t.stopCh:
+			return
+		case ip := <-t.resolveQ:
+			// The shared enrichment budget meters the actual queries
+			if t.budget != nil && !t.budget.wait(t.stopCh) {
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			names, err := t.resolver.LookupAddr(ctx, ip)
+			cancel()
+
+			name := ""
+			if err == nil && len(names) > 0 {
+				name = strings.TrimSuffix(names[0], ".")
+			}
+			ttl := dnsNegativeTTL
+			if name != "" {
+				ttl = dnsPositiveTTL
+			}
+			t.dnsCacheMu.Lock()
+			t.dnsCache[ip] = dnsEntry{name: name, expires: time.Now().Add(ttl)}
+			t.dnsCacheMu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package talkers
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// defaultSeenRetention is how long a remote host's first/last contact
+// record survives without traffic. Much longer than the bucket window:
+// a destination that reappears after a quiet week shouldn't look brand
+// new.
+const defaultSeenRetention = 30 * 24 * time.Hour
+
+// seenInfo is the contact record for one remote IP or ASN.
+type seenInfo struct {
+	first time.Time
+	last  time.Time
+}
+
+// seenTable holds first/last contact times, guarded separately from
+// t.mu so the per-packet update doesn't contend with stat reads.
+type seenTable struct {
+	mu        sync.RWMutex
+	ips       map[string]*seenInfo
+	asns      map[uint]*seenInfo
+	retention time.Duration
+}
+
+// FirstContact is one recently first-seen remote host, for the
+// new-destination alert rule.
+type FirstContact struct {
+	IP        string `json:"ip"`
+	FirstSeen int64  `json:"first_seen"` // unix ms
+}
+
+// SetSeenRetention overrides how long contact records are kept
+// (SEEN_RETENTION, default 30 days). Call before Run.
+func (t *Tracker) SetSeenRetention(d time.Duration) {
+	if d > 0 {
+		t.seen.retention = d
+	}
+}
+
+// observeSeen stamps a remote host's contact record; ip is the
+// (possibly anonymized) host key used everywhere else.
+func (t *Tracker) observeSeen(ip string, now time.Time) {
+	s := &t.seen
+	s.mu.Lock()
+	info, ok := s.ips[ip]
+	if !ok {
+		s.ips[ip] = &seenInfo{first: now, last: now}
+	} else {
+		info.last = now
+	}
+	s.mu.Unlock()
+}
+
+// observeSeenASN stamps an ASN's contact record; called from the
+// rotation-time breakdown rebuild where geo lookups already happen.
+func (t *Tracker) observeSeenASN(asn uint, now time.Time) {
+	s := &t.seen
+	s.mu.Lock()
+	info, ok := s.asns[asn]
+	if !ok {
+		s.asns[asn] = &seenInfo{first: now, last: now}
+	} else {
+		info.last = now
+	}
+	s.mu.Unlock()
+}
+
+// pruneSeen drops contact records idle past the seen retention.
+func (t *Tracker) pruneSeen(now time.Time) {
+	s := &t.seen
+	cutoff := now.Add(-s.retention)
+	s.mu.Lock()
+	for ip, info := range s.ips {
+		if info.last.Before(cutoff) {
+			delete(s.ips, ip)
+		}
+	}
+	for asn, info := range s.asns {
+		if info.last.Before(cutoff) {
+			delete(s.asns, asn)
+		}
+	}
+	s.mu.Unlock()
+}
+
+// fillSeen copies the contact timestamps into a stat being built.
+func (t *Tracker) fillSeen(st *TalkerStat) {
+	s := &t.seen
+	s.mu.RLock()
+	if info, ok := s.ips[st.IP]; ok {
+		st.FirstSeen = info.first.UnixMilli()
+		st.LastSeen = info.last.UnixMilli()
+	}
+	s.mu.RUnlock()
+}
+
+// FirstContactsSince lists remote hosts whose first contact falls after
+// since, for the new-destination alert rule.
+func (t *Tracker) FirstContactsSince(since time.Time) []FirstContact {
+	s := &t.seen
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []FirstContact
+	for ip, info := range s.ips {
+		if info.first.After(since) {
+			out = append(out, FirstContact{IP: ip, FirstSeen: info.first.UnixMilli()})
+		}
+	}
+	return out
+}
+
+// Wire types for persistence, mirroring state.go's compact DTOs.
+
+type seenState struct {
+	F int64 `json:"f"` // unix ms
+	L int64 `json:"l"`
+}
+
+type seenSnapshot struct {
+	IPs  map[string]seenState `json:"ips,omitempty"`
+	ASNs map[uint]seenState   `json:"asns,omitempty"`
+}
+
+// SeenStore adapts the contact table to its own persistence source, so
+// the bucket snapshot schema stays untouched.
+type SeenStore struct {
+	t *Tracker
+}
+
+// SeenStore returns the persistence adapter; attach it to the state
+// store under its own key.
+func (t *Tracker) SeenStore() *SeenStore { return &SeenStore{t: t} }
+
+// ExportState serializes the contact table.
+func (s *SeenStore) ExportState() ([]byte, error) {
+	tbl := &s.t.seen
+	tbl.mu.RLock()
+	snap := seenSnapshot{
+		IPs:  make(map[string]seenState, len(tbl.ips)),
+		ASNs: make(map[uint]seenState, len(tbl.asns)),
+	}
+	for ip, info := range tbl.ips {
+		snap.IPs[ip] = seenState{F: info.first.UnixMilli(), L: info.last.UnixMilli()}
+	}
+	for asn, info := range tbl.asns {
+		snap.ASNs[asn] = seenState{F: info.first.UnixMilli(), L: info.last.UnixMilli()}
+	}
+	tbl.mu.RUnlock()
+	return json.Marshal(snap)
+}
+
+// ImportState restores a contact table saved by ExportState, dropping
+// records that have aged out. Call before Run.
+func (s *SeenStore) ImportState(data []byte) error {
+	var snap seenSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return err
+	}
+	tbl := &s.t.seen
+	cutoff := time.Now().Add(-tbl.retention)
+	tbl.mu.Lock()
+	for ip, st := range snap.IPs {
+		if info := seenFromState(st, cutoff); info != nil {
+			tbl.ips[ip] = info
+		}
+	}
+	for asn, st := range snap.ASNs {
+		if info := seenFromState(st, cutoff); info != nil {
+			tbl.asns[asn] = info
+		}
+	}
+	tbl.mu.Unlock()
+	return nil
+}
+
+func seenFromState(st seenState, cutoff time.Time) *seenInfo {
+	last := time.UnixMilli(st.L)
+	if last.Before(cutoff) {
+		return nil
+	}
+	return &seenInfo{first: time.UnixMilli(st.F), last: last}
+}
@@ -0,0 +1,307 @@
+package talkers
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"sort"
+	"time"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// SNI inspection: the server name a client puts in its TLS ClientHello
+// is the one attribution signal that still works for cloud IPs whose
+// PTR records are useless. TCP handshakes carry it in the clear; QUIC
+// encrypts the Initial packet, but with keys any observer can derive
+// from the connection ID (RFC 9001 §5.2), so those are opened too.
+// Opt-in via SNI_INSPECT because it needs a larger snaplen than the
+// 128-byte default.
+
+const (
+	sniSnapLen int32 = 1600
+	sniTTL           = 1 * time.Hour
+	sniMapMax        = 65536
+)
+
+// EnableSNIInspection turns on ClientHello parsing for TCP and QUIC
+// traffic to port 443 and raises the capture snaplen. Call before Run.
+func (t *Tracker) EnableSNIInspection() {
+	t.inspectSNI = true
+	t.sniMap = make(map[string]dnsMapEntry)
+}
+
+func (t *Tracker) storeSNI(serverIP, host string) {
+	now := time.Now()
+	t.sniMu.Lock()
+	t.sniMap[serverIP] = dnsMapEntry{domain: host, expires: now.Add(sniTTL)}
+	if len(t.sniMap) > sniMapMax {
+		for ip, e := range t.sniMap {
+			if now.After(e.expires) {
+				delete(t.sniMap, ip)
+			}
+		}
+	}
+	t.sniMu.Unlock()
+}
+
+// sniName returns the server name last seen in a ClientHello to ip.
+func (t *Tracker) sniName(ip string) string {
+	if !t.inspectSNI {
+		return ""
+	}
+	t.sniMu.RLock()
+	e, ok := t.sniMap[ip]
+	t.sniMu.RUnlock()
+	if ok && time.Now().Before(e.expires) {
+		return e.domain
+	}
+	return ""
+}
+
+// inspectTLS tries to read an SNI out of a TCP segment towards :443.
+func (t *Tracker) inspectTLS(serverIP string, payload []byte) {
+	// TLS record: handshake(22), version, length — then a ClientHello(1)
+	if len(payload) < 9 || payload[0] != 22 || payload[5] != 1 {
+		return
+	}
+	end := 5 + int(binary.BigEndian.Uint16(payload[3:5]))
+	if end < 9 {
+		return // record too short to hold even the handshake header
+	}
+	if end > len(payload) {
+		end = len(payload) // truncated by snaplen; parse what arrived
+	}
+	if host := sniFromClientHello(payload[9:end]); host != "" {
+		t.storeSNI(serverIP, host)
+	}
+}
+
+// sniFromClientHello walks a ClientHello body (after the 4-byte
+// handshake header) to the server_name extension. Returns "" on any
+// overrun — truncated hellos just don't attribute.
+func sniFromClientHello(b []byte) string {
+	p := 2 + 32 // legacy_version + random
+	if p >= len(b) {
+		return ""
+	}
+	p += 1 + int(b[p]) // session id
+	if p+2 > len(b) {
+		return ""
+	}
+	p += 2 + int(binary.BigEndian.Uint16(b[p:])) // cipher suites
+	if p >= len(b) {
+		return ""
+	}
+	p += 1 + int(b[p]) // compression methods
+	if p+2 > len(b) {
+		return ""
+	}
+	extEnd := p + 2 + int(binary.BigEndian.Uint16(b[p:]))
+	if extEnd > len(b) {
+		extEnd = len(b)
+	}
+	p += 2
+	for p+4 <= extEnd {
+		typ := binary.BigEndian.Uint16(b[p:])
+		l := int(binary.BigEndian.Uint16(b[p+2:]))
+		p += 4
+		if p+l > extEnd {
+			return ""
+		}
+		if typ == 0 { // server_name
+			// list length(2), entry type(1)=host_name, name length(2)
+			if l < 5 || b[p+2] != 0 {
+				return ""
+			}
+			nl := int(binary.BigEndian.Uint16(b[p+3:]))
+			if 5+nl > l {
+				return ""
+			}
+			return string(b[p+5 : p+5+nl])
+		}
+		p += l
+	}
+	return ""
+}
+
+// --- QUIC v1 Initial decryption (RFC 9001 §5.2) ---
+
+var quicInitialSalt = []byte{
+	0x38, 0x76, 0x2c, 0xf7, 0xf5, 0x59, 0x34, 0xb3, 0x4d, 0x17,
+	0x9a, 0xe6, 0xa4, 0xc8, 0x0c, 0xad, 0xcc, 0xbb, 0x7f, 0x0a,
+}
+
+func hkdfExpandLabel(secret []byte, label string, length int) []byte {
+	full := "tls13 " + label
+	info := make([]byte, 0, 4+len(full))
+	info = append(info, byte(length>>8), byte(length), byte(len(full)))
+	info = append(info, full...)
+	info = append(info, 0)
+	out := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, secret, info), out); err != nil {
+		return nil
+	}
+	return out
+}
+
+func quicVarint(b []byte) (uint64, int) {
+	if len(b) == 0 {
+		return 0, 0
+	}
+	n := 1 << (b[0] >> 6)
+	if len(b) < n {
+		return 0, 0
+	}
+	v := uint64(b[0] & 0x3f)
+	for i := 1; i < n; i++ {
+		v = v<<8 | uint64(b[i])
+	}
+	return v, n
+}
+
+// inspectQUIC opens a QUIC v1 Initial and reads the SNI from the
+// ClientHello in its CRYPTO frames.
+func (t *Tracker) inspectQUIC(serverIP string, payload []byte) {
+	if host := sniFromQUICInitial(payload); host != "" {
+		t.storeSNI(serverIP, host)
+	}
+}
+
+func sniFromQUICInitial(pkt []byte) string {
+	// Long header, type Initial, version 1
+	if len(pkt) < 7 || pkt[0]&0xc0 != 0xc0 || (pkt[0]>>4)&0x03 != 0 {
+		return ""
+	}
+	if binary.BigEndian.Uint32(pkt[1:5]) != 1 {
+		return ""
+	}
+	p := 5
+	dcil := int(pkt[p])
+	p++
+	if dcil > 20 || p+dcil > len(pkt) {
+		return ""
+	}
+	dcid := pkt[p : p+dcil]
+	p += dcil
+	if p >= len(pkt) {
+		return ""
+	}
+	p += 1 + int(pkt[p]) // source connection id
+	if p >= len(pkt) {
+		return ""
+	}
+	tokenLen, n := quicVarint(pkt[p:])
+	if n == 0 {
+		return ""
+	}
+	p += n + int(tokenLen)
+	if p >= len(pkt) {
+		return ""
+	}
+	length, n := quicVarint(pkt[p:])
+	if n == 0 {
+		return ""
+	}
+	p += n
+	pnOffset := p
+	end := pnOffset + int(length)
+	if end > len(pkt) || pnOffset+4+16 > end {
+		return "" // truncated; Initials are >=1200 bytes so snaplen must cover them
+	}
+
+	// Client Initial keys are derived from the destination connection ID
+	initial := hkdf.Extract(sha256.New, dcid, quicInitialSalt)
+	client := hkdfExpandLabel(initial, "client in", 32)
+	key := hkdfExpandLabel(client, "quic key", 16)
+	iv := hkdfExpandLabel(client, "quic iv", 12)
+	hp := hkdfExpandLabel(client, "quic hp", 16)
+	if key == nil || iv == nil || hp == nil {
+		return ""
+	}
+
+	// Remove header protection (the capture buffer is NoCopy — work on a copy)
+	buf := append([]byte(nil), pkt[:end]...)
+	hpBlock, err := aes.NewCipher(hp)
+	if err != nil {
+		return ""
+	}
+	var mask [16]byte
+	hpBlock.Encrypt(mask[:], buf[pnOffset+4:pnOffset+20])
+	buf[0] ^= mask[0] & 0x0f
+	pnLen := int(buf[0]&0x03) + 1
+	for i := 0; i < pnLen; i++ {
+		buf[pnOffset+i] ^= mask[1+i]
+	}
+
+	nonce := append([]byte(nil), iv...)
+	for i := 0; i < pnLen; i++ {
+		nonce[12-pnLen+i] ^= buf[pnOffset+i]
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return ""
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return ""
+	}
+	plain, err := aead.Open(nil, nonce, buf[pnOffset+pnLen:end], buf[:pnOffset+pnLen])
+	if err != nil {
+		return ""
+	}
+
+	return sniFromCryptoFrames(plain)
+}
+
+// sniFromCryptoFrames reassembles the CRYPTO frames of a decrypted
+// Initial payload and parses the ClientHello they carry.
+func sniFromCryptoFrames(plain []byte) string {
+	type chunk struct {
+		off  uint64
+		data []byte
+	}
+	var chunks []chunk
+	p := 0
+	for p < len(plain) {
+		switch plain[p] {
+		case 0x00, 0x01: // PADDING, PING
+			p++
+		case 0x06: // CRYPTO
+			p++
+			off, n := quicVarint(plain[p:])
+			if n == 0 {
+				return ""
+			}
+			p += n
+			l, n := quicVarint(plain[p:])
+			if n == 0 || p+n+int(l) > len(plain) {
+				return ""
+			}
+			p += n
+			chunks = append(chunks, chunk{off: off, data: plain[p : p+int(l)]})
+			p += int(l)
+		default:
+			// ACK or anything else: frame lengths vary, stop here and
+			// work with the crypto data gathered so far
+			p = len(plain)
+		}
+	}
+	if len(chunks) == 0 {
+		return ""
+	}
+	sort.Slice(chunks, func(i, j int) bool { return chunks[i].off < chunks[j].off })
+	var stream []byte
+	for _, c := range chunks {
+		if c.off != uint64(len(stream)) {
+			break // gap; parse the contiguous prefix
+		}
+		stream = append(stream, c.data...)
+	}
+	if len(stream) < 4 || stream[0] != 1 { // ClientHello handshake message
+		return ""
+	}
+	return sniFromClientHello(stream[4:])
+}
@@ -0,0 +1,68 @@
+package talkers
+
+import (
+	"errors"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+	"github.com/gopacket/gopacket/pcap"
+)
+
+// packetSource abstracts the capture backend behind captureDevice:
+// libpcap by default, TPACKET_V3 ring buffers via CAPTURE_BACKEND=afpacket
+// when per-packet reads can't keep up with the line rate.
+type packetSource interface {
+	ReadPacketData() ([]byte, gopacket.CaptureInfo, error)
+	LinkType() layers.LinkType
+	// Stats reports packets the kernel delivered, dropped for lack of
+	// buffer space, and dropped by the interface itself; ok is false
+	// when the backend can't say.
+	Stats() (received, dropped, ifDropped uint64, ok bool)
+	Close()
+}
+
+// errCaptureTimeout is the backend-neutral poll timeout; the read loop
+// just tries again.
+var errCaptureTimeout = errors.New("capture poll timeout")
+
+type pcapSource struct {
+	h *pcap.Handle
+}
+
+// openPcap opens the default libpcap backend. A rejected filter is
+// reported separately — capture proceeds unfiltered, as before.
+func openPcap(device string, snap int32, promiscuous bool, filter string) (packetSource, error, error) {
+	h, err := pcap.OpenLive(device, snap, promiscuous, capTimeout)
+	if err != nil {
+		return nil, nil, err
+	}
+	var filterErr error
+	if filter != "" {
+		filterErr = h.SetBPFFilter(filter)
+	}
+	return &pcapSource{h: h}, filterErr, nil
+}
+
+func (p *pcapSource) ReadPacketData() ([]byte, gopacket.CaptureInfo, error) {
+	data, ci, err := p.h.ReadPacketData()
+	if err == pcap.NextErrorTimeoutExpired {
+		err = errCaptureTimeout
+	}
+	return data, ci, err
+}
+
+func (p *pcapSource) LinkType() layers.LinkType {
+	return p.h.LinkType()
+}
+
+func (p *pcapSource) Stats() (uint64, uint64, uint64, bool) {
+	s, err := p.h.Stats()
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	return uint64(s.PacketsReceived), uint64(s.PacketsDropped), uint64(s.PacketsIfDropped), true
+}
+
+func (p *pcapSource) Close() {
+	p.h.Close()
+}
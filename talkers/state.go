@@ -0,0 +1,160 @@
+package talkers
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Wire types for persistence: buckets hold unexported accumulators, so
+// snapshots round-trip through these compact DTOs.
+
+type hostState struct {
+	B  uint64            `json:"b"`
+	RX uint64            `json:"rx"`
+	TX uint64            `json:"tx"`
+	P  uint64            `json:"p"`
+	D  map[string]uint64 `json:"d,omitempty"` // per capture interface
+}
+
+type pairState struct {
+	B uint64 `json:"b"`
+	P uint64 `json:"p"`
+}
+
+type clientState struct {
+	B  uint64               `json:"b"`
+	RX uint64               `json:"rx"`
+	TX uint64               `json:"tx"`
+	P  uint64               `json:"p"`
+	R  map[string]hostState `json:"r,omitempty"`
+}
+
+type bucketState struct {
+	T       int64                  `json:"t"` // unix ms
+	Hosts   map[string]hostState   `json:"hosts"`
+	Proto   map[string]uint64      `json:"proto,omitempty"`
+	IPVer   map[string]uint64      `json:"ipver,omitempty"`
+	DSCP    map[string]uint64      `json:"dscp,omitempty"`
+	LAN     map[string]pairState   `json:"lan,omitempty"`
+	Clients map[string]clientState `json:"clients,omitempty"`
+	ICMP    map[string]hostState   `json:"icmp,omitempty"`
+}
+
+// ExportState serializes the retained minute buckets, including the
+// in-progress one (restart loses at most its remainder).
+func (t *Tracker) ExportState() ([]byte, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	all := t.buckets
+	if t.current != nil {
+		all = append(append([]*bucket(nil), t.buckets...), t.current)
+	}
+	out := make([]bucketState, 0, len(all))
+	for _, b := range all {
+		bs := bucketState{
+			T:     b.timestamp.UnixMilli(),
+			Hosts: make(map[string]hostState, len(b.hosts)),
+		}
+		for ip, h := range b.hosts {
+			bs.Hosts[ip] = hostState{B: h.bytes, RX: h.rxBytes, TX: h.txBytes, P: h.packets, D: h.devBytes}
+		}
+		if len(b.protoBytes) > 0 {
+			bs.Proto = b.protoBytes
+		}
+		if len(b.ipVerBytes) > 0 {
+			bs.IPVer = b.ipVerBytes
+		}
+		if len(b.dscpBytes) > 0 {
+			bs.DSCP = b.dscpBytes
+		}
+		if len(b.lanPairs) > 0 {
+			bs.LAN = make(map[string]pairState, len(b.lanPairs))
+			for k, a := range b.lanPairs {
+				bs.LAN[k] = pairState{B: a.bytes, P: a.packets}
+			}
+		}
+		if len(b.clients) > 0 {
+			bs.Clients = make(map[string]clientState, len(b.clients))
+			for ip, c := range b.clients {
+				cs := clientState{B: c.bytes, RX: c.rxBytes, TX: c.txBytes, P: c.packets}
+				if len(c.remotes) > 0 {
+					cs.R = make(map[string]hostState, len(c.remotes))
+					for rip, h := range c.remotes {
+						cs.R[rip] = hostState{B: h.bytes, RX: h.rxBytes, TX: h.txBytes, P: h.packets}
+					}
+				}
+				bs.Clients[ip] = cs
+			}
+		}
+		if len(b.icmpSrc) > 0 {
+			bs.ICMP = make(map[string]hostState, len(b.icmpSrc))
+			for ip, h := range b.icmpSrc {
+				bs.ICMP[ip] = hostState{B: h.bytes, P: h.packets}
+			}
+		}
+		out = append(out, bs)
+	}
+	return json.Marshal(out)
+}
+
+// ImportState restores buckets saved by ExportState, dropping any that
+// have aged out of the retention window. Call before Run.
+func (t *Tracker) ImportState(data []byte) error {
+	var states []bucketState
+	if err := json.Unmarshal(data, &states); err != nil {
+		return err
+	}
+	cutoff := time.Now().Add(-t.retention)
+	var buckets []*bucket
+	for _, bs := range states {
+		ts := time.UnixMilli(bs.T)
+		if ts.Before(cutoff) {
+			continue
+		}
+		nb := &bucket{
+			timestamp:  ts,
+			hosts:      make(map[string]*hostAccum, len(bs.Hosts)),
+			protoBytes: make(map[string]uint64, len(bs.Proto)),
+			ipVerBytes: make(map[string]uint64, len(bs.IPVer)),
+			dscpBytes:  make(map[string]uint64, len(bs.DSCP)),
+			lanPairs:   make(map[string]*matrixAcc, len(bs.LAN)),
+			clients:    make(map[string]*clientAccum, len(bs.Clients)),
+			icmpSrc:    make(map[string]*hostAccum, len(bs.ICMP)),
+		}
+		for ip, h := range bs.Hosts {
+			nb.hosts[ip] = &hostAccum{bytes: h.B, rxBytes: h.RX, txBytes: h.TX, packets: h.P, devBytes: h.D}
+		}
+		for k, v := range bs.Proto {
+			nb.protoBytes[k] = v
+		}
+		for k, v := range bs.IPVer {
+			nb.ipVerBytes[k] = v
+		}
+		for k, v := range bs.DSCP {
+			nb.dscpBytes[k] = v
+		}
+		for k, a := range bs.LAN {
+			nb.lanPairs[k] = &matrixAcc{bytes: a.B, packets: a.P}
+		}
+		for ip, cs := range bs.Clients {
+			c := &clientAccum{
+				hostAccum: hostAccum{bytes: cs.B, rxBytes: cs.RX, txBytes: cs.TX, packets: cs.P},
+				remotes:   make(map[string]*hostAccum, len(cs.R)),
+			}
+			for rip, h := range cs.R {
+				c.remotes[rip] = &hostAccum{bytes: h.B, rxBytes: h.RX, txBytes: h.TX, packets: h.P}
+			}
+			nb.clients[ip] = c
+		}
+		for ip, h := range bs.ICMP {
+			nb.icmpSrc[ip] = &hostAccum{bytes: h.B, packets: h.P}
+		}
+		buckets = append(buckets, nb)
+	}
+	t.mu.Lock()
+	t.buckets = buckets
+	t.mu.Unlock()
+	t.rebuildAggregates()
+	return nil
+}
@@ -5,10 +5,16 @@ import (
 	"net"
 	"os"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"bandwidth-monitor/events"
+	"bandwidth-monitor/flowexport"
+	"bandwidth-monitor/flowlog"
 	"bandwidth-monitor/geoip"
+	"bandwidth-monitor/privacy"
+	"bandwidth-monitor/schedule"
 
 	"github.com/gopacket/gopacket"
 	"github.com/gopacket/gopacket/layers"
@@ -18,8 +24,8 @@ import (
 const (
 	snapshotLen int32         = 128
 	capTimeout  time.Duration = 100 * time.Millisecond
-	bucketSize                = 1 * time.Minute
-	maxAge                    = 24 * time.Hour
+	bucketSize                = 1 * time.Minute // default; see SetRetention
+	maxAge                    = 24 * time.Hour  // default; see SetRetention
 )
 
 type TalkerKey struct {
@@ -40,6 +46,20 @@ type TalkerStat struct {
 	RxRate      float64 `json:"rx_rate"`
 	TxRate      float64 `json:"tx_rate"`
 	Packets     uint64  `json:"packets"`
+	// Hops is the estimated hop distance derived from observed TTLs;
+	// zero when no packet from this host has been seen directly.
+	Hops int `json:"hops,omitempty"`
+	// Pending is set when enrichment was deferred (lookup still queued
+	// or over the enrichment budget); a later poll fills the gaps.
+	Pending bool `json:"pending,omitempty"`
+	// Interfaces splits TotalBytes by the capture device that saw the
+	// traffic; absent for single-interface setups and ingested flows.
+	Interfaces map[string]uint64 `json:"interfaces,omitempty"`
+	// FirstSeen/LastSeen are the host's first and latest contact within
+	// the seen-retention window (unix ms); a recent FirstSeen drives the
+	// UI's "new destination" badge.
+	FirstSeen int64 `json:"first_seen,omitempty"`
+	LastSeen  int64 `json:"last_seen,omitempty"`
 }
 
 type bucket struct {
@@ -47,26 +67,79 @@ type bucket struct {
 	hosts      map[string]*hostAccum
 	protoBytes map[string]uint64
 	ipVerBytes map[string]uint64
+	dscpBytes  map[string]uint64
+	lanPairs   map[string]*matrixAcc
+	clients    map[string]*clientAccum
+	icmpSrc    map[string]*hostAccum
 }
 
 type hostAccum struct {
-	bytes   uint64
-	rxBytes uint64 // towards local nets (download)
-	txBytes uint64 // from local nets (upload)
-	packets uint64
+	bytes    uint64
+	rxBytes  uint64 // towards local nets (download)
+	txBytes  uint64 // from local nets (upload)
+	packets  uint64
+	devBytes map[string]uint64 // per capture interface; nil for flow sources
 }
 
 type Tracker struct {
-	device      string
-	promiscuous bool
-	localNets   []*net.IPNet // LOCAL_NETS for SPAN port direction detection
-	mu          sync.RWMutex
-	buckets     []*bucket
-	current     *bucket
-	stopCh      chan struct{}
-	dnsCache    map[string]string
-	dnsCacheMu  sync.RWMutex
-	geoDB       *geoip.DB
+	device       string
+	promiscuous  bool
+	localNets    []*net.IPNet // LOCAL_NETS for SPAN port direction detection
+	mu           sync.RWMutex
+	buckets      []*bucket
+	current      *bucket
+	stopCh       chan struct{}
+	dnsCache     map[string]dnsEntry
+	dnsCacheMu   sync.RWMutex
+	resolveQ     chan string
+	resolver     *net.Resolver
+	geoDB        *geoip.DB
+	namer        Namer
+	namerMu      sync.RWMutex
+	pause        *schedule.Windows
+	seen         seenTable
+	leakWAN      string
+	leakNets     []*net.IPNet
+	leaks        map[string]*leakInfo
+	ttls         map[string]*ttlInfo
+	ttlEvents    []TTLEvent
+	flowLog      *flowlog.Logger
+	flowExport   *flowexport.Exporter
+	lanMatrix    bool
+	trackClients bool
+	sniffDNS     bool
+	watchARP     bool
+	arp          *arpState
+	dnsMap       map[string]dnsMapEntry
+	dnsMapMu     sync.RWMutex
+	inspectSNI   bool
+	sniMap       map[string]dnsMapEntry
+	sniMu        sync.RWMutex
+	snapLen      int32
+	bpf          string
+	backend      string
+	budget       *enrichBudget
+	capStatus    map[string]*CaptureStatus
+	capSrc       map[string]packetSource
+	capDisabled  map[string]bool
+	capStatusMu  sync.RWMutex
+	bus          *events.Bus
+	dedup        *dedupe
+	enrichers    []Enricher
+	bucketEvery  time.Duration
+	retention    time.Duration
+
+	// Rotation-time window aggregates (see aggregate.go)
+	aggMu        sync.RWMutex
+	aggHosts     map[string]*hostWindow
+	aggCountries []CountryStat
+	aggASNs      []ASNStat
+}
+
+// Namer supplies user-assigned display names for IPs (e.g. the device
+// inventory); a non-empty answer takes precedence over reverse DNS.
+type Namer interface {
+	NameFor(ip string) string
 }
 
 func New(device string, promiscuous bool, localNets []*net.IPNet, geoDB *geoip.DB) *Tracker {
@@ -76,32 +149,202 @@ func New(device string, promiscuous bool, localNets []*net.IPNet, geoDB *geoip.D
 		localNets:   localNets,
 		buckets:     make([]*bucket, 0, 1440),
 		stopCh:      make(chan struct{}),
-		dnsCache:    make(map[string]string),
+		dnsCache:    make(map[string]dnsEntry),
+		resolveQ:    make(chan string, resolveQueue),
+		resolver:    net.DefaultResolver,
 		geoDB:       geoDB,
+		seen: seenTable{
+			ips:       make(map[string]*seenInfo),
+			asns:      make(map[uint]*seenInfo),
+			retention: defaultSeenRetention,
+		},
+		ttls:        make(map[string]*ttlInfo),
+		bucketEvery: bucketSize,
+		retention:   maxAge,
+		aggHosts:    make(map[string]*hostWindow),
+		capStatus:   make(map[string]*CaptureStatus),
+		capSrc:      make(map[string]packetSource),
+		capDisabled: make(map[string]bool),
+	}
+}
+
+// SetRetention overrides the default bucket width and retention window
+// (a week of buckets on a big box, an hour on a tiny router). Zero
+// leaves the corresponding default; retention never drops below one
+// bucket. Call before Run.
+func (t *Tracker) SetRetention(bucket, retention time.Duration) {
+	if bucket >= 10*time.Second {
+		t.bucketEvery = bucket
+	}
+	if retention > 0 {
+		if retention < t.bucketEvery {
+			retention = t.bucketEvery
+		}
+		t.retention = retention
+	}
+}
+
+// SetFlowLog attaches a persistent flow logger; every finished minute
+// bucket is written to it per remote host. Call before Run.
+func (t *Tracker) SetFlowLog(l *flowlog.Logger) {
+	t.flowLog = l
+}
+
+// SetFlowExporter attaches a NetFlow exporter fed the same per-host
+// records as the flow log. Call before Run.
+func (t *Tracker) SetFlowExporter(e *flowexport.Exporter) {
+	t.flowExport = e
+}
+
+// SetEventBus attaches the event bus that capture errors are published
+// to. Call before Run.
+func (t *Tracker) SetEventBus(b *events.Bus) {
+	t.bus = b
+}
+
+// SetCaptureOptions overrides the capture snaplen and BPF filter (e.g.
+// "not port 873" to exclude backup traffic, or "vlan 30" to watch one
+// segment). Zero and empty keep the headers-only defaults; SNI
+// inspection still raises a too-small snaplen. Call before Run.
+func (t *Tracker) SetCaptureOptions(snapLen int32, bpf string) {
+	if snapLen > 0 {
+		t.snapLen = snapLen
+	}
+	if bpf != "" {
+		t.bpf = bpf
+	}
+}
+
+// SetCaptureBackend selects the capture implementation: "pcap" (the
+// default), "afpacket" for TPACKET_V3 ring buffers on Linux, or "ebpf"
+// for in-kernel flow counting on Linux (see ebpf_linux.go). Call
+// before Run.
+func (t *Tracker) SetCaptureBackend(name string) error {
+	switch name {
+	case "", "pcap", "afpacket", "ebpf":
+		t.backend = name
+		return nil
 	}
+	return fmt.Errorf("unknown capture backend %q", name)
+}
+
+// CaptureStatus reflects one capture device's active configuration.
+// Received, Dropped and IfDropped are kernel socket counters — a
+// growing Dropped means the capture can't keep up, a growing IfDropped
+// means the NIC itself is shedding packets.
+type CaptureStatus struct {
+	Device    string `json:"device"`
+	SnapLen   int32  `json:"snaplen"`
+	Filter    string `json:"filter"`
+	State     string `json:"state"` // running, disabled or error
+	Error     string `json:"error,omitempty"`
+	Received  uint64 `json:"received"`
+	Dropped   uint64 `json:"dropped"`
+	IfDropped uint64 `json:"if_dropped"`
+}
+
+func (t *Tracker) setCaptureStatus(device string, snapLen int32, filter, state, errMsg string) {
+	t.capStatusMu.Lock()
+	t.capStatus[device] = &CaptureStatus{
+		Device:  device,
+		SnapLen: snapLen,
+		Filter:  filter,
+		State:   state,
+		Error:   errMsg,
+	}
+	t.capStatusMu.Unlock()
+}
+
+// CaptureStatuses returns the per-device capture state, sorted by name,
+// with live socket counters for devices still capturing.
+func (t *Tracker) CaptureStatuses() []CaptureStatus {
+	t.capStatusMu.RLock()
+	out := make([]CaptureStatus, 0, len(t.capStatus))
+	for dev, s := range t.capStatus {
+		cp := *s
+		if src, ok := t.capSrc[dev]; ok {
+			if recv, drop, ifDrop, ok := src.Stats(); ok {
+				cp.Received, cp.Dropped, cp.IfDropped = recv, drop, ifDrop
+			}
+		}
+		out = append(out, cp)
+	}
+	t.capStatusMu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Device < out[j].Device })
+	return out
+}
+
+// SetDeviceCapture enables or disables accounting for one capture
+// interface at runtime. The socket stays open — packets are drained and
+// discarded — so re-enabling takes effect immediately and without
+// re-opening the device. Unknown devices are an error.
+func (t *Tracker) SetDeviceCapture(device string, enabled bool) error {
+	t.capStatusMu.Lock()
+	defer t.capStatusMu.Unlock()
+	if _, ok := t.capStatus[device]; !ok {
+		return fmt.Errorf("unknown capture device %q", device)
+	}
+	if enabled {
+		delete(t.capDisabled, device)
+	} else {
+		t.capDisabled[device] = true
+	}
+	return nil
+}
+
+func (t *Tracker) deviceCaptureEnabled(device string) bool {
+	t.capStatusMu.RLock()
+	defer t.capStatusMu.RUnlock()
+	return !t.capDisabled[device]
+}
+
+// SetPauseWindows installs scheduled windows during which captured
+// packets are discarded without accounting. Call before Run. Ingested
+// flows (Zeek etc.) are not affected.
+func (t *Tracker) SetPauseWindows(w *schedule.Windows) {
+	t.pause = w
 }
 
 func (t *Tracker) Run() {
+	t.mu.Lock()
+	t.current = &bucket{
+		timestamp:  time.Now().Truncate(t.bucketEvery),
+		hosts:      make(map[string]*hostAccum),
+		protoBytes: make(map[string]uint64),
+		ipVerBytes: make(map[string]uint64),
+		dscpBytes:  make(map[string]uint64),
+		lanPairs:   make(map[string]*matrixAcc),
+		clients:    make(map[string]*clientAccum),
+		icmpSrc:    make(map[string]*hostAccum),
+	}
+	t.mu.Unlock()
+
+	go t.rotateBuckets()
+	for i := 0; i < resolveWorkers; i++ {
+		go t.resolveWorker()
+	}
+
 	devices, err := t.getDevices()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "talkers: cannot list devices: %v\n", err)
 		fmt.Fprintf(os.Stderr, "talkers: top-talkers feature requires root/CAP_NET_RAW\n")
+		// Keep the bucket pipeline alive for ingested flow sources (Zeek etc.)
+		<-t.stopCh
 		return
 	}
 	if len(devices) == 0 {
 		fmt.Fprintf(os.Stderr, "talkers: no capture devices found\n")
+		<-t.stopCh
 		return
 	}
 
-	t.current = &bucket{
-		timestamp:  time.Now().Truncate(bucketSize),
-		hosts:      make(map[string]*hostAccum),
-		protoBytes: make(map[string]uint64),
-		ipVerBytes: make(map[string]uint64),
+	// Multi-interface capture sees routed packets once per interface;
+	// dedupe so totals aren't inflated
+	if len(devices) > 1 {
+		t.dedup = newDedupe()
+		fmt.Fprintf(os.Stderr, "talkers: deduplicating across %d capture devices\n", len(devices))
 	}
 
-	go t.rotateBuckets()
-
 	for _, dev := range devices {
 		go t.captureDevice(dev)
 	}
@@ -114,11 +357,31 @@ func (t *Tracker) Stop() {
 }
 
 func (t *Tracker) TopByVolume(n int) []TalkerStat {
+	return t.TopByVolumeRange(n, time.Time{}, time.Time{})
+}
+
+// TopByVolumeRange aggregates only the buckets whose timestamp falls
+// within [from, to); a zero time leaves that side unbounded, so the
+// plain TopByVolume spans the whole retained window.
+func (t *Tracker) TopByVolumeRange(n int, from, to time.Time) []TalkerStat {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
 
+	inRange := func(ts time.Time) bool {
+		if !from.IsZero() && ts.Before(from) {
+			return false
+		}
+		if !to.IsZero() && !ts.Before(to) {
+			return false
+		}
+		return true
+	}
+
 	totals := make(map[string]*TalkerStat)
-	for _, b := range t.buckets {
+	add := func(b *bucket) {
+		if !inRange(b.timestamp) {
+			return
+		}
 		for ip, acc := range b.hosts {
 			if _, ok := totals[ip]; !ok {
 				totals[ip] = &TalkerStat{IP: ip}
@@ -127,24 +390,30 @@ func (t *Tracker) TopByVolume(n int) []TalkerStat {
 			totals[ip].RxBytes += acc.rxBytes
 			totals[ip].TxBytes += acc.txBytes
 			totals[ip].Packets += acc.packets
+			if len(acc.devBytes) > 0 {
+				if totals[ip].Interfaces == nil {
+					totals[ip].Interfaces = make(map[string]uint64, len(acc.devBytes))
+				}
+				for dev, n := range acc.devBytes {
+					totals[ip].Interfaces[dev] += n
+				}
+			}
 		}
 	}
+	for _, b := range t.buckets {
+		add(b)
+	}
 	if t.current != nil {
-		for ip, acc := range t.current.hosts {
-			if _, ok := totals[ip]; !ok {
-				totals[ip] = &TalkerStat{IP: ip}
-			}
-			totals[ip].TotalBytes += acc.bytes
-			totals[ip].RxBytes += acc.rxBytes
-			totals[ip].TxBytes += acc.txBytes
-			totals[ip].Packets += acc.packets
-		}
+		add(t.current)
 	}
 
 	list := make([]TalkerStat, 0, len(totals))
 	for _, s := range totals {
-		s.Hostname = t.resolveIP(s.IP)
-		t.enrichGeo(s)
+		t.enrich(s)
+		if info, ok := t.ttls[s.IP]; ok {
+			s.Hops = info.hops
+		}
+		t.fillSeen(s)
 		list = append(list, *s)
 	}
 	sort.Slice(list, func(i, j int) bool {
@@ -173,7 +442,6 @@ func (t *Tracker) TopByBandwidth(n int) []TalkerStat {
 	for ip, acc := range t.current.hosts {
 		s := TalkerStat{
 			IP:         ip,
-			Hostname:   t.resolveIP(ip),
 			TotalBytes: acc.bytes,
 			RxBytes:    acc.rxBytes,
 			TxBytes:    acc.txBytes,
@@ -182,7 +450,17 @@ func (t *Tracker) TopByBandwidth(n int) []TalkerStat {
 			TxRate:     float64(acc.txBytes) / elapsed,
 			Packets:    acc.packets,
 		}
-		t.enrichGeo(&s)
+		if len(acc.devBytes) > 0 {
+			s.Interfaces = make(map[string]uint64, len(acc.devBytes))
+			for dev, n := range acc.devBytes {
+				s.Interfaces[dev] = n
+			}
+		}
+		t.enrich(&s)
+		if info, ok := t.ttls[ip]; ok {
+			s.Hops = info.hops
+		}
+		t.fillSeen(&s)
 		list = append(list, s)
 	}
 	sort.Slice(list, func(i, j int) bool {
@@ -194,6 +472,24 @@ func (t *Tracker) TopByBandwidth(n int) []TalkerStat {
 	return list
 }
 
+// HasTalker reports whether ip currently appears in any retained bucket.
+// Used by the IDS ingestion to correlate alerts with observed traffic.
+func (t *Tracker) HasTalker(ip string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.current != nil {
+		if _, ok := t.current.hosts[ip]; ok {
+			return true
+		}
+	}
+	for _, b := range t.buckets {
+		if _, ok := b.hosts[ip]; ok {
+			return true
+		}
+	}
+	return false
+}
+
 func (t *Tracker) getDevices() ([]string, error) {
 	if t.device != "" {
 		return []string{t.device}, nil
@@ -213,46 +509,118 @@ func (t *Tracker) getDevices() ([]string, error) {
 }
 
 func (t *Tracker) captureDevice(device string) {
-	handle, err := pcap.OpenLive(device, snapshotLen, t.promiscuous, capTimeout)
+	snap := t.snapLen
+	if snap == 0 {
+		snap = snapshotLen
+	}
+	if t.inspectSNI && snap < sniSnapLen {
+		snap = sniSnapLen
+	}
+	filter := t.bpf
+	if filter == "" {
+		filter = "ip or ip6"
+		if t.watchARP {
+			filter = "arp or ip or ip6"
+		}
+	}
+
+	var src packetSource
+	var filterErr, err error
+	switch t.backend {
+	case "afpacket":
+		src, filterErr, err = openAFPacket(device, snap, filter)
+	case "ebpf":
+		src, filterErr, err = t.openEBPF(device)
+	default:
+		src, filterErr, err = openPcap(device, snap, t.promiscuous, filter)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "talkers: cannot open %s: %v\n", device, err)
+		t.bus.Publish("talkers", "capture_error", device, fmt.Sprintf("cannot open %s: %v", device, err))
+		t.setCaptureStatus(device, snap, filter, "error", err.Error())
 		return
 	}
-	defer handle.Close()
+	defer src.Close()
+	t.capStatusMu.Lock()
+	t.capSrc[device] = src
+	t.capStatusMu.Unlock()
+	defer func() {
+		t.capStatusMu.Lock()
+		delete(t.capSrc, device)
+		t.capStatusMu.Unlock()
+	}()
 
-	if err := handle.SetBPFFilter("ip or ip6"); err != nil {
-		fmt.Fprintf(os.Stderr, "talkers: BPF filter error on %s: %v\n", device, err)
+	t.setCaptureStatus(device, snap, filter, "running", "")
+	if filterErr != nil {
+		fmt.Fprintf(os.Stderr, "talkers: BPF filter error on %s: %v\n", device, filterErr)
+		// Capture continues unfiltered; surface the rejection in status
+		t.setCaptureStatus(device, snap, "", "running", fmt.Sprintf("filter rejected: %v", filterErr))
 	}
 
+	paused := false
+	disabled := false
 	for {
 		select {
 		case <-t.stopCh:
 			return
 		default:
 		}
-		data, ci, err := handle.ReadPacketData()
+		data, ci, err := src.ReadPacketData()
 		if err != nil {
 			// Timeout is expected — just loop
-			if err == pcap.NextErrorTimeoutExpired {
+			if err == errCaptureTimeout {
 				continue
 			}
 			// Real error
 			fmt.Fprintf(os.Stderr, "talkers: read error on %s: %v\n", device, err)
+			t.bus.Publish("talkers", "capture_error", device, fmt.Sprintf("read error on %s: %v", device, err))
+			t.setCaptureStatus(device, snap, filter, "error", err.Error())
 			return
 		}
-		pkt := gopacket.NewPacket(data, handle.LinkType(), gopacket.DecodeOptions{
+		// Drain but don't account while the device is disabled via the
+		// admin API
+		if !t.deviceCaptureEnabled(device) {
+			if !disabled {
+				disabled = true
+				fmt.Fprintf(os.Stderr, "talkers: %s capture disabled\n", device)
+				t.setCaptureStatus(device, snap, filter, "disabled", "")
+			}
+			continue
+		} else if disabled {
+			disabled = false
+			fmt.Fprintf(os.Stderr, "talkers: %s capture re-enabled\n", device)
+			t.setCaptureStatus(device, snap, filter, "running", "")
+		}
+		// Drain but don't account during a scheduled pause window
+		if t.pause != nil {
+			if now := t.pause.Active(time.Now()); now != paused {
+				paused = now
+				if paused {
+					fmt.Fprintf(os.Stderr, "talkers: %s entering scheduled capture pause\n", device)
+				} else {
+					fmt.Fprintf(os.Stderr, "talkers: %s leaving scheduled capture pause\n", device)
+				}
+			}
+			if paused {
+				continue
+			}
+		}
+		pkt := gopacket.NewPacket(data, src.LinkType(), gopacket.DecodeOptions{
 			Lazy:   true,
 			NoCopy: true,
 		})
 		_ = ci
-		t.processPacket(pkt)
+		t.processPacket(device, pkt)
 	}
 }
 
-func (t *Tracker) processPacket(pkt gopacket.Packet) {
+func (t *Tracker) processPacket(device string, pkt gopacket.Packet) {
 	var srcIP, dstIP string
 	var pktLen uint64
 	var ipVersion string
+	var dscp, ttl, protoNum uint8
+	var ipID uint16
+	var flowLabel uint32
 
 	if ipLayer := pkt.Layer(layers.LayerTypeIPv4); ipLayer != nil {
 		ip := ipLayer.(*layers.IPv4)
@@ -260,25 +628,89 @@ func (t *Tracker) processPacket(pkt gopacket.Packet) {
 		dstIP = ip.DstIP.String()
 		pktLen = uint64(ip.Length)
 		ipVersion = "IPv4"
+		dscp = ip.TOS >> 2
+		ttl = ip.TTL
+		protoNum = uint8(ip.Protocol)
+		ipID = ip.Id
 	} else if ipLayer := pkt.Layer(layers.LayerTypeIPv6); ipLayer != nil {
 		ip := ipLayer.(*layers.IPv6)
 		srcIP = ip.SrcIP.String()
 		dstIP = ip.DstIP.String()
 		pktLen = uint64(ip.Length) + 40
 		ipVersion = "IPv6"
+		dscp = ip.TrafficClass >> 2
+		ttl = ip.HopLimit
+		protoNum = uint8(ip.NextHeader)
+		flowLabel = ip.FlowLabel
 	} else {
+		if t.watchARP {
+			if l := pkt.Layer(layers.LayerTypeARP); l != nil {
+				t.inspectARP(device, l.(*layers.ARP))
+			}
+		}
 		return
 	}
 
+	if t.dedup != nil {
+		var sig uint64
+		if ipVersion == "IPv4" {
+			sig = sigV4(ipID, pktLen, protoNum)
+		} else {
+			sig = sigV6(srcIP, dstIP, flowLabel, pktLen)
+		}
+		if t.dedup.duplicate(sig) {
+			return
+		}
+	}
+
+	// Layer checks first so IPv6 extension-header chains still land on
+	// TCP/UDP; everything else is named by its IP protocol number
 	var proto string
-	if pkt.Layer(layers.LayerTypeTCP) != nil {
+	if l := pkt.Layer(layers.LayerTypeTCP); l != nil {
 		proto = "TCP"
-	} else if pkt.Layer(layers.LayerTypeUDP) != nil {
+		if t.inspectSNI {
+			if tcp := l.(*layers.TCP); tcp.DstPort == 443 && len(tcp.Payload) > 0 {
+				t.inspectTLS(dstIP, tcp.Payload)
+			}
+		}
+	} else if l := pkt.Layer(layers.LayerTypeUDP); l != nil {
 		proto = "UDP"
-	} else if pkt.Layer(layers.LayerTypeICMPv4) != nil || pkt.Layer(layers.LayerTypeICMPv6) != nil {
-		proto = "ICMP"
+		udp := l.(*layers.UDP)
+		if t.sniffDNS && udp.SrcPort == 53 {
+			t.sniffDNSAnswer(pkt)
+		}
+		if t.inspectSNI && udp.DstPort == 443 && len(udp.Payload) > 0 {
+			t.inspectQUIC(dstIP, udp.Payload)
+		}
+	} else if l := pkt.Layer(layers.LayerTypeICMPv4); l != nil {
+		proto = icmpV4Name(l.(*layers.ICMPv4).TypeCode.Type())
+	} else if l := pkt.Layer(layers.LayerTypeICMPv6); l != nil {
+		proto = icmpV6Name(l.(*layers.ICMPv6).TypeCode.Type())
 	} else {
-		proto = "Other"
+		proto = ipProtoName(protoNum)
+	}
+
+	t.record(device, srcIP, dstIP, pktLen, proto, ipVersion, dscpName(dscp), ttl)
+}
+
+// IngestFlow accounts an externally-observed flow (e.g. from a Zeek
+// conn.log) into the current bucket, using the same attribution rules as
+// captured packets. ipVersion is "IPv4" or "IPv6".
+func (t *Tracker) IngestFlow(srcIP, dstIP, proto, ipVersion string, bytes uint64) {
+	// Flow logs carry neither DSCP markings nor arriving TTLs — nor a
+	// capture interface
+	t.record("", srcIP, dstIP, bytes, proto, ipVersion, "", 0)
+}
+
+// record is the shared accounting path for captured packets and ingested
+// flows. device is the capture interface that saw the packet, or ""
+// for flow sources.
+func (t *Tracker) record(device, srcIP, dstIP string, pktLen uint64, proto, ipVersion, dscp string, ttl uint8) {
+	// Anonymize at record time so retained buckets never contain real
+	// remote addresses. Direction/locality checks still use the originals.
+	srcKey, dstKey := srcIP, dstIP
+	if privacy.Active("talkers") {
+		srcKey, dstKey = privacy.IP(srcIP), privacy.IP(dstIP)
 	}
 
 	t.mu.Lock()
@@ -288,15 +720,46 @@ func (t *Tracker) processPacket(pkt gopacket.Packet) {
 		return
 	}
 
-	for _, ip := range []string{srcIP, dstIP} {
+	t.checkLeak(device, srcIP, dstIP, pktLen)
+
+	now := time.Now()
+	for _, p := range [2][2]string{{srcIP, srcKey}, {dstIP, dstKey}} {
+		ip, key := p[0], p[1]
 		if isPrivateIP(ip) || t.isLocalNet(ip) {
 			continue
 		}
-		if _, ok := t.current.hosts[ip]; !ok {
-			t.current.hosts[ip] = &hostAccum{}
+		t.observeSeen(key, now)
+		if _, ok := t.current.hosts[key]; !ok {
+			t.current.hosts[key] = &hostAccum{}
+		}
+		h := t.current.hosts[key]
+		h.bytes += pktLen
+		h.packets++
+		if device != "" {
+			if h.devBytes == nil {
+				h.devBytes = make(map[string]uint64, 2)
+			}
+			h.devBytes[device] += pktLen
 		}
-		t.current.hosts[ip].bytes += pktLen
-		t.current.hosts[ip].packets++
+	}
+
+	// Per-client attribution: account internal↔external traffic under
+	// the internal side too, keyed down to the remote host
+	if t.trackClients {
+		srcInt := isPrivateIP(srcIP) || t.isLocalNet(srcIP)
+		dstInt := isPrivateIP(dstIP) || t.isLocalNet(dstIP)
+		if srcInt && !dstInt {
+			t.recordClient(srcKey, dstKey, pktLen, true)
+		} else if !srcInt && dstInt {
+			t.recordClient(dstKey, srcKey, pktLen, false)
+		}
+	}
+
+	// East-west traffic between internal subnets, normally discarded
+	if t.lanMatrix &&
+		(isPrivateIP(srcIP) || t.isLocalNet(srcIP)) &&
+		(isPrivateIP(dstIP) || t.isLocalNet(dstIP)) {
+		t.recordLAN(srcIP, dstIP, pktLen)
 	}
 
 	// Direction detection for SPAN/mirror port using LOCAL_NETS
@@ -305,88 +768,138 @@ func (t *Tracker) processPacket(pkt gopacket.Packet) {
 		dstLocal := t.isLocalNet(dstIP)
 		if srcLocal && !dstLocal {
 			// Local → Remote = upload (TX from local perspective)
-			if h, ok := t.current.hosts[dstIP]; ok {
+			if h, ok := t.current.hosts[dstKey]; ok {
 				h.txBytes += pktLen
 			}
 		} else if !srcLocal && dstLocal {
 			// Remote → Local = download (RX from local perspective)
-			if h, ok := t.current.hosts[srcIP]; ok {
+			if h, ok := t.current.hosts[srcKey]; ok {
 				h.rxBytes += pktLen
 			}
 		}
 	}
 
+	// Per-source ICMP accounting so floods are attributable
+	if strings.HasPrefix(proto, "ICMP") {
+		acc, ok := t.current.icmpSrc[srcKey]
+		if !ok {
+			acc = &hostAccum{}
+			t.current.icmpSrc[srcKey] = acc
+		}
+		acc.bytes += pktLen
+		acc.packets++
+	}
+
 	t.current.protoBytes[proto] += pktLen
 	t.current.ipVerBytes[ipVersion] += pktLen
+	if dscp != "" {
+		t.current.dscpBytes[dscp] += pktLen
+	}
+
+	// Hop-distance estimation rides on packets arriving from remote hosts
+	if ttl > 0 && !isPrivateIP(srcIP) && !t.isLocalNet(srcIP) {
+		t.observeTTL(srcKey, ttl)
+	}
 }
 
 func (t *Tracker) rotateBuckets() {
-	ticker := time.NewTicker(bucketSize)
+	ticker := time.NewTicker(t.bucketEvery)
 	defer ticker.Stop()
 	for {
 		select {
 		case <-ticker.C:
 			t.mu.Lock()
 			now := time.Now()
-			if t.current != nil {
-				t.buckets = append(t.buckets, t.current)
+			finished := t.current
+			if finished != nil {
+				t.buckets = append(t.buckets, finished)
 			}
-			cutoff := now.Add(-maxAge)
+			cutoff := now.Add(-t.retention)
 			idx := 0
 			for idx < len(t.buckets) && t.buckets[idx].timestamp.Before(cutoff) {
 				idx++
 			}
+			var expired []*bucket
 			if idx > 0 {
+				expired = t.buckets[:idx:idx]
 				t.buckets = t.buckets[idx:]
 			}
+			t.pruneTTLs(cutoff)
+			t.pruneSeen(now)
 			t.current = &bucket{
-				timestamp:  now.Truncate(bucketSize),
+				timestamp:  now.Truncate(t.bucketEvery),
 				hosts:      make(map[string]*hostAccum),
 				protoBytes: make(map[string]uint64),
 				ipVerBytes: make(map[string]uint64),
+				dscpBytes:  make(map[string]uint64),
+				lanPairs:   make(map[string]*matrixAcc),
+				clients:    make(map[string]*clientAccum),
+				icmpSrc:    make(map[string]*hostAccum),
 			}
 			t.mu.Unlock()
+			// Retired buckets are never written again, so persisting
+			// outside the lock is safe
+			if (t.flowLog != nil || t.flowExport != nil) && finished != nil && len(finished.hosts) > 0 {
+				go t.logFlows(finished, now)
+			}
+			go t.updateAggregates(finished, expired)
 		case <-t.stopCh:
 			return
 		}
 	}
 }
 
-func (t *Tracker) resolveIP(ip string) string {
-	t.dnsCacheMu.RLock()
-	name, ok := t.dnsCache[ip]
-	t.dnsCacheMu.RUnlock()
-
-	if ok {
-		return name
+// logFlows converts one finished bucket into flow records for the
+// attached sinks (JSONL log, NetFlow exporter).
+func (t *Tracker) logFlows(b *bucket, end time.Time) {
+	recs := make([]flowlog.Record, 0, len(b.hosts))
+	for ip, acc := range b.hosts {
+		r := flowlog.Record{
+			Start:   b.timestamp.UnixMilli(),
+			End:     end.UnixMilli(),
+			IP:      ip,
+			Bytes:   acc.bytes,
+			RxBytes: acc.rxBytes,
+			TxBytes: acc.txBytes,
+			Packets: acc.packets,
+			Device:  t.device,
+		}
+		if t.geoDB != nil {
+			if geo := t.geoDB.Lookup(ip); geo != nil {
+				r.Country = geo.Country
+				r.ASN = geo.ASN
+				r.ASOrg = geo.ASOrg
+			}
+		}
+		recs = append(recs, r)
 	}
-
-	// Store IP as placeholder immediately so we don't re-trigger
-	t.dnsCacheMu.Lock()
-	// Double-check after acquiring write lock
-	if name, ok := t.dnsCache[ip]; ok {
-		t.dnsCacheMu.Unlock()
-		return name
+	if t.flowLog != nil {
+		t.flowLog.Append(recs)
+	}
+	if t.flowExport != nil {
+		t.flowExport.Export(recs)
 	}
-	t.dnsCache[ip] = ip
-	t.dnsCacheMu.Unlock()
+}
 
-	// Resolve asynchronously
-	go func() {
-		names, err := net.LookupAddr(ip)
-		if err != nil || len(names) == 0 {
-			return
-		}
-		name := names[0]
-		if len(name) > 0 && name[len(name)-1] == '.' {
-			name = name[:len(name)-1]
+// ChainNamers combines naming sources; the first non-empty answer wins.
+func ChainNamers(ns ...Namer) Namer { return namerChain(ns) }
+
+type namerChain []Namer
+
+func (c namerChain) NameFor(ip string) string {
+	for _, n := range c {
+		if name := n.NameFor(ip); name != "" {
+			return name
 		}
-		t.dnsCacheMu.Lock()
-		t.dnsCache[ip] = name
-		t.dnsCacheMu.Unlock()
-	}()
+	}
+	return ""
+}
 
-	return ip
+// SetNamer attaches a display-name source consulted before reverse DNS.
+func (t *Tracker) SetNamer(n Namer) {
+	t.namerMu.Lock()
+	t.namer = n
+	t.namerMu.Unlock()
 }
 
 // GetProtocolBreakdown returns accumulated bytes per L4 protocol over the 24h window.
@@ -427,72 +940,136 @@ func (t *Tracker) GetIPVersionBreakdown() map[string]uint64 {
 	return totals
 }
 
-// CountryStat holds per-country traffic totals.
-type CountryStat struct {
-	Country     string `json:"country"`
-	CountryName string `json:"country_name"`
-	Bytes       uint64 `json:"bytes"`
-	Connections int    `json:"connections"`
+// BreakdownPoint is one minute bucket of a per-category byte series.
+type BreakdownPoint struct {
+	Timestamp int64             `json:"t"`
+	Bytes     map[string]uint64 `json:"bytes"`
 }
 
-// GetCountryBreakdown returns traffic grouped by country over the 24h window.
-func (t *Tracker) GetCountryBreakdown() []CountryStat {
-	if t.geoDB == nil || !t.geoDB.Available() {
-		return nil
-	}
-
+// breakdownSeries assembles the per-minute series for one bucket field,
+// copying each map so callers never alias live accumulators.
+func (t *Tracker) breakdownSeries(sel func(*bucket) map[string]uint64) []BreakdownPoint {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
 
-	// Aggregate bytes per IP across all buckets
-	ipTotals := make(map[string]uint64)
-	for _, b := range t.buckets {
-		for ip, acc := range b.hosts {
-			ipTotals[ip] += acc.bytes
+	point := func(b *bucket) BreakdownPoint {
+		m := sel(b)
+		cp := make(map[string]uint64, len(m))
+		for k, v := range m {
+			cp[k] = v
 		}
+		return BreakdownPoint{Timestamp: b.timestamp.UnixMilli(), Bytes: cp}
+	}
+
+	out := make([]BreakdownPoint, 0, len(t.buckets)+1)
+	for _, b := range t.buckets {
+		out = append(out, point(b))
 	}
 	if t.current != nil {
-		for ip, acc := range t.current.hosts {
-			ipTotals[ip] += acc.bytes
-		}
+		out = append(out, point(t.current))
+	}
+	return out
+}
+
+// GetProtocolSeries returns bytes per minute per L4 protocol, so the
+// TCP-vs-UDP mix can be graphed over the day rather than just totalled.
+func (t *Tracker) GetProtocolSeries() []BreakdownPoint {
+	return t.breakdownSeries(func(b *bucket) map[string]uint64 { return b.protoBytes })
+}
+
+// GetIPVersionSeries returns bytes per minute per IP version.
+func (t *Tracker) GetIPVersionSeries() []BreakdownPoint {
+	return t.breakdownSeries(func(b *bucket) map[string]uint64 { return b.ipVerBytes })
+}
+
+// ipProtoNames gives friendly names to the IP protocols worth calling
+// out — mostly VPN (ESP, AH, GRE, L2TP) and routing (OSPF, VRRP)
+// traffic that used to collapse into "Other".
+var ipProtoNames = map[uint8]string{
+	1:   "ICMP",
+	2:   "IGMP",
+	4:   "IP-in-IP",
+	6:   "TCP",
+	17:  "UDP",
+	41:  "IPv6-in-IP",
+	47:  "GRE",
+	50:  "ESP",
+	51:  "AH",
+	58:  "ICMP",
+	89:  "OSPF",
+	103: "PIM",
+	112: "VRRP",
+	115: "L2TP",
+	132: "SCTP",
+}
+
+func ipProtoName(v uint8) string {
+	if name, ok := ipProtoNames[v]; ok {
+		return name
 	}
+	return fmt.Sprintf("Proto %d", v)
+}
 
-	// Group by country
-	type countryAcc struct {
-		name  string
-		bytes uint64
-		ips   int
+// dscpNames maps DSCP code points to their RFC names; anything else is
+// rendered numerically.
+var dscpNames = map[uint8]string{
+	0: "BE", 8: "CS1", 16: "CS2", 24: "CS3", 32: "CS4", 40: "CS5",
+	48: "CS6", 56: "CS7", 46: "EF", 44: "VA",
+	10: "AF11", 12: "AF12", 14: "AF13", 18: "AF21", 20: "AF22", 22: "AF23",
+	26: "AF31", 28: "AF32", 30: "AF33", 34: "AF41", 36: "AF42", 38: "AF43",
+	1: "LE",
+}
+
+func dscpName(v uint8) string {
+	if name, ok := dscpNames[v]; ok {
+		return name
 	}
-	countries := make(map[string]*countryAcc)
-	for ip, bytes := range ipTotals {
-		geo := t.geoDB.Lookup(ip)
-		cc := "XX"
-		cname := "Unknown"
-		if geo != nil && geo.Country != "" {
-			cc = geo.Country
-			cname = geo.CountryName
+	return fmt.Sprintf("DSCP %d", v)
+}
+
+// GetDSCPBreakdown returns accumulated bytes per DSCP class over the 24h
+// window, so SQM/QoS users can verify traffic is marked as expected.
+func (t *Tracker) GetDSCPBreakdown() map[string]uint64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	totals := make(map[string]uint64)
+	for _, b := range t.buckets {
+		for class, bytes := range b.dscpBytes {
+			totals[class] += bytes
 		}
-		if _, ok := countries[cc]; !ok {
-			countries[cc] = &countryAcc{name: cname}
+	}
+	if t.current != nil {
+		for class, bytes := range t.current.dscpBytes {
+			totals[class] += bytes
 		}
-		countries[cc].bytes += bytes
-		countries[cc].ips++
 	}
+	return totals
+}
 
-	result := make([]CountryStat, 0, len(countries))
-	for cc, acc := range countries {
-		result = append(result, CountryStat{
-			Country:     cc,
-			CountryName: acc.name,
-			Bytes:       acc.bytes,
-			Connections: acc.ips,
-		})
+// CountryStat holds per-country traffic totals.
+type CountryStat struct {
+	Country     string `json:"country"`
+	CountryName string `json:"country_name"`
+	Bytes       uint64 `json:"bytes"`
+	Connections int    `json:"connections"`
+}
+
+// GetCountryBreakdown returns traffic grouped by country over the
+// retained window, capped at limit entries (<=0 returns everything).
+// Served from the precomputed rotation-time snapshot, so a read is
+// O(1); the in-progress bucket is folded in when it finishes.
+func (t *Tracker) GetCountryBreakdown(limit int) []CountryStat {
+	if t.geoDB == nil || !t.geoDB.Available() {
+		return nil
 	}
-	sort.Slice(result, func(i, j int) bool {
-		return result[i].Bytes > result[j].Bytes
-	})
-	if len(result) > 20 {
-		result = result[:20]
+
+	t.aggMu.RLock()
+	defer t.aggMu.RUnlock()
+
+	result := t.aggCountries
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
 	}
 	return result
 }
@@ -502,7 +1079,17 @@ func (t *Tracker) enrichGeo(s *TalkerStat) {
 	if t.geoDB == nil {
 		return
 	}
-	geo := t.geoDB.Lookup(s.IP)
+	var geo *geoip.Result
+	if t.budget == nil {
+		geo = t.geoDB.Lookup(s.IP)
+	} else if geo = t.geoDB.LookupCached(s.IP); geo == nil {
+		// Cache miss costs an MMDB read — only within budget
+		if t.budget.allow() {
+			geo = t.geoDB.Lookup(s.IP)
+		} else {
+			s.Pending = true
+		}
+	}
 	if geo == nil {
 		return
 	}
@@ -520,65 +1107,21 @@ type ASNStat struct {
 	Connections int    `json:"connections"`
 }
 
-// GetASNBreakdown returns traffic grouped by autonomous system over the 24h window.
-func (t *Tracker) GetASNBreakdown() []ASNStat {
+// GetASNBreakdown returns traffic grouped by autonomous system over the
+// retained window, capped at limit entries (<=0 returns everything).
+// Served from the precomputed rotation-time snapshot like
+// GetCountryBreakdown.
+func (t *Tracker) GetASNBreakdown(limit int) []ASNStat {
 	if t.geoDB == nil || !t.geoDB.Available() {
 		return nil
 	}
 
-	t.mu.RLock()
-	defer t.mu.RUnlock()
+	t.aggMu.RLock()
+	defer t.aggMu.RUnlock()
 
-	ipTotals := make(map[string]uint64)
-	for _, b := range t.buckets {
-		for ip, acc := range b.hosts {
-			ipTotals[ip] += acc.bytes
-		}
-	}
-	if t.current != nil {
-		for ip, acc := range t.current.hosts {
-			ipTotals[ip] += acc.bytes
-		}
-	}
-
-	type asnAcc struct {
-		org   string
-		bytes uint64
-		ips   int
-	}
-	asns := make(map[uint]*asnAcc)
-	for ip, bytes := range ipTotals {
-		geo := t.geoDB.Lookup(ip)
-		var asn uint
-		var org string
-		if geo != nil && geo.ASN != 0 {
-			asn = geo.ASN
-			org = geo.ASOrg
-		}
-		if asn == 0 {
-			continue
-		}
-		if _, ok := asns[asn]; !ok {
-			asns[asn] = &asnAcc{org: org}
-		}
-		asns[asn].bytes += bytes
-		asns[asn].ips++
-	}
-
-	result := make([]ASNStat, 0, len(asns))
-	for asn, acc := range asns {
-		result = append(result, ASNStat{
-			ASN:         asn,
-			ASOrg:       acc.org,
-			Bytes:       acc.bytes,
-			Connections: acc.ips,
-		})
-	}
-	sort.Slice(result, func(i, j int) bool {
-		return result[i].Bytes > result[j].Bytes
-	})
-	if len(result) > 20 {
-		result = result[:20]
+	result := t.aggASNs
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
 	}
 	return result
 }
@@ -0,0 +1,109 @@
+package talkers
+
+import (
+	"sort"
+	"time"
+)
+
+// hopChangeThreshold is how many hops the estimate must move before a
+// change event fires — ECMP paths routinely wobble by a hop or two.
+const hopChangeThreshold = 3
+
+// maxTTLEvents bounds the retained hop-change history.
+const maxTTLEvents = 200
+
+// ttlInfo is the per-remote-host TTL observation state.
+type ttlInfo struct {
+	ttl      uint8 // last arriving TTL
+	hops     int
+	lastSeen time.Time
+}
+
+// TTLEvent records a sudden hop-distance change for a remote host —
+// usually a route change, occasionally something nastier like spoofing.
+type TTLEvent struct {
+	Timestamp int64  `json:"timestamp"`
+	IP        string `json:"ip"`
+	OldHops   int    `json:"old_hops"`
+	NewHops   int    `json:"new_hops"`
+}
+
+// HopStat is one bar of the hop-distance distribution.
+type HopStat struct {
+	Hops  int `json:"hops"`
+	Hosts int `json:"hosts"`
+}
+
+// estimateHops converts an arriving TTL into a hop count by assuming
+// the sender started from the nearest common initial TTL (64, 128, 255).
+func estimateHops(ttl uint8) int {
+	for _, initial := range []int{64, 128, 255} {
+		if int(ttl) <= initial {
+			return initial - int(ttl)
+		}
+	}
+	return 0
+}
+
+// observeTTL updates the hop estimate for a remote host and flags large
+// jumps. Caller holds t.mu; ip is the (possibly anonymized) host key.
+func (t *Tracker) observeTTL(ip string, ttl uint8) {
+	now := time.Now()
+	hops := estimateHops(ttl)
+	info, ok := t.ttls[ip]
+	if !ok {
+		t.ttls[ip] = &ttlInfo{ttl: ttl, hops: hops, lastSeen: now}
+		return
+	}
+	if delta := hops - info.hops; delta >= hopChangeThreshold || -delta >= hopChangeThreshold {
+		t.ttlEvents = append(t.ttlEvents, TTLEvent{
+			Timestamp: now.UnixMilli(),
+			IP:        ip,
+			OldHops:   info.hops,
+			NewHops:   hops,
+		})
+		if len(t.ttlEvents) > maxTTLEvents {
+			t.ttlEvents = t.ttlEvents[len(t.ttlEvents)-maxTTLEvents:]
+		}
+	}
+	info.ttl = ttl
+	info.hops = hops
+	info.lastSeen = now
+}
+
+// pruneTTLs drops hosts not seen within the retention window. Caller
+// holds t.mu.
+func (t *Tracker) pruneTTLs(cutoff time.Time) {
+	for ip, info := range t.ttls {
+		if info.lastSeen.Before(cutoff) {
+			delete(t.ttls, ip)
+		}
+	}
+}
+
+// GetHopDistribution returns how many currently-tracked remote hosts sit
+// at each estimated hop distance.
+func (t *Tracker) GetHopDistribution() []HopStat {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	counts := make(map[int]int)
+	for _, info := range t.ttls {
+		counts[info.hops]++
+	}
+	out := make([]HopStat, 0, len(counts))
+	for hops, hosts := range counts {
+		out = append(out, HopStat{Hops: hops, Hosts: hosts})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Hops < out[j].Hops })
+	return out
+}
+
+// GetTTLEvents returns the retained hop-change events, newest last.
+func (t *Tracker) GetTTLEvents() []TTLEvent {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	out := make([]TTLEvent, len(t.ttlEvents))
+	copy(out, t.ttlEvents)
+	return out
+}
@@ -0,0 +1,294 @@
+// Package tracer periodically traces the network path to key destinations
+// and flags route changes or sudden per-hop latency increases, so "the
+// internet feels slow" can be pinned to a specific hop or a rerouted path.
+package tracer
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	maxHops        = 30
+	hopTimeout     = 1 * time.Second
+	maxChangeLog   = 200
+	latencySpikeMs = 20  // absolute increase before a hop is flagged
+	latencySpikeX  = 2.0 // relative increase before a hop is flagged
+)
+
+// Hop is a single hop in a traced path.
+type Hop struct {
+	TTL   int     `json:"ttl"`
+	IP    string  `json:"ip"` // "*" when the hop did not answer
+	RTTMs float64 `json:"rtt_ms"`
+}
+
+// PathStat is the most recent traced path for one target.
+type PathStat struct {
+	Target    string `json:"target"`
+	IP        string `json:"ip"`
+	Hops      []Hop  `json:"hops"`
+	Reached   bool   `json:"reached"`
+	Changes   int    `json:"changes"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+// PathEvent records a detected path change or hop latency spike.
+type PathEvent struct {
+	Target    string `json:"target"`
+	Type      string `json:"type"` // "path_change" or "latency_spike"
+	Detail    string `json:"detail"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+type target struct {
+	host string
+	addr *net.IPAddr
+
+	hops      []Hop
+	reached   bool
+	changes   int
+	updatedAt int64
+}
+
+// Tracer runs periodic traceroutes. Requires root or CAP_NET_RAW, same
+// as the capture subsystems.
+type Tracer struct {
+	interval time.Duration
+
+	mu      sync.RWMutex
+	targets []*target
+	events  []PathEvent
+
+	stopCh chan struct{}
+}
+
+// New creates a Tracer for the given hosts. Hosts that fail to resolve
+// are skipped with a log line.
+func New(hosts []string, interval time.Duration) *Tracer {
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	t := &Tracer{
+		interval: interval,
+		stopCh:   make(chan struct{}),
+	}
+	for _, h := range hosts {
+		addr, err := net.ResolveIPAddr("ip", h)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "tracer: cannot resolve %s: %v\n", h, err)
+			continue
+		}
+		t.targets = append(t.targets, &target{host: h, addr: addr})
+	}
+	return t
+}
+
+// Run traces all targets immediately and then on every interval tick.
+// It blocks until Stop() is called; intended to be launched as a goroutine.
+func (t *Tracer) Run() {
+	if len(t.targets) == 0 {
+		return
+	}
+	t.traceAll()
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			t.traceAll()
+		case <-t.stopCh:
+			return
+		}
+	}
+}
+
+// Stop signals the tracer to shut down.
+func (t *Tracer) Stop() {
+	select {
+	case <-t.stopCh:
+	default:
+		close(t.stopCh)
+	}
+}
+
+// GetAll returns the latest traced path for every target.
+func (t *Tracer) GetAll() []PathStat {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	out := make([]PathStat, 0, len(t.targets))
+	for _, tg := range t.targets {
+		hops := make([]Hop, len(tg.hops))
+		copy(hops, tg.hops)
+		out = append(out, PathStat{
+			Target:    tg.host,
+			IP:        tg.addr.IP.String(),
+			Hops:      hops,
+			Reached:   tg.reached,
+			Changes:   tg.changes,
+			UpdatedAt: tg.updatedAt,
+		})
+	}
+	return out
+}
+
+// GetEvents returns recent path change / latency spike events, newest last.
+func (t *Tracer) GetEvents() []PathEvent {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	cp := make([]PathEvent, len(t.events))
+	copy(cp, t.events)
+	return cp
+}
+
+// ---------- internal ----------
+
+func (t *Tracer) traceAll() {
+	for _, tg := range t.targets {
+		select {
+		case <-t.stopCh:
+			return
+		default:
+		}
+		hops, reached := t.trace(tg.addr)
+		t.mu.Lock()
+		t.diff(tg, hops)
+		tg.hops = hops
+		tg.reached = reached
+		tg.updatedAt = time.Now().UnixMilli()
+		t.mu.Unlock()
+	}
+}
+
+// trace performs one TTL-stepped ICMP echo traceroute to addr.
+func (t *Tracer) trace(addr *net.IPAddr) ([]Hop, bool) {
+	network, proto := "ip4:icmp", 1
+	var typ icmp.Type = ipv4.ICMPTypeEcho
+	v6 := addr.IP.To4() == nil
+	if v6 {
+		network, proto = "ip6:ipv6-icmp", 58
+		typ = ipv6.ICMPTypeEchoRequest
+	}
+
+	conn, err := icmp.ListenPacket(network, "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tracer: cannot open ICMP socket: %v\n", err)
+		return nil, false
+	}
+	defer conn.Close()
+
+	echoID := os.Getpid() & 0xffff
+	buf := make([]byte, 1500)
+	var hops []Hop
+
+	for ttl := 1; ttl <= maxHops; ttl++ {
+		if v6 {
+			conn.IPv6PacketConn().SetHopLimit(ttl)
+		} else {
+			conn.IPv4PacketConn().SetTTL(ttl)
+		}
+
+		msg := icmp.Message{
+			Type: typ,
+			Body: &icmp.Echo{ID: echoID, Seq: ttl, Data: []byte("bandwidth-monitor")},
+		}
+		wire, _ := msg.Marshal(nil)
+
+		sentAt := time.Now()
+		if _, err := conn.WriteTo(wire, addr); err != nil {
+			hops = append(hops, Hop{TTL: ttl, IP: "*"})
+			continue
+		}
+
+		hop := Hop{TTL: ttl, IP: "*"}
+		reached := false
+		conn.SetReadDeadline(sentAt.Add(hopTimeout))
+		for {
+			n, peer, err := conn.ReadFrom(buf)
+			if err != nil {
+				break
+			}
+			rm, err := icmp.ParseMessage(proto, buf[:n])
+			if err != nil {
+				continue
+			}
+			switch rm.Type {
+			case ipv4.ICMPTypeTimeExceeded, ipv6.ICMPTypeTimeExceeded:
+				hop.IP = peer.String()
+				hop.RTTMs = float64(time.Since(sentAt).Microseconds()) / 1000
+			case ipv4.ICMPTypeEchoReply, ipv6.ICMPTypeEchoReply:
+				echo, ok := rm.Body.(*icmp.Echo)
+				if !ok || echo.ID != echoID {
+					continue
+				}
+				hop.IP = peer.String()
+				hop.RTTMs = float64(time.Since(sentAt).Microseconds()) / 1000
+				reached = true
+			default:
+				continue
+			}
+			break
+		}
+		hops = append(hops, hop)
+		if reached {
+			return hops, true
+		}
+	}
+	return hops, false
+}
+
+// diff compares a fresh trace against the stored one and records events
+// for path changes and per-hop latency spikes. Caller holds t.mu.
+func (t *Tracer) diff(tg *target, fresh []Hop) {
+	if len(tg.hops) == 0 {
+		return
+	}
+	now := time.Now().UnixMilli()
+	for i := 0; i < len(fresh) && i < len(tg.hops); i++ {
+		old, cur := tg.hops[i], fresh[i]
+		if old.IP != cur.IP && old.IP != "*" && cur.IP != "*" {
+			tg.changes++
+			t.addEvent(PathEvent{
+				Target:    tg.host,
+				Type:      "path_change",
+				Detail:    fmt.Sprintf("hop %d changed %s → %s", cur.TTL, old.IP, cur.IP),
+				Timestamp: now,
+			})
+			return // one path-change event per trace is enough
+		}
+		if old.IP == cur.IP && old.RTTMs > 0 &&
+			cur.RTTMs > old.RTTMs*latencySpikeX && cur.RTTMs-old.RTTMs > latencySpikeMs {
+			t.addEvent(PathEvent{
+				Target:    tg.host,
+				Type:      "latency_spike",
+				Detail:    fmt.Sprintf("hop %d (%s) %.1fms → %.1fms", cur.TTL, cur.IP, old.RTTMs, cur.RTTMs),
+				Timestamp: now,
+			})
+		}
+	}
+	if len(fresh) != len(tg.hops) {
+		tg.changes++
+		t.addEvent(PathEvent{
+			Target:    tg.host,
+			Type:      "path_change",
+			Detail:    fmt.Sprintf("path length changed %d → %d hops", len(tg.hops), len(fresh)),
+			Timestamp: now,
+		})
+	}
+}
+
+// addEvent appends to the bounded change log. Caller holds t.mu.
+func (t *Tracer) addEvent(ev PathEvent) {
+	t.events = append(t.events, ev)
+	if len(t.events) > maxChangeLog {
+		t.events = t.events[len(t.events)-maxChangeLog:]
+	}
+}
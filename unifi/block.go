@@ -0,0 +1,61 @@
+package unifi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// BlockDevice blocks the client with this IP at the controller
+// (block-sta), used for budget enforcement. The MAC comes from the
+// cached client list, so a device the controller hasn't reported yet
+// can't be blocked.
+func (c *Client) BlockDevice(ip string) error {
+	return c.staCommand(ip, "block-sta")
+}
+
+// UnblockDevice lifts a block placed by BlockDevice.
+func (c *Client) UnblockDevice(ip string) error {
+	return c.staCommand(ip, "unblock-sta")
+}
+
+func (c *Client) staCommand(ip, cmd string) error {
+	// Snapshot everything the poll goroutine's login() can rewrite —
+	// this runs on the budget enforcer's goroutine. A token going stale
+	// right after the snapshot just 401s and the next check retries.
+	mac := ""
+	c.mu.RLock()
+	if c.summary != nil {
+		for _, cl := range c.summary.Clients {
+			if cl.IP == ip {
+				mac = cl.MAC
+				break
+			}
+		}
+	}
+	url := c.apiPrefix() + "/cmd/stamgr"
+	unifiOS, csrfToken := c.unifiOS, c.csrfToken
+	c.mu.RUnlock()
+	if mac == "" {
+		return fmt.Errorf("no client with IP %s in controller data", ip)
+	}
+
+	payload, _ := json.Marshal(map[string]string{"cmd": cmd, "mac": mac})
+	req, _ := http.NewRequest("POST", url, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	if unifiOS && csrfToken != "" {
+		req.Header.Set("X-CSRF-Token", csrfToken)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("POST %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s %s: status %d: %s", cmd, mac, resp.StatusCode, string(body))
+	}
+	return nil
+}
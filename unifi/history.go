@@ -0,0 +1,104 @@
+package unifi
+
+import "time"
+
+const (
+	historyMaxAge  = 24 * time.Hour
+	historyBucket  = 1 * time.Minute
+	historyMaxKeys = 256 // safety cap on tracked SSIDs + clients
+)
+
+// HistoryPoint is one per-minute rate sample for an SSID or client.
+type HistoryPoint struct {
+	Timestamp int64   `json:"t"`
+	TxRate    float64 `json:"tx_rate"`
+	RxRate    float64 `json:"rx_rate"`
+	Clients   int     `json:"clients,omitempty"` // SSID series only
+	Signal    int     `json:"signal,omitempty"`  // client series only
+}
+
+// History is the 24-hour per-minute rate history, keyed by SSID name and
+// client MAC respectively.
+type History struct {
+	SSIDs   map[string][]HistoryPoint `json:"ssids"`
+	Clients map[string][]HistoryPoint `json:"clients"`
+}
+
+// GetHistory returns a copy of the rate history rings.
+func (c *Client) GetHistory() *History {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	out := &History{
+		SSIDs:   make(map[string][]HistoryPoint, len(c.ssidHistory)),
+		Clients: make(map[string][]HistoryPoint, len(c.cliHistory)),
+	}
+	for name, pts := range c.ssidHistory {
+		cp := make([]HistoryPoint, len(pts))
+		copy(cp, pts)
+		out.SSIDs[name] = cp
+	}
+	for mac, pts := range c.cliHistory {
+		cp := make([]HistoryPoint, len(pts))
+		copy(cp, pts)
+		out.Clients[mac] = cp
+	}
+	return out
+}
+
+// recordHistory samples the summary's rates into the history rings, at
+// most once per bucket. Caller holds c.mu.
+func (c *Client) recordHistory(sum *Summary, now time.Time) {
+	if now.Sub(c.lastHistory) < historyBucket {
+		return
+	}
+	c.lastHistory = now
+	if c.ssidHistory == nil {
+		c.ssidHistory = make(map[string][]HistoryPoint)
+		c.cliHistory = make(map[string][]HistoryPoint)
+	}
+	ts := now.UnixMilli()
+	for _, s := range sum.SSIDs {
+		if _, ok := c.ssidHistory[s.Name]; !ok && len(c.ssidHistory) >= historyMaxKeys {
+			continue
+		}
+		c.ssidHistory[s.Name] = append(c.ssidHistory[s.Name], HistoryPoint{
+			Timestamp: ts,
+			TxRate:    s.TxRate,
+			RxRate:    s.RxRate,
+			Clients:   s.NumClients,
+		})
+	}
+	for _, cl := range sum.Clients {
+		if _, ok := c.cliHistory[cl.MAC]; !ok && len(c.cliHistory) >= historyMaxKeys {
+			continue
+		}
+		c.cliHistory[cl.MAC] = append(c.cliHistory[cl.MAC], HistoryPoint{
+			Timestamp: ts,
+			TxRate:    cl.TxRate,
+			RxRate:    cl.RxRate,
+			Signal:    cl.Signal,
+		})
+	}
+
+	// Prune old points; keys whose ring empties out (SSID removed,
+	// client gone for a day) are dropped entirely
+	cutoff := now.Add(-historyMaxAge).UnixMilli()
+	pruneHistory(c.ssidHistory, cutoff)
+	pruneHistory(c.cliHistory, cutoff)
+}
+
+func pruneHistory(m map[string][]HistoryPoint, cutoff int64) {
+	for key, pts := range m {
+		idx := 0
+		for idx < len(pts) && pts[idx].Timestamp < cutoff {
+			idx++
+		}
+		if idx == len(pts) {
+			delete(m, key)
+			continue
+		}
+		if idx > 0 {
+			m[key] = pts[idx:]
+		}
+	}
+}
@@ -2,7 +2,6 @@ package unifi
 
 import (
 	"bytes"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -12,6 +11,9 @@ import (
 	"sort"
 	"sync"
 	"time"
+
+	"bandwidth-monitor/httpclient"
+	"bandwidth-monitor/integration"
 )
 
 type Client struct {
@@ -21,6 +23,7 @@ type Client struct {
 	site       string
 	interval   time.Duration
 	httpClient *http.Client
+	breaker    *integration.Breaker
 	mu         sync.RWMutex
 	summary    *Summary
 	stopCh     chan struct{}
@@ -36,6 +39,11 @@ type Client struct {
 	prevAP   map[string]byteSnap // keyed by MAC
 	prevSSID map[string]byteSnap // keyed by SSID name
 	prevCli  map[string]byteSnap // keyed by client MAC
+
+	// per-SSID / per-client rate history (see history.go)
+	lastHistory time.Time
+	ssidHistory map[string][]HistoryPoint
+	cliHistory  map[string][]HistoryPoint
 }
 
 type byteSnap struct {
@@ -56,6 +64,20 @@ type APInfo struct {
 	RxBytes    int64   `json:"rx_bytes"`
 	TxRate     float64 `json:"tx_rate"`
 	RxRate     float64 `json:"rx_rate"`
+
+	// MeshUplink is set for APs whose uplink is wireless — mesh backhaul
+	// is often the hidden bandwidth bottleneck.
+	MeshUplink *MeshUplink `json:"mesh_uplink,omitempty"`
+}
+
+// MeshUplink describes the wireless backhaul link of a meshed AP.
+type MeshUplink struct {
+	APMAC   string `json:"ap_mac"`
+	APName  string `json:"ap_name,omitempty"`
+	Channel int    `json:"channel"`
+	Signal  int    `json:"signal"`  // dBm
+	TxRate  int64  `json:"tx_rate"` // PHY rate, kbps
+	RxRate  int64  `json:"rx_rate"`
 }
 
 type SSIDStat struct {
@@ -96,20 +118,18 @@ func New(baseURL, user, pass, site string, pollInterval time.Duration) *Client {
 		site = "default"
 	}
 	jar, _ := cookiejar.New(nil)
+	// Controllers ship self-signed certificates; skip verification
+	hc := httpclient.NewInsecure(15 * time.Second)
+	hc.Jar = jar
 	return &Client{
-		baseURL:  baseURL,
-		user:     user,
-		pass:     pass,
-		site:     site,
-		interval: pollInterval,
-		httpClient: &http.Client{
-			Timeout: 15 * time.Second,
-			Jar:     jar,
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-			},
-		},
-		stopCh: make(chan struct{}),
+		baseURL:    baseURL,
+		user:       user,
+		pass:       pass,
+		site:       site,
+		interval:   pollInterval,
+		httpClient: hc,
+		breaker:    integration.NewBreaker("unifi"),
+		stopCh:     make(chan struct{}),
 	}
 }
 
@@ -141,6 +161,15 @@ func (c *Client) GetSummary() *Summary {
 	return c.summary
 }
 
+// SetSummary replaces the cached summary directly, bypassing polling.
+// Used by demo mode to serve fabricated WiFi data.
+func (c *Client) SetSummary(s *Summary) {
+	c.mu.Lock()
+	c.summary = s
+	c.recordHistory(s, time.Now())
+	c.mu.Unlock()
+}
+
 func (c *Client) Available() bool {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -148,11 +177,27 @@ func (c *Client) Available() bool {
 }
 
 func (c *Client) poll() {
+	if !c.breaker.Allow() {
+		return
+	}
+	if err := c.pollOnce(); err != nil {
+		log.Printf("unifi: %v", err)
+		c.breaker.Failure(err)
+		return
+	}
+	c.breaker.Success()
+}
+
+// Health reports the circuit-breaker state for the health endpoint.
+func (c *Client) Health() integration.BreakerStatus {
+	return c.breaker.Status()
+}
+
+func (c *Client) pollOnce() error {
 	// Only login if we don't have a session yet
 	if !c.loggedIn {
 		if err := c.login(); err != nil {
-			log.Printf("unifi: login failed: %v", err)
-			return
+			return fmt.Errorf("login failed: %w", err)
 		}
 	}
 	devices, err := c.fetchDevices()
@@ -161,19 +206,16 @@ func (c *Client) poll() {
 		log.Printf("unifi: fetch devices: %v (re-authenticating)", err)
 		c.loggedIn = false
 		if err := c.login(); err != nil {
-			log.Printf("unifi: re-login failed: %v", err)
-			return
+			return fmt.Errorf("re-login failed: %w", err)
 		}
 		devices, err = c.fetchDevices()
 		if err != nil {
-			log.Printf("unifi: fetch devices after re-login: %v", err)
-			return
+			return fmt.Errorf("fetch devices after re-login: %w", err)
 		}
 	}
 	clients, err := c.fetchClients()
 	if err != nil {
-		log.Printf("unifi: fetch clients: %v", err)
-		return
+		return fmt.Errorf("fetch clients: %w", err)
 	}
 
 	now := time.Now()
@@ -204,7 +246,9 @@ func (c *Client) poll() {
 	c.prevSSID = newSSID
 	c.prevCli = newCli
 	c.lastPoll = now
+	c.recordHistory(sum, now)
 	c.mu.Unlock()
+	return nil
 }
 
 func (c *Client) login() error {
@@ -222,10 +266,14 @@ func (c *Client) login() error {
 			io.Copy(io.Discard, resp.Body)
 			resp.Body.Close()
 			if resp.StatusCode == http.StatusOK {
+				// unifiOS and csrfToken are also read by staCommand on
+				// the budget enforcer's goroutine
+				c.mu.Lock()
 				c.unifiOS = true
 				c.detected = true
 				c.loggedIn = true
 				c.csrfToken = resp.Header.Get("X-CSRF-Token")
+				c.mu.Unlock()
 				log.Printf("unifi: detected UniFi OS controller")
 				return nil
 			}
@@ -241,9 +289,11 @@ func (c *Client) login() error {
 		if resp.StatusCode != http.StatusOK {
 			return fmt.Errorf("login returned status %d", resp.StatusCode)
 		}
+		c.mu.Lock()
 		c.unifiOS = false
 		c.detected = true
 		c.loggedIn = true
+		c.mu.Unlock()
 		log.Printf("unifi: detected legacy controller")
 		return nil
 	}
@@ -259,10 +309,12 @@ func (c *Client) login() error {
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("login returned status %d", resp.StatusCode)
 	}
+	c.mu.Lock()
 	if c.unifiOS {
 		c.csrfToken = resp.Header.Get("X-CSRF-Token")
 	}
 	c.loggedIn = true
+	c.mu.Unlock()
 	return nil
 }
 
@@ -299,6 +351,15 @@ type rawDevice struct {
 	Uptime  int64  `json:"uptime"`
 	TxBytes int64  `json:"tx_bytes"`
 	RxBytes int64  `json:"rx_bytes"`
+	Uplink  struct {
+		Type      string `json:"type"` // "wire" or "wireless"
+		UplinkMAC string `json:"uplink_mac"`
+		RemoteMAC string `json:"uplink_remote_mac"`
+		Channel   int    `json:"channel"`
+		Signal    int    `json:"signal"`
+		TxRate    int64  `json:"tx_rate"`
+		RxRate    int64  `json:"rx_rate"`
+	} `json:"uplink"`
 }
 
 type clientResponse struct {
@@ -402,6 +463,19 @@ func (c *Client) buildSummary(devices []rawDevice, clients []rawClient, dt float
 				}
 			}
 		}
+		if d.Uplink.Type == "wireless" {
+			uplinkMAC := d.Uplink.UplinkMAC
+			if uplinkMAC == "" {
+				uplinkMAC = d.Uplink.RemoteMAC
+			}
+			ap.MeshUplink = &MeshUplink{
+				APMAC:   uplinkMAC,
+				Channel: d.Uplink.Channel,
+				Signal:  d.Uplink.Signal,
+				TxRate:  d.Uplink.TxRate,
+				RxRate:  d.Uplink.RxRate,
+			}
+		}
 		aps = append(aps, ap)
 	}
 	sort.Slice(aps, func(i, j int) bool { return aps[i].Name < aps[j].Name })
@@ -455,6 +529,13 @@ func (c *Client) buildSummary(devices []rawDevice, clients []rawClient, dt float
 		apNames[ap.MAC] = ap.Name
 	}
 
+	// Resolve mesh uplink AP names now that the lookup exists
+	for i := range aps {
+		if up := aps[i].MeshUplink; up != nil {
+			up.APName = apNames[up.APMAC]
+		}
+	}
+
 	// Build per-client list (wireless only), sorted by total traffic descending
 	var clientInfos []ClientInfo
 	for _, cl := range clients {
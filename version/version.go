@@ -0,0 +1,163 @@
+// Package version holds build metadata stamped in via -ldflags and an
+// optional update check against GitHub releases.
+package version
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Stamped at build time via
+// -ldflags "-X bandwidth-monitor/version.Version=... -X bandwidth-monitor/version.Commit=... -X bandwidth-monitor/version.Date=..."
+var (
+	Version = "dev"
+	Commit  = "none"
+	Date    = "unknown"
+)
+
+const (
+	releasesURL   = "https://api.github.com/repos/yeled/bandwidth-monitor/releases/latest"
+	checkInterval = 24 * time.Hour
+)
+
+// Info is the /api/version payload.
+type Info struct {
+	Version         string `json:"version"`
+	Commit          string `json:"commit"`
+	Date            string `json:"date"`
+	GoVersion       string `json:"go_version"`
+	UpdateAvailable bool   `json:"update_available"`
+	LatestVersion   string `json:"latest_version,omitempty"`
+	ReleaseURL      string `json:"release_url,omitempty"`
+}
+
+// String returns the one-line form logged at startup.
+func String() string {
+	return fmt.Sprintf("%s (commit %s, built %s, %s)", Version, Commit, Date, runtime.Version())
+}
+
+// Checker periodically compares the running version against the latest
+// GitHub release.
+type Checker struct {
+	httpClient *http.Client
+
+	mu     sync.RWMutex
+	latest string
+	url    string
+
+	stopCh chan struct{}
+}
+
+func NewChecker() *Checker {
+	return &Checker{
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+		stopCh:     make(chan struct{}),
+	}
+}
+
+// Run checks immediately and then daily. Call in a goroutine.
+func (c *Checker) Run() {
+	c.check()
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.check()
+		case <-c.stopCh:
+			return
+		}
+	}
+}
+
+func (c *Checker) Stop() {
+	select {
+	case <-c.stopCh:
+	default:
+		close(c.stopCh)
+	}
+}
+
+// Get returns build info, including update status when a Checker ran.
+// Safe on a nil receiver (update check disabled).
+func (c *Checker) Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+	}
+	if c == nil {
+		return info
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.latest != "" && newer(c.latest, Version) {
+		info.UpdateAvailable = true
+		info.LatestVersion = c.latest
+		info.ReleaseURL = c.url
+	}
+	return info
+}
+
+func (c *Checker) check() {
+	resp, err := c.httpClient.Get(releasesURL)
+	if err != nil {
+		log.Printf("version: update check: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("version: update check: status %d", resp.StatusCode)
+		return
+	}
+	var rel struct {
+		TagName string `json:"tag_name"`
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rel); err != nil {
+		log.Printf("version: update check: decode: %v", err)
+		return
+	}
+	if rel.TagName == "" {
+		return
+	}
+
+	c.mu.Lock()
+	c.latest = rel.TagName
+	c.url = rel.HTMLURL
+	c.mu.Unlock()
+	if newer(rel.TagName, Version) {
+		log.Printf("version: update available: %s (running %s)", rel.TagName, Version)
+	}
+}
+
+// newer reports whether release tag a is newer than running version b,
+// comparing dotted numeric components ("v1.2.3" vs "1.2"). Non-release
+// builds ("dev") never flag an update.
+func newer(a, b string) bool {
+	if b == "dev" {
+		return false
+	}
+	pa := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	pb := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var na, nb int
+		if i < len(pa) {
+			fmt.Sscanf(pa[i], "%d", &na)
+		}
+		if i < len(pb) {
+			fmt.Sscanf(pb[i], "%d", &nb)
+		}
+		if na != nb {
+			return na > nb
+		}
+	}
+	return false
+}
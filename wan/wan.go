@@ -0,0 +1,263 @@
+// Package wan tracks which interface currently carries the IPv4
+// default route, for multi-WAN setups with failover. Route changes are
+// recorded as timestamped failover events and the active-WAN timeline
+// is served alongside them, so usage graphs can be annotated with which
+// uplink the traffic actually left through.
+package wan
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"bandwidth-monitor/events"
+)
+
+const (
+	defaultInterval = 5 * time.Second
+	maxEvents       = 200 // retained failover history
+)
+
+// FailoverEvent is one default-route change.
+type FailoverEvent struct {
+	Timestamp int64  `json:"timestamp"` // unix ms
+	From      string `json:"from,omitempty"`
+	To        string `json:"to"`
+	Gateway   string `json:"gateway,omitempty"`
+}
+
+// Span is one stretch of the active-WAN timeline; To is zero while the
+// span is still open.
+type Span struct {
+	Iface string `json:"iface"`
+	From  int64  `json:"from"` // unix ms
+	To    int64  `json:"to,omitempty"`
+}
+
+// Status is the externally visible state.
+type Status struct {
+	Active   string          `json:"active"` // empty when no default route
+	Gateway  string          `json:"gateway,omitempty"`
+	Since    int64           `json:"since,omitempty"` // unix ms
+	Events   []FailoverEvent `json:"events"`
+	Timeline []Span          `json:"timeline"`
+}
+
+// Monitor polls the routing table for the default-route interface.
+type Monitor struct {
+	interval time.Duration
+	bus      *events.Bus
+
+	mu       sync.RWMutex
+	active   string
+	gateway  string
+	since    time.Time
+	events   []FailoverEvent
+	timeline []Span
+	stopCh   chan struct{}
+}
+
+// New creates the monitor.
+func New(interval time.Duration) *Monitor {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	return &Monitor{
+		interval: interval,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// SetEventBus attaches the bus that failovers are published to. Call
+// before Run.
+func (m *Monitor) SetEventBus(b *events.Bus) { m.bus = b }
+
+// Run checks immediately and then on every tick. Call in a goroutine.
+func (m *Monitor) Run() {
+	m.check()
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.check()
+		case <-m.stopCh:
+			return
+		}
+	}
+}
+
+// Stop terminates the poll loop.
+func (m *Monitor) Stop() {
+	select {
+	case <-m.stopCh:
+	default:
+		close(m.stopCh)
+	}
+}
+
+func (m *Monitor) check() {
+	iface, gw, err := defaultRoute()
+	if err != nil {
+		// No route table (non-Linux) or no default route at all; both
+		// surface as an empty active interface
+		iface, gw = "", ""
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if iface == m.active {
+		m.gateway = gw
+		return
+	}
+
+	now := time.Now()
+
+	// Restart with the same WAN still active: reopen the timeline
+	// quietly rather than logging a spurious failover
+	if m.active == "" && iface != "" && len(m.events) > 0 && m.events[len(m.events)-1].To == iface {
+		m.timeline = append(m.timeline, Span{Iface: iface, From: now.UnixMilli()})
+		m.active, m.gateway, m.since = iface, gw, now
+		return
+	}
+
+	ev := FailoverEvent{Timestamp: now.UnixMilli(), From: m.active, To: iface, Gateway: gw}
+	m.events = append(m.events, ev)
+	if len(m.events) > maxEvents {
+		m.events = m.events[len(m.events)-maxEvents:]
+	}
+	if n := len(m.timeline); n > 0 && m.timeline[n-1].To == 0 {
+		m.timeline[n-1].To = now.UnixMilli()
+	}
+	if iface != "" {
+		m.timeline = append(m.timeline, Span{Iface: iface, From: now.UnixMilli()})
+		if len(m.timeline) > maxEvents {
+			m.timeline = m.timeline[len(m.timeline)-maxEvents:]
+		}
+	}
+
+	if m.active == "" {
+		log.Printf("wan: default route via %s (%s)", iface, gw)
+	} else if iface == "" {
+		log.Printf("wan: default route via %s lost", m.active)
+	} else {
+		log.Printf("wan: failover %s -> %s (%s)", m.active, iface, gw)
+	}
+	m.bus.Publish("wan", "failover", iface, failoverMessage(m.active, iface, gw))
+
+	m.active = iface
+	m.gateway = gw
+	m.since = now
+}
+
+func failoverMessage(from, to, gw string) string {
+	switch {
+	case from == "":
+		return fmt.Sprintf("default route via %s (%s)", to, gw)
+	case to == "":
+		return fmt.Sprintf("default route via %s lost", from)
+	default:
+		return fmt.Sprintf("default route moved from %s to %s (%s)", from, to, gw)
+	}
+}
+
+// GetStatus reports the active WAN, the failover history (newest last)
+// and the active-WAN timeline.
+func (m *Monitor) GetStatus() Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	st := Status{
+		Active:   m.active,
+		Gateway:  m.gateway,
+		Events:   make([]FailoverEvent, len(m.events)),
+		Timeline: make([]Span, len(m.timeline)),
+	}
+	if !m.since.IsZero() {
+		st.Since = m.since.UnixMilli()
+	}
+	copy(st.Events, m.events)
+	copy(st.Timeline, m.timeline)
+	return st
+}
+
+// defaultRoute parses /proc/net/route for the lowest-metric IPv4
+// default route.
+func defaultRoute() (iface, gateway string, err error) {
+	data, err := os.ReadFile("/proc/net/route")
+	if err != nil {
+		return "", "", err
+	}
+	const rtfUp, rtfGateway = 0x1, 0x2
+	bestMetric := -1
+	for _, line := range strings.Split(string(data), "\n")[1:] {
+		f := strings.Fields(line)
+		if len(f) < 8 || f[1] != "00000000" {
+			continue
+		}
+		flags, _ := strconv.ParseUint(f[3], 16, 32)
+		if flags&rtfUp == 0 || flags&rtfGateway == 0 {
+			continue
+		}
+		metric, _ := strconv.Atoi(f[6])
+		if bestMetric != -1 && metric >= bestMetric {
+			continue
+		}
+		bestMetric = metric
+		iface = f[0]
+		gateway = parseHexIP(f[2])
+	}
+	if iface == "" {
+		return "", "", fmt.Errorf("no default route")
+	}
+	return iface, gateway, nil
+}
+
+// parseHexIP decodes the little-endian hex addresses /proc/net/route
+// uses.
+func parseHexIP(s string) string {
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d.%d.%d.%d", byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+// Wire type for persistence: the failover history survives restarts,
+// the live route state is re-detected.
+
+type wanState struct {
+	Events   []FailoverEvent `json:"events"`
+	Timeline []Span          `json:"timeline"`
+}
+
+// ExportState serializes the failover history and timeline.
+func (m *Monitor) ExportState() ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return json.Marshal(wanState{Events: m.events, Timeline: m.timeline})
+}
+
+// ImportState restores history saved by ExportState. Call before Run.
+func (m *Monitor) ImportState(data []byte) error {
+	var st wanState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.events = st.Events
+	// A span left open by the previous run ends at its last save; the
+	// first check opens a fresh one
+	for i := range st.Timeline {
+		if st.Timeline[i].To == 0 {
+			st.Timeline[i].To = time.Now().UnixMilli()
+		}
+	}
+	m.timeline = st.Timeline
+	m.mu.Unlock()
+	return nil
+}
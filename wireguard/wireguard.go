@@ -0,0 +1,174 @@
+// Package wireguard enumerates WireGuard interfaces via wgctrl (netlink)
+// and exposes per-peer transfer counters, last-handshake age and endpoint —
+// the interface-level counter of wg0 hides which peer is active.
+package wireguard
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"golang.zx2c4.com/wireguard/wgctrl"
+)
+
+// PeerStat is the per-peer snapshot with rates computed between polls.
+type PeerStat struct {
+	PublicKey     string   `json:"public_key"`
+	Endpoint      string   `json:"endpoint,omitempty"`
+	AllowedIPs    []string `json:"allowed_ips"`
+	LastHandshake int64    `json:"last_handshake"` // unix ms, 0 = never
+	HandshakeAge  float64  `json:"handshake_age"`  // seconds, -1 = never
+	RxBytes       int64    `json:"rx_bytes"`
+	TxBytes       int64    `json:"tx_bytes"`
+	RxRate        float64  `json:"rx_rate"` // bytes/sec
+	TxRate        float64  `json:"tx_rate"`
+	Active        bool     `json:"active"` // handshake within the last 3 minutes
+}
+
+// InterfaceStat groups the peers of one WireGuard interface.
+type InterfaceStat struct {
+	Name       string     `json:"name"`
+	ListenPort int        `json:"listen_port"`
+	Peers      []PeerStat `json:"peers"`
+	Timestamp  int64      `json:"timestamp"`
+}
+
+type byteSnap struct {
+	rx int64
+	tx int64
+}
+
+// Client polls the kernel WireGuard state on a fixed interval.
+type Client struct {
+	interval time.Duration
+
+	mu       sync.RWMutex
+	stats    []InterfaceStat
+	prev     map[string]byteSnap // keyed by iface/pubkey
+	lastPoll time.Time
+
+	stopCh chan struct{}
+}
+
+// New creates a WireGuard stats client.
+func New(pollInterval time.Duration) *Client {
+	if pollInterval <= 0 {
+		pollInterval = 10 * time.Second
+	}
+	return &Client{
+		interval: pollInterval,
+		prev:     make(map[string]byteSnap),
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Run starts the polling loop. Call in a goroutine.
+func (c *Client) Run() {
+	c.poll()
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.poll()
+		case <-c.stopCh:
+			return
+		}
+	}
+}
+
+// Stop terminates the polling loop.
+func (c *Client) Stop() {
+	select {
+	case <-c.stopCh:
+	default:
+		close(c.stopCh)
+	}
+}
+
+// GetAll returns the latest per-interface peer stats.
+func (c *Client) GetAll() []InterfaceStat {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	cp := make([]InterfaceStat, len(c.stats))
+	copy(cp, c.stats)
+	return cp
+}
+
+// Available returns true if at least one WireGuard interface was found.
+func (c *Client) Available() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.stats) > 0
+}
+
+// ---------- internal ----------
+
+func (c *Client) poll() {
+	wg, err := wgctrl.New()
+	if err != nil {
+		log.Printf("wireguard: open wgctrl: %v", err)
+		return
+	}
+	defer wg.Close()
+
+	devices, err := wg.Devices()
+	if err != nil {
+		log.Printf("wireguard: list devices: %v", err)
+		return
+	}
+
+	now := time.Now()
+	dt := now.Sub(c.lastPoll).Seconds()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	newPrev := make(map[string]byteSnap)
+	stats := make([]InterfaceStat, 0, len(devices))
+	for _, dev := range devices {
+		is := InterfaceStat{
+			Name:       dev.Name,
+			ListenPort: dev.ListenPort,
+			Timestamp:  now.UnixMilli(),
+		}
+		for _, peer := range dev.Peers {
+			key := peer.PublicKey.String()
+			ps := PeerStat{
+				PublicKey: key,
+				RxBytes:   peer.ReceiveBytes,
+				TxBytes:   peer.TransmitBytes,
+			}
+			if peer.Endpoint != nil {
+				ps.Endpoint = peer.Endpoint.String()
+			}
+			for _, ipn := range peer.AllowedIPs {
+				ps.AllowedIPs = append(ps.AllowedIPs, ipn.String())
+			}
+			if peer.LastHandshakeTime.IsZero() {
+				ps.HandshakeAge = -1
+			} else {
+				ps.LastHandshake = peer.LastHandshakeTime.UnixMilli()
+				ps.HandshakeAge = now.Sub(peer.LastHandshakeTime).Seconds()
+				ps.Active = ps.HandshakeAge < 180
+			}
+			snapKey := fmt.Sprintf("%s/%s", dev.Name, key)
+			if prev, ok := c.prev[snapKey]; ok && dt > 0 && !c.lastPoll.IsZero() {
+				if ps.RxBytes >= prev.rx {
+					ps.RxRate = float64(ps.RxBytes-prev.rx) / dt
+				}
+				if ps.TxBytes >= prev.tx {
+					ps.TxRate = float64(ps.TxBytes-prev.tx) / dt
+				}
+			}
+			newPrev[snapKey] = byteSnap{rx: ps.RxBytes, tx: ps.TxBytes}
+			is.Peers = append(is.Peers, ps)
+		}
+		stats = append(stats, is)
+	}
+
+	c.stats = stats
+	c.prev = newPrev
+	c.lastPoll = now
+}
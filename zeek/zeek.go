@@ -0,0 +1,203 @@
+// Package zeek tails a Zeek conn.log (TSV or JSON) and feeds the
+// connection records into the talkers bucket pipeline as an alternative
+// flow source, for networks that already run Zeek on the mirror port.
+package zeek
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const pollInterval = 1 * time.Second
+
+// FlowSink receives parsed connection records; implemented by
+// talkers.Tracker.
+type FlowSink interface {
+	IngestFlow(srcIP, dstIP, proto, ipVersion string, bytes uint64)
+}
+
+// jsonRecord is the subset of a JSON conn.log line we decode.
+type jsonRecord struct {
+	OrigH     string `json:"id.orig_h"`
+	RespH     string `json:"id.resp_h"`
+	Proto     string `json:"proto"`
+	OrigBytes uint64 `json:"orig_bytes"`
+	RespBytes uint64 `json:"resp_bytes"`
+}
+
+// Tailer follows a conn.log file (surviving rotation) and forwards each
+// completed connection to the sink.
+type Tailer struct {
+	path string
+	sink FlowSink
+
+	mu     sync.Mutex
+	fields map[string]int // TSV column positions from the #fields header
+
+	stopCh chan struct{}
+}
+
+// New creates a Tailer for the given conn.log path.
+func New(path string, sink FlowSink) *Tailer {
+	return &Tailer{
+		path:   path,
+		sink:   sink,
+		stopCh: make(chan struct{}),
+	}
+}
+
+// Run tails the file until Stop() is called. Call in a goroutine.
+func (t *Tailer) Run() {
+	var (
+		f      *os.File
+		reader *bufio.Reader
+		size   int64
+	)
+	defer func() {
+		if f != nil {
+			f.Close()
+		}
+	}()
+
+	open := func(seekEnd bool) bool {
+		var err error
+		f, err = os.Open(t.path)
+		if err != nil {
+			return false
+		}
+		if seekEnd {
+			f.Seek(0, io.SeekEnd)
+		}
+		fi, _ := f.Stat()
+		if fi != nil {
+			size = fi.Size()
+		}
+		reader = bufio.NewReader(f)
+		return true
+	}
+	if !open(true) {
+		log.Printf("zeek: cannot open %s yet — waiting for it to appear", t.path)
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.stopCh:
+			return
+		case <-ticker.C:
+		}
+		if f == nil {
+			if !open(false) {
+				continue
+			}
+		}
+		// Detect truncation / rotation (Zeek rotates conn.log hourly)
+		if fi, err := os.Stat(t.path); err != nil || fi.Size() < size {
+			f.Close()
+			f = nil
+			t.mu.Lock()
+			t.fields = nil
+			t.mu.Unlock()
+			if !open(false) {
+				continue
+			}
+		}
+		for {
+			line, err := reader.ReadString('\n')
+			if len(line) > 0 {
+				size += int64(len(line))
+				t.ingest(strings.TrimRight(line, "\n"))
+			}
+			if err != nil {
+				break // EOF — wait for more data
+			}
+		}
+	}
+}
+
+// Stop terminates the tailer.
+func (t *Tailer) Stop() {
+	select {
+	case <-t.stopCh:
+	default:
+		close(t.stopCh)
+	}
+}
+
+// ---------- internal ----------
+
+func (t *Tailer) ingest(line string) {
+	if line == "" {
+		return
+	}
+	if line[0] == '{' {
+		var rec jsonRecord
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			return
+		}
+		t.forward(rec.OrigH, rec.RespH, rec.Proto, rec.OrigBytes+rec.RespBytes)
+		return
+	}
+	if line[0] == '#' {
+		// Remember TSV column layout from the #fields header
+		if strings.HasPrefix(line, "#fields\t") {
+			cols := strings.Split(line[len("#fields\t"):], "\t")
+			fields := make(map[string]int, len(cols))
+			for i, c := range cols {
+				fields[c] = i
+			}
+			t.mu.Lock()
+			t.fields = fields
+			t.mu.Unlock()
+		}
+		return
+	}
+
+	t.mu.Lock()
+	fields := t.fields
+	t.mu.Unlock()
+	if fields == nil {
+		return
+	}
+	cols := strings.Split(line, "\t")
+	get := func(name string) string {
+		idx, ok := fields[name]
+		if !ok || idx >= len(cols) || cols[idx] == "-" {
+			return ""
+		}
+		return cols[idx]
+	}
+	orig, _ := strconv.ParseUint(get("orig_bytes"), 10, 64)
+	resp, _ := strconv.ParseUint(get("resp_bytes"), 10, 64)
+	t.forward(get("id.orig_h"), get("id.resp_h"), get("proto"), orig+resp)
+}
+
+func (t *Tailer) forward(src, dst, proto string, bytes uint64) {
+	if src == "" || dst == "" || bytes == 0 {
+		return
+	}
+	ipVersion := "IPv4"
+	if ip := net.ParseIP(src); ip != nil && ip.To4() == nil {
+		ipVersion = "IPv6"
+	}
+	switch strings.ToLower(proto) {
+	case "tcp":
+		proto = "TCP"
+	case "udp":
+		proto = "UDP"
+	case "icmp":
+		proto = "ICMP"
+	default:
+		proto = "Other"
+	}
+	t.sink.IngestFlow(src, dst, proto, ipVersion, bytes)
+}